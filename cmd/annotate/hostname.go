@@ -47,7 +47,7 @@ func (c *CommandHostname) Run() error {
 		return err
 	}
 
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
@@ -31,7 +31,7 @@ country code.
 }
 
 func (c *CommandIcon) Run() error {
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,334 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/commands"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+type CommandIPAM struct {
+	commands.Base // embed
+
+	pool   string
+	prefix int
+}
+
+func init() {
+	base := commands.Base{
+		Flags: flag.NewFlagSet("ipam", flag.ExitOnError),
+		Usage: "ipam [OPTION]...",
+		Short: "annotate with IP addresses and default routes",
+		Long: `
+Assigns an IPv4 subnet to each network and a unique address from that subnet
+to each connected edge without an IP. Networks keep their cidr, if set.
+Otherwise, they use the subnet of most of their edges' existing IPs or, if
+there are none, the next subnet from the pool that doesn't overlap any subnet
+in use. Existing IPs without a prefix length, or with a /31 or /32, are
+assumed to be in a subnet of the -prefix length. Existing IPs are never
+changed or reused.
+
+The first host address in each subnet is reserved for the routers
+(router=true) on the network. Endpoints that aren't routers get a
+default_route via a router on one of their networks, if there is one.
+
+Run before "mac", which only annotates edges with IPs.
+`,
+	}
+
+	cmd := &CommandIPAM{Base: base}
+	cmd.Flags.StringVar(&cmd.pool, "pool", "10.0.0.0/8", "pool to allocate subnets from")
+	cmd.Flags.IntVar(&cmd.prefix, "prefix", 24, "prefix length of allocated subnets")
+
+	commands.Append(cmd)
+}
+
+// ipamEdge is an edge and the endpoint it belongs to
+type ipamEdge struct {
+	e    *minigraph.Endpoint
+	edge *minigraph.Edge
+}
+
+// allocator tracks the addresses and subnets that are in use
+type allocator struct {
+	used    map[string]bool
+	subnets []*net.IPNet
+}
+
+func (c *CommandIPAM) Run() error {
+	_, pool, err := net.ParseCIDR(c.pool)
+	if err != nil {
+		return err
+	}
+	if pool.IP.To4() == nil {
+		return fmt.Errorf("invalid pool, must be IPv4: %v", c.pool)
+	}
+	if ones, _ := pool.Mask.Size(); c.prefix < ones || c.prefix > 30 {
+		return fmt.Errorf("invalid prefix length for %v: %v", pool, c.prefix)
+	}
+
+	networks, err := dc.GetNetworks("", "")
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := GetEndpoints()
+	if err != nil {
+		return err
+	}
+
+	a := &allocator{
+		used: map[string]bool{},
+	}
+
+	// edges connected to each network
+	edges := map[int][]ipamEdge{}
+
+	for _, v := range endpoints {
+		for _, edge := range v.Edges {
+			if ip, ipnet := parseEdgeIP(edge.D["ip"]); ip != nil {
+				a.used[ip.String()] = true
+
+				if ipnet == nil {
+					ipnet = &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}
+				}
+				a.subnets = append(a.subnets, ipnet)
+			}
+
+			if edge.N != minigraph.UNCONNECTED {
+				edges[edge.N] = append(edges[edge.N], ipamEdge{v, edge})
+			}
+		}
+	}
+
+	for _, n := range networks {
+		if _, ipnet, err := net.ParseCIDR(n.D["cidr"]); err == nil {
+			a.subnets = append(a.subnets, ipnet)
+		}
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].NID < networks[j].NID
+	})
+
+	// router IPs on each network
+	gateways := map[int][]net.IP{}
+
+	for _, n := range networks {
+		es := edges[n.NID]
+		if len(es) == 0 {
+			continue
+		}
+
+		subnet := c.subnet(n, es)
+		if subnet != nil {
+			a.subnets = append(a.subnets, subnet)
+		} else if subnet = a.subnet(pool, c.prefix); subnet == nil {
+			return fmt.Errorf("no free /%v subnets left in %v", c.prefix, pool)
+		}
+
+		if _, ok := n.D["cidr"]; !ok {
+			log.Debug("updating network %v with cidr %v", n, subnet)
+
+			if err := c.updateNetwork(n, subnet); err != nil {
+				return err
+			}
+		}
+
+		ones, _ := subnet.Mask.Size()
+		gateway := uintToIP(ipToUint(subnet.IP) + 1)
+
+		// routers first so that they get the gateway
+		sort.SliceStable(es, func(i, j int) bool {
+			return isRouter(es[i].e) && !isRouter(es[j].e)
+		})
+
+		for _, v := range es {
+			router := isRouter(v.e)
+
+			if ip, _ := parseEdgeIP(v.edge.D["ip"]); ip != nil {
+				if router && subnet.Contains(ip) {
+					gateways[n.NID] = append(gateways[n.NID], ip)
+				}
+				continue
+			}
+
+			ip := gateway
+			if !router || a.used[ip.String()] {
+				ip = a.host(subnet, gateway)
+			}
+			if ip == nil {
+				return fmt.Errorf("no free addresses left in %v for network %v", subnet, n.NID)
+			}
+
+			a.used[ip.String()] = true
+			v.edge.D["ip"] = fmt.Sprintf("%v/%v", ip, ones)
+			log.Debug("updating node %v with ip %v", v.e, v.edge.D["ip"])
+
+			if router {
+				gateways[n.NID] = append(gateways[n.NID], ip)
+			}
+		}
+	}
+
+	for _, v := range endpoints {
+		if _, ok := v.D["default_route"]; !isRouter(v) && (!ok || *f_overwrite) {
+			for _, edge := range v.Edges {
+				if gw := gateways[edge.N]; edge.N != minigraph.UNCONNECTED && len(gw) > 0 {
+					v.D["default_route"] = gw[0].String()
+					log.Debug("updating node %v with default_route %v", v, v.D["default_route"])
+					break
+				}
+			}
+		}
+
+		if err := UpdateEndpoint(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subnet returns the network's cidr or the subnet used by most of the edges
+// with IPs, nil if neither is known
+func (c *CommandIPAM) subnet(n *minigraph.Network, es []ipamEdge) *net.IPNet {
+	if _, ipnet, err := net.ParseCIDR(n.D["cidr"]); err == nil && ipnet.IP.To4() != nil {
+		return ipnet
+	}
+
+	counts := map[string]int{}
+
+	var res *net.IPNet
+
+	for _, v := range es {
+		ip, ipnet := parseEdgeIP(v.edge.D["ip"])
+		if ip == nil {
+			continue
+		}
+
+		// /31s and /32s have no room for more hosts
+		if ipnet != nil {
+			if ones, _ := ipnet.Mask.Size(); ones > 30 {
+				ipnet = nil
+			}
+		}
+
+		// assume IPs without a prefix length are in an allocated subnet
+		if ipnet == nil {
+			mask := net.CIDRMask(c.prefix, 32)
+			ipnet = &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		}
+
+		counts[ipnet.String()]++
+		if res == nil || counts[ipnet.String()] > counts[res.String()] {
+			res = ipnet
+		}
+	}
+
+	return res
+}
+
+func (c *CommandIPAM) updateNetwork(n *minigraph.Network, subnet *net.IPNet) error {
+	n.D["cidr"] = subnet.String()
+
+	if *f_dryrun {
+		return nil
+	}
+
+	p := &minigraph.Patch{
+		Data: minigraph.DataDiff{
+			Set: map[string]string{"cidr": n.D["cidr"]},
+		},
+	}
+
+	_, err := dc.PatchNetwork(n.NID, p, 0)
+	return err
+}
+
+// subnet allocates the first subnet in the pool that doesn't overlap any of
+// the subnets in use
+func (a *allocator) subnet(pool *net.IPNet, prefix int) *net.IPNet {
+	ones, _ := pool.Mask.Size()
+
+	start := uint64(ipToUint(pool.IP))
+	end := start + 1<<uint(32-ones)
+	size := uint64(1) << uint(32-prefix)
+
+	for v := start; v < end; v += size {
+		s := &net.IPNet{
+			IP:   uintToIP(uint32(v)),
+			Mask: net.CIDRMask(prefix, 32),
+		}
+
+		var overlaps bool
+		for _, s2 := range a.subnets {
+			if s.Contains(s2.IP) || s2.Contains(s.IP) {
+				overlaps = true
+				break
+			}
+		}
+
+		if !overlaps {
+			a.subnets = append(a.subnets, s)
+			return s
+		}
+	}
+
+	return nil
+}
+
+// host returns the first unused host address in the subnet other than the
+// gateway, nil if there are none
+func (a *allocator) host(subnet *net.IPNet, gateway net.IP) net.IP {
+	ones, bits := subnet.Mask.Size()
+	start := ipToUint(subnet.IP)
+
+	// skip the network and broadcast addresses
+	for i := uint32(1); i < 1<<uint(bits-ones)-1; i++ {
+		ip := uintToIP(start + i)
+		if !ip.Equal(gateway) && !a.used[ip.String()] {
+			return ip
+		}
+	}
+
+	return nil
+}
+
+// parseEdgeIP parses an IPv4 address with an optional prefix length. The
+// IPNet is nil if there is no prefix length.
+func parseEdgeIP(s string) (net.IP, *net.IPNet) {
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		ip = net.ParseIP(s)
+	}
+
+	if ip == nil || ip.To4() == nil {
+		return nil, nil
+	}
+
+	return ip.To4(), ipnet
+}
+
+func isRouter(v *minigraph.Endpoint) bool {
+	return v.D["router"] == "true"
+}
+
+func ipToUint(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uintToIP(v uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, v)
+	return ip
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+func TestIPAMSubnet(t *testing.T) {
+	c := &CommandIPAM{prefix: 24}
+
+	tests := []struct {
+		cidr string
+		ips  []string
+		want string
+	}{
+		{"", nil, "<nil>"},
+		{"192.168.0.0/16", []string{"10.0.0.1/24"}, "192.168.0.0/16"},
+		{"", []string{"10.0.0.1/24", "10.0.1.1/24", "10.0.1.2/24"}, "10.0.1.0/24"},
+		{"", []string{"10.0.0.1"}, "10.0.0.0/24"},
+		{"", []string{"10.0.0.1/28"}, "10.0.0.0/28"},
+		{"", []string{"10.0.0.1/32"}, "10.0.0.0/24"},
+		{"", []string{"10.0.0.0/31", "10.0.0.1/31"}, "10.0.0.0/24"},
+	}
+
+	for _, test := range tests {
+		n := &minigraph.Network{D: map[string]string{}}
+		if test.cidr != "" {
+			n.D["cidr"] = test.cidr
+		}
+
+		var es []ipamEdge
+		for _, ip := range test.ips {
+			es = append(es, ipamEdge{edge: &minigraph.Edge{D: map[string]string{"ip": ip}}})
+		}
+
+		if got := c.subnet(n, es); got.String() != test.want {
+			t.Errorf("%v %v: got %v, want %v", test.cidr, test.ips, got, test.want)
+		}
+	}
+}
+
+func TestIPAMHost(t *testing.T) {
+	a := &allocator{
+		used: map[string]bool{"10.0.0.2": true},
+	}
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/29")
+	gateway := net.ParseIP("10.0.0.1").To4()
+
+	var got []string
+	for ip := a.host(subnet, gateway); ip != nil; ip = a.host(subnet, gateway) {
+		a.used[ip.String()] = true
+		got = append(got, ip.String())
+	}
+
+	if want := "[10.0.0.3 10.0.0.4 10.0.0.5 10.0.0.6]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// no room for more hosts
+	_, subnet, _ = net.ParseCIDR("10.0.1.0/31")
+	if ip := a.host(subnet, nil); ip != nil {
+		t.Errorf("got %v in %v", ip, subnet)
+	}
+}
@@ -44,7 +44,7 @@ func (c *CommandLatLon) Run() error {
 	}
 	defer db.Close()
 
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
@@ -38,7 +38,7 @@ func (c *CommandMAC) Run() error {
 		validMACPrefix = append(validMACPrefix, k)
 	}
 
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
@@ -17,7 +17,7 @@ import (
 
 // universal flags
 var (
-	f_server    = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server    = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	f_seed      = flag.Int64("seed", 0, "seed for random number generator, 0 means use random seed")
 	f_dryrun    = flag.Bool("dry-run", false, "print updates rather than commit them")
 	f_overwrite = flag.Bool("overwrite", false, "overwrite values even if already set")
@@ -10,13 +10,56 @@ import (
 	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 )
 
+// originals holds a copy of each endpoint as it was read by GetEndpoints so
+// that UpdateEndpoint only sends the keys that we changed.
+var originals = map[int]*minigraph.Endpoint{}
+
+// GetEndpoints reads all the endpoints from the server.
+func GetEndpoints() ([]*minigraph.Endpoint, error) {
+	endpoints, err := dc.GetEndpoints("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range endpoints {
+		originals[v.NID] = v.Copy()
+	}
+
+	return endpoints, nil
+}
+
+// UpdateEndpoint patches the keys of the endpoint and its edges that changed
+// since it was read by GetEndpoints so that we don't overwrite concurrent
+// updates by other tools.
 func UpdateEndpoint(v *minigraph.Endpoint) error {
 	if *f_dryrun {
 		return nil
 	}
 
-	_, err := dc.UpdateEndpoints(v)
-	return err
+	orig, ok := originals[v.NID]
+	if !ok {
+		_, err := dc.UpdateEndpoints(v)
+		return err
+	}
+
+	p := minigraph.DiffEndpoint(orig, v)
+	if p.Empty() {
+		return nil
+	}
+
+	// edges are patched by index, which may change
+	var rev int
+	if len(p.Edges) > 0 {
+		rev = orig.Rev
+	}
+
+	res, err := dc.PatchEndpoint(v.NID, p, rev)
+	if err != nil {
+		return err
+	}
+
+	originals[v.NID] = res.Copy()
+	return nil
 }
 
 // GetIPs parses the IP field of all edges for a node.
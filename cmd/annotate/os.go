@@ -70,7 +70,7 @@ func (c *CommandOS) Run() error {
 		return err
 	}
 
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
@@ -28,7 +28,7 @@ Annotate nodes with random serial numbers. Serial numbers are 8 hex characters.
 }
 
 func (r *CommandSerial) Run() error {
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
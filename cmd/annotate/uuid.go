@@ -27,7 +27,7 @@ Annotate nodes with random UUIDs.
 }
 
 func (c *CommandUUID) Run() error {
-	endpoints, err := dc.GetEndpoints("", "")
+	endpoints, err := GetEndpoints()
 	if err != nil {
 		return err
 	}
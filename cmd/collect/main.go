@@ -15,7 +15,7 @@ import (
 
 // universal flags
 var (
-	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	f_dryrun = flag.Bool("dry-run", false, "print updates rather than commit them")
 )
 
@@ -0,0 +1,197 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/commands"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+type CommandRoutes struct {
+	commands.Base // embed
+
+	weight string
+}
+
+// Route is the next hop to a destination subnet, stored as JSON in the
+// router's routes field keyed by destination.
+type Route struct {
+	Via       string `json:"via"`
+	Interface int    `json:"interface"`
+}
+
+func init() {
+	base := commands.Base{
+		Flags: flag.NewFlagSet("routes", flag.ExitOnError),
+		Usage: "routes [OPTION]... [ROUTER ID]...",
+		Short: "collect static routing tables for routers",
+		Long: `
+Compute a static route to every network with a cidr or cidr6 that the router
+is not directly connected to. Routes follow the shortest path through the
+other routers (router=true) in the model. The next hop is the IP of the next
+router on the path and the interface is the index of the router's edge to
+it. Routes are stored in the router's routes field as JSON, replacing any
+existing routes.
+
+If no router IDs are given, routes are collected for all routers.
+`,
+	}
+
+	cmd := &CommandRoutes{Base: base}
+	cmd.Flags.StringVar(&cmd.weight, "weight", "", "edge attribute to weight links by, such as delay")
+
+	commands.Append(cmd)
+}
+
+func (c *CommandRoutes) Run() error {
+	endpoints, err := dc.GetEndpoints("", "")
+	if err != nil {
+		return err
+	}
+
+	networks, err := dc.GetNetworks("", "")
+	if err != nil {
+		return err
+	}
+
+	// graph of just the routers and networks so that paths never transit
+	// through other endpoints
+	g := minigraph.New()
+
+	routers := map[int]*minigraph.Endpoint{}
+
+	for _, e := range endpoints {
+		if e.D["router"] == "true" {
+			routers[e.NID] = e
+			g.Insert(e.Copy())
+		}
+	}
+
+	for _, n := range networks {
+		g.Insert(n.Copy())
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].NID < networks[j].NID
+	})
+
+	var ids []int
+	for _, v := range c.Flags.Args() {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		for id := range routers {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+	}
+
+	for _, id := range ids {
+		rtr, ok := routers[id]
+		if !ok {
+			log.Warn("skipping %v, not a router", id)
+			continue
+		}
+
+		routes, err := c.routes(g, rtr, routers, networks)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(routes)
+		if err != nil {
+			return err
+		}
+
+		rtr.D["routes"] = string(b)
+		log.Debug("updating router %v with routes %v", rtr.NID, rtr.D["routes"])
+
+		if err := UpdateEndpoint(rtr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// routes computes the route to each network that the router isn't connected to
+func (c *CommandRoutes) routes(g *minigraph.Graph, rtr *minigraph.Endpoint, routers map[int]*minigraph.Endpoint, networks []*minigraph.Network) (map[string]Route, error) {
+	routes := map[string]Route{}
+
+	for _, n := range networks {
+		if rtr.Connected(n.NID) {
+			continue
+		}
+
+		p, err := g.ShortestPath(rtr.NID, n.NID, c.weight)
+		if err != nil {
+			return nil, err
+		} else if p == nil {
+			log.Debug("no route from router %v to network %v", rtr.NID, n.NID)
+			continue
+		}
+
+		// path is router, network, next router, ..., network
+		via, next := p.Nodes[1], routers[p.Nodes[2]]
+
+		var iface int
+		for i, edge := range rtr.Edges {
+			if edge.N == via {
+				iface = i
+				break
+			}
+		}
+
+		for k, ipk := range map[string]string{"cidr": "ip", "cidr6": "ip6"} {
+			_, dst, err := net.ParseCIDR(n.D[k])
+			if err != nil {
+				continue
+			}
+
+			if _, ok := routes[dst.String()]; ok {
+				log.Warn("duplicate %v %v on network %v", k, dst, n.NID)
+				continue
+			}
+
+			var ip net.IP
+			for _, edge := range next.Edges {
+				if edge.N == via {
+					ip = parseIP(edge.D[ipk])
+					break
+				}
+			}
+
+			if ip == nil {
+				log.Warn("no route from router %v to %v, router %v has no %v on network %v", rtr.NID, dst, next.NID, ipk, via)
+				continue
+			}
+
+			routes[dst.String()] = Route{Via: ip.String(), Interface: iface}
+		}
+	}
+
+	return routes, nil
+}
+
+// parseIP parses an IP with an optional prefix length
+func parseIP(s string) net.IP {
+	if ip, _, err := net.ParseCIDR(s); err == nil {
+		return ip
+	}
+
+	return net.ParseIP(s)
+}
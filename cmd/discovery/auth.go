@@ -0,0 +1,194 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Authentication is enabled by -tokens and/or -tls-ca. Clients authenticate
+// with a bearer token or a client certificate signed by one of the CAs.
+// Read-only clients may only make GET requests, excluding /daemon/ which
+// reads and writes files on the server. Clients with a certificate have
+// read-write access.
+type access int
+
+const (
+	accessNone access = iota
+	accessRead
+	accessWrite
+)
+
+type token struct {
+	value  string
+	access access
+}
+
+type auth struct {
+	tokens []token
+
+	// verified client certificates grant read-write access
+	certs bool
+}
+
+// readTokens reads the tokens from a file with one token per line, followed by
+// ro or rw for read-only or read-write access. Tokens are read-only if the
+// access is not specified. Blank lines and lines starting with # are ignored.
+func readTokens(path string) ([]token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []token
+
+	scanner := bufio.NewScanner(f)
+	for i := 1; scanner.Scan(); i++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		t := token{value: fields[0], access: accessRead}
+
+		switch {
+		case len(fields) == 1:
+		case len(fields) == 2 && fields[1] == "ro":
+		case len(fields) == 2 && fields[1] == "rw":
+			t.access = accessWrite
+		default:
+			return nil, fmt.Errorf("%v:%v: expected <token> [ro|rw]", path, i)
+		}
+
+		res = append(res, t)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no tokens found in %v", path)
+	}
+
+	return res, nil
+}
+
+func (a *auth) enabled() bool {
+	return len(a.tokens) > 0 || a.certs
+}
+
+// access determines the access that the request is authorized for
+func (a *auth) access(r *http.Request) access {
+	if a.certs && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return accessWrite
+	}
+
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return accessNone
+	}
+
+	v := []byte(strings.TrimSpace(h[7:]))
+
+	// check every token so that timing doesn't reveal which one matched
+	res := accessNone
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(v, []byte(t.value)) == 1 && t.access > res {
+			res = t.access
+		}
+	}
+
+	return res
+}
+
+// writes returns true if the request requires read-write access
+func writes(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+	default:
+		return true
+	}
+
+	p := strings.Split(r.URL.Path, "/")
+	if len(p) > 3 && p[1] == modelDir {
+		// /models/<name>/...
+		p = p[2:]
+	}
+
+	return len(p) > 1 && p[1] == "daemon"
+}
+
+// wrap requires each request to be authorized, if authentication is enabled.
+func (a *auth) wrap(h http.Handler) http.Handler {
+	if !a.enabled() {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		need := accessRead
+		if writes(r) {
+			need = accessWrite
+		}
+
+		got := a.access(r)
+		if got >= need {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		log.Warn("unauthorized: %v\t%v from %v", r.Method, r.RequestURI, r.RemoteAddr)
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		if got == accessNone {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			discovery.WriteError(w, r, fmt.Errorf("authentication required"))
+			return
+		}
+
+		w.WriteHeader(http.StatusForbidden)
+		discovery.WriteError(w, r, fmt.Errorf("read-only access"))
+	})
+}
+
+// tlsConfig creates the TLS config for -tls-ca, which verifies client
+// certificates. If there are also tokens, clients may use either.
+func tlsConfig(a *auth) (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if *f_tlsCA == "" {
+		return config, nil
+	}
+
+	b, err := os.ReadFile(*f_tlsCA)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %v", *f_tlsCA)
+	}
+
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(a.tokens) > 0 {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	a := &auth{
+		tokens: []token{
+			{"ro-token", accessRead},
+			{"rw-token", accessWrite},
+		},
+	}
+
+	h := a.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/endpoints/", "", http.StatusUnauthorized},
+		{"GET", "/endpoints/", "bogus", http.StatusUnauthorized},
+		{"POST", "/endpoints/", "", http.StatusUnauthorized},
+
+		{"GET", "/endpoints/", "ro-token", http.StatusOK},
+		{"HEAD", "/networks/", "ro-token", http.StatusOK},
+		{"GET", "/models/lab/endpoints/", "ro-token", http.StatusOK},
+		{"GET", "/models/daemon/endpoints/", "ro-token", http.StatusOK},
+		{"POST", "/endpoints/", "ro-token", http.StatusForbidden},
+		{"PUT", "/networks/", "ro-token", http.StatusForbidden},
+		{"PATCH", "/endpoints/1", "ro-token", http.StatusForbidden},
+		{"DELETE", "/endpoints/1", "ro-token", http.StatusForbidden},
+		{"POST", "/batch/", "ro-token", http.StatusForbidden},
+		{"POST", "/models/lab/connect/1/2", "ro-token", http.StatusForbidden},
+		{"GET", "/daemon/save/graph.gob", "ro-token", http.StatusForbidden},
+		{"GET", "/models/lab/daemon/load/graph.gob", "ro-token", http.StatusForbidden},
+
+		{"POST", "/endpoints/", "rw-token", http.StatusOK},
+		{"DELETE", "/models/lab/endpoints/1", "rw-token", http.StatusOK},
+		{"GET", "/daemon/save/graph.gob", "rw-token", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != test.want {
+			t.Errorf("%v %v with %q: got %v, want %v", test.method, test.path, test.token, w.Code, test.want)
+		}
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	h := (&auth{}).wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/daemon/save/graph.gob", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("got %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestReadTokens(t *testing.T) {
+	tests := []struct {
+		name, data string
+		want       []token
+	}{
+		{"access", "# comment\n\nalpha\nbeta ro\ngamma rw\n", []token{
+			{"alpha", accessRead},
+			{"beta", accessRead},
+			{"gamma", accessWrite},
+		}},
+		{"bad access", "alpha admin\n", nil},
+		{"extra field", "alpha rw extra\n", nil},
+		{"empty", "# nothing here\n", nil},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "tokens")
+		if err := os.WriteFile(path, []byte(test.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := readTokens(path)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%v: got %v, want error", test.name, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+		} else if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
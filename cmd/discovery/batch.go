@@ -0,0 +1,70 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// webBatch applies a list of ops atomically and supports the following
+// methods:
+//
+//	POST
+//		/batch		apply a list of ops
+//
+// The response contains the ops as they were applied, with placeholder NIDs
+// replaced by the assigned NIDs and Endpoint/Network set to the resulting
+// state of the node, where applicable.
+func webBatch(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	switch r.Method {
+	case "POST":
+		var data bytes.Buffer
+		io.Copy(&data, r.Body)
+
+		var ops []*discovery.Op
+		err := json.Unmarshal(data.Bytes(), &ops)
+		if err != nil {
+			log.Errorln(err)
+			w.WriteHeader(422)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		if err := m.commit(ops...); err != nil {
+			writeCommitError(w, r, err)
+			return
+		}
+
+		for _, o := range ops {
+			switch o.Action {
+			case discovery.OP_CONNECT, discovery.OP_DISCONNECT:
+				o.Endpoint, _ = m.graph.Nodes[o.ENID].(*minigraph.Endpoint)
+			}
+		}
+
+		b, err := json.MarshalIndent(ops, "", "    ")
+		if err != nil {
+			log.Errorln(err)
+			w.WriteHeader(422)
+			discovery.WriteError(w, r, err)
+		} else {
+			w.WriteHeader(http.StatusOK)
+			w.Write(b)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// postBatch posts the ops to webBatch and decodes the applied ops
+func postBatch(t *testing.T, m *model, ops ...*discovery.Op) (int, []*discovery.Op) {
+	b, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	webBatch(m, w, httptest.NewRequest("POST", "/batch/", bytes.NewReader(b)))
+
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	var res []*discovery.Op
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return w.Code, res
+}
+
+func TestBatchPlaceholders(t *testing.T) {
+	m := newModel(defaultModel)
+
+	code, res := postBatch(t, m,
+		discovery.InsertNetworkOp(&minigraph.Network{NID: -1, D: map[string]string{"name": "lan"}}),
+		discovery.InsertEndpointOp(&minigraph.Endpoint{NID: -2, D: map[string]string{"hostname": "a"}}),
+		discovery.ConnectOp(-1, -2, discovery.EDGE_NONE),
+		discovery.SetConfigOp("owner", "alice"),
+	)
+	if code != http.StatusOK {
+		t.Fatalf("got status %v", code)
+	}
+
+	nnid, enid := res[0].Network.NID, res[1].Endpoint.NID
+	if nnid <= 0 || enid <= 0 || nnid == enid {
+		t.Fatalf("got network %v and endpoint %v", nnid, enid)
+	}
+
+	if res[2].NNID != nnid || res[2].ENID != enid {
+		t.Errorf("got connect %v to %v, want %v to %v", res[2].NNID, res[2].ENID, nnid, enid)
+	}
+
+	n, ok := m.graph.Nodes[nnid].(*minigraph.Network)
+	if !ok || !reflect.DeepEqual(n.Endpoints, []int{enid}) {
+		t.Errorf("got network %v, want endpoint %v connected", m.graph.Nodes[nnid], enid)
+	}
+
+	// one commit, one revision
+	if len(m.revisions) != 1 || m.events.current() != 1 {
+		t.Errorf("got %v revisions at rev %v, want 1", len(m.revisions), m.events.current())
+	}
+}
+
+func TestBatchAtomic(t *testing.T) {
+	m := newModel(defaultModel)
+
+	if code, _ := postBatch(t, m,
+		discovery.InsertEndpointOp(&minigraph.Endpoint{D: map[string]string{"hostname": "a"}}),
+		discovery.SetConfigOp("owner", "alice"),
+	); code != http.StatusOK {
+		t.Fatalf("got status %v", code)
+	}
+
+	var before []*minigraph.Endpoint
+	for _, e := range m.graph.GetEndpoints() {
+		before = append(before, e.Copy())
+	}
+
+	tests := []struct {
+		name string
+		ops  []*discovery.Op
+	}{
+		{"missing node", []*discovery.Op{
+			discovery.InsertEndpointOp(&minigraph.Endpoint{D: map[string]string{"hostname": "b"}}),
+			discovery.SetConfigOp("owner", "bob"),
+			discovery.DeleteOp(1000),
+		}},
+		{"unknown reference", []*discovery.Op{
+			discovery.InsertNetworkOp(&minigraph.Network{NID: -1}),
+			discovery.DeleteConfigOp("owner"),
+			discovery.ConnectOp(-1, -2, discovery.EDGE_NONE),
+		}},
+	}
+
+	for _, test := range tests {
+		if code, _ := postBatch(t, m, test.ops...); code == http.StatusOK {
+			t.Errorf("%v: batch succeeded", test.name)
+		}
+
+		if got := m.graph.GetEndpoints(); !reflect.DeepEqual(got, before) {
+			t.Errorf("%v: got endpoints %v, want %v", test.name, got, before)
+		}
+		if got := m.graph.GetNetworks(); len(got) != 0 {
+			t.Errorf("%v: got networks %v", test.name, got)
+		}
+		if got := m.config["owner"]; got != "alice" {
+			t.Errorf("%v: got owner %q, want alice", test.name, got)
+		}
+		if len(m.revisions) != 1 || m.events.current() != 1 {
+			t.Errorf("%v: got %v revisions at rev %v, want 1", test.name, len(m.revisions), m.events.current())
+		}
+	}
+}
@@ -16,10 +16,6 @@ import (
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
-var (
-	config map[string]string
-)
-
 // webConfig handles listing, adding, deleting, and modifying config parameters
 // and supports the following methods:
 //	GET
@@ -30,7 +26,7 @@ var (
 //	DELETE
 //		/config/<field>		delete an config
 //
-func webConfig(w http.ResponseWriter, r *http.Request) {
+func webConfig(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -50,9 +46,9 @@ func webConfig(w http.ResponseWriter, r *http.Request) {
 		var b []byte
 		var err error
 		if strings.TrimSpace(p[1]) == "" {
-			b, err = json.MarshalIndent(config, "", "    ")
+			b, err = json.MarshalIndent(m.config, "", "    ")
 		} else {
-			b, err = json.MarshalIndent(config[p[1]], "", "    ")
+			b, err = json.MarshalIndent(m.config[p[1]], "", "    ")
 		}
 		if err != nil {
 			log.Errorln(err)
@@ -79,7 +75,13 @@ func webConfig(w http.ResponseWriter, r *http.Request) {
 		var data bytes.Buffer
 		io.Copy(&data, r.Body)
 
-		config[k] = data.String()
+		err := m.commit(discovery.SetConfigOp(k, data.String()))
+		if err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			discovery.WriteError(w, r, err)
+			return
+		}
 
 		w.WriteHeader(http.StatusCreated)
 	case "DELETE":
@@ -90,8 +92,14 @@ func webConfig(w http.ResponseWriter, r *http.Request) {
 				discovery.WriteError(w, r, fmt.Errorf("delete requires a key"))
 				return
 			} else {
-				if _, ok := config[p[1]]; ok {
-					delete(config, p[1])
+				if _, ok := m.config[p[1]]; ok {
+					err := m.commit(discovery.DeleteConfigOp(p[1]))
+					if err != nil {
+						log.Errorln(err)
+						w.WriteHeader(http.StatusInternalServerError)
+						discovery.WriteError(w, r, err)
+						return
+					}
 				}
 				w.WriteHeader(http.StatusOK)
 			}
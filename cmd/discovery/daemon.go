@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -21,13 +22,17 @@ import (
 type store struct {
 	Config map[string]string
 	Graph  []byte
+
+	// Seq is the sequence number of the last journal record included in the
+	// store, only set for snapshots
+	Seq uint64
 }
 
 func init() {
 	gob.Register(&store{})
 }
 
-func webDaemon(w http.ResponseWriter, r *http.Request) {
+func webDaemon(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	// urls can be:
@@ -48,12 +53,17 @@ func webDaemon(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		path := filepath.Join(p[1:]...)
+		path, err := filesPath(p[1:])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
 		log.Debug("using path: %v", path)
 
 		switch strings.ToLower(p[0]) {
 		case "save":
-			err := daemonSave(path)
+			err := m.daemonSave(path)
 			if err != nil {
 				log.Errorln(err)
 				w.WriteHeader(http.StatusInternalServerError)
@@ -62,7 +72,7 @@ func webDaemon(w http.ResponseWriter, r *http.Request) {
 			}
 			w.WriteHeader(http.StatusOK)
 		case "load":
-			err := daemonLoad(path)
+			err := m.daemonLoad(path)
 			if err != nil {
 				log.Errorln(err)
 				w.WriteHeader(http.StatusInternalServerError)
@@ -82,31 +92,100 @@ func webDaemon(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func daemonLoad(path string) error {
+// filesPath resolves the path for /daemon/save and /daemon/load, which must
+// be within -files, even after following symlinks.
+func filesPath(p []string) (string, error) {
+	for _, v := range p {
+		if v == ".." {
+			return "", fmt.Errorf("invalid path: %v", strings.Join(p, "/"))
+		}
+	}
+
+	rel := filepath.Join(p...)
+	if rel == "" || rel == "." {
+		return "", fmt.Errorf("not enough arguments: %v", strings.Join(p, "/"))
+	}
+
+	root, err := filepath.Abs(*f_files)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	within := func(path string) bool {
+		return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+	}
+
+	path := filepath.Join(root, rel)
+
+	// the file itself may not exist yet when saving
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		// a dangling symlink could point anywhere
+		if _, err := os.Lstat(path); err == nil {
+			return "", fmt.Errorf("invalid path: %v is a dangling symlink", rel)
+		}
+
+		var dir string
+		dir, err = filepath.EvalSymlinks(filepath.Dir(path))
+		resolved = filepath.Join(dir, filepath.Base(path))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !within(resolved) {
+		return "", fmt.Errorf("invalid path: %v is outside of %v", rel, *f_files)
+	}
+
+	return resolved, nil
+}
+
+func (m *model) daemonLoad(path string) error {
 	// read/create the specified file
 	log.Debug("daemon load: %v", path)
-	f, err := os.Open(path)
+
+	s, err := readStore(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	s := &store{}
-	dec := gob.NewDecoder(f)
-	err = dec.Decode(s)
+	// create the graph from the input file
+	g, err := minigraph.Read(bytes.NewBuffer(s.Graph))
 	if err != nil {
 		return err
 	}
 
-	config = s.Config
-	b := bytes.NewBuffer(s.Graph)
+	return m.replace(g, s.Config)
+}
 
-	// create the graph from the input file
-	graph, err = minigraph.Read(b)
-	return err
+// replace replaces the current graph and config, creating a new revision.
+// Callers must hold the model's lock.
+func (m *model) replace(g *minigraph.Graph, c map[string]string) error {
+	before, saved := m.graph.Nodes, m.config
+
+	m.graph = g
+	indexGraph(m.graph)
+
+	m.config = c
+	if m.config == nil {
+		m.config = make(map[string]string)
+	}
+
+	m.events.reset()
+	m.recordRevision(before, saved, true)
+
+	// the journal no longer reflects the loaded model
+	if m.jrnl != nil {
+		return m.jrnl.compact()
+	}
+
+	return nil
 }
 
-func daemonSave(path string) error {
+func (m *model) daemonSave(path string) error {
 	log.Debug("daemon save: %v", path)
 
 	f, err := os.Create(path)
@@ -115,17 +194,38 @@ func daemonSave(path string) error {
 	}
 	defer f.Close()
 
-	var b bytes.Buffer
+	return m.writeStore(f, 0)
+}
 
-	err = graph.Write(&b)
+// readStore reads a store written by writeStore from the specified file.
+func readStore(path string) (*store, error) {
+	f, err := os.Open(path)
 	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &store{}
+	if err := gob.NewDecoder(f).Decode(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// writeStore encodes the current graph and config to w.
+func (m *model) writeStore(w io.Writer, seq uint64) error {
+	var b bytes.Buffer
+
+	if err := m.graph.Write(&b); err != nil {
 		return err
 	}
 
 	s := &store{
-		Config: config,
+		Config: m.config,
 		Graph:  b.Bytes(),
+		Seq:    seq,
 	}
 
-	return gob.NewEncoder(f).Encode(s)
+	return gob.NewEncoder(w).Encode(s)
 }
@@ -0,0 +1,399 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+const (
+	// number of events to keep so that clients can resume
+	eventHistory = 10000
+
+	// number of events to buffer per subscriber before dropping it
+	eventBuffer = 1024
+
+	keepalive = 30 * time.Second
+)
+
+// feed keeps the recent history of events and the set of subscribers that
+// are currently streaming events.
+type feed struct {
+	sync.Mutex
+
+	rev     uint64
+	history []*discovery.Event
+
+	subs map[chan []*discovery.Event]bool
+}
+
+func newFeed() *feed {
+	return &feed{
+		subs: make(map[chan []*discovery.Event]bool),
+	}
+}
+
+// publish assigns the next revision to the events and sends them to all
+// subscribers. Subscribers that can't keep up are dropped and must resume
+// using the last revision they received.
+func (f *feed) publish(evs []*discovery.Event) {
+	if len(evs) == 0 {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.rev += 1
+	for _, e := range evs {
+		e.Rev = f.rev
+	}
+
+	f.history = append(f.history, evs...)
+	if len(f.history) > eventHistory {
+		f.history = append([]*discovery.Event(nil), f.history[len(f.history)-eventHistory:]...)
+	}
+
+	for c := range f.subs {
+		select {
+		case c <- evs:
+		default:
+			log.Warn("dropping slow event subscriber")
+			delete(f.subs, c)
+			close(c)
+		}
+	}
+}
+
+// current returns the current revision.
+func (f *feed) current() uint64 {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.rev
+}
+
+// reset tells subscribers to refetch everything, used when the whole model
+// is replaced.
+func (f *feed) reset() {
+	f.publish([]*discovery.Event{{Type: discovery.EVENT_RESET}})
+}
+
+// subscribe returns a channel for new events along with the events after
+// rev from the history and the current revision, which the channel's events
+// follow. If the history doesn't go back far enough, the backlog starts with a
+// reset event.
+func (f *feed) subscribe(rev uint64) (chan []*discovery.Event, []*discovery.Event, uint64) {
+	f.Lock()
+	defer f.Unlock()
+
+	c := make(chan []*discovery.Event, eventBuffer)
+	f.subs[c] = true
+
+	if rev == 0 || rev == f.rev {
+		return c, nil, f.rev
+	}
+
+	// rev > f.rev if the client saw revisions from before a restart
+	if rev > f.rev || len(f.history) == 0 || f.history[0].Rev > rev+1 {
+		return c, []*discovery.Event{{Rev: f.rev, Type: discovery.EVENT_RESET}}, f.rev
+	}
+
+	var backlog []*discovery.Event
+	for _, e := range f.history {
+		if e.Rev > rev {
+			backlog = append(backlog, e)
+		}
+	}
+
+	return c, backlog, f.rev
+}
+
+func (f *feed) unsubscribe(c chan []*discovery.Event) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.subs[c] {
+		delete(f.subs, c)
+		close(c)
+	}
+}
+
+// close disconnects all the subscribers, used when the model is deleted.
+func (f *feed) close() {
+	f.Lock()
+	defer f.Unlock()
+
+	for c := range f.subs {
+		delete(f.subs, c)
+		close(c)
+	}
+}
+
+// opEvents returns a function that computes the events for the op once it
+// has been applied. Must be called before the op is applied since some
+// events depend on the previous state of the graph and config.
+func (m *model) opEvents(o *discovery.Op) func() []*discovery.Event {
+	switch o.Action {
+	case discovery.OP_INSERT:
+		return func() []*discovery.Event {
+			var n minigraph.Node = o.Network
+			if o.Endpoint != nil {
+				n = o.Endpoint
+			}
+
+			return []*discovery.Event{nodeEvent(discovery.EVENT_NODE_CREATED, n)}
+		}
+	case discovery.OP_UPDATE, discovery.OP_PATCH:
+		id := o.NID
+		if o.Endpoint != nil {
+			id = o.Endpoint.ID()
+		} else if o.Network != nil {
+			id = o.Network.ID()
+		}
+
+		old := m.graph.Nodes[id]
+		var oldD map[string]string
+		if old != nil {
+			oldD = old.Data()
+		}
+
+		// copy since the update replaces D and the patch modifies it
+		oldD = copyMap(oldD)
+
+		return func() []*discovery.Event {
+			n := m.graph.Nodes[id]
+
+			e := nodeEvent(discovery.EVENT_NODE_UPDATED, n)
+			diff := minigraph.DiffData(oldD, n.Data())
+			e.Diff = &diff
+
+			return []*discovery.Event{e}
+		}
+	case discovery.OP_DELETE:
+		var evs []*discovery.Event
+
+		if n, ok := m.graph.Nodes[o.NID]; ok {
+			for _, v := range n.Neighbors() {
+				e := &discovery.Event{Type: discovery.EVENT_EDGE_DISCONNECTED}
+
+				if endpoint, ok := n.(*minigraph.Endpoint); ok {
+					e.NNID, e.ENID, e.Edge = v, n.ID(), edgeIndex(endpoint, v)
+				} else if endpoint, ok := m.graph.Nodes[v].(*minigraph.Endpoint); ok {
+					e.NNID, e.ENID, e.Edge = n.ID(), v, edgeIndex(endpoint, n.ID())
+				}
+
+				evs = append(evs, e)
+			}
+		}
+
+		evs = append(evs, &discovery.Event{
+			Type: discovery.EVENT_NODE_DELETED,
+			NID:  o.NID,
+		})
+
+		return func() []*discovery.Event { return evs }
+	case discovery.OP_CONNECT:
+		return func() []*discovery.Event {
+			e := &discovery.Event{
+				Type: discovery.EVENT_EDGE_CONNECTED,
+				NNID: o.NNID,
+				ENID: o.ENID,
+				Edge: o.Edge,
+			}
+
+			if endpoint, ok := m.graph.Nodes[o.ENID].(*minigraph.Endpoint); ok {
+				e.Edge = edgeIndex(endpoint, o.NNID)
+			}
+
+			return []*discovery.Event{e}
+		}
+	case discovery.OP_DISCONNECT:
+		e := &discovery.Event{
+			Type: discovery.EVENT_EDGE_DISCONNECTED,
+			NNID: o.NNID,
+			ENID: o.ENID,
+		}
+
+		if endpoint, ok := m.graph.Nodes[o.ENID].(*minigraph.Endpoint); ok {
+			e.Edge = edgeIndex(endpoint, o.NNID)
+		}
+
+		return func() []*discovery.Event { return []*discovery.Event{e} }
+	case discovery.OP_SET_CONFIG:
+		return func() []*discovery.Event {
+			return []*discovery.Event{{
+				Type: discovery.EVENT_CONFIG_CHANGED,
+				Diff: &minigraph.DataDiff{Set: map[string]string{o.Key: o.Value}},
+			}}
+		}
+	case discovery.OP_DELETE_CONFIG:
+		return func() []*discovery.Event {
+			return []*discovery.Event{{
+				Type: discovery.EVENT_CONFIG_CHANGED,
+				Diff: &minigraph.DataDiff{Deleted: []string{o.Key}},
+			}}
+		}
+	}
+
+	return func() []*discovery.Event { return nil }
+}
+
+// nodeEvent creates an event containing a copy of the node.
+func nodeEvent(typ string, n minigraph.Node) *discovery.Event {
+	e := &discovery.Event{
+		Type: typ,
+		NID:  n.ID(),
+	}
+
+	switch n := minigraph.CopyNode(n).(type) {
+	case *minigraph.Endpoint:
+		e.Endpoint = n
+	case *minigraph.Network:
+		e.Network = n
+	}
+
+	return e
+}
+
+// edgeIndex finds the index of the endpoint's edge connected to the network,
+// or EDGE_NONE if there isn't one.
+func edgeIndex(e *minigraph.Endpoint, nid int) int {
+	for i, edge := range e.Edges {
+		if edge.N == nid {
+			return i
+		}
+	}
+
+	return discovery.EDGE_NONE
+}
+
+func copyMap(m map[string]string) map[string]string {
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// webEvents streams events to the client as server-sent events and supports
+// the following methods:
+//
+//	GET
+//		/events			stream new events
+//		/events?since=<rev>	stream events after rev, then new events
+//
+// The response's Discovery-Revision header contains the current revision,
+// which the new events follow. Clients may also resume using the
+// Last-Event-ID header. Only the last event
+// of each revision has an id so that clients don't resume in the middle of a
+// revision.
+func webEvents(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		err := fmt.Errorf("streaming not supported")
+		w.WriteHeader(http.StatusInternalServerError)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	since := r.Header.Get("Last-Event-ID")
+	if v := r.URL.Query().Get("since"); v != "" {
+		since = v
+	}
+
+	var rev uint64
+	if since != "" {
+		var err error
+		rev, err = strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+	}
+
+	c, backlog, cur := m.events.subscribe(rev)
+	defer m.events.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(discovery.REVISION_HEADER, strconv.FormatUint(cur, 10))
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvents(w, backlog); err != nil {
+		log.Debug("event stream closed: %v", err)
+		return
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case evs, ok := <-c:
+			if !ok {
+				// dropped, client should reconnect and resume
+				return
+			}
+
+			if err := writeEvents(w, evs); err != nil {
+				log.Debug("event stream closed: %v", err)
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// writeEvents writes the events in the server-sent events format.
+func writeEvents(w http.ResponseWriter, evs []*discovery.Event) error {
+	for i, e := range evs {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n", e.Type, b); err != nil {
+			return err
+		}
+
+		if i == len(evs)-1 || evs[i+1].Rev != e.Rev {
+			if _, err := fmt.Fprintf(w, "id: %v\n", e.Rev); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprint(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
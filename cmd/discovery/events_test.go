@@ -0,0 +1,57 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+func TestWatchResume(t *testing.T) {
+	m := newModel(defaultModel)
+	insertHosts(t, m, "a")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webEvents(m, w, r)
+	}))
+	defer srv.Close()
+
+	dc, err := discovery.NewWithCredentials(srv.URL, &discovery.Credentials{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evs, err := dc.WatchSince(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the stream before any events arrive and commit while the client
+	// is waiting to reconnect
+	srv.CloseClientConnections()
+
+	e := &minigraph.Endpoint{D: map[string]string{"hostname": "b"}}
+	if err := m.commit(discovery.InsertEndpointOp(e)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-evs:
+		if ev.Rev != 2 || ev.Type != discovery.EVENT_NODE_CREATED || ev.NID != e.ID() {
+			t.Errorf("got %v, want 2: %v %v", ev, discovery.EVENT_NODE_CREATED, e.ID())
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("missed the event committed while disconnected")
+	}
+}
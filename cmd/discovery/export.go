@@ -0,0 +1,116 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+var contentTypes = map[string]string{
+	minigraph.FORMAT_GRAPHML: "application/xml; charset=UTF-8",
+	minigraph.FORMAT_JSON:    "application/json; charset=UTF-8",
+	minigraph.FORMAT_DOT:     "text/vnd.graphviz; charset=UTF-8",
+}
+
+// webExport writes the model in a standard format and supports the following
+// methods:
+//
+//	GET
+//		/export/<fmt>		export the graph and config
+//
+// Where fmt is graphml, json (JSON Graph Format), or dot. The config is
+// written as graph-level attributes.
+func webExport(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := strings.ToLower(p[0])
+
+	ct, ok := contentTypes[format]
+	if len(p) != 1 || !ok {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		err := fmt.Errorf("invalid format: %v, expected one of %v", strings.Join(p, "/"), minigraph.Formats)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	var b bytes.Buffer
+	if err := m.graph.Export(&b, format, m.config); err != nil {
+		log.Errorln(err)
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// webImport replaces the model with one in a standard format and supports
+// the following methods:
+//
+//	POST
+//		/import/<fmt>		replace the graph and config
+//
+// Where fmt is graphml, json (JSON Graph Format), or dot. Graph-level
+// attributes become the config.
+func webImport(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := contentTypes[strings.ToLower(p[0])]; len(p) != 1 || !ok {
+		err := fmt.Errorf("invalid format: %v, expected one of %v", strings.Join(p, "/"), minigraph.Formats)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	var data bytes.Buffer
+	io.Copy(&data, r.Body)
+
+	g, c, err := minigraph.Import(&data, p[0])
+	if err != nil {
+		log.Errorln(err)
+		w.WriteHeader(422)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	if err := m.replace(g, c); err != nil {
+		log.Errorln(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,321 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// The journal provides crash-safe storage for the daemon. The data directory
+// contains a snapshot of the graph and config, and a journal of the ops that
+// were committed since the snapshot was taken. Each record in the journal is
+// framed as:
+//
+//	length (uint32) | crc32 of payload (uint32) | gob-encoded record
+//
+// Records are synced to disk before commit returns. On startup, the snapshot
+// is loaded and the journal is replayed on top of it. A torn record at the end
+// of the journal (e.g. from a crash mid-write) is discarded but any other
+// record that can't be replayed is an error.
+const (
+	journalFile  = "journal"
+	snapshotFile = "snapshot"
+
+	maxRecord = 1 << 30
+)
+
+var errTorn = errors.New("torn journal record")
+
+type record struct {
+	Seq uint64
+	Ops []*discovery.Op
+}
+
+type journal struct {
+	m   *model
+	dir string
+	f   *os.File
+
+	seq   uint64 // sequence number of the last record
+	count int    // number of records since the last snapshot
+	max   int    // compact after this many records, disabled if <= 0
+}
+
+// openJournal restores the model's graph and config from the data directory,
+// creating it if it does not exist, and opens the journal for appending.
+// Returns true if any state was restored.
+func openJournal(m *model, dir string, max int) (*journal, bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, false, err
+	}
+
+	j := &journal{
+		m:   m,
+		dir: dir,
+		max: max,
+	}
+
+	m.graph = minigraph.New()
+	m.config = make(map[string]string)
+
+	var restored bool
+
+	s, err := readStore(j.path(snapshotFile))
+	if err == nil {
+		g, err := minigraph.Read(bytes.NewBuffer(s.Graph))
+		if err != nil {
+			return nil, false, err
+		}
+
+		m.graph = g
+		if s.Config != nil {
+			m.config = s.Config
+		}
+		j.seq = s.Seq
+
+		log.Info("loaded snapshot at seq %v", j.seq)
+		restored = true
+	} else if !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	// index before replaying so that the replay can use the indexes
+	indexGraph(m.graph)
+
+	f, err := os.OpenFile(j.path(journalFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	j.f = f
+
+	n, err := j.replay()
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	if j.count > 0 {
+		log.Info("replayed %v journal records, now at seq %v", j.count, j.seq)
+		restored = true
+	}
+
+	// drop anything after the last good record and prepare to append
+	if err := f.Truncate(n); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	if _, err := f.Seek(n, io.SeekStart); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	return j, restored, nil
+}
+
+func (j *journal) path(name string) string {
+	return filepath.Join(j.dir, name)
+}
+
+// replay applies all the records in the journal that are newer than the
+// snapshot. Returns the offset of the end of the last good record.
+func (j *journal) replay() (int64, error) {
+	if _, err := j.f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(j.f)
+
+	var offset int64
+
+	for {
+		rec, n, err := readRecord(r)
+		if err == io.EOF {
+			return offset, nil
+		} else if err == errTorn {
+			log.Warn("discarding torn journal record at offset %v", offset)
+			return offset, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		offset += n
+
+		// already included in the snapshot
+		if rec.Seq <= j.seq {
+			continue
+		}
+
+		// one transaction per record so that node revisions match
+		if err := j.m.graph.Begin(); err != nil {
+			return 0, err
+		}
+
+		// every op applied when it was committed so a failure means that
+		// the journal doesn't match the snapshot
+		for i, o := range rec.Ops {
+			if err := j.m.apply(o); err != nil {
+				j.m.graph.Rollback()
+				return 0, fmt.Errorf("replay seq %v: op %v (%v): %v", rec.Seq, i, o.Action, err)
+			}
+		}
+
+		j.m.graph.Commit()
+
+		j.seq = rec.Seq
+		j.count++
+	}
+}
+
+// readRecord reads the next record, returning the number of bytes consumed.
+func readRecord(r io.Reader) (*record, int64, error) {
+	var header [8]byte
+
+	if n, err := io.ReadFull(r, header[:]); err == io.EOF {
+		return nil, 0, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, int64(n), errTorn
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+
+	// a garbage length is as good as a torn record
+	if size > maxRecord {
+		return nil, 0, errTorn
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, 0, errTorn
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, 0, errTorn
+	}
+
+	rec := &record{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(rec); err != nil {
+		return nil, 0, errTorn
+	}
+
+	return rec, int64(len(header) + len(payload)), nil
+}
+
+// append writes a new record containing the ops and syncs it to disk. May
+// compact the journal if it has grown too large -- failing to compact is not
+// an error since the record is already safe in the journal.
+func (j *journal) append(ops []*discovery.Op) error {
+	rec := &record{
+		Seq: j.seq + 1,
+		Ops: ops,
+	}
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(rec); err != nil {
+		return err
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+
+	if _, err := j.f.Write(append(header[:], payload.Bytes()...)); err != nil {
+		return err
+	}
+	if err := j.f.Sync(); err != nil {
+		return err
+	}
+
+	j.seq = rec.Seq
+	j.count++
+
+	if j.max > 0 && j.count >= j.max {
+		if err := j.compact(); err != nil {
+			log.Error("unable to compact journal: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// compact writes a new snapshot of the current graph and config and then
+// truncates the journal. The snapshot is written to a temporary file and
+// renamed into place so that there is always a complete snapshot on disk.
+func (j *journal) compact() error {
+	log.Info("compacting journal at seq %v", j.seq)
+
+	tmp := j.path(snapshotFile + ".tmp")
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := j.m.writeStore(f, j.seq); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, j.path(snapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(j.dir); err != nil {
+		return err
+	}
+
+	// records up to seq are now in the snapshot and will be skipped on replay
+	// even if we crash before the truncate completes
+	if err := j.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := j.f.Sync(); err != nil {
+		return err
+	}
+
+	j.count = 0
+
+	return nil
+}
+
+func (j *journal) Close() error {
+	return j.f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("sync %v: %v", dir, err)
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
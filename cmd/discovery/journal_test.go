@@ -0,0 +1,309 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// openTestModel opens a model backed by the journal in dir
+func openTestModel(t *testing.T, dir string, max int) (*model, bool) {
+	m := newModel(defaultModel)
+
+	j, restored, err := openJournal(m, dir, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.jrnl = j
+	m.events.rev = j.seq
+
+	t.Cleanup(func() {
+		if m.jrnl != nil {
+			m.jrnl.Close()
+		}
+	})
+
+	return m, restored
+}
+
+// insertHosts commits one endpoint per hostname, each in its own record
+func insertHosts(t *testing.T, m *model, hosts ...string) {
+	for _, h := range hosts {
+		e := &minigraph.Endpoint{D: map[string]string{"hostname": h}}
+		if err := m.commit(discovery.InsertEndpointOp(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func hostnames(m *model) map[string]bool {
+	res := map[string]bool{}
+	for _, e := range m.graph.GetEndpoints() {
+		res[e.D["hostname"]] = true
+	}
+
+	return res
+}
+
+func TestJournalReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	m, restored := openTestModel(t, dir, 0)
+	if restored {
+		t.Error("restored from an empty directory")
+	}
+
+	insertHosts(t, m, "a", "b")
+	if err := m.commit(discovery.SetConfigOp("owner", "alice")); err != nil {
+		t.Fatal(err)
+	}
+	m.jrnl.Close()
+	m.jrnl = nil
+
+	m2, restored := openTestModel(t, dir, 0)
+	if !restored {
+		t.Error("nothing restored")
+	}
+
+	if got := hostnames(m2); len(got) != 2 || !got["a"] || !got["b"] {
+		t.Errorf("got hosts %v, want a and b", got)
+	}
+	if got := m2.config["owner"]; got != "alice" {
+		t.Errorf("got owner %q, want alice", got)
+	}
+	if m2.jrnl.seq != 3 {
+		t.Errorf("got seq %v, want 3", m2.jrnl.seq)
+	}
+}
+
+func TestJournalTorn(t *testing.T) {
+	tests := []struct {
+		name string
+		tear func(b []byte, last int) []byte
+	}{
+		{"truncated payload", func(b []byte, last int) []byte {
+			return b[:len(b)-3]
+		}},
+		{"truncated header", func(b []byte, last int) []byte {
+			return b[:last+5]
+		}},
+		{"bad crc", func(b []byte, last int) []byte {
+			b[last+4] ^= 0xff
+			return b
+		}},
+		{"corrupt payload", func(b []byte, last int) []byte {
+			b[len(b)-1] ^= 0xff
+			return b
+		}},
+		{"garbage length", func(b []byte, last int) []byte {
+			copy(b[last:], []byte{0xff, 0xff, 0xff, 0xff})
+			return b
+		}},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, journalFile)
+
+		m, _ := openTestModel(t, dir, 0)
+		insertHosts(t, m, "a", "b")
+
+		// remember where the last record starts
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := int(fi.Size())
+
+		insertHosts(t, m, "c")
+		m.jrnl.Close()
+		m.jrnl = nil
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, test.tear(b, last), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		m2, _ := openTestModel(t, dir, 0)
+
+		if got := hostnames(m2); len(got) != 2 || !got["a"] || !got["b"] {
+			t.Errorf("%v: got hosts %v, want a and b", test.name, got)
+		}
+		if m2.jrnl.seq != 2 {
+			t.Errorf("%v: got seq %v, want 2", test.name, m2.jrnl.seq)
+		}
+
+		// the torn record is dropped so new records follow the good ones
+		if fi, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		} else if int(fi.Size()) != last {
+			t.Errorf("%v: got journal size %v, want %v", test.name, fi.Size(), last)
+		}
+
+		insertHosts(t, m2, "d")
+		m2.jrnl.Close()
+		m2.jrnl = nil
+
+		m3, _ := openTestModel(t, dir, 0)
+		if got := hostnames(m3); len(got) != 3 || !got["d"] {
+			t.Errorf("%v: got hosts %v after append, want a, b, and d", test.name, got)
+		}
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	m, _ := openTestModel(t, dir, 2)
+	insertHosts(t, m, "a", "b", "c")
+
+	// compacted after the second record
+	if m.jrnl.count != 1 {
+		t.Errorf("got %v records since the snapshot, want 1", m.jrnl.count)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Errorf("no snapshot: %v", err)
+	}
+
+	m.close()
+
+	// close compacts everything into the snapshot
+	if fi, err := os.Stat(filepath.Join(dir, journalFile)); err != nil {
+		t.Fatal(err)
+	} else if fi.Size() != 0 {
+		t.Errorf("got journal size %v after close, want 0", fi.Size())
+	}
+
+	m2, restored := openTestModel(t, dir, 2)
+	if !restored {
+		t.Error("nothing restored")
+	}
+	if got := hostnames(m2); len(got) != 3 {
+		t.Errorf("got hosts %v, want a, b, and c", got)
+	}
+	if m2.jrnl.seq != 3 || m2.jrnl.count != 0 {
+		t.Errorf("got seq %v and count %v, want 3 and 0", m2.jrnl.seq, m2.jrnl.count)
+	}
+
+	insertHosts(t, m2, "d")
+	m2.jrnl.Close()
+	m2.jrnl = nil
+
+	m3, _ := openTestModel(t, dir, 0)
+	if got := hostnames(m3); len(got) != 4 {
+		t.Errorf("got hosts %v, want a through d", got)
+	}
+	if m3.jrnl.seq != 4 {
+		t.Errorf("got seq %v, want 4", m3.jrnl.seq)
+	}
+}
+
+func TestJournalCompactLostTruncate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, journalFile)
+
+	m, _ := openTestModel(t, dir, 0)
+	insertHosts(t, m, "a", "b")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.close()
+
+	// crash after the snapshot was renamed but before the journal was
+	// truncated
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m2, _ := openTestModel(t, dir, 0)
+	if got := m2.graph.GetEndpoints(); len(got) != 2 {
+		t.Errorf("got %v endpoints, want 2", len(got))
+	}
+	if m2.jrnl.seq != 2 || m2.jrnl.count != 0 {
+		t.Errorf("got seq %v and count %v, want 2 and 0", m2.jrnl.seq, m2.jrnl.count)
+	}
+}
+
+func TestJournalReplayBatch(t *testing.T) {
+	dir := t.TempDir()
+
+	m, _ := openTestModel(t, dir, 0)
+
+	n := &minigraph.Network{D: map[string]string{"name": "lan"}}
+	if err := m.commit(discovery.InsertNetworkOp(n)); err != nil {
+		t.Fatal(err)
+	}
+	nnid := n.ID()
+
+	// connect a new endpoint to the existing network and update an existing
+	// endpoint in the same batch
+	e := &minigraph.Endpoint{D: map[string]string{"hostname": "a"}}
+	if err := m.commit(discovery.InsertEndpointOp(e)); err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := e.Copy()
+	e2.D["os"] = "linux"
+
+	if err := m.commit(
+		discovery.InsertEndpointOp(&minigraph.Endpoint{NID: -2, D: map[string]string{"hostname": "b"}}),
+		discovery.ConnectOp(nnid, -2, discovery.EDGE_NONE),
+		discovery.UpdateEndpointOp(e2),
+		discovery.ConnectOp(nnid, e2.NID, discovery.EDGE_NONE),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]minigraph.Node{}
+	for id, n := range m.graph.Nodes {
+		want[id] = n
+	}
+
+	m.jrnl.Close()
+	m.jrnl = nil
+
+	m2, _ := openTestModel(t, dir, 0)
+
+	if !reflect.DeepEqual(m2.graph.Nodes, want) {
+		for id, n := range want {
+			t.Errorf("node %v: got %+v, want %+v", id, m2.graph.Nodes[id], n)
+		}
+	}
+
+	n2, ok := m2.graph.Nodes[nnid].(*minigraph.Network)
+	if !ok || len(n2.Endpoints) != 2 {
+		t.Errorf("got network %v, want two endpoints", m2.graph.Nodes[nnid])
+	}
+}
+
+func TestJournalReplayFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	m, _ := openTestModel(t, dir, 0)
+	insertHosts(t, m, "a")
+
+	// a record that no longer applies, as if the snapshot and journal disagree
+	if err := m.jrnl.append([]*discovery.Op{discovery.DeleteOp(1000)}); err != nil {
+		t.Fatal(err)
+	}
+	m.jrnl.Close()
+	m.jrnl = nil
+
+	if _, _, err := openJournal(newModel(defaultModel), dir, 0); err == nil {
+		t.Error("replayed a record with an op that failed")
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Listings of nodes (GET /nodes, /endpoints, and /networks) are sorted by NID
+// and may be paginated with the following parameters:
+//
+//	limit=<n>	return at most n nodes
+//	after=<nid>	return the nodes with NIDs greater than nid
+//
+// If there are more nodes, the response has a Link header with rel="next"
+// and the URL of the next page. Clients may also use the NID of the last node
+// as the next cursor.
+//
+// With format=ndjson or Accept: application/x-ndjson, the nodes are streamed
+// as newline-delimited JSON instead, one node per line. The model is only
+// locked while each chunk of nodes is copied so that large listings don't
+// block other requests. Nodes that are modified during the stream are written
+// as they were when their chunk was copied and nodes that are deleted are
+// skipped.
+const (
+	contentNDJSON = "application/x-ndjson"
+
+	// number of nodes to copy each time we lock the model while streaming
+	streamChunk = 1000
+)
+
+type page struct {
+	after, limit int
+}
+
+// webPage parses the pagination parameters from the request.
+func webPage(r *http.Request) (*page, error) {
+	pg := &page{}
+
+	for _, v := range []struct {
+		name string
+		dst  *int
+	}{
+		{"after", &pg.after},
+		{"limit", &pg.limit},
+	} {
+		s := r.URL.Query().Get(v.name)
+		if s == "" {
+			continue
+		}
+
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid %v: %v", v.name, s)
+		}
+		*v.dst = i
+	}
+
+	return pg, nil
+}
+
+// apply returns the nodes on the page and whether there are more nodes after
+// it. The nodes must be sorted by NID.
+func (pg *page) apply(nodes []minigraph.Node) ([]minigraph.Node, bool) {
+	if pg.after > 0 {
+		i := sort.Search(len(nodes), func(i int) bool {
+			return nodes[i].ID() > pg.after
+		})
+		nodes = nodes[i:]
+	}
+
+	if pg.limit > 0 && len(nodes) > pg.limit {
+		return nodes[:pg.limit], true
+	}
+
+	return nodes, false
+}
+
+// wantsNDJSON returns true if the client asked for newline-delimited JSON
+func wantsNDJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), contentNDJSON)
+}
+
+// findNodes finds the nodes for a listing, where p is the split path:
+//
+//	/<kind>/			all nodes
+//	/<kind>/<value>			freeform search
+//	/<kind>/<field>/<value>		search by field
+//
+// Only nodes of the given type are returned, unless typ is TYPE_NODE. The
+// nodes are filtered by q, if it is not nil, and sorted by NID.
+func (m *model) findNodes(typ int, p []string, q *minigraph.Query) ([]minigraph.Node, error) {
+	var nodes []minigraph.Node
+	switch len(p) {
+	case 2: // return all nodes or freeform search
+		if strings.TrimSpace(p[1]) == "" {
+			nodes = m.graph.GetNodes()
+		} else {
+			nodes = m.graph.FindNodes("", p[1])
+		}
+	case 3: // search
+		nodes = m.graph.FindNodes(p[1], p[2])
+	default:
+		return nil, fmt.Errorf("invalid path: /%v", strings.Join(p, "/"))
+	}
+
+	var res []minigraph.Node
+	for _, n := range nodes {
+		if typ != minigraph.TYPE_NODE && n.Type() != typ {
+			continue
+		}
+		if q != nil && !q.Match(n) {
+			continue
+		}
+
+		res = append(res, n)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID() < res[j].ID()
+	})
+
+	return res, nil
+}
+
+// writeNodes writes the requested page of nodes as a JSON array.
+func writeNodes(m *model, w http.ResponseWriter, r *http.Request, nodes []minigraph.Node) {
+	pg, err := webPage(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	nodes, more := pg.apply(nodes)
+	if more {
+		v := r.URL.Query()
+		v.Set("after", strconv.Itoa(nodes[len(nodes)-1].ID()))
+
+		next := m.prefix() + r.URL.Path + "?" + v.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<%v>; rel=\"next\"", next))
+	}
+
+	b, err := json.MarshalIndent(nodes, "", "    ")
+	if err != nil {
+		log.Errorln(err)
+		w.WriteHeader(422)
+		discovery.WriteError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
+
+// lister streams listings of nodes of the given type as NDJSON, if the client
+// asked for it, and passes all other requests to fn. Not wrapped in muer since
+// it only locks the model while copying each chunk.
+func lister(typ int, fn handler) handler {
+	return func(m *model, w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || !wantsNDJSON(r) {
+			fn(m, w, r)
+			return
+		}
+
+		webStream(m, typ, w, r)
+	}
+}
+
+// webStream streams the nodes as NDJSON, see above.
+func webStream(m *model, typ int, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[1:]
+	log.Debug("split path: %v", p)
+
+	q, err := webQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	pg, err := webPage(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	m.Lock()
+
+	if m.deleted {
+		m.Unlock()
+
+		err := fmt.Errorf("no such model: %v", m.name)
+		w.WriteHeader(http.StatusNotFound)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	nodes, err := m.findNodes(typ, p, q)
+	if err != nil {
+		m.Unlock()
+
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	nodes, _ = pg.apply(nodes)
+
+	// only hold on to the IDs, the nodes may change once we unlock
+	ids := make([]int, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID()
+	}
+
+	m.Unlock()
+
+	w.Header().Set("Content-Type", contentNDJSON)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	for len(ids) > 0 {
+		chunk := ids[:min(streamChunk, len(ids))]
+		ids = ids[len(chunk):]
+
+		var copies []minigraph.Node
+
+		m.Lock()
+		for _, id := range chunk {
+			if n, ok := m.graph.Nodes[id]; ok {
+				copies = append(copies, minigraph.CopyNode(n))
+			}
+		}
+		m.Unlock()
+
+		for _, n := range copies {
+			if err := enc.Encode(n); err != nil {
+				// client went away
+				log.Debug("stream: %v", err)
+				return
+			}
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
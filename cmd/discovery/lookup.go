@@ -0,0 +1,122 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// indexGraph adds the indexes specified by -index to the graph
+func indexGraph(g *minigraph.Graph) {
+	for _, f := range strings.Split(*f_index, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			g.AddIndex(f)
+		}
+	}
+}
+
+// webLookup finds nodes by exact value or by subnet, using the indexes when
+// possible, and supports the following methods:
+//
+//	GET
+//		/lookup					list indexed fields
+//		/lookup/<kind>/<field>/<value>		find nodes where field is value
+//		/lookup/<kind>/<field>?cidr=<cidr>	find nodes where field is an IP in cidr
+//
+// Where kind is nodes, endpoints, or networks. Values may contain slashes.
+func webLookup(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var res interface{}
+
+	cidr := r.URL.Query().Get("cidr")
+
+	switch {
+	case len(p) == 1 && p[0] == "":
+		// nid is always indexed
+		res = append([]string{"nid"}, m.graph.Indexes()...)
+	case len(p) == 2 && cidr != "":
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		res = filterKind(p[0], m.graph.LookupCIDR(p[1], subnet))
+	case len(p) >= 3:
+		res = filterKind(p[0], m.graph.Lookup(p[1], strings.Join(p[2:], "/")))
+	default:
+		err := fmt.Errorf("invalid lookup: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	if res == nil {
+		err := fmt.Errorf("invalid kind: %v", p[0])
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	b, err := json.MarshalIndent(res, "", "    ")
+	if err != nil {
+		log.Errorln(err)
+		w.WriteHeader(422)
+		discovery.WriteError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
+
+// filterKind returns the nodes of the given kind, or nil if the kind is
+// invalid.
+func filterKind(kind string, nodes []minigraph.Node) interface{} {
+	switch kind {
+	case "nodes":
+		if nodes == nil {
+			return []minigraph.Node{}
+		}
+		return nodes
+	case "endpoints":
+		res := []*minigraph.Endpoint{}
+		for _, n := range nodes {
+			if e, ok := n.(*minigraph.Endpoint); ok {
+				res = append(res, e)
+			}
+		}
+		return res
+	case "networks":
+		res := []*minigraph.Network{}
+		for _, n := range nodes {
+			if n, ok := n.(*minigraph.Network); ok {
+				res = append(res, n)
+			}
+		}
+		return res
+	}
+
+	return nil
+}
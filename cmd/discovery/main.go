@@ -11,33 +11,55 @@ import (
 	"os/signal"
 
 	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
-	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 	"syscall"
 )
 
 var (
 	f_panic = flag.Bool("panic", false, "panic on quit, producing stack traces for debugging")
-	f_file  = flag.String("f", "", "filename of graph to use/create")
+	f_file  = flag.String("f", "", "filename of graph to use/create for the default model")
 	f_serve = flag.String("serve", fmt.Sprintf(":%v", discovery.Port), "web service address")
 	f_web   = flag.String("web", "misc/web/", "path to static web content")
+
+	f_data    = flag.String("data", "data", "directory for the journal and snapshots, disables durable storage if empty")
+	f_compact = flag.Int("compact", 10000, "snapshot and compact the journal after this many commits")
+
+	f_index = flag.String("index", "hostname,edge.ip,edge.ip6,edge.mac", "comma-separated list of fields to index")
+
+	f_files = flag.String("files", ".", "directory that /daemon/save and /daemon/load are confined to")
+
+	f_tlsCert = flag.String("tls-cert", "", "certificate for serving HTTPS")
+	f_tlsKey  = flag.String("tls-key", "", "private key for -tls-cert, if not in the same file")
+	f_tlsCA   = flag.String("tls-ca", "", "CA bundle to verify client certificates, enables authentication")
+	f_tokens  = flag.String("tokens", "", "file of bearer tokens, one per line with ro or rw access, enables authentication")
 )
 
 func main() {
-	var err error
-
 	flag.Parse()
 
 	log.Init()
 
+	m := newModel(defaultModel)
+
+	restored, err := m.open()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	if *f_file != "" {
-		err = daemonLoad(*f_file)
-		if err != nil {
+		if restored {
+			log.Warn("ignoring %v, using model restored from %v", *f_file, *f_data)
+		} else if err := m.daemonLoad(*f_file); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	models[defaultModel] = m
+
+	if *f_data != "" {
+		if err := loadModels(); err != nil {
 			log.Fatalln(err)
 		}
-	} else {
-		graph = minigraph.New()
-		config = make(map[string]string)
 	}
 
 	// start the web service
@@ -51,6 +73,14 @@ func main() {
 
 	log.Debugln("caught signal")
 
+	modelsMu.Lock()
+	for _, m := range models {
+		m.Lock()
+		m.close()
+		m.Unlock()
+	}
+	modelsMu.Unlock()
+
 	if *f_panic {
 		panic("stacktrace")
 	}
@@ -0,0 +1,101 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// webMerge merges another model into the model and supports the following
+// methods:
+//
+//	POST
+//		/merge/<src>	merge the current state of src into the model
+//
+// The keys parameter is a comma-separated list of identity keys and the
+// policy parameter resolves conflicting values, see minigraph.MergeOptions.
+// The merge is committed as a single transaction and the response is the
+// minigraph.MergeResult. Not wrapped in muer since it locks the source model
+// first to copy it.
+func webMerge(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(p) != 1 || p[0] == "" {
+		err := fmt.Errorf("expected /merge/<model>: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	opts := &minigraph.MergeOptions{
+		Policy: r.URL.Query().Get("policy"),
+	}
+	if s := r.URL.Query().Get("keys"); s != "" {
+		opts.Keys = strings.Split(s, ",")
+	}
+
+	src := getModel(p[0])
+	if src == m {
+		err := fmt.Errorf("cannot merge a model into itself")
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	// copy the source so that we never hold both models' locks
+	var other *minigraph.Graph
+	if src != nil {
+		src.Lock()
+		if !src.deleted {
+			other = src.graph.Extract(nil)
+		}
+		src.Unlock()
+	}
+
+	if other == nil {
+		err := fmt.Errorf("no such model: %v", p[0])
+		w.WriteHeader(http.StatusNotFound)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	muer(func(m *model, w http.ResponseWriter, r *http.Request) {
+		g := m.graph.Extract(nil)
+
+		res, err := g.Merge(other, opts)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		ops := m.stateOps(g.Nodes, m.config)
+
+		log.Info("merge %v into %v: %v ops", src.name, m.name, len(ops))
+
+		if err := m.commit(ops...); err != nil {
+			writeCommitError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, res)
+	})(m, w, r)
+}
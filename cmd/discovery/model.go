@@ -0,0 +1,452 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// The daemon hosts one or more named models, each with its own graph, config,
+// journal, events, and revision history. The default model is served at the
+// top level (e.g. /endpoints/) and is stored in the root of the data directory
+// so that existing clients and data directories continue to work. Other models
+// are served under /models/<name>/ and are stored in <data>/models/<name>.
+const (
+	defaultModel = discovery.DEFAULT_MODEL
+
+	modelDir = "models"
+)
+
+type model struct {
+	sync.Mutex
+
+	name string
+
+	graph  *minigraph.Graph
+	config map[string]string
+
+	// nil if durable storage is disabled
+	jrnl *journal
+
+	events *feed
+
+	revisions []*revision
+	snapshots map[string]*snapshot
+
+	// set when the model is deleted so that requests that were waiting for
+	// the lock fail
+	deleted bool
+}
+
+var (
+	models   = map[string]*model{}
+	modelsMu sync.Mutex
+)
+
+var validModel = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*$`)
+
+func newModel(name string) *model {
+	m := &model{
+		name:      name,
+		graph:     minigraph.New(),
+		config:    make(map[string]string),
+		events:    newFeed(),
+		snapshots: map[string]*snapshot{},
+	}
+
+	indexGraph(m.graph)
+
+	return m
+}
+
+// modelPath returns the data directory for the model, empty if durable
+// storage is disabled.
+func modelPath(name string) string {
+	if *f_data == "" {
+		return ""
+	}
+
+	if name == defaultModel {
+		return *f_data
+	}
+
+	return filepath.Join(*f_data, modelDir, name)
+}
+
+// open restores the model from its data directory, if there is one, and
+// opens the journal. Returns true if any state was restored.
+func (m *model) open() (bool, error) {
+	dir := modelPath(m.name)
+	if dir == "" {
+		return false, nil
+	}
+
+	j, restored, err := openJournal(m, dir, *f_compact)
+	if err != nil {
+		return false, err
+	}
+	m.jrnl = j
+
+	// continue numbering revisions where we left off
+	m.events.rev = j.seq
+
+	if err := m.loadSnapshots(); err != nil {
+		j.Close()
+		return false, err
+	}
+
+	return restored, nil
+}
+
+// close snapshots the model so that we don't have to replay the journal next
+// time and then closes the journal. Callers must hold the model's lock.
+func (m *model) close() {
+	if m.jrnl == nil {
+		return
+	}
+
+	if err := m.jrnl.compact(); err != nil {
+		log.Error("unable to compact journal for %v: %v", m.name, err)
+	}
+	m.jrnl.Close()
+	m.jrnl = nil
+}
+
+// prefix returns the path that the model's API is served under
+func (m *model) prefix() string {
+	if m.name == defaultModel {
+		return ""
+	}
+
+	return "/" + modelDir + "/" + m.name
+}
+
+// info summarizes the model. Callers must hold the model's lock.
+func (m *model) info() *discovery.Model {
+	return &discovery.Model{
+		Name:      m.name,
+		Rev:       m.events.current(),
+		Endpoints: len(m.graph.GetEndpoints()),
+		Networks:  len(m.graph.GetNetworks()),
+	}
+}
+
+func getModel(name string) *model {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	return models[name]
+}
+
+// loadModels restores the named models from the data directory.
+func loadModels() error {
+	files, err := os.ReadDir(filepath.Join(*f_data, modelDir))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	for _, f := range files {
+		name := f.Name()
+		if !f.IsDir() || !validModel.MatchString(name) || name == defaultModel {
+			continue
+		}
+
+		m := newModel(name)
+		if _, err := m.open(); err != nil {
+			return fmt.Errorf("model %v: %v", name, err)
+		}
+
+		models[name] = m
+	}
+
+	log.Info("found %v models", len(models))
+
+	return nil
+}
+
+// createModel creates a new, empty model or, if src is not empty, a copy of
+// the current state of src. If q is not nil, only the subgraph selected by the
+// query is copied.
+func createModel(name, src string, q *minigraph.Query) (*model, error) {
+	if !validModel.MatchString(name) {
+		return nil, fmt.Errorf("invalid model name: %v", name)
+	}
+
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	if _, ok := models[name]; ok {
+		return nil, fmt.Errorf("model already exists: %v", name)
+	}
+
+	var s *store
+
+	if src != "" {
+		sm, ok := models[src]
+		if !ok {
+			return nil, fmt.Errorf("no such model: %v", src)
+		}
+
+		var b bytes.Buffer
+
+		sm.Lock()
+		err := sm.writeStore(&b, 0)
+		sm.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		s = &store{}
+		if err := gob.NewDecoder(&b).Decode(s); err != nil {
+			return nil, err
+		}
+	}
+
+	m := newModel(name)
+
+	// clear out anything left behind by a model with the same name
+	if dir := modelPath(name); dir != "" {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := m.open(); err != nil {
+		return nil, err
+	}
+
+	if s != nil {
+		g, err := minigraph.Read(bytes.NewBuffer(s.Graph))
+		if err == nil {
+			if q != nil {
+				g = g.Extract(q)
+			}
+			err = m.replace(g, s.Config)
+		}
+		if err != nil {
+			m.close()
+			os.RemoveAll(modelPath(name))
+			return nil, err
+		}
+	}
+
+	models[name] = m
+
+	log.Info("created model %v", name)
+
+	return m, nil
+}
+
+// deleteModel deletes the model, its snapshots, and its data directory. The
+// default model cannot be deleted.
+func deleteModel(name string) error {
+	if name == defaultModel {
+		return fmt.Errorf("cannot delete the default model")
+	}
+
+	modelsMu.Lock()
+	m, ok := models[name]
+	delete(models, name)
+	modelsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no such model: %v", name)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.deleted = true
+	m.events.close()
+
+	if m.jrnl != nil {
+		m.jrnl.Close()
+		m.jrnl = nil
+
+		if err := os.RemoveAll(modelPath(name)); err != nil {
+			return err
+		}
+	}
+
+	log.Info("deleted model %v", name)
+
+	return nil
+}
+
+// webModels manages the models hosted by the daemon and supports the
+// following methods:
+//
+//	GET
+//		/models				list models
+//		/models/<name>			get a model
+//	POST
+//		/models/<name>			create an empty model
+//		/models/<name>?clone=<src>	create a copy of another model
+//		/models/<name>?clone=<src>&q=<query>
+//						create a copy of the subgraph of
+//						another model selected by the query,
+//						see minigraph.Extract
+//	DELETE
+//		/models/<name>			delete a model
+//
+// Everything under /models/<name>/ is served by the model in the same way as
+// the top level is served by the default model. For example,
+// /models/<name>/endpoints/ lists the model's endpoints and /models/<name>/
+// visualizes the model's graph.
+func webModels(w http.ResponseWriter, r *http.Request) {
+	p := strings.Split(r.URL.Path, "/")[2:]
+
+	if len(p) > 1 {
+		webModel(p[0], p[1:], w, r)
+		return
+	}
+
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+	log.Debug("split path: %v", p)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	name := p[0]
+
+	if name == "" && r.Method != "GET" {
+		err := fmt.Errorf("not enough arguments: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if name == "" {
+			modelsMu.Lock()
+			var ms []*model
+			for _, m := range models {
+				ms = append(ms, m)
+			}
+			modelsMu.Unlock()
+
+			res := []*discovery.Model{}
+			for _, m := range ms {
+				m.Lock()
+				if !m.deleted {
+					res = append(res, m.info())
+				}
+				m.Unlock()
+			}
+
+			sort.Slice(res, func(i, j int) bool {
+				return res[i].Name < res[j].Name
+			})
+
+			writeJSON(w, r, res)
+			return
+		}
+
+		m := getModel(name)
+		if m == nil {
+			err := fmt.Errorf("no such model: %v", name)
+			w.WriteHeader(http.StatusNotFound)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		m.Lock()
+		defer m.Unlock()
+
+		writeJSON(w, r, m.info())
+	case "POST":
+		src := r.URL.Query().Get("clone")
+
+		q, err := webQuery(r)
+		if err == nil && q != nil && src == "" {
+			err = fmt.Errorf("query requires clone")
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		m, err := createModel(name, src, q)
+		if err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		m.Lock()
+		defer m.Unlock()
+
+		writeJSON(w, r, m.info())
+	case "DELETE":
+		if err := deleteModel(name); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// webModel dispatches a request under /models/<name>/ to the model. The
+// handlers see the path without the /models/<name> prefix.
+func webModel(name string, p []string, w http.ResponseWriter, r *http.Request) {
+	m := getModel(name)
+	if m == nil {
+		log.Info("%v\t%v", r.Method, r.RequestURI)
+
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		err := fmt.Errorf("no such model: %v", name)
+		w.WriteHeader(http.StatusNotFound)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	path := "/" + strings.Join(p, "/")
+
+	fn, ok := routes[p[0]]
+	if ok && len(p) == 1 {
+		// what the mux would redirect to for the default model
+		path += "/"
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = path
+	r2.URL.RawPath = ""
+
+	if ok {
+		fn(m, w, r2)
+	} else {
+		static.ServeHTTP(w, r2)
+	}
+}
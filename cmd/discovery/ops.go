@@ -0,0 +1,322 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// apply performs the operation on the model's graph and config. Inserts
+// update the op with the assigned NID so that replaying the op recreates the
+// same node.
+func (m *model) apply(o *discovery.Op) error {
+	switch o.Action {
+	case discovery.OP_INSERT, discovery.OP_UPDATE:
+		var n minigraph.Node
+
+		switch {
+		case o.Endpoint != nil:
+			if o.Endpoint.D == nil {
+				o.Endpoint.D = make(map[string]string)
+			}
+			n = o.Endpoint
+		case o.Network != nil:
+			if o.Network.D == nil {
+				o.Network.D = make(map[string]string)
+			}
+			n = o.Network
+		default:
+			return fmt.Errorf("%v requires a node", o.Action)
+		}
+
+		if o.Action == discovery.OP_INSERT {
+			_, err := m.graph.Insert(n)
+			return err
+		}
+
+		if err := m.checkRev(n.ID(), o.Rev); err != nil {
+			return err
+		}
+
+		_, err := m.graph.Update(n)
+		return err
+	case discovery.OP_PATCH:
+		if o.Patch == nil {
+			return fmt.Errorf("%v requires a patch", o.Action)
+		}
+
+		if err := m.checkRev(o.NID, o.Rev); err != nil {
+			return err
+		}
+
+		_, err := m.graph.Patch(o.NID, o.Patch)
+		return err
+	case discovery.OP_DELETE:
+		n, ok := m.graph.Nodes[o.NID]
+		if !ok {
+			return fmt.Errorf("no such node: %v", o.NID)
+		}
+
+		if err := m.checkRev(o.NID, o.Rev); err != nil {
+			return err
+		}
+
+		return m.graph.Delete(n)
+	case discovery.OP_CONNECT:
+		endpoint, network, err := m.opNodes(o)
+		if err != nil {
+			return err
+		}
+
+		var edge *minigraph.Edge
+
+		// we're about to modify the endpoint directly
+		m.graph.Touch(endpoint.ID())
+
+		if o.Edge == discovery.EDGE_NONE {
+			edge = endpoint.NewEdge()
+		} else if o.Edge >= 0 && o.Edge < len(endpoint.Edges) {
+			edge = endpoint.Edges[o.Edge]
+		} else {
+			return fmt.Errorf("invalid edge id: %v", o.Edge)
+		}
+
+		if err := m.graph.Connect(endpoint, network, edge); err != nil {
+			if o.Edge == discovery.EDGE_NONE {
+				// remove the edge we just created
+				endpoint.Edges = endpoint.Edges[:len(endpoint.Edges)-1]
+			}
+
+			return err
+		}
+
+		return nil
+	case discovery.OP_DISCONNECT:
+		endpoint, network, err := m.opNodes(o)
+		if err != nil {
+			return err
+		}
+
+		return m.graph.Disconnect(endpoint, network)
+	case discovery.OP_SET_CONFIG:
+		m.config[o.Key] = o.Value
+		return nil
+	case discovery.OP_DELETE_CONFIG:
+		delete(m.config, o.Key)
+		return nil
+	}
+
+	return fmt.Errorf("invalid op: %v", o.Action)
+}
+
+// checkRev checks that the node is at the expected revision, if there is one.
+func (m *model) checkRev(nid, rev int) error {
+	if rev == 0 {
+		return nil
+	}
+
+	n, ok := m.graph.Nodes[nid]
+	if !ok {
+		return fmt.Errorf("no such node: %v", nid)
+	}
+
+	if n.Revision() != rev {
+		return fmt.Errorf("%w: node %v is at revision %v, not %v", discovery.ErrConflict, nid, n.Revision(), rev)
+	}
+
+	return nil
+}
+
+// opNodes looks up the endpoint and network referenced by ENID and NNID.
+func (m *model) opNodes(o *discovery.Op) (*minigraph.Endpoint, *minigraph.Network, error) {
+	endpoint, ok := m.graph.Nodes[o.ENID].(*minigraph.Endpoint)
+	if !ok {
+		return nil, nil, fmt.Errorf("no such endpoint: %v", o.ENID)
+	}
+
+	network, ok := m.graph.Nodes[o.NNID].(*minigraph.Network)
+	if !ok {
+		return nil, nil, fmt.Errorf("no such network: %v", o.NNID)
+	}
+
+	return endpoint, network, nil
+}
+
+// copyOp returns a copy of the op that does not share nodes with it.
+func copyOp(o *discovery.Op) *discovery.Op {
+	c := *o
+
+	if o.Endpoint != nil {
+		c.Endpoint = o.Endpoint.Copy()
+	}
+	if o.Network != nil {
+		c.Network = o.Network.Copy()
+	}
+
+	return &c
+}
+
+// resolve replaces placeholder (negative) NIDs in the op with the NIDs that
+// were assigned to the nodes inserted earlier in the same commit.
+func resolve(o *discovery.Op, refs map[int]int) error {
+	lookup := func(id *int) error {
+		if *id >= 0 {
+			return nil
+		}
+
+		v, ok := refs[*id]
+		if !ok {
+			return fmt.Errorf("unknown reference: %v", *id)
+		}
+
+		*id = v
+		return nil
+	}
+
+	switch o.Action {
+	case discovery.OP_INSERT:
+		// placeholders are recorded after the insert
+		if o.Endpoint != nil && o.Endpoint.NID < 0 {
+			o.Endpoint.NID = 0
+		}
+		if o.Network != nil && o.Network.NID < 0 {
+			o.Network.NID = 0
+		}
+		return nil
+	case discovery.OP_UPDATE:
+		if o.Endpoint != nil {
+			return lookup(&o.Endpoint.NID)
+		}
+		if o.Network != nil {
+			return lookup(&o.Network.NID)
+		}
+	case discovery.OP_DELETE, discovery.OP_PATCH:
+		return lookup(&o.NID)
+	case discovery.OP_CONNECT, discovery.OP_DISCONNECT:
+		if err := lookup(&o.NNID); err != nil {
+			return err
+		}
+		return lookup(&o.ENID)
+	}
+
+	return nil
+}
+
+// commit applies the ops in order as a single transaction: if any op fails,
+// the graph and config are rolled back and none of the ops take effect. The
+// error wraps discovery.ErrConflict if an op's Rev did not match. The
+// ops are recorded in the journal, if there is one, before commit returns.
+// Once committed, the resulting events are published to subscribers and the
+// commit is recorded as a new revision. Callers must hold the model's lock.
+func (m *model) commit(ops ...*discovery.Op) error {
+	if err := m.graph.Begin(); err != nil {
+		return err
+	}
+
+	saved := make(map[string]string)
+	for k, v := range m.config {
+		saved[k] = v
+	}
+
+	rollback := func() {
+		m.graph.Rollback()
+		m.config = saved
+	}
+
+	// placeholder NID -> assigned NID
+	refs := map[int]int{}
+
+	// copies of the ops for the journal since apply links inserted and
+	// updated nodes into the graph and later ops may modify them
+	journaled := make([]*discovery.Op, 0, len(ops))
+
+	var evs []*discovery.Event
+
+	for i, o := range ops {
+		var placeholder int
+		if o.Action == discovery.OP_INSERT {
+			if o.Endpoint != nil {
+				placeholder = o.Endpoint.NID
+			} else if o.Network != nil {
+				placeholder = o.Network.NID
+			}
+		}
+
+		var pending func() []*discovery.Event
+
+		err := resolve(o, refs)
+		if err == nil {
+			journaled = append(journaled, copyOp(o))
+			pending = m.opEvents(o)
+			err = m.apply(o)
+		}
+		if err != nil {
+			rollback()
+
+			if len(ops) > 1 {
+				return fmt.Errorf("op %v (%v): %w", i, o.Action, err)
+			}
+			return err
+		}
+
+		evs = append(evs, pending()...)
+
+		// replay must recreate the node with the same NID
+		if o.Action == discovery.OP_INSERT {
+			j := journaled[len(journaled)-1]
+			if o.Endpoint != nil {
+				j.Endpoint.NID = o.Endpoint.ID()
+			} else {
+				j.Network.NID = o.Network.ID()
+			}
+		}
+
+		if placeholder < 0 {
+			if o.Endpoint != nil {
+				refs[placeholder] = o.Endpoint.ID()
+			} else {
+				refs[placeholder] = o.Network.ID()
+			}
+		}
+	}
+
+	if m.jrnl != nil && len(ops) > 0 {
+		if err := m.jrnl.append(journaled); err != nil {
+			log.Error("unable to journal ops: %v", err)
+			rollback()
+			return err
+		}
+	}
+
+	// original state of the nodes for the revision history
+	touched := m.graph.Touched()
+
+	m.graph.Commit()
+
+	// the events were created before the revisions were incremented
+	for _, e := range evs {
+		n, ok := m.graph.Nodes[e.NID]
+		if !ok {
+			continue
+		}
+
+		if e.Endpoint != nil {
+			e.Endpoint.Rev = n.Revision()
+		} else if e.Network != nil {
+			e.Network.Rev = n.Revision()
+		}
+	}
+
+	if len(evs) > 0 {
+		m.events.publish(evs)
+		m.recordRevision(touched, saved, false)
+	}
+
+	return nil
+}
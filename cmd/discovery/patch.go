@@ -0,0 +1,99 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// webPatch applies a minigraph.Patch to a single node, for PATCH
+// /endpoints/<nid> and /networks/<nid>. If-Match may be set to the ETag of
+// the node to fail with 409 if the node has been modified since it was read.
+// The response contains the node after the patch was applied.
+func webPatch(m *model, w http.ResponseWriter, r *http.Request, typ int, s string) {
+	nid, err := strconv.Atoi(s)
+	if err != nil {
+		err := fmt.Errorf("invalid nid: %v", s)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	rev, err := ifMatch(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	p := &minigraph.Patch{}
+	if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+		log.Errorln(err)
+		w.WriteHeader(422)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	// make sure the node is the type that the path refers to
+	if n, ok := m.graph.Nodes[nid]; !ok || n.Type() != typ {
+		err := fmt.Errorf("no such node: %v", nid)
+		w.WriteHeader(http.StatusNotFound)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	o := discovery.PatchOp(nid, p)
+	o.Rev = rev
+
+	if err := m.commit(o); err != nil {
+		writeCommitError(w, r, err)
+		return
+	}
+
+	n := m.graph.Nodes[nid]
+
+	b, err := json.MarshalIndent(n, "", "    ")
+	if err != nil {
+		log.Errorln(err)
+		w.WriteHeader(422)
+		discovery.WriteError(w, r, err)
+	} else {
+		w.Header().Set("ETag", discovery.ETag(n.Revision()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
+
+// ifMatch parses the revision from If-Match. Returns 0 if it is not set or is
+// "*", meaning that any revision matches.
+func ifMatch(r *http.Request) (int, error) {
+	v := r.Header.Get("If-Match")
+	if v == "" {
+		return 0, nil
+	}
+
+	return discovery.ParseETag(v)
+}
+
+// writeCommitError writes the error from commit, using 409 for conflicts.
+func writeCommitError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Errorln(err)
+
+	if errors.Is(err, discovery.ErrConflict) {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	discovery.WriteError(w, r, err)
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// webQuery parses the q parameter from the request, if there is one. See
+// minigraph.Query for the syntax. Returns nil if there is no q parameter.
+func webQuery(r *http.Request) (*minigraph.Query, error) {
+	s := r.URL.Query().Get("q")
+	if s == "" {
+		return nil, nil
+	}
+
+	return minigraph.ParseQuery(s)
+}
+
+// filterNodes returns the nodes that match the query, or all the nodes if q
+// is nil.
+func filterNodes(nodes []minigraph.Node, q *minigraph.Query) []minigraph.Node {
+	if q == nil {
+		return nodes
+	}
+
+	var ret []minigraph.Node
+	for _, n := range nodes {
+		if q.Match(n) {
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
+
+// filterEndpoints is filterNodes for endpoints
+func filterEndpoints(endpoints []*minigraph.Endpoint, q *minigraph.Query) []*minigraph.Endpoint {
+	if q == nil {
+		return endpoints
+	}
+
+	var ret []*minigraph.Endpoint
+	for _, e := range endpoints {
+		if q.Match(e) {
+			ret = append(ret, e)
+		}
+	}
+
+	return ret
+}
+
+// filterNetworks is filterNodes for networks
+func filterNetworks(networks []*minigraph.Network, q *minigraph.Query) []*minigraph.Network {
+	if q == nil {
+		return networks
+	}
+
+	var ret []*minigraph.Network
+	for _, n := range networks {
+		if q.Match(n) {
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
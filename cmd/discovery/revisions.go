@@ -0,0 +1,576 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Each commit creates a new revision (see feed.publish). For the most recent
+// revisions, we keep the state of the nodes and config from before the commit
+// so that we can walk backwards from the current model to reconstruct older
+// revisions for diffs and rollbacks. Older revisions are only available if
+// they were tagged as snapshots, which keep a full copy of the model and are
+// saved in the data directory, if there is one.
+const (
+	revisionHistory = 1000
+
+	snapshotDir = "snapshots"
+)
+
+// revision records what a commit changed.
+type revision struct {
+	discovery.Revision
+
+	// state of the nodes before the commit, nil if the node did not exist
+	before map[int]minigraph.Node
+
+	// before contains every node, set when the whole model was replaced
+	full bool
+
+	// config before the commit
+	config map[string]string
+}
+
+type snapshot struct {
+	discovery.Snapshot
+
+	// encoded store, nil if the snapshot is in the data directory
+	data []byte
+}
+
+var validSnapshot = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*$`)
+
+// recordRevision records the revision that was just published. Callers must
+// hold the model's lock.
+func (m *model) recordRevision(before map[int]minigraph.Node, config map[string]string, full bool) {
+	r := &revision{
+		Revision: discovery.Revision{
+			Rev:   m.events.current(),
+			Time:  time.Now(),
+			Nodes: len(before),
+		},
+		before: before,
+		full:   full,
+		config: config,
+	}
+
+	if full {
+		r.Nodes = len(m.graph.Nodes)
+		for id := range before {
+			if _, ok := m.graph.Nodes[id]; !ok {
+				r.Nodes++
+			}
+		}
+	}
+
+	m.revisions = append(m.revisions, r)
+	if len(m.revisions) > revisionHistory {
+		m.revisions = append([]*revision(nil), m.revisions[len(m.revisions)-revisionHistory:]...)
+	}
+}
+
+// stateAt reconstructs the nodes and config at the revision. The returned
+// nodes are shared with the graph and history and must not be modified.
+// Callers must hold the model's lock.
+func (m *model) stateAt(rev uint64) (map[int]minigraph.Node, map[string]string, error) {
+	cur := m.events.current()
+
+	if rev > cur {
+		return nil, nil, fmt.Errorf("no such revision: %v", rev)
+	}
+
+	// the oldest revision that we can get to from the history
+	oldest := cur
+	if len(m.revisions) > 0 {
+		oldest = m.revisions[0].Rev - 1
+	}
+
+	if rev < oldest {
+		return nil, nil, fmt.Errorf("revision %v is no longer available, oldest is %v", rev, oldest)
+	}
+
+	nodes := make(map[int]minigraph.Node, len(m.graph.Nodes))
+	for id, n := range m.graph.Nodes {
+		nodes[id] = n
+	}
+
+	cfg := m.config
+
+	for i := len(m.revisions) - 1; i >= 0 && m.revisions[i].Rev > rev; i-- {
+		r := m.revisions[i]
+
+		if r.full {
+			nodes = make(map[int]minigraph.Node, len(r.before))
+		}
+
+		for id, n := range r.before {
+			if n == nil {
+				delete(nodes, id)
+			} else {
+				nodes[id] = n
+			}
+		}
+
+		cfg = r.config
+	}
+
+	return nodes, cfg, nil
+}
+
+// resolveState finds the revision and state for a snapshot name or revision
+// number. Callers must hold the model's lock.
+func (m *model) resolveState(s string) (uint64, map[int]minigraph.Node, map[string]string, error) {
+	if snap, ok := m.snapshots[s]; ok {
+		st, err := m.readSnapshot(snap)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		g, err := minigraph.Read(bytes.NewBuffer(st.Graph))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		return snap.Rev, g.Nodes, st.Config, nil
+	}
+
+	rev, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("no such snapshot: %v", s)
+	}
+
+	nodes, cfg, err := m.stateAt(rev)
+	return rev, nodes, cfg, err
+}
+
+// rollback restores the state for the snapshot name or revision number by
+// committing the ops to get there from the current state, creating a new
+// revision. Callers must hold the model's lock.
+func (m *model) rollback(s string) error {
+	_, nodes, cfg, err := m.resolveState(s)
+	if err != nil {
+		return err
+	}
+
+	ops := m.stateOps(nodes, cfg)
+
+	log.Info("rollback to %v: %v ops", s, len(ops))
+
+	return m.commit(ops...)
+}
+
+// stateOps returns the ops to get from the current state to the nodes and
+// config. Callers must hold the model's lock.
+func (m *model) stateOps(nodes map[int]minigraph.Node, cfg map[string]string) []*discovery.Op {
+	var deletes, inserts, updates []*discovery.Op
+
+	for _, id := range sortedIDs(m.graph.Nodes) {
+		n := m.graph.Nodes[id]
+		if n2, ok := nodes[id]; !ok || n2.Type() != n.Type() {
+			deletes = append(deletes, discovery.DeleteOp(id))
+		}
+	}
+
+	for _, id := range sortedIDs(nodes) {
+		n := nodes[id]
+		n2, ok := m.graph.Nodes[id]
+		if ok && n2.Type() == n.Type() && minigraph.DiffNode(n2, n) == nil {
+			continue
+		}
+
+		var o *discovery.Op
+
+		switch n := minigraph.CopyNode(n).(type) {
+		case *minigraph.Endpoint:
+			if ok && n2.Type() == n.Type() {
+				o = discovery.UpdateEndpointOp(n)
+			} else {
+				o = discovery.InsertEndpointOp(n)
+			}
+		case *minigraph.Network:
+			if ok && n2.Type() == n.Type() {
+				o = discovery.UpdateNetworkOp(n)
+			} else {
+				o = discovery.InsertNetworkOp(n)
+			}
+		}
+
+		if o.Action == discovery.OP_INSERT {
+			inserts = append(inserts, o)
+		} else {
+			updates = append(updates, o)
+		}
+	}
+
+	ops := append(deletes, inserts...)
+	ops = append(ops, updates...)
+
+	d := minigraph.DiffData(m.config, cfg)
+	for k, v := range d.Set {
+		ops = append(ops, discovery.SetConfigOp(k, v))
+	}
+	for _, k := range d.Deleted {
+		ops = append(ops, discovery.DeleteConfigOp(k))
+	}
+
+	return ops
+}
+
+func sortedIDs(nodes map[int]minigraph.Node) []int {
+	var res []int
+	for id := range nodes {
+		res = append(res, id)
+	}
+
+	sort.Ints(res)
+
+	return res
+}
+
+// createSnapshot tags the current state of the model with the name. Callers
+// must hold the model's lock.
+func (m *model) createSnapshot(name string) (*snapshot, error) {
+	if !validSnapshot.MatchString(name) {
+		return nil, fmt.Errorf("invalid snapshot name: %v", name)
+	}
+	if _, err := strconv.ParseUint(name, 10, 64); err == nil {
+		return nil, fmt.Errorf("snapshot name cannot be a revision: %v", name)
+	}
+	if _, ok := m.snapshots[name]; ok {
+		return nil, fmt.Errorf("snapshot already exists: %v", name)
+	}
+
+	snap := &snapshot{
+		Snapshot: discovery.Snapshot{
+			Name: name,
+			Rev:  m.events.current(),
+			Time: time.Now(),
+		},
+	}
+
+	var b bytes.Buffer
+	if err := m.writeStore(&b, snap.Rev); err != nil {
+		return nil, err
+	}
+
+	if m.jrnl == nil {
+		snap.data = b.Bytes()
+	} else {
+		dir := m.jrnl.path(snapshotDir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+
+		tmp := filepath.Join(dir, "."+name+".tmp")
+		if err := os.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	m.snapshots[name] = snap
+
+	return snap, nil
+}
+
+// deleteSnapshot removes the snapshot. Callers must hold the model's lock.
+func (m *model) deleteSnapshot(name string) error {
+	if _, ok := m.snapshots[name]; !ok {
+		return fmt.Errorf("no such snapshot: %v", name)
+	}
+
+	if m.jrnl != nil {
+		if err := os.Remove(m.jrnl.path(filepath.Join(snapshotDir, name))); err != nil {
+			return err
+		}
+	}
+
+	delete(m.snapshots, name)
+
+	return nil
+}
+
+// loadSnapshots finds the model's snapshots in the data directory.
+func (m *model) loadSnapshots() error {
+	dir := m.jrnl.path(snapshotDir)
+
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !validSnapshot.MatchString(name) {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			return err
+		}
+
+		s, err := readStore(filepath.Join(dir, name))
+		if err != nil {
+			log.Error("unable to read snapshot %v: %v", name, err)
+			continue
+		}
+
+		m.snapshots[name] = &snapshot{
+			Snapshot: discovery.Snapshot{
+				Name: name,
+				Rev:  s.Seq,
+				Time: info.ModTime(),
+			},
+		}
+	}
+
+	log.Info("found %v snapshots", len(m.snapshots))
+
+	return nil
+}
+
+func (m *model) readSnapshot(s *snapshot) (*store, error) {
+	if s.data == nil {
+		return readStore(m.jrnl.path(filepath.Join(snapshotDir, s.Name)))
+	}
+
+	st := &store{}
+	err := gob.NewDecoder(bytes.NewReader(s.data)).Decode(st)
+	return st, err
+}
+
+// webRevisions lists the recent revisions and supports the following
+// methods:
+//
+//	GET
+//		/revisions		current and recent revisions
+func webRevisions(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := &discovery.History{
+		Rev:       m.events.current(),
+		Revisions: []*discovery.Revision{},
+	}
+	for _, v := range m.revisions {
+		res.Revisions = append(res.Revisions, &v.Revision)
+	}
+
+	writeJSON(w, r, res)
+}
+
+// webSnapshots manages snapshots and supports the following methods:
+//
+//	GET
+//		/snapshots		list snapshots
+//		/snapshots/<name>	get a snapshot
+//	POST
+//		/snapshots/<name>	snapshot the current revision
+//	DELETE
+//		/snapshots/<name>	delete a snapshot
+func webSnapshots(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	name := strings.Join(p, "/")
+
+	if name == "" && r.Method != "GET" {
+		err := fmt.Errorf("not enough arguments: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if name == "" {
+			res := []*discovery.Snapshot{}
+			for _, v := range m.snapshots {
+				res = append(res, &v.Snapshot)
+			}
+
+			sort.Slice(res, func(i, j int) bool {
+				return res[i].Name < res[j].Name
+			})
+
+			writeJSON(w, r, res)
+			return
+		}
+
+		snap, ok := m.snapshots[name]
+		if !ok {
+			err := fmt.Errorf("no such snapshot: %v", name)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, &snap.Snapshot)
+	case "POST":
+		snap, err := m.createSnapshot(name)
+		if err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, &snap.Snapshot)
+	case "DELETE":
+		if err := m.deleteSnapshot(name); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// webDiff compares revisions and supports the following methods:
+//
+//	GET
+//		/diff/<from>		changes from a revision to the current revision
+//		/diff/<from>/<to>	changes between two revisions
+//
+// Where from and to are revision numbers or snapshot names.
+func webDiff(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(p) < 1 || len(p) > 2 || p[0] == "" {
+		err := fmt.Errorf("invalid diff: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	to := strconv.FormatUint(m.events.current(), 10)
+	if len(p) == 2 && p[1] != "" {
+		to = p[1]
+	}
+
+	fromRev, fromNodes, fromConfig, err := m.resolveState(p[0])
+	if err == nil {
+		var toRev uint64
+		var toNodes map[int]minigraph.Node
+		var toConfig map[string]string
+
+		toRev, toNodes, toConfig, err = m.resolveState(to)
+		if err == nil {
+			res := &discovery.Diff{
+				From:   fromRev,
+				To:     toRev,
+				Config: minigraph.DiffData(fromConfig, toConfig),
+				Nodes:  minigraph.DiffNodes(fromNodes, toNodes),
+			}
+
+			writeJSON(w, r, res)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusBadRequest)
+	discovery.WriteError(w, r, err)
+}
+
+// webRollback restores an earlier revision and supports the following
+// methods:
+//
+//	POST
+//		/rollback/<rev>		roll back to a revision number or snapshot name
+//
+// The response contains the new revision, which may be used to undo the
+// rollback.
+func webRollback(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(p) != 1 || p[0] == "" {
+		err := fmt.Errorf("invalid rollback: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	if err := m.rollback(p[0]); err != nil {
+		log.Errorln(err)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	res := &discovery.Revision{Rev: m.events.current()}
+	if len(m.revisions) > 0 && m.revisions[len(m.revisions)-1].Rev == res.Rev {
+		res = &m.revisions[len(m.revisions)-1].Revision
+	}
+
+	writeJSON(w, r, res)
+}
+
+// writeJSON writes v as the response
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		log.Errorln(err)
+		w.WriteHeader(422)
+		discovery.WriteError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// webPaths finds the shortest path between two nodes and supports the
+// following methods:
+//
+//	GET
+//		/paths/<from nid>/<to nid>			fewest hops
+//		/paths/<from nid>/<to nid>?weight=<key>	lowest total weight
+//
+// The weight is the key of a numeric edge attribute, such as delay. Links
+// without the attribute have a weight of 1. Responds with 404 if there is no
+// path between the nodes.
+func webPaths(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	switch r.Method {
+	case "GET":
+		if len(p) != 2 {
+			err := fmt.Errorf("expected /paths/<from nid>/<to nid>: %v", r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		var ids [2]int
+		for i, v := range p {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				err := fmt.Errorf("invalid nid: %v", v)
+				w.WriteHeader(http.StatusBadRequest)
+				discovery.WriteError(w, r, err)
+				return
+			}
+			ids[i] = id
+		}
+
+		path, err := m.graph.ShortestPath(ids[0], ids[1], r.URL.Query().Get("weight"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		if path == nil {
+			err := fmt.Errorf("no path from %v to %v", ids[0], ids[1])
+			w.WriteHeader(http.StatusNotFound)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, path)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// webComponents analyzes the connectivity of the graph and supports the
+// following methods:
+//
+//	GET
+//		/components			connected components, largest first
+//		/components/<nid>		component containing the node
+//		/components/articulation	nodes whose removal would
+//						disconnect the graph
+//		/components/bridges		links whose removal would
+//						disconnect the graph
+//
+// Components are lists of NIDs, sorted by NID.
+func webComponents(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	switch r.Method {
+	case "GET":
+		var res interface{}
+
+		switch v := p[0]; {
+		case len(p) > 1:
+			err := fmt.Errorf("invalid path: %v", r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		case v == "":
+			res = m.graph.Components()
+		case v == "articulation":
+			res = m.graph.ArticulationPoints()
+		case v == "bridges":
+			res = m.graph.Bridges()
+		default:
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				err := fmt.Errorf("invalid nid: %v", v)
+				w.WriteHeader(http.StatusBadRequest)
+				discovery.WriteError(w, r, err)
+				return
+			}
+
+			c, err := m.graph.Component(id)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				discovery.WriteError(w, r, err)
+				return
+			}
+
+			res = c
+		}
+
+		writeJSON(w, r, res)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
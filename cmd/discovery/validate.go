@@ -0,0 +1,75 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// webValidate checks whether the model can be emulated and supports the
+// following methods:
+//
+//	GET
+//		/validate	report of errors and warnings, see
+//				minigraph.Validate
+//	POST
+//		/validate	repair the referential inconsistencies and return
+//				the report from before the repairs, with the
+//				repaired problems marked as fixed
+//
+// The repairs are committed as a single transaction.
+func webValidate(m *model, w http.ResponseWriter, r *http.Request) {
+	log.Info("%v\t%v", r.Method, r.RequestURI)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	p := strings.Split(r.URL.Path, "/")[2:]
+	log.Debug("split path: %v", p)
+
+	if len(p) > 1 || p[0] != "" {
+		err := fmt.Errorf("invalid path: %v", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		discovery.WriteError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, r, m.graph.Validate())
+	case "POST":
+		report := m.graph.Validate()
+
+		var ops []*discovery.Op
+		for _, n := range m.graph.Repairs() {
+			switch n := n.(type) {
+			case *minigraph.Endpoint:
+				ops = append(ops, discovery.UpdateEndpointOp(n))
+			case *minigraph.Network:
+				ops = append(ops, discovery.UpdateNetworkOp(n))
+			}
+		}
+
+		if len(ops) > 0 {
+			if err := m.commit(ops...); err != nil {
+				writeCommitError(w, r, err)
+				return
+			}
+		}
+
+		for _, p := range report.Problems {
+			p.Fixed = p.Fixable
+		}
+
+		writeJSON(w, r, report)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -29,19 +29,21 @@ func init() {
 }
 
 type walker struct {
+	m       *model
 	id      int
 	last    time.Time
 	filter  int
 	visited map[int]bool
 }
 
-func NewWalker(filter int) int {
+func NewWalker(m *model, filter int) int {
 	walkerLock.Lock()
 	defer walkerLock.Unlock()
 
 	walkerID++
 
 	walkers[walkerID] = &walker{
+		m:       m,
 		id:      walkerID,
 		last:    time.Now(),
 		filter:  filter,
@@ -51,11 +53,11 @@ func NewWalker(filter int) int {
 	return walkerID
 }
 
-func WalkerNext(wid int) (minigraph.Node, error) {
+func WalkerNext(m *model, wid int) (minigraph.Node, error) {
 	walkerLock.Lock()
 	defer walkerLock.Unlock()
 	w, ok := walkers[wid]
-	if !ok {
+	if !ok || w.m != m {
 		return nil, fmt.Errorf("no such walker %v", wid)
 	}
 
@@ -91,7 +93,7 @@ func (w *walker) next() minigraph.Node {
 	// way. There are plenty of optimizations for this later on if we need
 	// to do so.
 	// search the graph for an unvisited node
-	for k, v := range graph.Nodes {
+	for k, v := range w.m.graph.Nodes {
 		if w.visited[k] {
 			continue
 		}
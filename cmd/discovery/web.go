@@ -10,9 +10,28 @@ package main
 // 	/node/<x>	node(s) by search field
 // 	/endpoint	all endpoints
 // 	/endpoint/<x>	endpoint(s) by search field
+//	/endpoint/<nid>	PATCH to set or delete individual keys
 // 	/network	all networks
 // 	/network/<x>	network(s) by search field
+//	/network/<nid>	PATCH to set or delete individual keys
 //	/walk
+//	/paths		shortest path between two nodes
+//	/components	connected components, articulation points, and bridges
+//	/validate	check that the model can be emulated and repair it
+//	/merge		merge another model into the model
+//	/batch		apply a list of ops atomically
+//	/events		stream changes as server-sent events
+//	/lookup		find nodes by exact value or subnet using indexes
+//	/revisions	list recent revisions
+//	/snapshots	tag, list, and delete snapshots of the model
+//	/diff		changes between two revisions
+//	/rollback	restore an earlier revision
+//	/export		export the model as GraphML, JSON Graph Format, or DOT
+//	/import		replace the model from GraphML, JSON Graph Format, or DOT
+//	/models		create, clone, list, and delete models
+//
+// The above are for the default model. Each of them is also available for
+// the named models under /models/<name>/, e.g. /models/<name>/endpoints.
 
 import (
 	"bytes"
@@ -23,44 +42,136 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
 	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
+// handler handles a request for a model
+type handler func(*model, http.ResponseWriter, *http.Request)
+
+var (
+	// handlers for each model, by the first element of the path
+	routes = map[string]handler{}
+
+	static http.Handler
+)
+
 func init() {
-	http.Handle("/", http.StripPrefix("/", http.FileServer(http.Dir(*f_web))))
-	http.HandleFunc("/nodes/", muer(webNodes))
-	http.HandleFunc("/endpoints/", muer(webEndpoints))
-	http.HandleFunc("/networks/", muer(webNetworks))
-	http.HandleFunc("/neighbors/", muer(webNeighbors))
-	http.HandleFunc("/walk/", muer(webWalk))
-	http.HandleFunc("/daemon/", muer(webDaemon))
-	http.HandleFunc("/connect/", muer(webConnect))
-	http.HandleFunc("/disconnect/", muer(webDisconnect))
-	http.HandleFunc("/config/", muer(webConfig))
-	http.HandleFunc("/image/", muer(webImage))
+	static = http.FileServer(http.Dir(*f_web))
+
+	http.Handle("/", http.StripPrefix("/", static))
+	http.HandleFunc("/models/", webModels)
+
+	route("nodes", lister(minigraph.TYPE_NODE, muer(webNodes)))
+	route("endpoints", lister(minigraph.TYPE_ENDPOINT, muer(webEndpoints)))
+	route("networks", lister(minigraph.TYPE_NETWORK, muer(webNetworks)))
+	route("neighbors", muer(webNeighbors))
+	route("paths", muer(webPaths))
+	route("components", muer(webComponents))
+	route("validate", muer(webValidate))
+	route("walk", muer(webWalk))
+	route("daemon", muer(webDaemon))
+	route("connect", muer(webConnect))
+	route("disconnect", muer(webDisconnect))
+	route("config", muer(webConfig))
+	route("image", muer(webImage))
+	route("batch", muer(webBatch))
+	route("lookup", muer(webLookup))
+	route("revisions", muer(webRevisions))
+	route("snapshots", muer(webSnapshots))
+	route("diff", muer(webDiff))
+	route("rollback", muer(webRollback))
+	route("export", muer(webExport))
+	route("import", muer(webImport))
+
+	// not wrapped in muer since it locks another model first
+	route("merge", webMerge)
+
+	// not wrapped in muer since it streams until the client disconnects
+	route("events", webEvents)
+}
+
+// route registers the handler for /<name>/ on the default model and for
+// /models/<model>/<name>/ on the named models (see webModel).
+func route(name string, fn handler) {
+	routes[name] = fn
+
+	http.HandleFunc("/"+name+"/", func(w http.ResponseWriter, r *http.Request) {
+		fn(getModel(defaultModel), w, r)
+	})
 }
 
-var mu sync.Mutex
+// muer holds the model's lock while handling the request
+func muer(fn handler) handler {
+	return func(m *model, w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
 
-func muer(fn func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		mu.Lock()
-		defer mu.Unlock()
+		if m.deleted {
+			log.Info("%v\t%v", r.Method, r.RequestURI)
 
-		fn(w, r)
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			err := fmt.Errorf("no such model: %v", m.name)
+			w.WriteHeader(http.StatusNotFound)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		fn(m, w, r)
 	}
 }
 
 func web() {
+	a := &auth{
+		certs: *f_tlsCA != "",
+	}
+
+	if *f_tokens != "" {
+		tokens, err := readTokens(*f_tokens)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		a.tokens = tokens
+	}
+
+	if *f_tlsCert == "" && (*f_tlsKey != "" || *f_tlsCA != "") {
+		log.Fatalln("-tls-key and -tls-ca require -tls-cert")
+	}
+
+	config, err := tlsConfig(a)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if !a.enabled() {
+		log.Warn("authentication disabled, anyone who can connect has read-write access")
+	} else if *f_tlsCert == "" {
+		log.Warn("tokens will be sent in the clear, use -tls-cert")
+	}
+
+	srv := &http.Server{
+		Addr:      *f_serve,
+		Handler:   a.wrap(http.DefaultServeMux),
+		TLSConfig: config,
+	}
+
+	if *f_tlsCert != "" {
+		key := *f_tlsKey
+		if key == "" {
+			key = *f_tlsCert
+		}
+
+		log.Debug("starting web service on %v with TLS", *f_serve)
+		log.Fatalln(srv.ListenAndServeTLS(*f_tlsCert, key))
+	}
+
 	log.Debug("starting web service on %v", *f_serve)
-	log.Fatalln(http.ListenAndServe(*f_serve, nil))
+	log.Fatalln(srv.ListenAndServe())
 }
 
-func webImage(w http.ResponseWriter, r *http.Request) {
+func webImage(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	p := strings.Split(r.URL.Path, "/")[2:]
@@ -77,7 +188,7 @@ func webImage(w http.ResponseWriter, r *http.Request) {
 
 		log.Debug("using NID: %v", p[0])
 
-		endpoint := graph.FindEndpoints("nid", p[0])
+		endpoint := m.graph.FindEndpoints("nid", p[0])
 		if endpoint == nil {
 			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 			err := fmt.Errorf("no such endpoint: %v", p[0])
@@ -116,7 +227,7 @@ func webImage(w http.ResponseWriter, r *http.Request) {
 //	/connect/<network nid>/<endpoint nid>/<edge index>
 //
 // If no edge index is specified, a new one is created.
-func webConnect(w http.ResponseWriter, r *http.Request) {
+func webConnect(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -135,7 +246,7 @@ func webConnect(w http.ResponseWriter, r *http.Request) {
 
 		log.Debug("using NIDs: %v, %v", p[0], p[1])
 
-		endpoint := graph.FindEndpoints("nid", p[1])
+		endpoint := m.graph.FindEndpoints("nid", p[1])
 		if endpoint == nil {
 			err := fmt.Errorf("no such endpoint: %v", p[1])
 			w.WriteHeader(http.StatusBadRequest)
@@ -143,7 +254,7 @@ func webConnect(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		network := graph.FindNetworks("nid", p[0])
+		network := m.graph.FindNetworks("nid", p[0])
 		if network == nil {
 			err := fmt.Errorf("no such network: %v", p[0])
 			w.WriteHeader(http.StatusBadRequest)
@@ -151,40 +262,36 @@ func webConnect(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var edge *minigraph.Edge
+		// a new edge unless an index is specified
+		eid := discovery.EDGE_NONE
 
 		if len(p) == 3 {
 			log.Debug("using edge index: %v", p[2])
-			eid, err := strconv.Atoi(p[2])
+			var err error
+			eid, err = strconv.Atoi(p[2])
 			if err != nil {
 				w.WriteHeader(http.StatusBadRequest)
 				discovery.WriteError(w, r, err)
 				return
 			}
 
-			if len(endpoint[0].Edges) <= eid {
+			if eid < 0 || len(endpoint[0].Edges) <= eid {
 				err := fmt.Errorf("invalid edge id: %v", p[2])
 				w.WriteHeader(http.StatusBadRequest)
 				discovery.WriteError(w, r, err)
 				return
 			}
-
-			edge = endpoint[0].Edges[eid]
-		} else {
-			// a new edge
-			edge = endpoint[0].NewEdge()
 		}
 
-		err := graph.Connect(endpoint[0], network[0], edge)
+		err := m.commit(discovery.ConnectOp(network[0].ID(), endpoint[0].ID(), eid))
 		if err != nil {
-			endpoint[0].Edges = endpoint[0].Edges[:len(endpoint[0].Edges)-1]
 			log.Errorln(err)
 			w.WriteHeader(http.StatusInternalServerError)
 			discovery.WriteError(w, r, err)
 			return
 		}
 
-		n := graph.FindEndpoints("nid", p[1])
+		n := m.graph.FindEndpoints("nid", p[1])
 		b, err := json.MarshalIndent(n[0], "", "    ")
 		if err != nil {
 			log.Errorln(err)
@@ -202,7 +309,7 @@ func webConnect(w http.ResponseWriter, r *http.Request) {
 // disconnect an endpoint to a network. Nodes must be specified by NID. The URL
 // must be of the form:
 //	/connect/<network nid>/<endpoint nid>
-func webDisconnect(w http.ResponseWriter, r *http.Request) {
+func webDisconnect(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -221,7 +328,7 @@ func webDisconnect(w http.ResponseWriter, r *http.Request) {
 
 		log.Debug("using NIDs: %v, %v", p[0], p[1])
 
-		endpoint := graph.FindEndpoints("nid", p[1])
+		endpoint := m.graph.FindEndpoints("nid", p[1])
 		if endpoint == nil {
 			err := fmt.Errorf("no such endpoint: %v", p[1])
 			w.WriteHeader(http.StatusBadRequest)
@@ -229,7 +336,7 @@ func webDisconnect(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		network := graph.FindNetworks("nid", p[0])
+		network := m.graph.FindNetworks("nid", p[0])
 		if network == nil {
 			err := fmt.Errorf("no such network: %v", p[0])
 			w.WriteHeader(http.StatusBadRequest)
@@ -237,7 +344,7 @@ func webDisconnect(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err := graph.Disconnect(endpoint[0], network[0])
+		err := m.commit(discovery.DisconnectOp(network[0].ID(), endpoint[0].ID()))
 		if err != nil {
 			log.Errorln(err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -245,7 +352,7 @@ func webDisconnect(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		n := graph.FindEndpoints("nid", p[1])
+		n := m.graph.FindEndpoints("nid", p[1])
 		b, err := json.MarshalIndent(n[0], "", "    ")
 		if err != nil {
 			log.Errorln(err)
@@ -264,13 +371,18 @@ func webDisconnect(w http.ResponseWriter, r *http.Request) {
 // the following methods:
 //	GET
 //		/nodes			list all nodes
+//		/nodes?q=<query>	find nodes matching a query
 //		/nodes/<field>/<value>	find nodes by a field
 //		/nodes/<value>
 //	DELETE
+//		/nodes?q=<query>	delete nodes matching a query
 //		/nodes/<field>/<value>	delete a node
 //		/nodes/<value>
 //
-func webNodes(w http.ResponseWriter, r *http.Request) {
+// The q parameter may be combined with a search to narrow the results. See
+// minigraph.Query for the query syntax. Listings may be paginated with limit
+// and after or streamed as NDJSON, see list.go.
+func webNodes(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -280,58 +392,61 @@ func webNodes(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		var nodes []minigraph.Node
-		switch len(p) {
-		case 2: // return all nodes or freeform search
-			if strings.TrimSpace(p[1]) == "" {
-				nodes = graph.GetNodes()
-			} else {
-				nodes = graph.FindNodes("", p[1])
-			}
-		case 3: // search
-			nodes = graph.FindNodes(p[1], p[2])
-		default:
+		q, err := webQuery(r)
+		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			// TODO: write Allow in the header
+			discovery.WriteError(w, r, err)
 			return
 		}
 
-		b, err := json.MarshalIndent(nodes, "", "    ")
+		nodes, err := m.findNodes(minigraph.TYPE_NODE, p, q)
 		if err != nil {
-			log.Errorln(err)
-			w.WriteHeader(422)
+			w.WriteHeader(http.StatusBadRequest)
 			discovery.WriteError(w, r, err)
-		} else {
-			w.WriteHeader(http.StatusOK)
-			w.Write(b)
+			return
 		}
+
+		writeNodes(m, w, r, nodes)
 	case "DELETE":
+		q, err := webQuery(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
 		var nodes []minigraph.Node
 		switch len(p) {
-		case 2: // delete by freeform search
-			if strings.TrimSpace(p[1]) == "" {
+		case 2: // delete by freeform search or query
+			if strings.TrimSpace(p[1]) == "" && q == nil {
 				w.WriteHeader(http.StatusBadRequest)
 				discovery.WriteError(w, r, fmt.Errorf("delete requires a search term"))
 				return
+			} else if strings.TrimSpace(p[1]) == "" {
+				nodes = m.graph.GetNodes()
 			} else {
-				nodes = graph.FindNodes("", p[1])
+				nodes = m.graph.FindNodes("", p[1])
 			}
 		case 3: // search
-			nodes = graph.FindNodes(p[1], p[2])
+			nodes = m.graph.FindNodes(p[1], p[2])
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 			// TODO: write Allow in the header
 			return
 		}
 
+		nodes = filterNodes(nodes, q)
+
+		var ops []*discovery.Op
 		for _, v := range nodes {
-			err := graph.Delete(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
+			ops = append(ops, discovery.DeleteOp(v.ID()))
+		}
+
+		if err := m.commit(ops...); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			discovery.WriteError(w, r, err)
+			return
 		}
 
 		// write out the nodes we deleted
@@ -353,17 +468,27 @@ func webNodes(w http.ResponseWriter, r *http.Request) {
 // supports the following methods:
 //	GET
 //		/endpoints			list all endpoints
+//		/endpoints?q=<query>		find endpoints matching a query
 //		/endpoints/<field>/<value>	find endpoints by a field
 //		/endpoints/<value>
 //	POST
 //		/endpoints			insert a new endpoint
 //	PUT
 //		/endpoints			update an endpoint
+//	PATCH
+//		/endpoints/<nid>		set or delete individual keys
 //	DELETE
+//		/endpoints?q=<query>		delete endpoints matching a query
 //		/endpoints/<field>/<value>	delete an endpoint
 //		/endpoints/<value>
 //
-func webEndpoints(w http.ResponseWriter, r *http.Request) {
+// Listings may be paginated with limit and after or streamed as NDJSON, see
+// list.go.
+//
+// GET /endpoints/nid/<nid>, PUT with a single node, and PATCH set ETag to the
+// node's revision. PUT and PATCH with If-Match fail with 409 if the node has
+// been modified since that revision.
+func webEndpoints(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -373,31 +498,25 @@ func webEndpoints(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		var endpoints []*minigraph.Endpoint
-		switch len(p) {
-		case 2: // return all endpoints or freeform search
-			if strings.TrimSpace(p[1]) == "" {
-				endpoints = graph.GetEndpoints()
-			} else {
-				endpoints = graph.FindEndpoints("", p[1])
-			}
-		case 3: // search
-			endpoints = graph.FindEndpoints(p[1], p[2])
-		default:
+		q, err := webQuery(r)
+		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			// TODO: write Allow in the header
+			discovery.WriteError(w, r, err)
 			return
 		}
 
-		b, err := json.MarshalIndent(endpoints, "", "    ")
+		nodes, err := m.findNodes(minigraph.TYPE_ENDPOINT, p, q)
 		if err != nil {
-			log.Errorln(err)
-			w.WriteHeader(422)
+			w.WriteHeader(http.StatusBadRequest)
 			discovery.WriteError(w, r, err)
-		} else {
-			w.WriteHeader(http.StatusOK)
-			w.Write(b)
+			return
+		}
+
+		if len(p) == 3 && strings.ToLower(p[1]) == "nid" && len(nodes) == 1 {
+			w.Header().Set("ETag", discovery.ETag(nodes[0].Revision()))
 		}
+
+		writeNodes(m, w, r, nodes)
 	case "POST":
 		var data bytes.Buffer
 		io.Copy(&data, r.Body)
@@ -412,22 +531,19 @@ func webEndpoints(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var nodes []minigraph.Node
+		var ops []*discovery.Op
 		for _, v := range endpoints {
-			if v.D == nil {
-				v.D = make(map[string]string)
-			}
-			n, err := graph.Insert(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
-			nodes = append(nodes, n)
+			ops = append(ops, discovery.InsertEndpointOp(v))
 		}
 
-		b, err := json.MarshalIndent(nodes, "", "    ")
+		if err := m.commit(ops...); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		b, err := json.MarshalIndent(endpoints, "", "    ")
 		if err != nil {
 			log.Errorln(err)
 			w.WriteHeader(422)
@@ -450,58 +566,90 @@ func webEndpoints(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		rev, err := ifMatch(r)
+		if err == nil && rev != 0 && len(endpoints) != 1 {
+			err = fmt.Errorf("If-Match requires a single endpoint")
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
 		// update each one based on NID
-		var nodes []minigraph.Node
+		var ops []*discovery.Op
 		for _, v := range endpoints {
-			if v.D == nil {
-				v.D = make(map[string]string)
-			}
-			n, err := graph.Update(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
-			nodes = append(nodes, n)
+			ops = append(ops, discovery.UpdateEndpointOp(v))
 		}
 
-		b, err := json.MarshalIndent(nodes, "", "    ")
+		if rev != 0 {
+			ops[0].Rev = rev
+		}
+
+		if err := m.commit(ops...); err != nil {
+			writeCommitError(w, r, err)
+			return
+		}
+
+		b, err := json.MarshalIndent(endpoints, "", "    ")
 		if err != nil {
 			log.Errorln(err)
 			w.WriteHeader(422)
 			discovery.WriteError(w, r, err)
 		} else {
+			if len(endpoints) == 1 {
+				w.Header().Set("ETag", discovery.ETag(endpoints[0].Rev))
+			}
 			w.WriteHeader(http.StatusCreated)
 			w.Write(b)
 		}
+	case "PATCH":
+		if len(p) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		webPatch(m, w, r, minigraph.TYPE_ENDPOINT, p[1])
 	case "DELETE":
+		q, err := webQuery(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
 		var endpoints []*minigraph.Endpoint
 		switch len(p) {
-		case 2: // delete by freeform search
-			if strings.TrimSpace(p[1]) == "" {
+		case 2: // delete by freeform search or query
+			if strings.TrimSpace(p[1]) == "" && q == nil {
 				w.WriteHeader(http.StatusBadRequest)
 				discovery.WriteError(w, r, fmt.Errorf("delete requires a search term"))
 				return
+			} else if strings.TrimSpace(p[1]) == "" {
+				endpoints = m.graph.GetEndpoints()
 			} else {
-				endpoints = graph.FindEndpoints("", p[1])
+				endpoints = m.graph.FindEndpoints("", p[1])
 			}
 		case 3: // search
-			endpoints = graph.FindEndpoints(p[1], p[2])
+			endpoints = m.graph.FindEndpoints(p[1], p[2])
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 			// TODO: write Allow in the header
 			return
 		}
 
+		endpoints = filterEndpoints(endpoints, q)
+
+		var ops []*discovery.Op
 		for _, v := range endpoints {
-			err := graph.Delete(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
+			ops = append(ops, discovery.DeleteOp(v.ID()))
+		}
+
+		if err := m.commit(ops...); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			discovery.WriteError(w, r, err)
+			return
 		}
 
 		// write out the endpoints we deleted
@@ -523,17 +671,27 @@ func webEndpoints(w http.ResponseWriter, r *http.Request) {
 // supports the following methods:
 //	GET
 //		/networks			list all networks
+//		/networks?q=<query>		find networks matching a query
 //		/networks/<field>/<value>	find networks by a field
 //		/networks/<value>
 //	POST
 //		/networks			insert a new network
 //	PUT
 //		/networks			update an network
+//	PATCH
+//		/networks/<nid>		set or delete individual keys
 //	DELETE
+//		/networks?q=<query>		delete networks matching a query
 //		/networks/<field>/<value>	delete an network
 //		/networks/<value>
 //
-func webNetworks(w http.ResponseWriter, r *http.Request) {
+// Listings may be paginated with limit and after or streamed as NDJSON, see
+// list.go.
+//
+// GET /networks/nid/<nid>, PUT with a single node, and PATCH set ETag to the
+// node's revision. PUT and PATCH with If-Match fail with 409 if the node has
+// been modified since that revision.
+func webNetworks(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -543,31 +701,25 @@ func webNetworks(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		var networks []*minigraph.Network
-		switch len(p) {
-		case 2: // return all networks or freeform search
-			if strings.TrimSpace(p[1]) == "" {
-				networks = graph.GetNetworks()
-			} else {
-				networks = graph.FindNetworks("", p[1])
-			}
-		case 3: // search
-			networks = graph.FindNetworks(p[1], p[2])
-		default:
+		q, err := webQuery(r)
+		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			// TODO: write Allow in the header
+			discovery.WriteError(w, r, err)
 			return
 		}
 
-		b, err := json.MarshalIndent(networks, "", "    ")
+		nodes, err := m.findNodes(minigraph.TYPE_NETWORK, p, q)
 		if err != nil {
-			log.Errorln(err)
-			w.WriteHeader(422)
+			w.WriteHeader(http.StatusBadRequest)
 			discovery.WriteError(w, r, err)
-		} else {
-			w.WriteHeader(http.StatusOK)
-			w.Write(b)
+			return
+		}
+
+		if len(p) == 3 && strings.ToLower(p[1]) == "nid" && len(nodes) == 1 {
+			w.Header().Set("ETag", discovery.ETag(nodes[0].Revision()))
 		}
+
+		writeNodes(m, w, r, nodes)
 	case "POST":
 		var data bytes.Buffer
 		io.Copy(&data, r.Body)
@@ -582,19 +734,19 @@ func webNetworks(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var nodes []minigraph.Node
+		var ops []*discovery.Op
 		for _, v := range networks {
-			n, err := graph.Insert(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
-			nodes = append(nodes, n)
+			ops = append(ops, discovery.InsertNetworkOp(v))
 		}
 
-		b, err := json.MarshalIndent(nodes, "", "    ")
+		if err := m.commit(ops...); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		b, err := json.MarshalIndent(networks, "", "    ")
 		if err != nil {
 			log.Errorln(err)
 			w.WriteHeader(422)
@@ -617,55 +769,90 @@ func webNetworks(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		rev, err := ifMatch(r)
+		if err == nil && rev != 0 && len(networks) != 1 {
+			err = fmt.Errorf("If-Match requires a single network")
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
 		// update each one based on NID
-		var nodes []minigraph.Node
+		var ops []*discovery.Op
 		for _, v := range networks {
-			n, err := graph.Update(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
-			nodes = append(nodes, n)
+			ops = append(ops, discovery.UpdateNetworkOp(v))
 		}
 
-		b, err := json.MarshalIndent(nodes, "", "    ")
+		if rev != 0 {
+			ops[0].Rev = rev
+		}
+
+		if err := m.commit(ops...); err != nil {
+			writeCommitError(w, r, err)
+			return
+		}
+
+		b, err := json.MarshalIndent(networks, "", "    ")
 		if err != nil {
 			log.Errorln(err)
 			w.WriteHeader(422)
 			discovery.WriteError(w, r, err)
 		} else {
+			if len(networks) == 1 {
+				w.Header().Set("ETag", discovery.ETag(networks[0].Rev))
+			}
 			w.WriteHeader(http.StatusCreated)
 			w.Write(b)
 		}
+	case "PATCH":
+		if len(p) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		webPatch(m, w, r, minigraph.TYPE_NETWORK, p[1])
 	case "DELETE":
+		q, err := webQuery(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
 		var networks []*minigraph.Network
 		switch len(p) {
-		case 2: // delete by freeform search
-			if strings.TrimSpace(p[1]) == "" {
+		case 2: // delete by freeform search or query
+			if strings.TrimSpace(p[1]) == "" && q == nil {
 				w.WriteHeader(http.StatusBadRequest)
 				discovery.WriteError(w, r, fmt.Errorf("delete requires a search term"))
 				return
+			} else if strings.TrimSpace(p[1]) == "" {
+				networks = m.graph.GetNetworks()
 			} else {
-				networks = graph.FindNetworks("", p[1])
+				networks = m.graph.FindNetworks("", p[1])
 			}
 		case 3: // search
-			networks = graph.FindNetworks(p[1], p[2])
+			networks = m.graph.FindNetworks(p[1], p[2])
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 			// TODO: write Allow in the header
 			return
 		}
 
+		networks = filterNetworks(networks, q)
+
+		var ops []*discovery.Op
 		for _, v := range networks {
-			err := graph.Delete(v)
-			if err != nil {
-				log.Errorln(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				discovery.WriteError(w, r, err)
-				return
-			}
+			ops = append(ops, discovery.DeleteOp(v.ID()))
+		}
+
+		if err := m.commit(ops...); err != nil {
+			log.Errorln(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			discovery.WriteError(w, r, err)
+			return
 		}
 
 		// write out the networks we deleted
@@ -688,8 +875,12 @@ func webNetworks(w http.ResponseWriter, r *http.Request) {
 //	GET
 //		/neighbors/<field>/<value>	find nodes by a field
 //		/neighbors/<value>
+//		/neighbors/...?depth=<k>	nodes within k hops, or all the
+//						connected nodes if k is -1
 //
-func webNeighbors(w http.ResponseWriter, r *http.Request) {
+// The default depth is 1, the node's direct neighbors. Endpoints are only
+// linked to networks so endpoints on the same network are two hops apart.
+func webNeighbors(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -699,14 +890,25 @@ func webNeighbors(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
+		depth := 1
+		if v := r.URL.Query().Get("depth"); v != "" {
+			var err error
+			if depth, err = strconv.Atoi(v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				discovery.WriteError(w, r, fmt.Errorf("invalid depth: %v", v))
+				return
+			}
+		}
+
 		var nodes []minigraph.Node
 		switch len(p) {
 		case 2: // reeform search
 			if strings.TrimSpace(p[1]) == "" {
 				w.WriteHeader(http.StatusBadRequest)
 				discovery.WriteError(w, r, fmt.Errorf("invalid search term"))
+				return
 			} else {
-				nodes = graph.FindNodes("", p[1])
+				nodes = m.graph.FindNodes("", p[1])
 				if len(nodes) > 1 {
 					w.WriteHeader(http.StatusBadRequest)
 					discovery.WriteError(w, r, fmt.Errorf("search term not unique"))
@@ -714,7 +916,7 @@ func webNeighbors(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		case 3: // search
-			nodes = graph.FindNodes(p[1], p[2])
+			nodes = m.graph.FindNodes(p[1], p[2])
 			if len(nodes) > 1 {
 				discovery.WriteError(w, r, fmt.Errorf("search term not unique"))
 				return
@@ -725,10 +927,21 @@ func webNeighbors(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		nodeIDs := nodes[0].Neighbors()
-		nodes = []minigraph.Node{}
-		for _, v := range nodeIDs {
-			nodes = append(nodes, graph.Nodes[v])
+		if len(nodes) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			discovery.WriteError(w, r, fmt.Errorf("no such node"))
+			return
+		}
+
+		nodes, err := m.graph.Neighborhood(nodes[0].ID(), depth)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			discovery.WriteError(w, r, err)
+			return
+		}
+
+		if nodes == nil {
+			nodes = []minigraph.Node{}
 		}
 
 		b, err := json.MarshalIndent(nodes, "", "    ")
@@ -745,7 +958,7 @@ func webNeighbors(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func webWalk(w http.ResponseWriter, r *http.Request) {
+func webWalk(m *model, w http.ResponseWriter, r *http.Request) {
 	log.Info("%v\t%v", r.Method, r.RequestURI)
 
 	if r.Method != http.MethodGet {
@@ -764,20 +977,20 @@ func webWalk(w http.ResponseWriter, r *http.Request) {
 	switch strings.ToLower(p[1]) {
 	case "nodes":
 		// create a new node walker
-		id := NewWalker(minigraph.TYPE_NODE)
-		url := fmt.Sprintf("/walk/%v", id)
+		id := NewWalker(m, minigraph.TYPE_NODE)
+		url := fmt.Sprintf("%v/walk/%v", m.prefix(), id)
 		http.Redirect(w, r, url, http.StatusFound)
 		return
 	case "endpoints":
 		// create a new endpoint walker
-		id := NewWalker(minigraph.TYPE_ENDPOINT)
-		url := fmt.Sprintf("/walk/%v", id)
+		id := NewWalker(m, minigraph.TYPE_ENDPOINT)
+		url := fmt.Sprintf("%v/walk/%v", m.prefix(), id)
 		http.Redirect(w, r, url, http.StatusFound)
 		return
 	case "networks":
 		// create a new network walker
-		id := NewWalker(minigraph.TYPE_NETWORK)
-		url := fmt.Sprintf("/walk/%v", id)
+		id := NewWalker(m, minigraph.TYPE_NETWORK)
+		url := fmt.Sprintf("%v/walk/%v", m.prefix(), id)
 		http.Redirect(w, r, url, http.StatusFound)
 		return
 	default:
@@ -790,7 +1003,7 @@ func webWalk(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		n, err := WalkerNext(id)
+		n, err := WalkerNext(m, id)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			discovery.WriteError(w, r, err)
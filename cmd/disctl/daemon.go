@@ -4,6 +4,14 @@
 
 package main
 
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
 func daemonSave(path string) (string, error) {
 	return "", dc.Save(path)
 }
@@ -11,3 +19,45 @@ func daemonSave(path string) (string, error) {
 func daemonLoad(path string) (string, error) {
 	return "", dc.Load(path)
 }
+
+// export the model to a file or stdout:
+//
+//	[file]
+func daemonExport(format string) (string, error) {
+	args := flag.Args()
+	if len(args) > 1 {
+		return "", fmt.Errorf("invalid arguments: %v", args)
+	}
+
+	if len(args) == 0 {
+		var b bytes.Buffer
+		err := dc.Export(format, &b)
+		return strings.TrimRight(b.String(), "\n"), err
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return "", dc.Export(format, f)
+}
+
+// import the model from a file:
+//
+//	<file>
+func daemonImport(format string) (string, error) {
+	args := flag.Args()
+	if len(args) != 1 {
+		return "", fmt.Errorf("invalid arguments: %v", args)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return "", dc.Import(format, f)
+}
@@ -14,25 +14,43 @@ import (
 
 var (
 	f_panic  = flag.Bool("panic", false, "panic on quit, producing stack traces for debugging")
-	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	dc       *discovery.Client
 )
 
 // server operations
 var (
-	f_daemonSave   = flag.String("save", "", "save current model to file")
-	f_daemonLoad   = flag.String("load", "", "load a model from a file")
-	f_newEndpoint  = flag.Bool("ne", false, "create a new endpoint")
-	f_newNetwork   = flag.Bool("nn", false, "create a new network")
-	f_connect      = flag.Bool("c", false, "connect two nodes")
-	f_disconnect   = flag.Bool("d", false, "disconnect two nodes")
-	f_remove       = flag.Bool("r", false, "remove a node")
-	f_f            = flag.Bool("f", false, "find nodes")
-	f_fn           = flag.Bool("fn", false, "find networks")
-	f_update       = flag.Bool("u", false, "update fields of an endpoint")
-	f_updateConfig = flag.Bool("update-config", false, "update or add a config field")
-	f_deleteConfig = flag.Bool("delete-config", false, "delete a config field")
-	f_getConfig    = flag.Bool("config", false, "list one or all config fields")
+	f_daemonSave    = flag.String("save", "", "save current model to file")
+	f_daemonLoad    = flag.String("load", "", "load a model from a file")
+	f_export        = flag.String("export", "", "export the model as graphml, json, or dot to a file or stdout: [file]")
+	f_import        = flag.String("import", "", "replace the model from a graphml, json, or dot file: <file>")
+	f_newEndpoint   = flag.Bool("ne", false, "create a new endpoint")
+	f_newNetwork    = flag.Bool("nn", false, "create a new network")
+	f_connect       = flag.Bool("c", false, "connect two nodes")
+	f_disconnect    = flag.Bool("d", false, "disconnect two nodes")
+	f_remove        = flag.Bool("r", false, "remove a node")
+	f_f             = flag.Bool("f", false, "find nodes matching a query, such as: os=linux AND edge.ip in 10.0.0.0/8")
+	f_fn            = flag.Bool("fn", false, "find networks")
+	f_update        = flag.Bool("u", false, "update fields of an endpoint")
+	f_updateNetwork = flag.Bool("un", false, "update fields of a network")
+	f_updateConfig  = flag.Bool("update-config", false, "update or add a config field")
+	f_deleteConfig  = flag.Bool("delete-config", false, "delete a config field")
+	f_getConfig     = flag.Bool("config", false, "list one or all config fields")
+	f_revisions     = flag.Bool("revisions", false, "list recent revisions")
+	f_snapshot      = flag.String("snapshot", "", "tag the current revision as a named snapshot")
+	f_snapshots     = flag.Bool("snapshots", false, "list snapshots")
+	f_delSnapshot   = flag.String("delete-snapshot", "", "delete a snapshot")
+	f_diff          = flag.Bool("diff", false, "show changes between two revisions or snapshots: <from> [to]")
+	f_rollback      = flag.String("rollback", "", "roll back to a revision or snapshot")
+	f_models        = flag.Bool("models", false, "list models hosted by the server")
+	f_createModel   = flag.String("create-model", "", "create a model, copying an existing model if specified: [src]")
+	f_deleteModel   = flag.String("delete-model", "", "delete a model")
+	f_validate      = flag.Bool("validate", false, "check that the model can be emulated")
+	f_fix           = flag.Bool("fix", false, "with -validate, repair dangling references and unsynced networks")
+	f_merge         = flag.String("merge", "", "merge another model into the model")
+	f_mergeKeys     = flag.String("keys", "", "with -merge, comma-separated identity keys to match endpoints (default edge.mac,edge.ip,uuid,hostname)")
+	f_mergePolicy   = flag.String("policy", "", "with -merge, resolve conflicting values: prefer-left, prefer-right, or join (default prefer-left)")
+	f_extract       = flag.String("extract", "", "create a model from the nodes matching a query and their networks: <query>")
 )
 
 func main() {
@@ -67,6 +85,14 @@ func main() {
 		resp, err = daemonLoad(*f_daemonLoad)
 		return
 	}
+	if *f_export != "" {
+		resp, err = daemonExport(*f_export)
+		return
+	}
+	if *f_import != "" {
+		resp, err = daemonImport(*f_import)
+		return
+	}
 	if *f_newEndpoint {
 		resp, err = endpointInsert()
 		return
@@ -99,6 +125,10 @@ func main() {
 		resp, err = update()
 		return
 	}
+	if *f_updateNetwork {
+		resp, err = updateNetwork()
+		return
+	}
 	if *f_updateConfig {
 		resp, err = updateConfig()
 		return
@@ -111,6 +141,54 @@ func main() {
 		resp, err = getConfig()
 		return
 	}
+	if *f_revisions {
+		resp, err = listRevisions()
+		return
+	}
+	if *f_snapshot != "" {
+		resp, err = createSnapshot(*f_snapshot)
+		return
+	}
+	if *f_snapshots {
+		resp, err = listSnapshots()
+		return
+	}
+	if *f_delSnapshot != "" {
+		resp, err = deleteSnapshot(*f_delSnapshot)
+		return
+	}
+	if *f_diff {
+		resp, err = diff()
+		return
+	}
+	if *f_rollback != "" {
+		resp, err = rollback(*f_rollback)
+		return
+	}
+	if *f_models {
+		resp, err = listModels()
+		return
+	}
+	if *f_createModel != "" {
+		resp, err = createModel(*f_createModel)
+		return
+	}
+	if *f_deleteModel != "" {
+		resp, err = deleteModel(*f_deleteModel)
+		return
+	}
+	if *f_validate {
+		resp, err = validate()
+		return
+	}
+	if *f_merge != "" {
+		resp, err = merge(*f_merge)
+		return
+	}
+	if *f_extract != "" {
+		resp, err = extract(*f_extract)
+		return
+	}
 }
 
 func flagCheck() error {
@@ -122,6 +200,12 @@ func flagCheck() error {
 	if *f_daemonLoad != "" {
 		count++
 	}
+	if *f_export != "" {
+		count++
+	}
+	if *f_import != "" {
+		count++
+	}
 	if *f_newEndpoint {
 		count++
 	}
@@ -146,6 +230,9 @@ func flagCheck() error {
 	if *f_update {
 		count++
 	}
+	if *f_updateNetwork {
+		count++
+	}
 	if *f_updateConfig {
 		count++
 	}
@@ -155,6 +242,42 @@ func flagCheck() error {
 	if *f_getConfig {
 		count++
 	}
+	if *f_revisions {
+		count++
+	}
+	if *f_snapshot != "" {
+		count++
+	}
+	if *f_snapshots {
+		count++
+	}
+	if *f_delSnapshot != "" {
+		count++
+	}
+	if *f_diff {
+		count++
+	}
+	if *f_rollback != "" {
+		count++
+	}
+	if *f_models {
+		count++
+	}
+	if *f_createModel != "" {
+		count++
+	}
+	if *f_deleteModel != "" {
+		count++
+	}
+	if *f_validate {
+		count++
+	}
+	if *f_merge != "" {
+		count++
+	}
+	if *f_extract != "" {
+		count++
+	}
 
 	switch count {
 	case 0:
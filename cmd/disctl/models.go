@@ -0,0 +1,91 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+func listModels() (string, error) {
+	models, err := dc.Models()
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	for i, m := range models {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.String())
+	}
+
+	return b.String(), nil
+}
+
+// create a new model, optionally copying an existing one:
+//
+//	[src]
+func createModel(name string) (string, error) {
+	args := flag.Args()
+	if len(args) > 1 {
+		return "", fmt.Errorf("invalid arguments: %v", args)
+	}
+
+	var src string
+	if len(args) == 1 {
+		src = args[0]
+	}
+
+	m, err := dc.CreateModel(name, src)
+	if err != nil {
+		return "", err
+	}
+
+	return m.String(), nil
+}
+
+// merge another model into the model
+func merge(src string) (string, error) {
+	opts := &minigraph.MergeOptions{
+		Policy: *f_mergePolicy,
+	}
+	if *f_mergeKeys != "" {
+		opts.Keys = strings.Split(*f_mergeKeys, ",")
+	}
+
+	res, err := dc.Merge(src, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v matched, %v inserted, %v conflicts", res.Matched, res.Inserted, res.Conflicts), nil
+}
+
+// create a new model from a subgraph of the model:
+//
+//	<query>
+func extract(name string) (string, error) {
+	args := flag.Args()
+	if len(args) == 0 {
+		return "", fmt.Errorf("invalid arguments: %v", args)
+	}
+
+	m, err := dc.ExtractModel(name, strings.Join(args, " "))
+	if err != nil {
+		return "", err
+	}
+
+	return m.String(), nil
+}
+
+func deleteModel(name string) (string, error) {
+	return "", dc.DeleteModel(name)
+}
@@ -33,29 +33,61 @@ func update() (string, error) {
 	if err != nil {
 		return "", err
 	}
+
+	// only send the changed key so that we don't overwrite concurrent
+	// updates to other keys
+	diff := minigraph.DataDiff{Set: map[string]string{key: value}}
+	p := &minigraph.Patch{}
+
+	var rev int
+
 	if len(args) == 4 {
 		edge, err := findUniqueEdge(endpoint, edgeSearch)
 		if err != nil {
 			return "", err
 		}
-		edge.D[key] = value
+
+		for i, v := range endpoint.Edges {
+			if v == edge {
+				p.Edges = map[int]minigraph.DataDiff{i: diff}
+			}
+		}
+
+		// edges are patched by index, which may change
+		rev = endpoint.Rev
 	} else {
-		endpoint.D[key] = value
+		p.Data = diff
 	}
 
-	ret, err := dc.UpdateEndpoints(endpoint)
-	var r string
-	for i, v := range ret {
-		if i == 0 {
-			r = fmt.Sprintf("%v", v)
-		} else {
-			r = fmt.Sprintf("%v%v", r, v)
-		}
-		if i != len(ret)-1 {
-			r += "\n\n"
-		}
+	ret, err := dc.PatchEndpoint(endpoint.NID, p, rev)
+	if err != nil {
+		return "", err
 	}
-	return r, err
+	return ret.String(), nil
+}
+
+// update a network field, such as cidr, vlan, name, bridge, or mtu.
+//	[network key=]<value> <key> <value>
+func updateNetwork() (string, error) {
+	args := flag.Args()
+	if len(args) != 3 {
+		return "", fmt.Errorf("invalid arguments: %v", args)
+	}
+
+	network, err := findUniqueNetwork(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	p := &minigraph.Patch{
+		Data: minigraph.DataDiff{Set: map[string]string{args[1]: args[2]}},
+	}
+
+	ret, err := dc.PatchNetwork(network.NID, p, 0)
+	if err != nil {
+		return "", err
+	}
+	return ret.String(), nil
 }
 
 // find networks based on properties of connected edges
@@ -117,30 +149,24 @@ func findNetworks() (string, error) {
 }
 
 func find() (string, error) {
-	// we expect exactly 1 additional cli
-	//	 [node key=]<value>
-	var err error
-
+	// we expect a query, possibly split across several args:
+	//	 <query>
 	args := flag.Args()
 
-	if len(args) != 1 {
+	if len(args) == 0 {
 		return "", fmt.Errorf("invalid arguments: %v", args)
 	}
 
-	f := strings.Split(args[0], "=")
+	q := strings.Join(args, " ")
 
-	var n []*minigraph.Network
-	var e []*minigraph.Endpoint
+	n, err := dc.QueryNetworks(q)
+	if err != nil {
+		return "", err
+	}
 
-	switch len(f) {
-	case 1:
-		n, err = dc.GetNetworks("", f[0])
-		e, err = dc.GetEndpoints("", f[0])
-	case 2:
-		n, err = dc.GetNetworks(f[0], f[1])
-		e, err = dc.GetEndpoints(f[0], f[1])
-	default:
-		return "", fmt.Errorf("invalid search term: %v", args[0])
+	e, err := dc.QueryEndpoints(q)
+	if err != nil {
+		return "", err
 	}
 
 	var r string
@@ -0,0 +1,165 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+func listRevisions() (string, error) {
+	h, err := dc.Revisions()
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	for _, r := range h.Revisions {
+		fmt.Fprintln(&b, r)
+	}
+	fmt.Fprintf(&b, "current revision: %v", h.Rev)
+
+	return b.String(), nil
+}
+
+func listSnapshots() (string, error) {
+	snaps, err := dc.Snapshots()
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	for i, s := range snaps {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(s.String())
+	}
+
+	return b.String(), nil
+}
+
+func createSnapshot(name string) (string, error) {
+	s, err := dc.CreateSnapshot(name)
+	if err != nil {
+		return "", err
+	}
+
+	return s.String(), nil
+}
+
+func deleteSnapshot(name string) (string, error) {
+	return "", dc.DeleteSnapshot(name)
+}
+
+func rollback(rev string) (string, error) {
+	r, err := dc.Rollback(rev)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("rolled back to %v, now at revision %v", rev, r.Rev), nil
+}
+
+// diff between revisions or snapshots:
+//
+//	<from> [to]
+func diff() (string, error) {
+	args := flag.Args()
+	if len(args) != 1 && len(args) != 2 {
+		return "", fmt.Errorf("invalid arguments: %v", args)
+	}
+
+	var to string
+	if len(args) == 2 {
+		to = args[1]
+	}
+
+	d, err := dc.GetDiff(args[0], to)
+	if err != nil {
+		return "", err
+	}
+
+	return formatDiff(d), nil
+}
+
+// formatDiff prints one line per added (+), removed (-), or changed (~) node
+// followed by the details of the changes.
+func formatDiff(d *discovery.Diff) string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "revision %v -> %v", d.From, d.To)
+
+	if !d.Config.Empty() {
+		b.WriteString("\n~ config")
+		formatData(&b, "\t", d.Config)
+	}
+
+	for _, n := range d.Nodes {
+		kind := "endpoint"
+		if n.Type == minigraph.TYPE_NETWORK {
+			kind = "network"
+		}
+
+		switch {
+		case n.Added:
+			fmt.Fprintf(&b, "\n+ %v %v", kind, n.NID)
+		case n.Removed:
+			fmt.Fprintf(&b, "\n- %v %v", kind, n.NID)
+			continue
+		default:
+			fmt.Fprintf(&b, "\n~ %v %v", kind, n.NID)
+		}
+
+		formatData(&b, "\t", n.Data)
+
+		for _, e := range n.Edges {
+			switch {
+			case e.Added:
+				fmt.Fprintf(&b, "\n\t+ edge %v", e.Edge)
+			case e.Removed:
+				fmt.Fprintf(&b, "\n\t- edge %v", e.Edge)
+				continue
+			default:
+				fmt.Fprintf(&b, "\n\t~ edge %v", e.Edge)
+			}
+
+			if e.From != e.To {
+				fmt.Fprintf(&b, "\n\t\tnetwork %v -> %v", e.From, e.To)
+			}
+
+			formatData(&b, "\t\t", e.Data)
+		}
+
+		for _, v := range n.Connected {
+			fmt.Fprintf(&b, "\n\tconnected %v", v)
+		}
+		for _, v := range n.Disconnected {
+			fmt.Fprintf(&b, "\n\tdisconnected %v", v)
+		}
+	}
+
+	return b.String()
+}
+
+func formatData(b *bytes.Buffer, indent string, d minigraph.DataDiff) {
+	var keys []string
+	for k := range d.Set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(b, "\n%vset %v=%v", indent, k, d.Set[k])
+	}
+	for _, k := range d.Deleted {
+		fmt.Fprintf(b, "\n%vdelete %v", indent, k)
+	}
+}
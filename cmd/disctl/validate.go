@@ -0,0 +1,72 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// validate prints the problems with the model, grouped by node. Returns an
+// error if there are errors that weren't fixed so that the model isn't
+// emulated by mistake.
+func validate() (string, error) {
+	var report *minigraph.Report
+	var err error
+
+	if *f_fix {
+		report, err = dc.Repair()
+	} else {
+		report, err = dc.Validate()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+
+	var remaining, fixed int
+	prev := 0
+
+	for _, p := range report.Problems {
+		if p.NID != prev {
+			fmt.Fprintf(&b, "node %v:\n", p.NID)
+			prev = p.NID
+		}
+
+		fmt.Fprintf(&b, "\t%v", p.Severity)
+		if p.Edge >= 0 {
+			fmt.Fprintf(&b, " (edge %v)", p.Edge)
+		}
+		fmt.Fprintf(&b, ": %v: %v", p.Check, p.Message)
+
+		switch {
+		case p.Fixed:
+			b.WriteString(" (fixed)")
+			fixed++
+		case p.Fixable:
+			b.WriteString(" (fixable with -fix)")
+		}
+		b.WriteString("\n")
+
+		if p.Severity == minigraph.SEVERITY_ERROR && !p.Fixed {
+			remaining++
+		}
+	}
+
+	fmt.Fprintf(&b, "%v errors, %v warnings", report.Errors, report.Warnings)
+	if *f_fix {
+		fmt.Fprintf(&b, ", %v fixed", fixed)
+	}
+
+	if remaining > 0 {
+		fmt.Print(b.String() + "\n")
+		return "", fmt.Errorf("model has %v errors", remaining)
+	}
+
+	return b.String(), nil
+}
@@ -25,7 +25,7 @@ type Updater struct {
 }
 
 var (
-	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	f_limit  = flag.Int("limit", 1000, "limit the number of clients to add")
 	f_start  = flag.String("start", "", "earliest time to add")
 	f_end    = flag.String("end", "", "latest time to add")
@@ -180,13 +180,9 @@ func main() {
 func (u *Updater) PopulateNetmasks() {
 	u.masks = map[int][]*net.IPNet{}
 
-	endpoints, err := u.GetEndpoints("", "")
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	for _, e := range endpoints {
-		for _, edge := range e.Edges {
+	it := u.IterEndpoints("", "")
+	for it.Next() {
+		for _, edge := range it.Endpoint().Edges {
 			for _, v := range []string{"ip", "ip6"} {
 				if ip, ok := edge.D[v]; ok {
 					_, ipn, err := net.ParseCIDR(ip)
@@ -200,6 +196,10 @@ func (u *Updater) PopulateNetmasks() {
 			}
 		}
 	}
+
+	if err := it.Err(); err != nil {
+		log.Fatalln(err)
+	}
 }
 
 func (u *Updater) GetOrCreate(mac string) (*minigraph.Endpoint, error) {
@@ -259,12 +259,9 @@ func (u *Updater) Update(e *minigraph.Endpoint, s, mac string) error {
 					}
 				}
 
-				e, err := u.Connect(id, e.ID(), index)
-				if err != nil {
-					return err
-				}
-
 				if index == discovery.EDGE_NONE {
+					edge := e.NewEdge()
+					edge.N = minigraph.UNCONNECTED
 					index = len(e.Edges) - 1
 				}
 
@@ -275,9 +272,18 @@ func (u *Updater) Update(e *minigraph.Endpoint, s, mac string) error {
 				}).String()
 				edge.D["mac"] = mac
 
+				// update and connect in a single batch so that we don't
+				// leave a half-built edge if the connect fails
+				_, err := u.Batch(
+					discovery.UpdateEndpointOp(e),
+					discovery.ConnectOp(id, e.ID(), index),
+				)
+				if err != nil {
+					return err
+				}
+
 				u.count += 1
-				_, err = u.UpdateEndpoints(e)
-				return err
+				return nil
 			}
 		}
 	}
@@ -9,6 +9,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -19,7 +20,7 @@ import (
 )
 
 var (
-	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	f_out    = flag.String("out", "", "save copy of input data for offline processing")
 	f_url    = flag.String("url", "https://oscars.es.net/topology-publisher", "URL to process")
 )
@@ -30,6 +31,35 @@ func usage() {
 	os.Exit(1)
 }
 
+// setCIDR sets the cidr field on the network based on an address with either a
+// prefix length or a dotted netmask (e.g. 10.0.0.1/255.255.255.0). Addresses
+// that cannot be parsed are ignored.
+func setCIDR(n *minigraph.Network, addr string) {
+	f := strings.SplitN(addr, "/", 2)
+	if len(f) != 2 {
+		return
+	}
+
+	if mask := net.ParseIP(f[1]).To4(); mask != nil {
+		ones, bits := net.IPMask(mask).Size()
+		if bits == 0 {
+			log.Info("invalid netmask: %v", f[1])
+			return
+		}
+		f[1] = fmt.Sprintf("%v", ones)
+	}
+
+	_, ipnet, err := net.ParseCIDR(f[0] + "/" + f[1])
+	if err != nil {
+		return
+	}
+
+	if n.D == nil {
+		n.D = make(map[string]string)
+	}
+	n.D["cidr"] = ipnet.String()
+}
+
 func main() {
 	flag.Usage = usage
 	flag.Parse()
@@ -128,6 +158,8 @@ func main() {
 						},
 					})
 
+					setCIDR(network, p.IPAddress+"/"+p.Netmask)
+
 					networks[network.ID()] = network
 				}
 
@@ -156,6 +188,8 @@ func main() {
 						network = networks[nid]
 					}
 
+					setCIDR(network, l.Name)
+
 					e.Edges = append(e.Edges, &minigraph.Edge{
 						N: network.ID(),
 						D: map[string]string{
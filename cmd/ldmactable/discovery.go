@@ -0,0 +1,348 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/ifname"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Stats about what was added to the graph
+type Stats struct {
+	Hosts, Edges, Updated, Skipped int
+}
+
+// number of times to retry an entry if the host was modified concurrently
+const conflictRetries = 3
+
+// Updater adds the entries from the devices' tables to the graph.
+type Updater struct {
+	*discovery.Client
+
+	// devices by name, fetched as needed
+	devices map[string]*minigraph.Endpoint
+
+	// networks by ID, fetched as needed
+	networks map[int]*minigraph.Network
+
+	stats *Stats
+}
+
+// findDevice finds the endpoint for the device by name, with or without the
+// domain, which should have been added by ldrouterconfig or ldneighbors
+func findDevice(dc *discovery.Client, name string) (*minigraph.Endpoint, error) {
+	names := []string{name}
+	if i := strings.Index(name, "."); i > 0 {
+		names = append(names, name[:i])
+	}
+
+	for _, field := range []string{"name", "hostname"} {
+		for _, v := range names {
+			res, err := dc.LookupEndpoints(field, v)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(res) > 1 {
+				log.Warn("found %v endpoints with %v = %v, using %v", len(res), field, v, res[0].ID())
+			}
+			if len(res) > 0 {
+				return res[0], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no endpoint for %v, import its config or neighbors first", name)
+}
+
+// network returns the network, or nil if it doesn't exist
+func (u *Updater) network(nid int) (*minigraph.Network, error) {
+	if n, ok := u.networks[nid]; ok {
+		return n, nil
+	}
+
+	res, err := u.GetNetworks("nid", strconv.Itoa(nid))
+	if err != nil {
+		return nil, err
+	}
+
+	var n *minigraph.Network
+	if len(res) == 1 {
+		n = res[0]
+	}
+
+	u.networks[nid] = n
+	return n, nil
+}
+
+// subnet returns the subnet of the edge if it contains the IP
+func subnet(edge *minigraph.Edge, ip net.IP) *net.IPNet {
+	_, ipnet, err := net.ParseCIDR(edge.D["ip"])
+	if err != nil || ip == nil || !ipnet.Contains(ip) {
+		return nil
+	}
+
+	return ipnet
+}
+
+// deviceEdge returns the device's edge that the entry was learned on: the edge
+// for the interface, or for the VLAN or subnet if the interface doesn't have
+// one. MAC address table entries learned on links to other switches are
+// ignored since the hosts aren't attached there.
+func (u *Updater) deviceEdge(d *minigraph.Endpoint, entry Entry) (*minigraph.Edge, error) {
+	var byIface, byVLAN, bySubnet *minigraph.Edge
+
+	ip := net.ParseIP(entry.IP)
+
+	for _, edge := range d.Edges {
+		if edge.N == minigraph.UNCONNECTED {
+			continue
+		}
+
+		vlan := edge.D["vlan"]
+
+		// trunks have an edge per VLAN
+		if byIface == nil && ifname.Same(entry.Interface, edge.D["interface"]) && (entry.VLAN == "" || vlan == "" || vlan == entry.VLAN) {
+			byIface = edge
+		}
+
+		if byVLAN == nil && entry.VLAN != "" && vlan == entry.VLAN {
+			byVLAN = edge
+		}
+
+		if bySubnet == nil && subnet(edge, ip) != nil {
+			bySubnet = edge
+		}
+	}
+
+	if byIface != nil && entry.IP == "" {
+		n, err := u.network(byIface.N)
+		if err != nil {
+			return nil, err
+		}
+
+		if n != nil && n.D["discovered_by"] != "" {
+			log.Debug("%v was learned on a link to a neighbor", entry)
+			byIface = nil
+		}
+	}
+
+	for _, edge := range []*minigraph.Edge{byIface, byVLAN, bySubnet} {
+		if edge != nil {
+			return edge, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findHost finds the host and the edge with the MAC or the IP. Returns a nil
+// edge if the host doesn't have one.
+func (u *Updater) findHost(entry Entry) (*minigraph.Endpoint, *minigraph.Edge, error) {
+	res, err := u.LookupEndpoints("edge.mac", entry.MAC)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(res) == 0 && entry.IP != "" {
+		res, err = u.LookupEndpointsCIDR("edge.ip", entry.IP+"/32")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, nil, nil
+	}
+
+	if len(res) > 1 {
+		log.Info("more than one endpoint with MAC: %v", entry.MAC)
+	}
+
+	e := res[0]
+
+	for _, edge := range e.Edges {
+		if edge.D["mac"] == entry.MAC {
+			return e, edge, nil
+		}
+	}
+
+	for _, edge := range e.Edges {
+		if ip, _, err := net.ParseCIDR(edge.D["ip"]); err == nil && ip.String() == entry.IP {
+			return e, edge, nil
+		}
+	}
+
+	return e, nil, nil
+}
+
+// Update adds the entry to the graph, connecting the host to the network that
+// the device learned it on
+func (u *Updater) Update(entry Entry) error {
+	d, ok := u.devices[entry.Device]
+	if !ok {
+		var err error
+		d, err = findDevice(u.Client, entry.Device)
+		if err != nil {
+			return err
+		}
+
+		u.devices[entry.Device] = d
+	}
+
+	edge, err := u.deviceEdge(d, entry)
+	if err != nil {
+		return err
+	}
+
+	if edge == nil {
+		log.Debug("no network for %v", entry)
+		u.stats.Skipped++
+		return nil
+	}
+
+	e, hedge, err := u.findHost(entry)
+	if err != nil {
+		return err
+	}
+
+	if e != nil && e.ID() == d.ID() {
+		// one of the device's own addresses
+		u.stats.Skipped++
+		return nil
+	}
+
+	learnedOn := entry.Device + " " + entry.Interface
+	if entry.Port != "" {
+		learnedOn = entry.Device + " " + entry.Port
+	}
+
+	var ip string
+	if ipnet := subnet(edge, net.ParseIP(entry.IP)); ipnet != nil {
+		ip = (&net.IPNet{IP: net.ParseIP(entry.IP), Mask: ipnet.Mask}).String()
+	}
+
+	if e == nil {
+		// insert and connect the endpoint in a single batch so that we
+		// don't leave half-built endpoints if something fails
+		e = &minigraph.Endpoint{
+			NID: -1,
+			D:   map[string]string{},
+		}
+
+		hedge := e.NewEdge()
+		hedge.N = minigraph.UNCONNECTED
+		hedge.D["mac"] = entry.MAC
+		hedge.D["learned_on"] = learnedOn
+		if ip != "" {
+			hedge.D["ip"] = ip
+		}
+
+		log.Info("connect %v <-> new host -- %v", edge.N, entry)
+
+		_, err := u.Batch(
+			discovery.InsertEndpointOp(e),
+			discovery.ConnectOp(edge.N, e.NID, 0),
+		)
+		if err != nil {
+			return err
+		}
+
+		u.stats.Hosts++
+		u.stats.Edges++
+		return nil
+	}
+
+	if hedge != nil && hedge.N != minigraph.UNCONNECTED && hedge.N != edge.N {
+		log.Warn("%v is connected to network %v rather than %v", entry, hedge.N, edge.N)
+		u.stats.Skipped++
+		return nil
+	}
+
+	connect := hedge == nil || hedge.N == minigraph.UNCONNECTED
+	if hedge == nil {
+		hedge = e.NewEdge()
+		hedge.N = minigraph.UNCONNECTED
+	}
+
+	// switch ports are more specific than the interfaces that ARP entries
+	// are learned on
+	update := connect
+	set := func(k, v string, overwrite bool) {
+		if v != "" && hedge.D[k] != v && (overwrite || hedge.D[k] == "") {
+			hedge.D[k] = v
+			update = true
+		}
+	}
+
+	set("mac", entry.MAC, false)
+	set("ip", ip, false)
+	set("learned_on", learnedOn, entry.IP == "")
+
+	if !update {
+		return nil
+	}
+
+	// fails if the host was updated since we looked it up
+	op := discovery.UpdateEndpointOp(e)
+	op.Rev = e.Rev
+
+	ops := []*discovery.Op{op}
+
+	if connect {
+		log.Info("connect %v <-> %v -- %v", edge.N, e.ID(), entry)
+		for i := range e.Edges {
+			if e.Edges[i] == hedge {
+				ops = append(ops, discovery.ConnectOp(edge.N, e.ID(), i))
+			}
+		}
+	}
+
+	if _, err := u.Batch(ops...); err != nil {
+		return err
+	}
+
+	if connect {
+		u.stats.Edges++
+	} else {
+		u.stats.Updated++
+	}
+
+	return nil
+}
+
+// Push adds the entries from the devices' tables to the graph.
+func Push(dc *discovery.Client, entries []Entry) (*Stats, error) {
+	stats := &Stats{}
+
+	u := &Updater{
+		Client:   dc,
+		devices:  map[string]*minigraph.Endpoint{},
+		networks: map[int]*minigraph.Network{},
+		stats:    stats,
+	}
+
+	for _, entry := range entries {
+		err := u.Update(entry)
+		for i := 0; i < conflictRetries && errors.Is(err, discovery.ErrConflict); i++ {
+			log.Info("retrying %v: %v", entry, err)
+			err = u.Update(entry)
+		}
+
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/importer"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+var (
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
+	f_dryrun = flag.Bool("dry-run", false, "do a dry run and do not push data to the server")
+	f_name   = flag.String("name", "", "name of the device for show output without a prompt, defaults to the filename")
+)
+
+// Result of processing one file
+type Result struct {
+	File    string
+	Name    string
+	Entries []Entry
+	Stats   *Stats
+	Err     error
+}
+
+func usage() {
+	fmt.Printf("USAGE: %v [OPTIONS] FILE...\n", os.Args[0])
+	fmt.Println("\nFILE may be a file, a directory, or a glob. Files are the output of show arp,")
+	fmt.Println("show ip arp [vrf NAME], show mac address-table, or show ethernet-switching")
+	fmt.Println("table from a device that is already in the graph.")
+	fmt.Println()
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+// process parses the entries from the file and pushes them to the server,
+// unless this is a dry run
+func process(dc *discovery.Client, filename string) *Result {
+	res := &Result{File: filename, Name: "-"}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer f.Close()
+
+	local := *f_name
+	if local == "" {
+		local = filepath.Base(filename)
+	}
+
+	res.Entries, res.Name, res.Err = parseTable(f, local)
+	if res.Err != nil {
+		return res
+	}
+
+	if !*f_dryrun {
+		res.Stats, res.Err = Push(dc, res.Entries)
+	}
+
+	return res
+}
+
+func main() {
+	flag.Parse()
+
+	log.Init()
+
+	dc := discovery.New(*f_server)
+
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	files, err := importer.Expand(flag.Args())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// process files in order so that hosts are merged consistently
+	results := importer.Process(files, 1, func(f string) *Result {
+		return process(dc, f)
+	})
+
+	if *f_dryrun {
+		for _, res := range results {
+			for _, entry := range res.Entries {
+				fmt.Printf("%v: %v\n", res.File, entry)
+			}
+		}
+	}
+
+	var hosts, edges, updated int
+
+	t := importer.NewTable(os.Stdout, "NAME", "ENTRIES", "HOSTS", "EDGES", "UPDATED", "SKIPPED")
+	t.DryRun = *f_dryrun
+
+	for _, res := range results {
+		added, connected, changed, skipped := "-", "-", "-", "-"
+		if s := res.Stats; s != nil {
+			added, connected, changed, skipped = fmt.Sprint(s.Hosts), fmt.Sprint(s.Edges), fmt.Sprint(s.Updated), fmt.Sprint(s.Skipped)
+			hosts += s.Hosts
+			edges += s.Edges
+			updated += s.Updated
+		}
+
+		t.Row(res.File, res.Err, res.Name, len(res.Entries), added, connected, changed, skipped)
+	}
+	t.Flush()
+
+	fmt.Printf("\n%v files, %v failed, %v hosts and %v edges added, %v edges updated\n", t.Files, t.Failed, hosts, edges, updated)
+
+	if t.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Entry is a host from an ARP or MAC address table.
+type Entry struct {
+	// Device that the table is from
+	Device string
+
+	MAC string
+
+	// IP is only set for ARP entries
+	IP string
+
+	// Interface that the entry was learned on: the layer 3 interface for
+	// ARP entries and the port for MAC address table entries
+	Interface string
+
+	// Port is the switch port behind a VLAN interface for ARP entries, if
+	// the device reports it (Arista)
+	Port string
+
+	// VLAN is the VLAN ID for MAC address table entries
+	VLAN string
+}
+
+func (e Entry) String() string {
+	res := e.Device + " " + e.MAC
+	if e.IP != "" {
+		res += " " + e.IP
+	}
+	res += " on " + e.Interface
+	if e.Port != "" {
+		res += ", " + e.Port
+	}
+	if e.VLAN != "" {
+		res += " vlan " + e.VLAN
+	}
+
+	return res
+}
+
+var (
+	// prompt before the command, the hostname may be prefixed with the user
+	// as on Juniper devices
+	showPrompt = regexp.MustCompile(`^(?:\S+@)?([\w.-]+)[#>]\s*show\s`)
+
+	// interface names such as Gi1/0/5, Vlan10, Port-Channel1, ge-0/0/5.0, or
+	// irb.10
+	interfaceName = regexp.MustCompile(`^[A-Za-z][A-Za-z-]*(\d+(/\d+)*(\.\d+)?|\.\d+)$`)
+
+	entryFlags = regexp.MustCompile(`^\s*[*+G]\s+`)
+)
+
+// parseMAC returns the MAC in the colon form used by the other tools, or ""
+// if it is invalid or not a unicast address
+func parseMAC(s string) string {
+	mac, err := net.ParseMAC(s)
+	if err != nil || len(mac) != 6 {
+		return ""
+	}
+
+	// multicast, broadcast, and null
+	if mac[0]&1 == 1 || mac.String() == "00:00:00:00:00:00" {
+		return ""
+	}
+
+	return mac.String()
+}
+
+// parseEntry finds the MAC, IP, interfaces, and VLAN in a line from the
+// table. The columns differ between vendors but the interfaces are always
+// after the MAC and the VLAN is always before it.
+func parseEntry(fields []string) (Entry, bool) {
+	var res Entry
+
+	// interfaces after the MAC, including the separators
+	var ifaces []string
+
+	for _, f := range fields {
+		switch {
+		case res.MAC == "" && parseMAC(f) != "":
+			res.MAC = parseMAC(f)
+		case res.IP == "" && net.ParseIP(f).To4() != nil:
+			res.IP = f
+		case res.MAC == "":
+			// numeric VLAN IDs, names don't help and ARP entries have
+			// the age here
+			if _, err := strconv.Atoi(f); err == nil && res.IP == "" {
+				res.VLAN = f
+			}
+		case strings.HasPrefix(f, "[") && interfaceName.MatchString(strings.Trim(f, "[]")):
+			// juniper shows the port for irb interfaces
+			res.Port = strings.Trim(f, "[]")
+		case interfaceName.MatchString(strings.TrimSuffix(f, ",")):
+			ifaces = append(ifaces, f)
+		}
+	}
+
+	if res.MAC == "" || len(ifaces) == 0 {
+		return res, false
+	}
+
+	// the last interface is the one that the entry was learned on, unless
+	// it's part of a list such as "Vlan10, Ethernet5"
+	n := len(ifaces)
+	res.Interface = strings.TrimSuffix(ifaces[n-1], ",")
+	if n > 1 && strings.HasSuffix(ifaces[n-2], ",") && res.IP != "" {
+		res.Interface = strings.TrimSuffix(ifaces[n-2], ",")
+		res.Port = ifaces[n-1]
+	}
+
+	return res, true
+}
+
+// parseTable parses the output of show arp, show ip arp [vrf NAME], show mac
+// address-table, or show ethernet-switching table from Cisco IOS, NX-OS,
+// Arista, or Juniper devices. The device is named by the prompt before each
+// command, if there is one, and otherwise by local. Returns the entries and
+// the name of the last device.
+func parseTable(f io.Reader, local string) ([]Entry, string, error) {
+	var res []Entry
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := showPrompt.FindStringSubmatch(line); m != nil {
+			log.Debug("found device name in prompt: %v", m[1])
+			local = m[1]
+			continue
+		}
+
+		// NX-OS marks entries with *, +, or G
+		fields := strings.Fields(entryFlags.ReplaceAllString(line, ""))
+
+		entry, ok := parseEntry(fields)
+		if !ok {
+			continue
+		}
+		entry.Device = local
+
+		log.Debug("got entry %v", entry)
+
+		res = append(res, entry)
+	}
+
+	return res, local, scanner.Err()
+}
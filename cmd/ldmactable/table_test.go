@@ -0,0 +1,178 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMAC(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"0050.56aa.0005", "00:50:56:aa:00:05"},
+		{"00:50:56:AA:00:05", "00:50:56:aa:00:05"},
+		{"00-50-56-aa-00-05", "00:50:56:aa:00:05"},
+		// multicast, broadcast, and null
+		{"0100.0ccc.cccc", ""},
+		{"ffff.ffff.ffff", ""},
+		{"0000.0000.0000", ""},
+		{"Incomplete", ""},
+	}
+
+	for _, test := range tests {
+		if got := parseMAC(test.s); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name, output string
+
+		// name of the last device from the prompts
+		local string
+
+		want []string
+	}{
+		{"ios arp", `r1#show ip arp
+Protocol  Address          Age (min)  Hardware Addr   Type   Interface
+Internet  10.0.0.1                -   0011.2233.0001  ARPA   GigabitEthernet0/1
+Internet  10.0.0.5                3   0050.56aa.0005  ARPA   GigabitEthernet0/1
+Internet  10.0.0.6                3   0050.56aa.0006  ARPA   GigabitEthernet0/1
+Internet  172.16.0.2             10   0011.2233.0002  ARPA   GigabitEthernet0/2
+Internet  10.0.0.7                0   Incomplete      ARPA
+`, "r1", []string{
+			"r1 00:11:22:33:00:01 10.0.0.1 on GigabitEthernet0/1",
+			"r1 00:50:56:aa:00:05 10.0.0.5 on GigabitEthernet0/1",
+			"r1 00:50:56:aa:00:06 10.0.0.6 on GigabitEthernet0/1",
+			"r1 00:11:22:33:00:02 172.16.0.2 on GigabitEthernet0/2",
+		}},
+		{"ios arp vrf", `sw1#show ip arp vrf default
+Protocol  Address          Age (min)  Hardware Addr   Type   Interface
+Internet  10.0.10.20              1   0050.56aa.0010  ARPA   Vlan10
+Internet  10.0.50.9               1   0050.56aa.0050  ARPA   GigabitEthernet0/0.50
+`, "sw1", []string{
+			"sw1 00:50:56:aa:00:10 10.0.10.20 on Vlan10",
+			"sw1 00:50:56:aa:00:50 10.0.50.9 on GigabitEthernet0/0.50",
+		}},
+		{"ios mac address-table", `sw1#show mac address-table
+          Mac Address Table
+-------------------------------------------
+
+Vlan    Mac Address       Type        Ports
+----    -----------       --------    -----
+ All    0100.0ccc.cccc    STATIC      CPU
+  20    0050.56aa.0020    DYNAMIC     Gi0/1
+  10    0050.56aa.0010    DYNAMIC     Gi0/2
+  99    0050.56aa.0099    DYNAMIC     Gi0/5
+Total Mac Addresses for this criterion: 4
+`, "sw1", []string{
+			"sw1 00:50:56:aa:00:20 on Gi0/1 vlan 20",
+			"sw1 00:50:56:aa:00:10 on Gi0/2 vlan 10",
+			"sw1 00:50:56:aa:00:99 on Gi0/5 vlan 99",
+		}},
+		{"arista", `leaf1#show ip arp
+Address         Age (sec)  Hardware Addr   Interface
+10.6.0.10         0:01:02  0050.56cc.0010  Vlan6, Ethernet5
+10.6.0.11             N/A  0050.56cc.0011  Vlan6, not learned
+10.7.0.1          0:00:03  0050.56cc.0012  Ethernet49/1
+leaf1#show mac address-table
+          Mac Address Table
+------------------------------------------------------------------
+
+Vlan    Mac Address       Type        Ports      Moves   Last Move
+----    -----------       ----        -----      -----   ---------
+   6    0050.56cc.0010    DYNAMIC     Et5        1       0:00:10 ago
+Total Mac Addresses for this criterion: 1
+`, "leaf1", []string{
+			"leaf1 00:50:56:cc:00:10 10.6.0.10 on Vlan6, Ethernet5",
+			"leaf1 00:50:56:cc:00:11 10.6.0.11 on Vlan6",
+			"leaf1 00:50:56:cc:00:12 10.7.0.1 on Ethernet49/1",
+			"leaf1 00:50:56:cc:00:10 on Et5 vlan 6",
+		}},
+		{"juniper", `admin@ex1> show arp no-resolve
+MAC Address       Address         Interface                Flags
+00:50:56:dd:00:10 10.8.0.10       irb.8 [ge-0/0/3.0]       none
+00:50:56:dd:00:11 10.8.1.11       ge-0/0/1.0               none
+admin@srx1> show arp
+MAC Address       Address         Name                      Interface               Flags
+00:50:56:dd:00:12 10.3.0.12       host12                    ge-0/0/1.0              none
+admin@ex1> show ethernet-switching table
+MAC flags (S - static MAC, D - dynamic MAC, L - locally learned, P - Persistent static)
+Ethernet switching table : 2 entries, 2 learned
+Routing instance : default-switch
+   Vlan                MAC                 MAC         Age    Logical
+   name                address             flags              interface
+   vlan8               00:50:56:dd:00:10   D             -   ge-0/0/3.0
+   default             00:50:56:dd:00:13   D             -   ge-0/0/4.0
+`, "ex1", []string{
+			"ex1 00:50:56:dd:00:10 10.8.0.10 on irb.8, ge-0/0/3.0",
+			"ex1 00:50:56:dd:00:11 10.8.1.11 on ge-0/0/1.0",
+			"srx1 00:50:56:dd:00:12 10.3.0.12 on ge-0/0/1.0",
+			"ex1 00:50:56:dd:00:10 on ge-0/0/3.0",
+			"ex1 00:50:56:dd:00:13 on ge-0/0/4.0",
+		}},
+		{"nx-os", `nx1# show ip arp vrf prod
+Flags: * - Adjacencies learnt on non-active FHRP router
+IP ARP Table for context prod
+Total number of entries: 2
+Address         Age       MAC Address     Interface       Flags
+10.5.0.10       00:10:02  0050.56bb.0010  Vlan5
+10.5.0.11       00:00:12  0050.56bb.0011  Ethernet1/7
+nx1# show mac address-table
+Legend:
+        * - primary entry, G - Gateway MAC, (R) - Routed MAC, O - Overlay MAC
+   VLAN     MAC Address      Type      age     Secure NTFY Ports
+---------+-----------------+--------+---------+------+----+------------------
+*    5     0050.56bb.0010   dynamic  0         F      F    Eth1/5
+G    -     0011.2233.5555   static   -         F      F    sup-eth1(R)
++    5     0050.56bb.0012   dynamic  0         F      F    Po10
+`, "nx1", []string{
+			"nx1 00:50:56:bb:00:10 10.5.0.10 on Vlan5",
+			"nx1 00:50:56:bb:00:11 10.5.0.11 on Ethernet1/7",
+			"nx1 00:50:56:bb:00:10 on Eth1/5 vlan 5",
+			"nx1 00:50:56:bb:00:12 on Po10 vlan 5",
+		}},
+	}
+
+	for _, test := range tests {
+		entries, local, err := parseTable(strings.NewReader(test.output), "file")
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if local != test.local {
+			t.Errorf("%v: got local device %v, want %v", test.name, local, test.local)
+		}
+
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.String())
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got\n%v\nwant\n%v", test.name, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestParseTableWithoutPrompt(t *testing.T) {
+	output := `  10    0050.56aa.0010    DYNAMIC     Gi0/2
+`
+
+	entries, local, err := parseTable(strings.NewReader(output), "sw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if local != "sw1" || len(entries) != 1 || entries[0].Device != "sw1" {
+		t.Errorf("got %v %v, want sw1", local, entries)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/ifname"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Stats about what was added to the graph
+type Stats struct {
+	Endpoints, Edges, Networks int
+}
+
+// findEndpoint finds the endpoint for the device by name or management IP,
+// creating a switch (or router) endpoint if there isn't one
+func findEndpoint(dc *discovery.Client, d Device, stats *Stats) (*minigraph.Endpoint, error) {
+	type lookup struct {
+		field, value string
+		cidr         bool
+	}
+
+	lookups := []lookup{
+		{field: "name", value: d.Name},
+		{field: "hostname", value: d.Name},
+	}
+
+	// configs usually don't have the domain name
+	if i := strings.Index(d.Name, "."); i > 0 {
+		lookups = append(lookups, lookup{field: "name", value: d.Name[:i]})
+	}
+
+	if d.MgmtIP != "" {
+		lookups = append(lookups,
+			lookup{field: "mgmt_ip", value: d.MgmtIP},
+			lookup{field: "edge.ip", value: d.MgmtIP + "/32", cidr: true},
+		)
+	}
+
+	for _, l := range lookups {
+		var res []*minigraph.Endpoint
+		var err error
+
+		if l.cidr {
+			res, err = dc.LookupEndpointsCIDR(l.field, l.value)
+		} else {
+			res, err = dc.LookupEndpoints(l.field, l.value)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res) > 1 {
+			log.Warn("found %v endpoints with %v = %v, using %v", len(res), l.field, l.value, res[0].ID())
+		}
+		if len(res) > 0 {
+			log.Debug("found %v by %v: %v", d.Name, l.field, res[0].ID())
+			return res[0], nil
+		}
+	}
+
+	e := &minigraph.Endpoint{
+		D: map[string]string{
+			"name": d.Name,
+			"type": "switch",
+			"icon": "switch",
+		},
+	}
+
+	// assume that it's a switch unless it says that it's only a router
+	if d.Router && !d.Bridge {
+		e.D["type"] = "router"
+		e.D["icon"] = "router"
+		e.D["router"] = "true"
+	} else {
+		e.D["switch"] = "true"
+	}
+
+	if d.MgmtIP != "" {
+		e.D["mgmt_ip"] = d.MgmtIP
+	}
+	if d.Platform != "" {
+		e.D["platform"] = d.Platform
+	}
+
+	log.Info("creating %v for %v", e.D["type"], d.Name)
+
+	es, err := dc.InsertEndpoints(e)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Endpoints++
+
+	return es[0], nil
+}
+
+// findEdge returns the index of the edge for the port, preferring the
+// physical interface over logical units, or -1 if there isn't one
+func findEdge(e *minigraph.Endpoint, port string) int {
+	res := -1
+
+	for i, edge := range e.Edges {
+		iface := edge.D["interface"]
+		if iface == "" || !ifname.Same(port, iface) {
+			continue
+		}
+
+		if ifname.Canonical(port) == ifname.Canonical(iface) {
+			return i
+		}
+
+		if res == -1 {
+			res = i
+		}
+	}
+
+	return res
+}
+
+// Push adds the links to the graph. Each link's ports are connected to the
+// same network, reusing a network that one of the ports is already in.
+func Push(dc *discovery.Client, links []Link) (*Stats, error) {
+	stats := &Stats{}
+
+	for _, link := range links {
+		if err := pushLink(dc, link, stats); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+func pushLink(dc *discovery.Client, link Link, stats *Stats) error {
+	endpoints := make([]*minigraph.Endpoint, len(link.Ends))
+	edges := make([]int, len(link.Ends))
+
+	// the same device may be on the link more than once
+	byID := map[int]*minigraph.Endpoint{}
+
+	nnid := minigraph.UNCONNECTED
+
+	for i, end := range link.Ends {
+		e, err := findEndpoint(dc, end.Device, stats)
+		if err != nil {
+			return err
+		}
+
+		if v, ok := byID[e.ID()]; ok {
+			e = v
+		}
+		byID[e.ID()] = e
+
+		endpoints[i] = e
+		edges[i] = findEdge(e, end.Port)
+
+		if edges[i] == -1 {
+			continue
+		}
+
+		n := e.Edges[edges[i]].N
+		if n == minigraph.UNCONNECTED {
+			continue
+		}
+
+		if nnid == minigraph.UNCONNECTED {
+			nnid = n
+		} else if nnid != n {
+			// probably trunks or a stale network, leave it alone
+			log.Warn("%v %v is in network %v rather than %v", end.Device.Name, end.Port, n, nnid)
+		}
+	}
+
+	var ops, connects []*discovery.Op
+
+	if nnid == minigraph.UNCONNECTED {
+		nnid = -1
+
+		n := &minigraph.Network{NID: nnid, D: map[string]string{
+			"discovered_by": link.Protocol,
+		}}
+		ops = append(ops, discovery.InsertNetworkOp(n))
+	}
+
+	// endpoints that need to be updated, in order
+	var updated []*minigraph.Endpoint
+	seen := map[int]bool{}
+
+	connected := map[[2]int]bool{}
+
+	for i, end := range link.Ends {
+		e := endpoints[i]
+
+		if edges[i] == -1 {
+			edge := e.NewEdge()
+			edge.N = minigraph.UNCONNECTED
+			edge.D["name"] = end.Port
+			edge.D["interface"] = end.Port
+
+			edges[i] = len(e.Edges) - 1
+		} else if e.Edges[edges[i]].N != minigraph.UNCONNECTED {
+			// already connected, possibly to another network
+			continue
+		}
+
+		// so that a duplicate end isn't connected twice
+		k := [2]int{e.ID(), edges[i]}
+		if connected[k] {
+			continue
+		}
+		connected[k] = true
+
+		// fill in whatever the endpoint is missing
+		if end.Device.MgmtIP != "" && e.D["mgmt_ip"] == "" {
+			e.D["mgmt_ip"] = end.Device.MgmtIP
+		}
+		if end.Device.Platform != "" && e.D["platform"] == "" {
+			e.D["platform"] = end.Device.Platform
+		}
+
+		if !seen[e.ID()] {
+			seen[e.ID()] = true
+			updated = append(updated, e)
+		}
+
+		log.Info("connect %v <-> %v -- %v %v", nnid, e.ID(), end.Device.Name, end.Port)
+
+		connects = append(connects, discovery.ConnectOp(nnid, e.ID(), edges[i]))
+
+		stats.Edges++
+	}
+
+	if len(connects) == 0 {
+		return nil
+	}
+
+	for _, e := range updated {
+		ops = append(ops, discovery.UpdateEndpointOp(e))
+	}
+	ops = append(ops, connects...)
+
+	if _, err := dc.Batch(ops...); err != nil {
+		return err
+	}
+
+	if nnid == -1 {
+		stats.Networks++
+	}
+
+	return nil
+}
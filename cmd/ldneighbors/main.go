@@ -0,0 +1,137 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/importer"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+var (
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
+	f_dryrun = flag.Bool("dry-run", false, "do a dry run and do not push data to the server")
+	f_name   = flag.String("name", "", "name of the local device for show output without a prompt, defaults to the filename")
+)
+
+// Result of processing one file
+type Result struct {
+	File  string
+	Type  string
+	Name  string
+	Links []Link
+	Stats *Stats
+	Err   error
+}
+
+func usage() {
+	fmt.Printf("USAGE: %v [OPTIONS] FILE...\n", os.Args[0])
+	fmt.Println("\nFILE may be a file, a directory, or a glob. Files are either the output of")
+	fmt.Println("show lldp neighbors detail or show cdp neighbors detail, or pcaps with LLDP")
+	fmt.Println("or CDP frames captured on a single segment.")
+	fmt.Println()
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+// process parses the neighbors from the file and pushes them to the server,
+// unless this is a dry run
+func process(dc *discovery.Client, filename string) *Result {
+	res := &Result{File: filename, Name: "-"}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	if isPcap(b) {
+		res.Type = "pcap"
+		res.Links, res.Err = parsePcap(filename)
+	} else {
+		res.Type = "show"
+
+		local := *f_name
+		if local == "" {
+			local = filepath.Base(filename)
+		}
+
+		res.Links, res.Name, res.Err = parseShow(bytes.NewReader(b), local)
+	}
+
+	if res.Err != nil {
+		return res
+	}
+
+	if len(res.Links) > 0 {
+		res.Type = res.Links[0].Protocol + " " + res.Type
+	}
+
+	if !*f_dryrun {
+		res.Stats, res.Err = Push(dc, res.Links)
+	}
+
+	return res
+}
+
+func main() {
+	flag.Parse()
+
+	log.Init()
+
+	dc := discovery.New(*f_server)
+
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	files, err := importer.Expand(flag.Args())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// process files in order since they share switches
+	results := importer.Process(files, 1, func(f string) *Result {
+		return process(dc, f)
+	})
+
+	if *f_dryrun {
+		for _, res := range results {
+			for _, link := range res.Links {
+				fmt.Printf("%v: %v\n", res.File, link)
+			}
+		}
+	}
+
+	var endpoints, edges, networks int
+
+	t := importer.NewTable(os.Stdout, "TYPE", "NAME", "LINKS", "ENDPOINTS", "EDGES", "NETWORKS")
+	t.DryRun = *f_dryrun
+
+	for _, res := range results {
+		added, connected, created := "-", "-", "-"
+		if s := res.Stats; s != nil {
+			added, connected, created = fmt.Sprint(s.Endpoints), fmt.Sprint(s.Edges), fmt.Sprint(s.Networks)
+			endpoints += s.Endpoints
+			edges += s.Edges
+			networks += s.Networks
+		}
+
+		t.Row(res.File, res.Err, res.Type, res.Name, len(res.Links), added, connected, created)
+	}
+	t.Flush()
+
+	fmt.Printf("\n%v files, %v failed, %v endpoints, %v edges and %v networks added\n", t.Files, t.Failed, endpoints, edges, networks)
+
+	if t.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Protocols that neighbors are learned from
+const (
+	PROTOCOL_LLDP = "lldp"
+	PROTOCOL_CDP  = "cdp"
+)
+
+// Device is a device that advertised itself with LLDP or CDP or the device
+// that the show output is from.
+type Device struct {
+	Name string
+
+	// MgmtIP is the management address, if there is one
+	MgmtIP string
+
+	// Platform is the CDP platform or the LLDP system description
+	Platform string
+
+	// Bridge and Router are the enabled capabilities
+	Bridge, Router bool
+}
+
+// End is a port on a device.
+type End struct {
+	Device Device
+	Port   string
+}
+
+// Link is a set of ports that are on the same segment: two for neighbors from
+// show output and all the ports that advertised themselves in a pcap.
+type Link struct {
+	Protocol string
+	Ends     []End
+}
+
+func (l Link) String() string {
+	var ends []string
+	for _, e := range l.Ends {
+		v := e.Device.Name + " " + e.Port
+		if e.Device.MgmtIP != "" {
+			v += " (" + e.Device.MgmtIP + ")"
+		}
+		ends = append(ends, v)
+	}
+
+	return fmt.Sprintf("%v: %v", l.Protocol, strings.Join(ends, " <-> "))
+}
+
+var (
+	// prompt before the command, the hostname may be prefixed with the user
+	// as on Juniper devices
+	showPrompt = regexp.MustCompile(`^(?:\S+@)?([\w.-]+)[#>]\s*show\s+(lldp|cdp)\b`)
+
+	// Arista lists the neighbors under each local interface
+	aristaInterface = regexp.MustCompile(`^Interface (\S+) detected \d+ LLDP neighbors`)
+	aristaNeighbor  = regexp.MustCompile(`^\s*Neighbor \S+, age `)
+
+	// CDP puts two fields on some lines
+	cdpFields = regexp.MustCompile(`,\s+(Port ID \(outgoing port\)|Capabilities):`)
+
+	separator = regexp.MustCompile(`^-{4,}\s*$`)
+
+	// NX-OS appends the serial number to the device ID
+	serialNumber = regexp.MustCompile(`\([^)]*\)$`)
+
+	// port IDs that aren't interface names
+	macAddress = regexp.MustCompile(`^([0-9a-fA-F]{2}[:-]){5}[0-9a-fA-F]{2}$|^([0-9a-fA-F]{4}\.){2}[0-9a-fA-F]{4}$|^\d+$`)
+)
+
+// neighborStart are the fields that start a new neighbor in outputs that
+// don't separate them
+var neighborStart = []string{"device id", "chassis id", "local intf"}
+
+// addressFields are the fields that may hold the management address
+var addressFields = map[string]bool{
+	"ip address":         true,
+	"ipv4 address":       true,
+	"ip":                 true,
+	"address":            true,
+	"management address": true,
+	"mgmt address":       true,
+}
+
+// parseShow parses the output of show lldp neighbors detail or show cdp
+// neighbors detail from Cisco IOS, NX-OS, Arista, or Juniper devices. The
+// local device is named by the prompt, if there is one, and otherwise by
+// local. Returns the neighbors and the name of the local device.
+func parseShow(f io.Reader, local string) ([]Link, string, error) {
+	var res []Link
+
+	// fields of the current neighbor, lowercase names
+	var fields map[string]string
+
+	// local interface for arista
+	var iface string
+
+	flush := func() {
+		if len(fields) == 0 {
+			return
+		}
+
+		if link, ok := showLink(fields, local, iface); ok {
+			res = append(res, link)
+		}
+		fields = nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := showPrompt.FindStringSubmatch(line); m != nil {
+			flush()
+			log.Debug("found device name in prompt: %v", m[1])
+			local = m[1]
+			continue
+		}
+
+		if separator.MatchString(line) || strings.HasPrefix(line, "LLDP Neighbor Information") {
+			flush()
+			continue
+		}
+
+		if m := aristaInterface.FindStringSubmatch(line); m != nil {
+			flush()
+			iface = m[1]
+			continue
+		}
+
+		if aristaNeighbor.MatchString(line) {
+			flush()
+			continue
+		}
+
+		for _, line := range strings.Split(cdpFields.ReplaceAllString(line, "\n$1:"), "\n") {
+			k, v, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+
+			k = strings.ToLower(strings.Join(strings.Fields(strings.TrimLeft(k, " -")), " "))
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+
+			for _, f := range neighborStart {
+				if _, ok := fields[f]; ok && k == f {
+					flush()
+				}
+			}
+
+			if fields == nil {
+				fields = map[string]string{}
+			}
+
+			if addressFields[k] && net.ParseIP(v) != nil {
+				k = "mgmt"
+			}
+
+			// first one wins, juniper repeats port id in the management
+			// info
+			if _, ok := fields[k]; !ok && v != "" {
+				fields[k] = v
+			}
+		}
+	}
+
+	flush()
+
+	return res, local, scanner.Err()
+}
+
+// first returns the value of the first field that is set
+func first(fields map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := fields[k]; v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// parseCapabilities parses the enabled capabilities, either the letters from
+// Cisco LLDP output or the names
+func parseCapabilities(d *Device, s string) {
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		switch strings.ToLower(v) {
+		case "b", "bridge", "switch":
+			d.Bridge = true
+		case "r", "router":
+			d.Router = true
+		}
+	}
+}
+
+// showLink converts the fields of a neighbor to a link between the local
+// device and the neighbor
+func showLink(fields map[string]string, local, iface string) (Link, bool) {
+	link := Link{Protocol: PROTOCOL_LLDP}
+	if _, ok := fields["device id"]; ok {
+		link.Protocol = PROTOCOL_CDP
+	}
+
+	localPort := first(fields, "local intf", "local interface", "interface", "local port id")
+	if localPort == "" {
+		localPort = iface
+	}
+
+	remote := Device{
+		Name:     serialNumber.ReplaceAllString(first(fields, "system name", "device id", "chassis id"), ""),
+		MgmtIP:   fields["mgmt"],
+		Platform: first(fields, "platform", "system description"),
+	}
+	parseCapabilities(&remote, first(fields, "enabled capabilities", "enabled", "capabilities", "system capabilities"))
+
+	remotePort := first(fields, "port id (outgoing port)", "port id")
+	if (remotePort == "" || macAddress.MatchString(remotePort)) && fields["port description"] != "" {
+		// port id is a MAC or an index, the description is usually the
+		// interface name
+		remotePort = fields["port description"]
+	}
+
+	if local == "" || localPort == "" || remote.Name == "" || remotePort == "" {
+		log.Warn("ignoring incomplete neighbor: %v", fields)
+		return link, false
+	}
+
+	link.Ends = []End{
+		{Device: Device{Name: local}, Port: localPort},
+		{Device: remote, Port: remotePort},
+	}
+
+	log.Debug("got neighbor %v", link)
+
+	return link, true
+}
@@ -0,0 +1,210 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseShow(t *testing.T) {
+	tests := []struct {
+		name, output string
+
+		// name of the local device from the prompt
+		local string
+
+		want []Link
+	}{
+		{"ios cdp", `core1#show cdp neighbors detail
+-------------------------
+Device ID: dist1.example.com
+Entry address(es): 
+  IP address: 10.0.0.2
+Platform: cisco WS-C3850-24T,  Capabilities: Router Switch IGMP 
+Interface: GigabitEthernet0/1,  Port ID (outgoing port): GigabitEthernet1/0/24
+Holdtime : 150 sec
+
+Version :
+Cisco IOS Software, IOS-XE Software
+
+advertisement version: 2
+Management address(es): 
+  IP address: 10.0.0.2
+
+-------------------------
+Device ID: access1
+Entry address(es): 
+  IP address: 192.168.50.3
+Platform: cisco WS-C2960-24TT-L,  Capabilities: Switch IGMP 
+Interface: GigabitEthernet0/2,  Port ID (outgoing port): FastEthernet0/1
+Holdtime : 170 sec
+
+Total cdp entries displayed : 2
+`, "core1", []Link{
+			{Protocol: PROTOCOL_CDP, Ends: []End{
+				{Device: Device{Name: "core1"}, Port: "GigabitEthernet0/1"},
+				{Device: Device{Name: "dist1.example.com", MgmtIP: "10.0.0.2", Platform: "cisco WS-C3850-24T", Bridge: true, Router: true}, Port: "GigabitEthernet1/0/24"},
+			}},
+			{Protocol: PROTOCOL_CDP, Ends: []End{
+				{Device: Device{Name: "core1"}, Port: "GigabitEthernet0/2"},
+				{Device: Device{Name: "access1", MgmtIP: "192.168.50.3", Platform: "cisco WS-C2960-24TT-L", Bridge: true}, Port: "FastEthernet0/1"},
+			}},
+		}},
+		{"ios lldp", `core1#show lldp neighbors detail
+------------------------------------------------
+Local Intf: Gi0/3
+Chassis id: 0011.2233.4455
+Port id: Gi1/0/1
+Port Description: GigabitEthernet1/0/1
+System Name: access2.example.com
+
+System Description: 
+Cisco IOS Software, C2960X Software
+
+Time remaining: 100 seconds
+System Capabilities: B,R
+Enabled Capabilities: B
+Management Addresses:
+    IP: 192.168.50.4
+Auto Negotiation - supported, enabled
+
+------------------------------------------------
+Local Intf: Gi0/4
+Chassis id: 0011.2233.4466
+Port id: 0011.2233.4466
+Port Description - not advertised
+System Name - not advertised
+
+Total entries displayed: 2
+`, "core1", []Link{
+			{Protocol: PROTOCOL_LLDP, Ends: []End{
+				{Device: Device{Name: "core1"}, Port: "Gi0/3"},
+				{Device: Device{Name: "access2.example.com", MgmtIP: "192.168.50.4", Bridge: true}, Port: "Gi1/0/1"},
+			}},
+			{Protocol: PROTOCOL_LLDP, Ends: []End{
+				{Device: Device{Name: "core1"}, Port: "Gi0/4"},
+				{Device: Device{Name: "0011.2233.4466"}, Port: "0011.2233.4466"},
+			}},
+		}},
+		{"nx-os cdp", `nx1# show cdp neighbors detail
+----------------------------------------
+Device ID:core1(FOC1234X0AB)
+System Name: core1
+
+Interface address(es):
+    IPv4 Address: 10.0.0.1
+Platform: N9K-C93180YC-EX, Capabilities: Router Switch IGMP Filtering Supports-STP-Dispute
+
+Interface: Ethernet1/1, Port ID (outgoing port): GigabitEthernet0/5
+Holdtime: 133 sec
+`, "nx1", []Link{
+			{Protocol: PROTOCOL_CDP, Ends: []End{
+				{Device: Device{Name: "nx1"}, Port: "Ethernet1/1"},
+				{Device: Device{Name: "core1", MgmtIP: "10.0.0.1", Platform: "N9K-C93180YC-EX", Bridge: true, Router: true}, Port: "GigabitEthernet0/5"},
+			}},
+		}},
+		{"arista lldp", `leaf1#show lldp neighbors detail
+Interface Ethernet1 detected 1 LLDP neighbors:
+
+  Neighbor 0050.5600.0001/"Ethernet2", age 4 seconds
+  Discovered 2 days, 3:04:05 ago; Last changed 2 days, 3:04:05 ago
+  - Chassis ID type: MAC address (4)
+    Chassis ID     : 0050.5600.0001
+  - Port ID type: Interface name (5)
+    Port ID        : "Ethernet2"
+  - Time To Live: 120 seconds
+  - Port Description: "to-leaf1"
+  - System Name: "spine1"
+  - System Capabilities : Bridge, Router
+    Enabled Capabilities: Bridge, Router
+  - Management Address Subtype: IPv4 (1)
+    Management Address        : 172.16.0.1
+
+Interface Ethernet2 detected 1 LLDP neighbors:
+
+  Neighbor 0050.5600.0002/"Ethernet1", age 4 seconds
+  - Chassis ID     : 0050.5600.0002
+  - Port ID        : "Ethernet1"
+  - System Name: "spine2"
+  - Management Address        : 172.16.0.2
+`, "leaf1", []Link{
+			{Protocol: PROTOCOL_LLDP, Ends: []End{
+				{Device: Device{Name: "leaf1"}, Port: "Ethernet1"},
+				{Device: Device{Name: "spine1", MgmtIP: "172.16.0.1", Bridge: true, Router: true}, Port: "Ethernet2"},
+			}},
+			{Protocol: PROTOCOL_LLDP, Ends: []End{
+				{Device: Device{Name: "leaf1"}, Port: "Ethernet2"},
+				{Device: Device{Name: "spine2", MgmtIP: "172.16.0.2"}, Port: "Ethernet1"},
+			}},
+		}},
+		{"juniper lldp", `admin@srx1> show lldp neighbors detail
+
+LLDP Neighbor Information:
+Local Information:
+Index: 1 Time to live: 120 Time mark: Fri Oct 18 10:00:00 2026 Age: 10 secs
+Local Interface    : ge-0/0/1
+Parent Interface   : -
+Local Port ID      : 513
+Ageout Count       : 0
+
+Neighbour Information:
+Chassis type       : Mac address
+Chassis ID         : 00:11:22:33:44:77
+Port type          : Interface name
+Port ID            : ge-0/0/10
+Port description   : ge-0/0/10
+System name        : ex1
+
+System Description : Juniper Networks, Inc. ex2300-24t
+
+System capabilities
+        Supported: Bridge Router
+        Enabled  : Bridge Router
+
+Management Info
+Type              : IPv4
+Address           : 10.9.9.9
+Port ID           : 10
+`, "srx1", []Link{
+			{Protocol: PROTOCOL_LLDP, Ends: []End{
+				{Device: Device{Name: "srx1"}, Port: "ge-0/0/1"},
+				{Device: Device{Name: "ex1", MgmtIP: "10.9.9.9", Platform: "Juniper Networks, Inc. ex2300-24t", Bridge: true, Router: true}, Port: "ge-0/0/10"},
+			}},
+		}},
+	}
+
+	for _, test := range tests {
+		links, local, err := parseShow(strings.NewReader(test.output), "file")
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if local != test.local {
+			t.Errorf("%v: got local device %v, want %v", test.name, local, test.local)
+		}
+
+		if !reflect.DeepEqual(links, test.want) {
+			t.Errorf("%v: got\n%+v\nwant\n%+v", test.name, links, test.want)
+		}
+	}
+}
+
+func TestParseShowWithoutPrompt(t *testing.T) {
+	output := `Device ID: sw2
+Interface: GigabitEthernet0/1,  Port ID (outgoing port): GigabitEthernet0/2
+`
+
+	links, local, err := parseShow(strings.NewReader(output), "sw1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if local != "sw1" || len(links) != 1 || links[0].Ends[0].Device.Name != "sw1" {
+		t.Errorf("got %v %v, want sw1", local, links)
+	}
+}
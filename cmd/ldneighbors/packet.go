@@ -0,0 +1,123 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"net"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/ifname"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// advertisement returns the device and port from an LLDP or CDP frame
+func advertisement(p gopacket.Packet) (End, string, bool) {
+	if l := p.Layer(layers.LayerTypeCiscoDiscoveryInfo); l != nil {
+		info := l.(*layers.CiscoDiscoveryInfo)
+
+		end := End{
+			Device: Device{
+				Name:     serialNumber.ReplaceAllString(info.DeviceID, ""),
+				Platform: info.Platform,
+				Bridge:   info.Capabilities.L2Switch || info.Capabilities.TBBridge || info.Capabilities.SPBridge,
+				Router:   info.Capabilities.L3Router,
+			},
+			Port: info.PortID,
+		}
+
+		for _, ip := range append(info.MgmtAddresses, info.Addresses...) {
+			if ip.To4() != nil {
+				end.Device.MgmtIP = ip.String()
+				break
+			}
+		}
+
+		return end, PROTOCOL_CDP, end.Device.Name != "" && end.Port != ""
+	}
+
+	l := p.Layer(layers.LayerTypeLinkLayerDiscovery)
+	if l == nil {
+		return End{}, "", false
+	}
+	lldp := l.(*layers.LinkLayerDiscovery)
+
+	end := End{}
+
+	switch lldp.ChassisID.Subtype {
+	case layers.LLDPChassisIDSubTypeMACAddr:
+		end.Device.Name = net.HardwareAddr(lldp.ChassisID.ID).String()
+	default:
+		end.Device.Name = string(lldp.ChassisID.ID)
+	}
+
+	switch lldp.PortID.Subtype {
+	case layers.LLDPPortIDSubtypeMACAddr:
+		end.Port = net.HardwareAddr(lldp.PortID.ID).String()
+	default:
+		end.Port = string(lldp.PortID.ID)
+	}
+
+	if l := p.Layer(layers.LayerTypeLinkLayerDiscoveryInfo); l != nil {
+		info := l.(*layers.LinkLayerDiscoveryInfo)
+
+		if info.SysName != "" {
+			end.Device.Name = info.SysName
+		}
+		end.Device.Platform = info.SysDescription
+		end.Device.Bridge = info.SysCapabilities.EnabledCap.Bridge
+		end.Device.Router = info.SysCapabilities.EnabledCap.Router
+
+		if info.MgmtAddress.Subtype == layers.IANAAddressFamilyIPV4 && len(info.MgmtAddress.Address) == net.IPv4len {
+			end.Device.MgmtIP = net.IP(info.MgmtAddress.Address).String()
+		}
+
+		if (end.Port == "" || macAddress.MatchString(end.Port)) && info.PortDescription != "" {
+			// same as the show output
+			end.Port = info.PortDescription
+		}
+	}
+
+	return end, PROTOCOL_LLDP, end.Device.Name != "" && end.Port != ""
+}
+
+// packetLinks returns the link between all the devices that advertised
+// themselves in the packets, which were all captured on the same segment.
+// Returns false if there aren't at least two devices.
+func packetLinks(packets <-chan gopacket.Packet) (Link, bool) {
+	var link Link
+
+	// devices may advertise themselves with both protocols
+	seen := map[string]bool{}
+	protocols := map[string]bool{}
+
+	for p := range packets {
+		end, protocol, ok := advertisement(p)
+		if !ok {
+			continue
+		}
+
+		if !protocols[protocol] {
+			protocols[protocol] = true
+			if link.Protocol != "" {
+				link.Protocol += ","
+			}
+			link.Protocol += protocol
+		}
+
+		k := end.Device.Name + " " + ifname.Canonical(end.Port)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		log.Debug("got advertisement from %v %v", end.Device.Name, end.Port)
+
+		link.Ends = append(link.Ends, end)
+	}
+
+	return link, len(link.Ends) > 1
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// lldpPacket returns an LLDP frame with the chassis ID as a MAC and the port
+// ID as the interface name
+func lldpPacket(t *testing.T, mac, port, name string, mgmt net.IP) gopacket.Packet {
+	src, _ := net.ParseMAC(mac)
+
+	tlv := func(typ layers.LLDPTLVType, v []byte) layers.LinkLayerDiscoveryValue {
+		return layers.LinkLayerDiscoveryValue{Type: typ, Length: uint16(len(v)), Value: v}
+	}
+
+	// address length, family, address, interface subtype and number, OID
+	// length
+	addr := append([]byte{5, byte(layers.IANAAddressFamilyIPV4)}, mgmt.To4()...)
+	addr = append(addr, 2, 0, 0, 0, 1, 0)
+
+	lldp := &layers.LinkLayerDiscovery{
+		ChassisID: layers.LLDPChassisID{Subtype: layers.LLDPChassisIDSubTypeMACAddr, ID: src},
+		PortID:    layers.LLDPPortID{Subtype: layers.LLDPPortIDSubtypeIfaceName, ID: []byte(port)},
+		TTL:       120,
+		Values: []layers.LinkLayerDiscoveryValue{
+			tlv(layers.LLDPTLVSysName, []byte(name)),
+			tlv(layers.LLDPTLVSysDescription, []byte("Arista Networks EOS")),
+			// bridge and router, only bridge enabled
+			tlv(layers.LLDPTLVSysCapabilities, []byte{0, 0x14, 0, 0x04}),
+			tlv(layers.LLDPTLVMgmtAddress, addr),
+		},
+	}
+
+	eth := &layers.Ethernet{
+		SrcMAC:       src,
+		DstMAC:       net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e},
+		EthernetType: layers.EthernetTypeLinkLayerDiscovery,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, eth, lldp); err != nil {
+		t.Fatal(err)
+	}
+
+	return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+// cdpPacket returns a CDP frame, which gopacket can't serialize
+func cdpPacket(mac, port, name string, addr net.IP) gopacket.Packet {
+	var cdp []byte
+
+	tlv := func(typ layers.CDPTLVType, v []byte) {
+		cdp = binary.BigEndian.AppendUint16(cdp, uint16(typ))
+		cdp = binary.BigEndian.AppendUint16(cdp, uint16(len(v)+4))
+		cdp = append(cdp, v...)
+	}
+
+	// version, ttl, and checksum
+	cdp = append(cdp, 2, 180, 0, 0)
+
+	tlv(layers.CDPTLVDevID, []byte(name+"(FOC1234X0AB)"))
+	tlv(layers.CDPTLVPortID, []byte(port))
+	// router and switch
+	tlv(layers.CDPTLVCapabilities, []byte{0, 0, 0, 0x09})
+	tlv(layers.CDPTLVPlatform, []byte("cisco WS-C3850-24T"))
+	// one NLPID IP address
+	tlv(layers.CDPTLVAddress, append([]byte{0, 0, 0, 1, 1, 1, 0xcc, 0, 4}, addr.To4()...))
+
+	src, _ := net.ParseMAC(mac)
+
+	// 802.3 with LLC and SNAP
+	b := append([]byte{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}, src...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(cdp)+8))
+	b = append(b, 0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x00)
+	b = append(b, cdp...)
+
+	return gopacket.NewPacket(b, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestPacketLinks(t *testing.T) {
+	packets := make(chan gopacket.Packet, 4)
+	packets <- lldpPacket(t, "00:50:56:00:00:01", "Ethernet1", "leaf1", net.ParseIP("172.16.0.11"))
+	packets <- cdpPacket("00:50:56:00:00:02", "GigabitEthernet1/0/24", "core1", net.ParseIP("10.0.0.1"))
+	// the same port again, abbreviated
+	packets <- lldpPacket(t, "00:50:56:00:00:01", "Et1", "leaf1", net.ParseIP("172.16.0.11"))
+	close(packets)
+
+	link, ok := packetLinks(packets)
+	if !ok {
+		t.Fatalf("got no link from %v", link)
+	}
+
+	want := Link{
+		Protocol: "lldp,cdp",
+		Ends: []End{
+			{Device: Device{Name: "leaf1", MgmtIP: "172.16.0.11", Platform: "Arista Networks EOS", Bridge: true}, Port: "Ethernet1"},
+			{Device: Device{Name: "core1", MgmtIP: "10.0.0.1", Platform: "cisco WS-C3850-24T", Bridge: true, Router: true}, Port: "GigabitEthernet1/0/24"},
+		},
+	}
+
+	if !reflect.DeepEqual(link, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", link, want)
+	}
+}
+
+func TestPacketLinksOneDevice(t *testing.T) {
+	packets := make(chan gopacket.Packet, 1)
+	packets <- lldpPacket(t, "00:50:56:00:00:01", "Ethernet1", "leaf1", net.ParseIP("172.16.0.11"))
+	close(packets)
+
+	if link, ok := packetLinks(packets); ok {
+		t.Errorf("got link from a single device: %v", link)
+	}
+}
+
+func TestIsPcap(t *testing.T) {
+	if !isPcap([]byte{0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00}) {
+		t.Error("pcap not detected")
+	}
+
+	if isPcap([]byte("core1#show cdp neighbors detail")) {
+		t.Error("show output detected as pcap")
+	}
+}
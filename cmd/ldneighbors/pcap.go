@@ -0,0 +1,29 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+)
+
+// magic numbers for pcap, nanosecond pcap, and pcapng in either byte order
+var pcapMagic = [][]byte{
+	{0xa1, 0xb2, 0xc3, 0xd4},
+	{0xd4, 0xc3, 0xb2, 0xa1},
+	{0xa1, 0xb2, 0x3c, 0x4d},
+	{0x4d, 0x3c, 0xb2, 0xa1},
+	{0x0a, 0x0d, 0x0d, 0x0a},
+}
+
+// isPcap tests whether the file starts with a pcap magic number
+func isPcap(b []byte) bool {
+	for _, v := range pcapMagic {
+		if bytes.HasPrefix(b, v) {
+			return true
+		}
+	}
+
+	return false
+}
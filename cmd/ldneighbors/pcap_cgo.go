@@ -0,0 +1,29 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+//go:build cgo
+
+package main
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+)
+
+// parsePcap reads the LLDP and CDP frames from a capture
+func parsePcap(filename string) ([]Link, error) {
+	handle, err := pcap.OpenOffline(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	packets := gopacket.NewPacketSource(handle, handle.LinkType())
+
+	if link, ok := packetLinks(packets.Packets()); ok {
+		return []Link{link}, nil
+	}
+
+	return nil, nil
+}
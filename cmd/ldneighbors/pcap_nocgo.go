@@ -2,12 +2,16 @@
 // (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
 // Government retains certain rights in this software.
 
+//go:build !cgo
+
 package main
 
 import (
-	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	"errors"
 )
 
-var (
-	graph *minigraph.Graph
-)
+// parsePcap requires libpcap, which requires cgo. Show output can still be
+// parsed.
+func parsePcap(filename string) ([]Link, error) {
+	return nil, errors.New("reading pcaps requires cgo")
+}
@@ -18,7 +18,7 @@ import (
 )
 
 var (
-	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	dc       *discovery.Client
 )
 
@@ -97,35 +97,35 @@ func main() {
 			continue
 		}
 
-		// for now just create a new node, we'll merge another day
-		e := &minigraph.Endpoint{}
-		es, err := dc.InsertEndpoints(e)
-		if err != nil {
-			log.Fatalln(err)
+		// for now just create a new node, we'll merge another day. Insert
+		// everything in a single batch so that we don't leave half-built
+		// endpoints if something fails.
+		e := &minigraph.Endpoint{
+			NID: -1,
+			D:   map[string]string{},
+		}
+		ops := []*discovery.Op{
+			discovery.InsertEndpointOp(e),
 		}
-		e = es[0]
 
 		// populate the endpoint
 		if v.Addr.IP != "" {
 			// figure out which network this belongs on
 			newip, n := findNet(v.Addr.IP)
+			nnid := -2
 			if n == nil {
 				// add a new network
-				n = &minigraph.Network{}
-				ns, err := dc.InsertNetworks(n)
-				n = ns[0]
-				if err != nil {
-					log.Fatalln(err)
-				}
+				ops = append(ops, discovery.InsertNetworkOp(&minigraph.Network{NID: nnid}))
 				newip = v.Addr.IP
+			} else {
+				nnid = n.ID()
 			}
 
-			e, err = dc.Connect(n.ID(), e.ID(), discovery.EDGE_NONE)
-			if err != nil {
-				log.Fatalln(err)
-			}
-			edg := e.Edges[len(e.Edges)-1]
+			edg := e.NewEdge()
+			edg.N = minigraph.UNCONNECTED
 			edg.D["ip"] = newip
+
+			ops = append(ops, discovery.ConnectOp(nnid, e.NID, 0))
 		}
 		for _, p := range v.Ports.Ports {
 			if p.State.State == "open" {
@@ -146,7 +146,7 @@ func main() {
 			}
 		}
 
-		_, err = dc.UpdateEndpoints(e)
+		_, err = dc.Batch(ops...)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -154,11 +154,10 @@ func main() {
 }
 
 func findNet(ip string) (string, *minigraph.Network) {
-	endpoints, err := dc.GetEndpoints("", "")
-	if err != nil {
-		log.Fatalln(err)
-	}
-	for _, e := range endpoints {
+	// stop fetching endpoints as soon as we find a match
+	it := dc.IterEndpoints("", "")
+	for it.Next() {
+		e := it.Endpoint()
 		for _, edg := range e.Edges {
 			if dip, ok := edg.D["ip"]; ok {
 				_, ipn, err := net.ParseCIDR(dip)
@@ -181,5 +180,10 @@ func findNet(ip string) (string, *minigraph.Network) {
 			}
 		}
 	}
+
+	if err := it.Err(); err != nil {
+		log.Fatalln(err)
+	}
+
 	return "", nil
 }
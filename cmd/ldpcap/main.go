@@ -36,7 +36,7 @@ var (
 
 	f_profile = flag.String("profile", "", "write cpu profile to file")
 
-	f_push = flag.String("push", "", "read hosts output and push to specified server")
+	f_push = flag.String("push", "", "read hosts output and push to specified server, host:port[/model] to use a named model")
 )
 
 // used for live capture to signal when to stop
@@ -35,12 +35,15 @@ func pushHosts() {
 			continue
 		}
 
-		e := &minigraph.Endpoint{}
-		es, err := dc.InsertEndpoints(e)
-		if err != nil {
-			log.Fatalln(err)
+		// insert and connect the endpoint in a single batch so that we
+		// don't leave half-built endpoints if something fails
+		e := &minigraph.Endpoint{
+			NID: -1,
+			D:   map[string]string{},
+		}
+		ops := []*discovery.Op{
+			discovery.InsertEndpointOp(e),
 		}
-		e = es[0]
 
 		if len(h.MACs) > 1 {
 			log.Info("found machine with more than one MAC: %v", h.MACs)
@@ -60,29 +63,18 @@ func pushHosts() {
 			log.Info("found machine with more than one IP: %v", ips)
 		}
 
+		edge := e.NewEdge()
+		edge.N = minigraph.UNCONNECTED
+		edge.D["mac"] = h.MACs[0]
+
 		// populate the endpoint
 		if len(ips) > 0 {
 			// figure out which network this belongs on
 			newip, n := findNet(dc, ips[0])
 			if n != nil {
-				e, err = dc.Connect(n.ID(), e.ID(), discovery.EDGE_NONE)
-				if err != nil {
-					log.Fatalln(err)
-				}
-
-				edge := e.Edges[len(e.Edges)-1]
 				edge.D["ip"] = newip
-				edge.D["mac"] = h.MACs[0]
-			} else {
-				edge := e.NewEdge()
-				edge.N = minigraph.UNCONNECTED
-				//edge.D["ip"] = ips[0].String()
-				edge.D["mac"] = h.MACs[0]
+				ops = append(ops, discovery.ConnectOp(n.ID(), e.NID, 0))
 			}
-		} else {
-			edge := e.NewEdge()
-			edge.N = minigraph.UNCONNECTED
-			edge.D["mac"] = h.MACs[0]
 		}
 
 		max := 0.0
@@ -135,20 +127,17 @@ func pushHosts() {
 			}
 		}
 
-		_, err = dc.UpdateEndpoints(e)
-		if err != nil {
+		if _, err := dc.Batch(ops...); err != nil {
 			log.Fatalln(err)
 		}
 	}
 }
 
 func findNet(dc *discovery.Client, ip net.IP) (string, *minigraph.Network) {
-	endpoints, err := dc.GetEndpoints("", "")
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	for _, e := range endpoints {
+	// stop fetching endpoints as soon as we find a match
+	it := dc.IterEndpoints("", "")
+	for it.Next() {
+		e := it.Endpoint()
 		for _, edg := range e.Edges {
 			if dip, ok := edg.D["ip"]; ok {
 				_, ipn, err := net.ParseCIDR(dip)
@@ -171,5 +160,10 @@ func findNet(dc *discovery.Client, ip net.IP) (string, *minigraph.Network) {
 			}
 		}
 	}
+
+	if err := it.Err(); err != nil {
+		log.Fatalln(err)
+	}
+
 	return "", nil
 }
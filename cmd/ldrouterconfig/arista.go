@@ -6,95 +6,236 @@ package main
 
 import (
 	"bufio"
-	"os"
-	"path/filepath"
+	"io"
+	"net"
+	"strconv"
 	"strings"
 
-	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
-	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
-func parseArista(f *os.File, dc *discovery.Client) error {
-	e := &minigraph.Endpoint{
-		D: map[string]string{
-			"router": "true",
-			"type":   "arista",
-			"name":   filepath.Base(f.Name()),
-			"icon":   "router",
-		},
-	}
+func init() {
+	// addresses in prefix notation are parsed by the arista parser, which
+	// also handles NX-OS style configs
+	Register(&Parser{
+		Name:  "arista",
+		Parse: parseArista,
+		Signatures: signatures(
+			`(?m)^! device: `,
+			`(?m)^! Command: show running-config`,
+			`EOS-\d`,
+			`(?m)^service routing protocols model `,
+			`(?m)^management api http-commands`,
+		),
+		Priority: 10,
+	})
+}
 
-	var ID int
+func parseArista(f io.Reader) (*Device, error) {
+	d := NewDevice("arista")
 
-	if !*f_dryrun {
-		es, err := dc.InsertEndpoints(e)
-		if err != nil {
-			return err
-		}
+	// state machine is "interface" -> description,ip[v6] address,switchport, !,interface,
+	// "router ospf|bgp" -> network,passive-interface,neighbor, ! and
+	// "ip access-list" -> permit,deny, !
+	scanner := bufio.NewScanner(f)
+	var iface Interface
 
-		ID = es[0].ID()
-	}
+	// section that the current line is in: interface, ospf, bgp, acl, or
+	// empty
+	var section string
 
-	// state machine is "interface" -> description,ip[v6] address, !,interface
-	scanner := bufio.NewScanner(f)
-	var desc, ip, ip6 string
+	// name and kind of the current ip access-list section
+	var acl string
+	var standard bool
 
-	addInterface := func() error {
-		if err := AddInterface(dc, ID, desc, ip, ip6); err != nil {
-			return err
-		}
+	r := d.Routing
+	s := d.Security
 
-		desc = ""
-		ip = ""
-		ip6 = ""
+	// NAT pools by name
+	pools := map[string]string{}
 
-		return nil
+	addInterface := func() {
+		d.AddInterface(iface)
+		iface = Interface{}
 	}
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		fields := strings.Fields(line)
+
+		// unindented lines end the previous section
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			section = ""
+		}
+
 		if len(fields) < 2 {
 			continue
 		}
 
 		log.Debug("processing line: %v", line)
 
+		if _, err := strconv.Atoi(fields[0]); err == nil && section == "acl" {
+			// drop sequence number
+			fields = fields[1:]
+		}
+
 		switch fields[0] {
+		case "hostname":
+			if section == "" {
+				d.Name = fields[1]
+			}
 		case "interface":
-			if err := addInterface(); err != nil {
-				return err
+			addInterface()
+			section = "interface"
+			iface.Name = strings.Join(fields[1:], " ")
+			iface.VLAN = vlanInterface(iface.Name)
+		case "switchport":
+			if section == "interface" {
+				iface.Switchport(fields[1:])
+			}
+		case "encapsulation":
+			if section == "interface" {
+				iface.Encapsulation(fields[1:])
 			}
 		case "description":
-			desc = strings.Join(fields[1:], " ")
+			iface.Desc = strings.Join(fields[1:], " ")
+		case "permit", "deny", "remark":
+			if section == "acl" {
+				s.AddACE(acl, fields, standard)
+			}
 		case "ip", "ipv6":
-			if len(fields) != 3 || fields[1] != "address" {
+			switch {
+			case len(fields) > 2 && fields[0] == "ip" && fields[1] == "access-list" && section == "":
+				section = "acl"
+				standard = fields[2] == "standard"
+				acl = fields[len(fields)-1]
+			case len(fields) == 4 && fields[1] == "access-group" && section == "interface":
+				s.AddAccessGroup(iface.Name, fields[2], fields[3])
+			case len(fields) > 5 && fields[1] == "nat" && fields[2] == "pool" && section == "":
+				if start, end := net.ParseIP(fields[4]), net.ParseIP(fields[5]); start != nil && end != nil {
+					pools[fields[3]] = start.String() + "-" + end.String()
+				}
+			case len(fields) > 3 && fields[1] == "nat" && section == "interface":
+				parseAristaNAT(s, iface.Name, fields[2:], pools)
+			case len(fields) == 3 && fields[1] == "address":
+				if fields[0] == "ip" {
+					iface.IP = fields[2]
+					log.Debug("got ip address: %v", iface.IP)
+				} else if !strings.Contains(line, "link-local") {
+					iface.IP6 = fields[2]
+					log.Debug("got ipv6 address: %v", iface.IP6)
+				}
+			case len(fields) > 2 && fields[1] == "route" && section == "":
+				r.AddRoute(fields[2:])
+			case len(fields) == 4 && fields[1] == "ospf" && fields[2] == "area" && section == "interface":
+				log.Debug("got ospf area %v on %v", fields[3], iface.Name)
+				r.OSPFInterfaces[strings.ToLower(iface.Name)] = fields[3]
+			}
+		case "router":
+			section = fields[1]
+			if section == "bgp" && len(fields) > 2 {
+				r.ASN = fields[2]
+				log.Debug("got bgp asn: %v", r.ASN)
+			}
+		case "network":
+			if section == "ospf" {
+				r.AddOSPFNetwork(fields[1:])
+			}
+		case "passive-interface":
+			if section != "ospf" {
 				continue
 			}
 
-			if fields[0] == "ip" {
-				ip = fields[2]
-				log.Debug("got ip address: %v", ip)
-			} else if !strings.Contains(line, "link-local") {
-				ip6 = fields[2]
-				log.Debug("got ipv6 address: %v", ip6)
+			if fields[1] == "default" {
+				r.PassiveDefault = true
+			} else {
+				r.Passive[strings.ToLower(strings.Join(fields[1:], " "))] = true
+			}
+		case "no":
+			if section == "ospf" && len(fields) > 2 && fields[1] == "passive-interface" {
+				r.Passive[strings.ToLower(strings.Join(fields[2:], " "))] = false
+			}
+		case "neighbor":
+			if section == "bgp" && len(fields) == 4 && fields[2] == "remote-as" {
+				r.AddNeighbor(fields[1], fields[3])
 			}
 		case "router-id":
 			// found router-id, flush any previous interface
-			if err := addInterface(); err != nil {
-				return err
+			addInterface()
+
+			if section == "bgp" || r.RouterID == "" {
+				r.RouterID = fields[1]
 			}
 
-			desc = "router-id"
-			ip = fields[1] + "/32"
-			log.Debug("got router-id: %v", ip)
+			iface.Desc = "router-id"
+			iface.IP = fields[1] + "/32"
+			log.Debug("got router-id: %v", iface.IP)
 		}
 	}
 
-	if err := addInterface(); err != nil {
-		return err
+	addInterface()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
 
-	return scanner.Err()
+	return d, nil
+}
+
+// parseAristaNAT parses the arguments of ip nat on an interface: source
+// dynamic access-list ACL overload|pool POOL, source static IP IP, or
+// destination static IP [PORT] IP [PORT] [protocol tcp|udp].
+func parseAristaNAT(s *Security, iface string, fields []string, pools map[string]string) {
+	// translations on an interface imply its role
+	s.NATRole[strings.ToLower(iface)] = "outside"
+
+	switch {
+	case len(fields) > 3 && fields[0] == "source" && fields[1] == "dynamic" && fields[2] == "access-list":
+		n := NAT{Type: NAT_SOURCE, ACL: fields[3], Interface: iface}
+
+		if len(fields) > 5 && fields[4] == "pool" {
+			to, ok := pools[fields[5]]
+			if !ok {
+				log.Warn("undefined nat pool: %v", fields[5])
+				return
+			}
+			n.To = to
+		}
+
+		s.AddNAT(n)
+	case len(fields) > 3 && fields[0] == "source" && fields[1] == "static":
+		if net.ParseIP(fields[2]) == nil || net.ParseIP(fields[3]) == nil {
+			log.Warn("invalid nat: %v", strings.Join(fields, " "))
+			return
+		}
+
+		s.AddNAT(NAT{Type: NAT_STATIC, Src: fields[2], To: fields[3], Interface: iface})
+	case len(fields) > 3 && fields[0] == "destination" && fields[1] == "static":
+		n := NAT{Type: NAT_DESTINATION, Interface: iface}
+
+		// addresses, each optionally followed by a port
+		var addrs, ports []string
+		for i, f := range fields[2:] {
+			if net.ParseIP(f) != nil {
+				addrs = append(addrs, f)
+				ports = append(ports, "")
+			} else if _, err := strconv.Atoi(f); err == nil && len(addrs) > 0 {
+				ports[len(ports)-1] = f
+			} else if f == "protocol" && i+3 < len(fields) {
+				n.Protocol = fields[i+3]
+			}
+		}
+
+		if len(addrs) != 2 {
+			log.Warn("invalid nat: %v", strings.Join(fields, " "))
+			return
+		}
+
+		n.Dst, n.DstPort = addrs[0], ports[0]
+		n.To, n.ToPort = addrs[1], ports[1]
+
+		s.AddNAT(n)
+	default:
+		log.Warn("ignoring nat: %v", strings.Join(fields, " "))
+	}
 }
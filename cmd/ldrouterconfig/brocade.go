@@ -6,55 +6,72 @@ package main
 
 import (
 	"bufio"
-	"os"
-	"path/filepath"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
-	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
-func parseBrocade(f *os.File, dc *discovery.Client) error {
-	e := &minigraph.Endpoint{
-		D: map[string]string{
-			"router": "true",
-			"type":   "brocade",
-			"name":   filepath.Base(f.Name()),
-			"icon":   "router",
-		},
-	}
+func init() {
+	Register(&Parser{
+		Name:  "brocade",
+		Parse: parseBrocade,
+		Signatures: signatures(
+			`(?m)^\s*router-interface ve `,
+			`(?m)^\s*port-name `,
+			`(?m)^interface (ethernet|ve) \d`,
+			`(?m)^ver \d`,
+			`FastIron|ICX\d`,
+		),
+		Priority: 20,
+	})
+}
 
-	var ID int
+func parseBrocade(f io.Reader) (*Device, error) {
+	d := NewDevice("brocade")
 
-	if !*f_dryrun {
-		es, err := dc.InsertEndpoints(e)
-		if err != nil {
-			return err
-		}
+	// state machine is "interface" -> port-name,ip[v6] address, !,interface,
+	// "vlan" -> tagged,untagged,router-interface, ! and "router bgp" ->
+	// local-as,neighbor, !
+	scanner := bufio.NewScanner(f)
+	var iface Interface
 
-		ID = es[0].ID()
-	}
+	// section that the current line is in: interface, vlan, ospf, bgp, or
+	// empty
+	var section string
 
-	// state machine is "interface" -> port-name,ip[v6] address, !,interface
-	scanner := bufio.NewScanner(f)
-	var desc, ip, ip6 string
+	// VLAN of the current vlan section
+	var vlan string
 
-	addInterface := func() error {
-		if err := AddInterface(dc, ID, desc, ip, ip6); err != nil {
-			return err
-		}
+	// VLAN memberships of ports and the VLANs of the ve interfaces, VLANs come
+	// before interfaces in the config
+	ports := map[string]*brocadePort{}
+	ves := map[string]string{}
 
-		desc = ""
-		ip = ""
-		ip6 = ""
+	r := d.Routing
 
-		return nil
+	addInterface := func() {
+		if p, ok := ports[strings.ToLower(iface.Name)]; ok {
+			p.apply(&iface)
+			delete(ports, strings.ToLower(iface.Name))
+		}
+
+		d.AddInterface(iface)
+		iface = Interface{}
 	}
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		fields := strings.Fields(line)
+
+		// unindented lines end the previous section
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			section = ""
+		}
+
 		if len(fields) < 2 {
 			continue
 		}
@@ -62,45 +79,178 @@ func parseBrocade(f *os.File, dc *discovery.Client) error {
 		log.Debug("processing line: %v", line)
 
 		switch fields[0] {
+		case "hostname":
+			if section == "" {
+				d.Name = fields[1]
+			}
 		case "interface":
 			// found next interface, flush previous one
-			if err := addInterface(); err != nil {
-				return err
+			addInterface()
+			section = "interface"
+			iface.Name = strings.Join(fields[1:], " ")
+
+			if len(fields) == 3 && fields[1] == "ve" {
+				iface.VLAN = ves[fields[2]]
 			}
-		case "port-name":
-			desc = strings.Join(fields[1:], " ")
-		case "ip", "ipv6":
-			if len(fields) != 3 {
+		case "vlan":
+			section = "vlan"
+			vlan = fields[1]
+		case "tagged", "untagged":
+			if section != "vlan" {
 				continue
 			}
 
+			for _, name := range brocadePorts(fields[1:]) {
+				p, ok := ports[name]
+				if !ok {
+					p = &brocadePort{name: name}
+					ports[name] = p
+				}
+
+				if fields[0] == "tagged" {
+					p.tagged = append(p.tagged, vlan)
+				} else {
+					p.untagged = vlan
+				}
+			}
+		case "router-interface":
+			if section == "vlan" && len(fields) == 3 && fields[1] == "ve" {
+				ves[fields[2]] = vlan
+			}
+		case "port-name":
+			iface.Desc = strings.Join(fields[1:], " ")
+		case "ip", "ipv6":
 			switch fields[1] {
 			case "router-id":
-				// found router-id, flush any previous interface
-				if err := addInterface(); err != nil {
-					return err
+				if len(fields) != 3 {
+					continue
 				}
 
-				desc = "router-id"
-				ip = fields[2] + "/32"
-				log.Debug("got router-id: %v", ip)
+				// found router-id, flush any previous interface
+				addInterface()
+
+				r.RouterID = fields[2]
+
+				iface.Desc = "router-id"
+				iface.IP = fields[2] + "/32"
+				log.Debug("got router-id: %v", iface.IP)
 			case "address":
+				if len(fields) != 3 {
+					continue
+				}
+
 				if fields[0] == "ip" {
-					ip = fields[2]
-					log.Debug("got ip address: %v", ip)
+					iface.IP = fields[2]
+					log.Debug("got ip address: %v", iface.IP)
 				} else if !strings.Contains(line, "link-local") {
-					ip6 = fields[2]
-					log.Debug("got ipv6 address: %v", ip6)
+					iface.IP6 = fields[2]
+					log.Debug("got ipv6 address: %v", iface.IP6)
+				}
+			case "route":
+				if section == "" {
+					r.AddRoute(fields[2:])
+				}
+			case "ospf":
+				if fields[0] != "ip" || section != "interface" {
+					continue
+				}
+
+				if len(fields) == 4 && fields[2] == "area" {
+					log.Debug("got ospf area %v on %v", fields[3], iface.Name)
+					r.OSPFInterfaces[strings.ToLower(iface.Name)] = fields[3]
+				} else if len(fields) == 3 && fields[2] == "passive" {
+					r.Passive[strings.ToLower(iface.Name)] = true
 				}
 			default:
 				// ignore
 			}
+		case "router":
+			section = fields[1]
+		case "local-as":
+			if section == "bgp" {
+				r.ASN = fields[1]
+				log.Debug("got bgp asn: %v", r.ASN)
+			}
+		case "neighbor":
+			if section == "bgp" && len(fields) == 4 && fields[2] == "remote-as" {
+				r.AddNeighbor(fields[1], fields[3])
+			}
 		}
 	}
 
-	if err := addInterface(); err != nil {
-		return err
+	addInterface()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// add ports that are in VLANs but didn't have an interface section
+	var names []string
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		iface = Interface{Name: name}
+		addInterface()
+	}
+
+	return d, nil
+}
+
+// brocadePort is the VLAN membership of a port from the vlan sections
+type brocadePort struct {
+	name     string
+	untagged string
+	tagged   []string
+}
+
+// apply sets the VLAN on the interface, ports with tagged VLANs are trunks
+func (p *brocadePort) apply(iface *Interface) {
+	if len(p.tagged) == 0 {
+		iface.VLAN = p.untagged
+		iface.Mode = VLAN_ACCESS
+		return
+	}
+
+	iface.Mode = VLAN_TRUNK
+	iface.Allowed = p.tagged
+	if p.untagged != "" {
+		iface.Allowed = append(iface.Allowed, p.untagged)
+	}
+}
+
+// brocadePorts expands a list of ports such as "ethe 1/1 to 1/4 ethe 1/6" to
+// the interface names, e.g. "ethernet 1/1"
+func brocadePorts(fields []string) []string {
+	var res []string
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		kind, port := fields[i], fields[i+1]
+		if strings.HasPrefix(kind, "eth") {
+			kind = "ethernet"
+		}
+
+		end := port
+		if i+3 < len(fields) && fields[i+2] == "to" {
+			end = fields[i+3]
+			i += 2
+		}
+
+		// ranges only vary the last part of the port
+		j, j2 := strings.LastIndex(port, "/")+1, strings.LastIndex(end, "/")+1
+		start, err := strconv.Atoi(port[j:])
+		stop, err2 := strconv.Atoi(end[j2:])
+		if err != nil || err2 != nil || port[:j] != end[:j2] {
+			log.Warn("invalid port range: %v to %v", port, end)
+			continue
+		}
+
+		for k := start; k <= stop; k++ {
+			res = append(res, fmt.Sprintf("%v %v%v", kind, port[:j], k))
+		}
 	}
 
-	return scanner.Err()
+	return res
 }
@@ -6,82 +6,139 @@ package main
 
 import (
 	"bufio"
+	"io"
 	"net"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
-	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
-func parseCisco(f *os.File, dc *discovery.Client) error {
-	e := &minigraph.Endpoint{
-		D: map[string]string{
-			"router": "true",
-			"type":   "cisco",
-			"name":   filepath.Base(f.Name()),
-			"icon":   "router",
-		},
-	}
+func init() {
+	// cisco is also the fallback for configs that aren't recognized, see
+	// DEFAULT_TYPE
+	Register(&Parser{
+		Name:  "cisco",
+		Parse: parseCisco,
+	})
+}
 
-	var ID int
+func parseCisco(f io.Reader) (*Device, error) {
+	d := NewDevice("cisco")
 
-	if !*f_dryrun {
-		es, err := dc.InsertEndpoints(e)
-		if err != nil {
-			return err
-		}
+	// state machine is "interface" -> description,ip address,switchport, !,interface,
+	// "router ospf|bgp" -> network,passive-interface,neighbor, ! and
+	// "ip access-list" -> permit,deny, !
+	scanner := bufio.NewScanner(f)
+	var iface Interface
 
-		ID = es[0].ID()
-	}
+	// section that the current line is in: interface, ospf, bgp, acl, or
+	// empty
+	var section string
 
-	// state machine is "interface" -> description,ip address, !,interface
-	scanner := bufio.NewScanner(f)
-	var desc, ip, ip6 string
+	// name and kind of the current ip access-list section
+	var acl string
+	var standard bool
 
-	addInterface := func() error {
-		if err := AddInterface(dc, ID, desc, ip, ip6); err != nil {
-			return err
-		}
+	r := d.Routing
+	s := d.Security
 
-		desc = ""
-		ip = ""
-		ip6 = ""
+	// NAT pools by name
+	pools := map[string]string{}
 
-		return nil
+	addInterface := func() {
+		d.AddInterface(iface)
+		iface = Interface{}
 	}
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		fields := strings.Fields(line)
+
+		// unindented lines end the previous section
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			section = ""
+		}
+
 		if len(fields) < 2 {
 			continue
 		}
 
 		log.Debug("processing line: %v", line)
 
+		if _, err := strconv.Atoi(fields[0]); err == nil && section == "acl" {
+			// drop sequence number
+			fields = fields[1:]
+		}
+
 		switch fields[0] {
-		case "interface":
-			if err := addInterface(); err != nil {
-				return err
+		case "hostname":
+			if section == "" {
+				d.Name = fields[1]
 			}
+		case "interface":
+			addInterface()
+			section = "interface"
+			iface.Name = strings.Join(fields[1:], " ")
+			iface.VLAN = vlanInterface(iface.Name)
 			// use the interface name as the initial description
-			desc = strings.Join(fields[1:], " ")
+			iface.Desc = iface.Name
+		case "switchport":
+			if section == "interface" {
+				iface.Switchport(fields[1:])
+			}
+		case "encapsulation":
+			if section == "interface" {
+				iface.Encapsulation(fields[1:])
+			}
 		case "description":
-			desc = strings.Join(fields[1:], " ")
-		case "ip", "ipv4":
-			if len(fields) != 4 || fields[1] != "address" {
-				continue
+			iface.Desc = strings.Join(fields[1:], " ")
+		case "access-list":
+			// numbered ACLs, 1-99 and 1300-1999 are standard
+			if section == "" && len(fields) > 2 {
+				n, _ := strconv.Atoi(fields[1])
+				s.AddACE(fields[1], fields[2:], n < 100 || (n >= 1300 && n < 2000))
 			}
-			ipn := &net.IPNet{
-				IP:   net.ParseIP(fields[2]),
-				Mask: net.IPMask(net.ParseIP(fields[3])),
+		case "permit", "deny", "remark":
+			if section == "acl" {
+				s.AddACE(acl, fields, standard)
+			}
+		case "ip", "ipv4":
+			switch {
+			case len(fields) > 3 && fields[1] == "access-list" && section == "":
+				section = "acl"
+				standard = fields[2] == "standard"
+				acl = fields[3]
+			case len(fields) == 4 && fields[1] == "access-group" && section == "interface":
+				s.AddAccessGroup(iface.Name, fields[2], fields[3])
+			case len(fields) == 3 && fields[1] == "nat" && section == "interface":
+				log.Debug("got nat %v on %v", fields[2], iface.Name)
+				s.NATRole[strings.ToLower(iface.Name)] = fields[2]
+			case len(fields) > 5 && fields[1] == "nat" && fields[2] == "pool" && section == "":
+				if start, end := net.ParseIP(fields[4]), net.ParseIP(fields[5]); start != nil && end != nil {
+					pools[fields[3]] = start.String() + "-" + end.String()
+				}
+			case len(fields) > 4 && fields[1] == "nat" && fields[2] == "inside" && fields[3] == "source" && section == "":
+				parseCiscoNAT(s, fields[4:], pools)
+			case len(fields) == 4 && fields[1] == "address":
+				ipn := &net.IPNet{
+					IP:   net.ParseIP(fields[2]),
+					Mask: net.IPMask(net.ParseIP(fields[3])),
+				}
+				log.Debug("got ipv4 address: %v", ipn)
+				iface.IP = ipn.String()
+			case len(fields) > 2 && fields[1] == "route" && section == "":
+				r.AddRoute(fields[2:])
+			case len(fields) == 5 && fields[1] == "ospf" && fields[3] == "area" && section == "interface":
+				log.Debug("got ospf area %v on %v", fields[4], iface.Name)
+				r.OSPFInterfaces[strings.ToLower(iface.Name)] = fields[4]
 			}
-			log.Debug("got ipv4 address: %v", ipn)
-			ip = ipn.String()
 		case "ipv6":
+			if len(fields) > 2 && fields[1] == "route" && section == "" {
+				r.AddRoute(fields[2:])
+				continue
+			}
+
 			if len(fields) != 3 || fields[1] != "address" {
 				continue
 			}
@@ -90,27 +147,125 @@ func parseCisco(f *os.File, dc *discovery.Client) error {
 				continue
 			}
 
-			ip6 = fields[2]
-			log.Debug("got ipv6 address: %v", ip6)
+			iface.IP6 = fields[2]
+			log.Debug("got ipv6 address: %v", iface.IP6)
+		case "router":
+			section = fields[1]
+			if section == "bgp" && len(fields) > 2 {
+				r.ASN = fields[2]
+				log.Debug("got bgp asn: %v", r.ASN)
+			}
+		case "network":
+			if section == "ospf" {
+				r.AddOSPFNetwork(fields[1:])
+			}
+		case "passive-interface":
+			if section != "ospf" {
+				continue
+			}
+
+			if fields[1] == "default" {
+				r.PassiveDefault = true
+			} else {
+				r.Passive[strings.ToLower(strings.Join(fields[1:], " "))] = true
+			}
+		case "no":
+			if section == "ospf" && len(fields) > 2 && fields[1] == "passive-interface" {
+				r.Passive[strings.ToLower(strings.Join(fields[2:], " "))] = false
+			}
+		case "neighbor":
+			if section == "bgp" && len(fields) == 4 && fields[2] == "remote-as" {
+				r.AddNeighbor(fields[1], fields[3])
+			}
 		case "bgp":
 			if len(fields) != 3 || fields[1] != "router-id" {
 				continue
 			}
 
 			// found router-id, flush any previous interface
-			if err := addInterface(); err != nil {
-				return err
-			}
+			addInterface()
+
+			r.RouterID = fields[2]
 
-			desc = "router-id"
-			ip = fields[2] + "/32"
-			log.Debug("got router-id: %v", ip)
+			iface.Desc = "router-id"
+			iface.IP = fields[2] + "/32"
+			log.Debug("got router-id: %v", iface.IP)
 		}
 	}
 
-	if err := addInterface(); err != nil {
-		return err
+	addInterface()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
 
-	return scanner.Err()
+	return d, nil
+}
+
+// parseCiscoNAT parses the arguments of ip nat inside source: either list
+// ACL interface IFACE|pool POOL [overload] or static [tcp|udp] IP [PORT] IP
+// [PORT].
+func parseCiscoNAT(s *Security, fields []string, pools map[string]string) {
+	switch {
+	case len(fields) > 3 && fields[0] == "list":
+		n := NAT{Type: NAT_SOURCE, ACL: fields[1]}
+
+		switch fields[2] {
+		case "interface":
+			n.Interface = fields[3]
+		case "pool":
+			to, ok := pools[fields[3]]
+			if !ok {
+				log.Warn("undefined nat pool: %v", fields[3])
+				return
+			}
+			n.To = to
+		default:
+			log.Warn("ignoring nat: %v", strings.Join(fields, " "))
+			return
+		}
+
+		s.AddNAT(n)
+	case len(fields) > 2 && fields[0] == "static":
+		n := NAT{Type: NAT_STATIC}
+		fields = fields[1:]
+
+		if fields[0] == "tcp" || fields[0] == "udp" {
+			// port forward from the outside address, or interface, and port
+			// to the inside address and port
+			if len(fields) < 5 {
+				log.Warn("invalid nat: %v", strings.Join(fields, " "))
+				return
+			}
+
+			n.Type = NAT_DESTINATION
+			n.Protocol = fields[0]
+			n.To, n.ToPort = fields[1], fields[2]
+
+			if fields[3] == "interface" && len(fields) > 5 {
+				n.Interface, n.DstPort = fields[4], fields[5]
+			} else {
+				n.Dst, n.DstPort = fields[3], fields[4]
+			}
+
+			to, ok := parsePort(n.ToPort)
+			port, ok2 := parsePort(n.DstPort)
+			if !ok || !ok2 {
+				log.Warn("invalid nat: %v", strings.Join(fields, " "))
+				return
+			}
+			n.ToPort, n.DstPort = strconv.Itoa(to), strconv.Itoa(port)
+		} else {
+			n.Src, n.To = fields[0], fields[1]
+		}
+
+		if net.ParseIP(n.To) == nil || (n.Src != "" && net.ParseIP(n.Src) == nil) || (n.Dst != "" && net.ParseIP(n.Dst) == nil) {
+			log.Warn("invalid nat: %v", strings.Join(fields, " "))
+			return
+		}
+
+		s.AddNAT(n)
+	default:
+		log.Warn("ignoring nat: %v", strings.Join(fields, " "))
+	}
 }
@@ -0,0 +1,95 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// DEFAULT_TYPE is used when auto-detection doesn't recognize the config since
+// many configs look a lot like Cisco's.
+const DEFAULT_TYPE = "cisco"
+
+// Parser is a config type that can be imported. Each parser registers itself
+// in an init function so that new config types don't have to touch main.
+type Parser struct {
+	// Name of the config type for -type, e.g. cisco
+	Name string
+
+	// Parse parses the config into a Device
+	Parse func(io.Reader) (*Device, error)
+
+	// Signatures are patterns that identify configs of this type
+	Signatures []*regexp.Regexp
+
+	// Priority orders the signature checks, highest first, for config types
+	// that match the signatures of more generic types
+	Priority int
+}
+
+// parsers by name, see Register
+var parsers = map[string]*Parser{}
+
+// Register adds a parser, panics if there is already one with the same name
+func Register(p *Parser) {
+	if _, ok := parsers[p.Name]; ok {
+		panic(fmt.Sprintf("parser %v already registered", p.Name))
+	}
+
+	parsers[p.Name] = p
+}
+
+// signatures compiles the patterns for a Parser
+func signatures(patterns ...string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, v := range patterns {
+		res = append(res, regexp.MustCompile(v))
+	}
+
+	return res
+}
+
+// parserNames returns the names of the parsers in sorted order
+func parserNames() []string {
+	var res []string
+	for k := range parsers {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// detectType guesses the config type from the contents of the config
+func detectType(b []byte) string {
+	var ps []*Parser
+	for _, p := range parsers {
+		ps = append(ps, p)
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Priority != ps[j].Priority {
+			return ps[i].Priority > ps[j].Priority
+		}
+
+		return ps[i].Name < ps[j].Name
+	})
+
+	for _, p := range ps {
+		for _, re := range p.Signatures {
+			if re.Match(b) {
+				log.Debug("detected %v config, matched %v", p.Name, re)
+				return p.Name
+			}
+		}
+	}
+
+	return DEFAULT_TYPE
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name, config, want string
+	}{
+		{"cisco ios", `
+hostname r1
+!
+interface GigabitEthernet0/0
+ ip address 10.0.0.1 255.255.255.0
+!
+router ospf 1
+ network 10.0.0.0 0.0.0.255 area 0
+`, "cisco"},
+		{"cisco nx-os", `
+!Command: show running-config
+version 9.3(8) Bios:version 05.45
+hostname n1
+feature ospf
+interface Ethernet1/1
+  no switchport
+  ip address 10.0.0.1/24
+  no shutdown
+`, "cisco"},
+		{"arista device header", `
+! device: a1 (DCS-7050TX-64, EOS-4.22.1F)
+!
+hostname a1
+interface Ethernet1
+   no switchport
+   ip address 10.0.0.1/24
+`, "arista"},
+		{"arista without header", `
+service routing protocols model multi-agent
+!
+hostname a1
+!
+interface Ethernet1
+   no switchport
+   ip address 10.0.0.1/24
+`, "arista"},
+		{"brocade", `
+ver 08.0.30qT211
+!
+interface ethernet 1/1/1
+ port-name uplink
+!
+interface ve 10
+ ip address 10.0.0.1 255.255.255.0
+`, "brocade"},
+		{"juniper set", `
+set version 18.4R1.8
+set system host-name j1
+set interfaces ge-0/0/0 unit 0 family inet address 10.0.0.1/24
+`, "juniper"},
+		{"juniper curly", `
+## Last commit: 2019-01-01 00:00:00 UTC by admin
+version 18.4R1.8;
+system {
+    host-name j1;
+}
+interfaces {
+    ge-0/0/0 {
+        unit 0 {
+            family inet {
+                address 10.0.0.1/24;
+            }
+        }
+    }
+}
+`, "juniper"},
+		{"juniper curly without header", `
+system {
+    host-name j1;
+}
+version 18.4R1.8;
+interfaces {
+    ge-0/0/0 {
+        vlan-tagging;
+        unit 10 {
+            vlan-id 10;
+        }
+    }
+}
+`, "juniper"},
+		{"juniper xml", `
+<rpc-reply>
+<configuration junos:commit-seconds="1546300800">
+    <system><host-name>j1</host-name></system>
+</configuration>
+</rpc-reply>
+`, "juniper"},
+		{"juniper json", `
+{
+    "configuration" : {
+        "system" : { "host-name" : "j1" }
+    }
+}
+`, "juniper"},
+		{"vyos set", `
+set interfaces ethernet eth0 address '10.0.0.1/24'
+set system host-name 'v1'
+`, "vyos"},
+		{"vyos config.boot", `
+interfaces {
+    ethernet eth0 {
+        address 10.0.0.1/24
+    }
+    loopback lo {
+    }
+}
+system {
+    host-name v1
+}
+`, "vyos"},
+		{"vyos vif", `
+system {
+    host-name v1
+}
+interfaces {
+    ethernet eth1 {
+        vif 10 {
+            address 10.0.10.1/24
+        }
+    }
+}
+`, "vyos"},
+		{"mikrotik", `
+# jan/01/2019 00:00:00 by RouterOS 6.44
+/interface bridge
+add name=bridge1
+/ip address
+add address=10.0.0.1/24 interface=ether1
+`, "mikrotik"},
+		{"fortigate", `
+#config-version=FGT60E-6.0.4-FW-build0231-190107:opmode=0:vdom=0
+config system interface
+    edit "port1"
+        set ip 10.0.0.1 255.255.255.0
+    next
+end
+`, "fortigate"},
+		{"paloalto", `
+<config version="9.0.0" urldb="paloaltonetworks">
+  <devices><entry name="localhost.localdomain"><deviceconfig/></entry></devices>
+</config>
+`, "paloalto"},
+		{"pfsense", `
+<?xml version="1.0"?>
+<pfsense>
+  <system><hostname>pf1</hostname></system>
+</pfsense>
+`, "pfsense"},
+		{"opnsense", `
+<?xml version="1.0"?>
+<opnsense>
+  <system><hostname>opn1</hostname></system>
+</opnsense>
+`, "pfsense"},
+	}
+
+	for _, test := range tests {
+		if got := detectType([]byte(test.config)); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
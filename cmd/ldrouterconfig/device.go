@@ -0,0 +1,247 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Device is a router, switch, or firewall parsed from a config, see Push.
+type Device struct {
+	// Name is the hostname from the config, if there is one
+	Name string
+
+	// Type is the config type, e.g. cisco
+	Type string
+
+	// Firewall is set for firewalls, which are tagged type=firewall rather
+	// than with the config type
+	Firewall bool
+
+	Interfaces []Interface
+
+	Routing *Routing
+
+	Security *Security
+}
+
+// Stats are the number of edges and new networks that Push added.
+type Stats struct {
+	Edges, Networks int
+}
+
+func NewDevice(typ string) *Device {
+	return &Device{
+		Type:     typ,
+		Routing:  NewRouting(),
+		Security: NewSecurity(),
+	}
+}
+
+// AddInterface adds the interface, ignoring empty interfaces from the parsers
+// flushing before the first interface.
+func (d *Device) AddInterface(iface Interface) {
+	if iface.Name == "" && iface.Desc == "" && iface.IP == "" && iface.IP6 == "" {
+		return
+	}
+
+	d.Interfaces = append(d.Interfaces, iface)
+}
+
+// Push inserts the device as a router endpoint, adds its interfaces, and
+// applies its routing and security configuration.
+func (d *Device) Push(dc *discovery.Client) (*Stats, error) {
+	e := &minigraph.Endpoint{
+		D: map[string]string{
+			"router": "true",
+			"type":   d.Type,
+			"name":   d.Name,
+			"icon":   "router",
+			"vendor": d.Type,
+		},
+	}
+
+	if d.Firewall {
+		e.D["type"] = "firewall"
+		e.D["icon"] = "firewall"
+	}
+
+	es, err := dc.InsertEndpoints(e)
+	if err != nil {
+		return nil, err
+	}
+
+	ID := es[0].ID()
+
+	stats := &Stats{}
+
+	for _, iface := range d.Interfaces {
+		edges, networks, err := AddInterface(dc, ID, iface)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Edges += edges
+		stats.Networks += networks
+	}
+
+	if err := AddRouting(dc, ID, d.Routing); err != nil {
+		return stats, err
+	}
+
+	return stats, AddSecurity(dc, ID, d.Security)
+}
+
+// interfaceTable collects interfaces by name for configs that spread the
+// settings for an interface across the config.
+type interfaceTable struct {
+	names      []string
+	interfaces map[string]*Interface
+}
+
+// Get returns the interface with the given name, creating it if necessary
+func (t *interfaceTable) Get(name string) *Interface {
+	if iface, ok := t.interfaces[name]; ok {
+		return iface
+	}
+
+	if t.interfaces == nil {
+		t.interfaces = map[string]*Interface{}
+	}
+
+	// use the interface name as the initial description
+	iface := &Interface{Name: name, Desc: name}
+	t.interfaces[name] = iface
+	t.names = append(t.names, name)
+
+	return iface
+}
+
+// SetZone sets the zone for the interface, if there is one
+func (t *interfaceTable) SetZone(name, zone string) {
+	if iface, ok := t.interfaces[name]; ok && iface.Zone == "" {
+		log.Debug("got zone %v on %v", zone, name)
+		iface.Zone = zone
+	}
+}
+
+// AddTo adds the interfaces to the device in the order they were created
+func (t *interfaceTable) AddTo(d *Device) {
+	for _, name := range t.names {
+		d.AddInterface(*t.interfaces[name])
+	}
+}
+
+// String describes what Push would create.
+func (d *Device) String() string {
+	var b bytes.Buffer
+
+	kind := "router"
+	if d.Firewall {
+		kind = "firewall"
+	}
+
+	fmt.Fprintf(&b, "%v %v (%v) with %v interfaces\n", kind, d.Name, d.Type, len(d.Interfaces))
+
+	for _, iface := range d.Interfaces {
+		fmt.Fprintf(&b, "\tinterface %v", iface.Name)
+		if iface.Desc != "" && iface.Desc != iface.Name {
+			fmt.Fprintf(&b, " (%v)", iface.Desc)
+		}
+		for _, v := range []string{iface.IP, iface.IP6} {
+			if v != "" {
+				fmt.Fprintf(&b, " %v", v)
+			}
+		}
+		if iface.VLAN != "" {
+			fmt.Fprintf(&b, " vlan %v", iface.VLAN)
+		}
+		if len(iface.Allowed) > 0 {
+			fmt.Fprintf(&b, " vlans %v", strings.Join(iface.Allowed, ","))
+		}
+		if iface.Mode != "" {
+			fmt.Fprintf(&b, " %v", iface.Mode)
+		}
+		if iface.Zone != "" {
+			fmt.Fprintf(&b, " zone %v", iface.Zone)
+		}
+		b.WriteString("\n")
+	}
+
+	r := d.Routing
+
+	for _, v := range r.Routes {
+		fmt.Fprintf(&b, "\tstatic route %v via %v\n", v.Dst, v.Via)
+	}
+
+	for _, v := range r.OSPFNetworks {
+		fmt.Fprintf(&b, "\tospf network %v area %v\n", v.Net, v.Area)
+	}
+
+	for _, k := range sortedKeys(r.OSPFInterfaces) {
+		fmt.Fprintf(&b, "\tospf interface %v area %v\n", k, r.OSPFInterfaces[k])
+	}
+
+	if r.ASN != "" {
+		fmt.Fprintf(&b, "\tbgp as %v\n", r.ASN)
+	}
+
+	for _, k := range sortedKeys(r.Neighbors) {
+		fmt.Fprintf(&b, "\tbgp neighbor %v remote-as %v\n", k, r.Neighbors[k])
+	}
+
+	s := d.Security
+
+	for _, k := range sortedKeys(s.ACLIn) {
+		fmt.Fprintf(&b, "\taccess list %v in on %v\n", s.ACLIn[k], k)
+	}
+
+	for _, k := range sortedKeys(s.ACLOut) {
+		fmt.Fprintf(&b, "\taccess list %v out on %v\n", s.ACLOut[k], k)
+	}
+
+	for _, k := range sortedKeys(s.NATRole) {
+		fmt.Fprintf(&b, "\tnat %v on %v\n", s.NATRole[k], k)
+	}
+
+	var acls []string
+	for k := range s.ACLs {
+		acls = append(acls, k)
+	}
+	sort.Strings(acls)
+
+	for _, k := range acls {
+		for _, v := range s.ACLs[k] {
+			fmt.Fprintf(&b, "\taccess list %v: %v\n", k, v)
+		}
+	}
+
+	for _, v := range s.Policies {
+		fmt.Fprintf(&b, "\tpolicy %v\n", v)
+	}
+
+	for _, v := range s.NAT {
+		fmt.Fprintf(&b, "\tnat %v\n", v)
+	}
+
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
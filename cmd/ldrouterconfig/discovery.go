@@ -5,39 +5,150 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"strconv"
+	"sync"
 
 	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
 	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
-func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
-	if *f_dryrun {
-		return nil
+// Interface is an interface parsed from a config.
+type Interface struct {
+	// Name of the interface in the config, used to match it with the routing
+	// configuration
+	Name string
+
+	// Desc is the description, if there is one
+	Desc string
+
+	IP, IP6 string
+
+	// VLAN that the interface is in (access ports and VLAN interfaces) or
+	// tags (sub-interfaces)
+	VLAN string
+
+	// Mode is VLAN_ACCESS or VLAN_TRUNK for switch ports and sub-interfaces
+	Mode string
+
+	// Allowed VLANs on a trunk
+	Allowed []string
+
+	// Zone is the firewall zone or interface list that the interface is in
+	Zone string
+}
+
+// AddAddress sets the IPv4 or IPv6 address from a prefix such as
+// 10.0.0.1/24 unless it is already set. Loopback, link-local, and invalid
+// addresses are ignored.
+func (iface *Interface) AddAddress(s string) {
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		log.Debug("ignoring address %v on %v: %v", s, iface.Name, err)
+		return
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return
+	}
+
+	v := (&net.IPNet{IP: ip, Mask: ipnet.Mask}).String()
+
+	if ip.To4() != nil && iface.IP == "" {
+		log.Debug("got ipv4 address %v on %v", v, iface.Name)
+		iface.IP = v
+	} else if ip.To4() == nil && iface.IP6 == "" {
+		log.Debug("got ipv6 address %v on %v", v, iface.Name)
+		iface.IP6 = v
+	}
+}
+
+// networkLock serializes finding or creating the network for an interface so
+// that configs imported concurrently don't create duplicate networks
+var networkLock sync.Mutex
+
+// number of times to retry an interface if the router was modified
+// concurrently
+const conflictRetries = 3
+
+// AddInterface adds an edge to the router for the interface and connects it to
+// the network for the VLAN or with the same subnet, creating the network if
+// there isn't one. Trunks have an edge for each allowed VLAN. Returns the
+// number of edges and networks that were added.
+func AddInterface(dc *discovery.Client, ID int, iface Interface) (int, int, error) {
+	var edges, networks int
+
+	add := func(iface Interface) error {
+		edge, network, err := addInterface(dc, ID, iface)
+		for i := 0; i < conflictRetries && errors.Is(err, discovery.ErrConflict); i++ {
+			log.Info("retrying %v: %v", iface.Name, err)
+			edge, network, err = addInterface(dc, ID, iface)
+		}
+
+		if edge {
+			edges++
+		}
+		if network {
+			networks++
+		}
+		return err
 	}
 
-	if desc == "" || (ip == "" && ip6 == "") {
+	if iface.Mode != VLAN_TRUNK || len(iface.Allowed) == 0 {
+		err := add(iface)
+		return edges, networks, err
+	}
+
+	for _, v := range iface.Allowed {
+		iface2 := Interface{
+			Name: iface.Name,
+			Desc: iface.Desc,
+			VLAN: v,
+			Mode: VLAN_TRUNK,
+			Zone: iface.Zone,
+		}
+
+		if err := add(iface2); err != nil {
+			return edges, networks, err
+		}
+	}
+
+	return edges, networks, nil
+}
+
+// addInterface returns whether it added an edge and a network
+func addInterface(dc *discovery.Client, ID int, iface Interface) (bool, bool, error) {
+	ip, ip6, desc := iface.IP, iface.IP6, iface.Desc
+
+	if desc == "" && iface.VLAN != "" {
+		// switch ports don't usually have descriptions
+		desc = iface.Name
+	}
+
+	if desc == "" || (ip == "" && ip6 == "" && iface.VLAN == "") {
 		// what's the point?
-		return nil
+		return false, false, nil
 	}
 
+	networkLock.Lock()
+	defer networkLock.Unlock()
+
 	// try to find network with a matching subnet before creating a new network
 	var network *minigraph.Network
 
-	endpoints, err := dc.GetEndpoints("", "")
-	if err != nil {
-		log.Fatalln(err)
-	}
-
 	var ipnet, ipnet6 *net.IPNet
 
+	// endpoints with edges in either subnet
+	var endpoints []*minigraph.Endpoint
+
 	if ip != "" {
 		var err error
 		_, ipnet, err = net.ParseCIDR(ip)
 		if err != nil {
-			return err
+			return false, false, err
 		}
 	}
 
@@ -45,8 +156,40 @@ func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
 		var err error
 		_, ipnet6, err = net.ParseCIDR(ip6)
 		if err != nil {
-			return err
+			return false, false, err
+		}
+	}
+
+	// networks are shared by everything in the same VLAN, as long as the
+	// subnets agree
+	if iface.VLAN != "" {
+		res, err := dc.LookupNetworks("vlan", iface.VLAN)
+		if err != nil {
+			return false, false, err
+		}
+
+		for _, n := range res {
+			if sameSubnet(n.D["cidr"], ipnet) && sameSubnet(n.D["cidr6"], ipnet6) {
+				network = n
+				break
+			}
+		}
+	}
+
+	if network == nil && ipnet != nil {
+		res, err := dc.LookupEndpointsCIDR("edge.ip", ipnet.String())
+		if err != nil {
+			return false, false, err
 		}
+		endpoints = append(endpoints, res...)
+	}
+
+	if network == nil && ipnet6 != nil {
+		res, err := dc.LookupEndpointsCIDR("edge.ip6", ipnet6.String())
+		if err != nil {
+			return false, false, err
+		}
+		endpoints = append(endpoints, res...)
 	}
 
 	for _, e := range endpoints {
@@ -56,7 +199,7 @@ func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
 				if ipnet != nil && err == nil && got.String() == ipnet.String() {
 					if e.ID() == ID {
 						// we found ourselves... redundant info in configs?
-						return nil
+						return false, false, nil
 					}
 
 					// Ayyyy, we found a network this should belong to
@@ -67,7 +210,7 @@ func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
 
 					networks, err := dc.GetNetworks("nid", strconv.Itoa(edge.N))
 					if err != nil {
-						return err
+						return false, false, err
 					}
 					if len(networks) == 1 {
 						network = networks[0]
@@ -80,7 +223,7 @@ func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
 				if ipnet6 != nil && err == nil && got.String() == ipnet6.String() {
 					if e.ID() == ID {
 						// we found ourselves... redundant info in configs?
-						return nil
+						return false, false, nil
 					}
 
 					// Ayyyy, we found a network this should belong to
@@ -91,7 +234,7 @@ func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
 
 					networks, err := dc.GetNetworks("nid", strconv.Itoa(edge.N))
 					if err != nil {
-						return err
+						return false, false, err
 					}
 					if len(networks) == 1 {
 						network = networks[0]
@@ -102,32 +245,124 @@ func AddInterface(dc *discovery.Client, ID int, desc, ip, ip6 string) error {
 		}
 	}
 
-	// didn't find anything to connect to
-	if network == nil {
-		n := &minigraph.Network{}
-		networks, err := dc.InsertNetworks(n)
-		if err != nil {
-			return err
-		}
+	// find ourselves so that we can add the new edge
+	res, err := dc.LookupEndpoints("nid", strconv.Itoa(ID))
+	if err != nil {
+		return false, false, err
+	}
+	if len(res) != 1 {
+		return false, false, fmt.Errorf("no such endpoint: %v", ID)
+	}
+	e := res[0]
 
-		network = networks[0]
+	var edge *minigraph.Edge
+	if network != nil {
+		for _, v := range e.Edges {
+			if v.N == network.ID() {
+				edge = v
+			}
+		}
 	}
 
-	log.Info("connect %v <-> %v -- %v, %v", network.ID(), ID, ip, ip6)
-	e, err := dc.Connect(network.ID(), ID, discovery.EDGE_NONE)
-	if err != nil {
-		return err
+	if edge != nil {
+		// already in the VLAN, e.g. through a trunk and a VLAN interface,
+		// so add any addresses to the existing edge
+		if (ip == "" || edge.D["ip"] != "") && (ip6 == "" || edge.D["ip6"] != "") {
+			return false, false, nil
+		}
+	} else {
+		edge = e.NewEdge()
+		edge.N = minigraph.UNCONNECTED
+		edge.D["name"] = desc
+		if iface.Name != "" {
+			edge.D["interface"] = iface.Name
+		}
+		if iface.VLAN != "" {
+			edge.D["vlan"] = iface.VLAN
+		}
+		if iface.Mode != "" {
+			edge.D["vlan_mode"] = iface.Mode
+		}
+		if iface.Zone != "" {
+			edge.D["zone"] = iface.Zone
+		}
 	}
 
-	edge := e.Edges[len(e.Edges)-1]
-	edge.D["name"] = desc
-	if ip != "" {
+	if ip != "" && edge.D["ip"] == "" {
 		edge.D["ip"] = ip
 	}
-	if ip6 != "" {
+	if ip6 != "" && edge.D["ip6"] == "" {
 		edge.D["ip6"] = ip6
 	}
 
-	_, err = dc.UpdateEndpoints(e)
-	return err
+	var ops []*discovery.Op
+
+	// didn't find anything to connect to, create a new network in the same
+	// batch so that we don't leave it dangling if the connect fails
+	nnid := -1
+	if network == nil {
+		n := &minigraph.Network{NID: nnid, D: map[string]string{}}
+		if ipnet != nil {
+			n.D["cidr"] = ipnet.String()
+		}
+		if ipnet6 != nil {
+			n.D["cidr6"] = ipnet6.String()
+		}
+		if iface.VLAN != "" {
+			n.D["vlan"] = iface.VLAN
+		}
+
+		ops = append(ops, discovery.InsertNetworkOp(n))
+	} else {
+		nnid = network.ID()
+
+		// fill in whatever the network is missing
+		set := map[string]string{}
+		if ipnet != nil && network.D["cidr"] == "" {
+			set["cidr"] = ipnet.String()
+		}
+		if ipnet6 != nil && network.D["cidr6"] == "" {
+			set["cidr6"] = ipnet6.String()
+		}
+		if iface.VLAN != "" && network.D["vlan"] == "" {
+			set["vlan"] = iface.VLAN
+		} else if iface.VLAN != "" && network.D["vlan"] != iface.VLAN {
+			log.Warn("vlan %v on %v but network %v has vlan %v", iface.VLAN, desc, nnid, network.D["vlan"])
+		}
+
+		if len(set) > 0 {
+			p := &minigraph.Patch{
+				Data: minigraph.DataDiff{Set: set},
+			}
+			ops = append(ops, discovery.PatchOp(nnid, p))
+		}
+	}
+
+	// fails if another importer updated the router since we looked it up
+	update := discovery.UpdateEndpointOp(e)
+	update.Rev = e.Rev
+	ops = append(ops, update)
+
+	connect := edge.N == minigraph.UNCONNECTED
+	if connect {
+		log.Info("connect %v <-> %v -- %v, %v, vlan %v", nnid, ID, ip, ip6, iface.VLAN)
+		ops = append(ops, discovery.ConnectOp(nnid, ID, len(e.Edges)-1))
+	}
+
+	if _, err := dc.Batch(ops...); err != nil {
+		return false, false, err
+	}
+
+	return connect, network == nil, nil
+}
+
+// sameSubnet returns true if the cidr is unset or the subnet is unknown or
+// they are the same
+func sameSubnet(cidr string, ipnet *net.IPNet) bool {
+	if cidr == "" || ipnet == nil {
+		return true
+	}
+
+	_, got, err := net.ParseCIDR(cidr)
+	return err == nil && got.String() == ipnet.String()
 }
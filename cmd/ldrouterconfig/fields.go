@@ -0,0 +1,51 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"strings"
+)
+
+// splitQuoted splits a line into words like strings.Fields except that words
+// in quotes, using any of the quote characters, may contain spaces. Quotes
+// are removed and backslashes escape the next character within quotes.
+func splitQuoted(s, quotes string) []string {
+	var res []string
+	var word strings.Builder
+	var escaped, inWord bool
+
+	// quote that the current word is in, 0 if not quoted
+	var quote rune
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && strings.ContainsRune(quotes, r):
+			quote = r
+			inWord = true
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if inWord {
+				res = append(res, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		res = append(res, word.String())
+	}
+
+	return res
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+func init() {
+	Register(&Parser{
+		Name:  "fortigate",
+		Parse: parseFortiGate,
+		Signatures: signatures(
+			`#config-version=F`,
+			`(?m)^config system (global|interface|zone)\s*$`,
+		),
+		Priority: 40,
+	})
+}
+
+// fortiGateBlock is a config block and the entry that is being edited in it
+type fortiGateBlock struct {
+	Name, Edit string
+}
+
+func parseFortiGate(f io.Reader) (*Device, error) {
+	d := NewDevice("fortigate")
+	d.Firewall = true
+
+	// state machine is "config system interface" -> edit,set,config ipv6,next,
+	// end and "config system zone" -> edit,set interface,next,end
+	var stack []fortiGateBlock
+
+	var interfaces interfaceTable
+
+	zones := map[string]string{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := splitQuoted(line, `"`)
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		log.Debug("processing line: %v", line)
+
+		switch fields[0] {
+		case "config":
+			stack = append(stack, fortiGateBlock{Name: strings.Join(fields[1:], " ")})
+			continue
+		case "end":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		case "edit":
+			if len(stack) > 0 && len(fields) == 2 {
+				stack[len(stack)-1].Edit = fields[1]
+			}
+			continue
+		case "next":
+			if len(stack) > 0 {
+				stack[len(stack)-1].Edit = ""
+			}
+			continue
+		}
+
+		if fields[0] != "set" || len(fields) < 3 || len(stack) == 0 {
+			continue
+		}
+
+		block := stack[len(stack)-1]
+
+		// the interface that is being edited, if any, including in the
+		// nested config ipv6 block
+		var name string
+		if block.Name == "system interface" {
+			name = block.Edit
+		} else if block.Name == "ipv6" && len(stack) > 1 && stack[len(stack)-2].Name == "system interface" {
+			name = stack[len(stack)-2].Edit
+		}
+
+		switch {
+		case block.Name == "system global" && fields[1] == "hostname":
+			d.Name = fields[2]
+		case block.Name == "system zone" && block.Edit != "" && fields[1] == "interface":
+			for _, v := range fields[2:] {
+				zones[v] = block.Edit
+			}
+		case name == "":
+			continue
+		case fields[1] == "ip" && len(fields) == 4:
+			ip, mask := net.ParseIP(fields[2]), net.ParseIP(fields[3]).To4()
+			if ip == nil || mask == nil || ip.IsUnspecified() {
+				continue
+			}
+
+			interfaces.Get(name).AddAddress((&net.IPNet{IP: ip, Mask: net.IPMask(mask)}).String())
+		case fields[1] == "ip" || fields[1] == "ip6-address":
+			// ip may also be a prefix
+			interfaces.Get(name).AddAddress(fields[2])
+		case fields[1] == "alias" || fields[1] == "description":
+			iface := interfaces.Get(name)
+			if fields[1] == "alias" || iface.Desc == iface.Name {
+				// prefer the alias, which is usually shorter
+				iface.Desc = fields[2]
+			}
+		case fields[1] == "vlanid":
+			iface := interfaces.Get(name)
+			iface.VLAN, iface.Mode = fields[2], VLAN_TRUNK
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, zone := range zones {
+		interfaces.SetZone(name, zone)
+	}
+
+	interfaces.AddTo(d)
+
+	return d, nil
+}
@@ -5,19 +5,30 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
+	"io"
 	"net"
-	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
-	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
+func init() {
+	Register(&Parser{
+		Name:  "juniper",
+		Parse: parseJuniper,
+		Signatures: signatures(
+			`display (json|xml|set)`,
+			`"configuration"\s*:`,
+			`<configuration[\s>]`,
+			`(?m)^set (version|system|interfaces|routing-options|protocols) `,
+			`(?m)^## Last (commit|changed): `,
+			`(?m)^version [\w.-]+;\s*$`,
+		),
+		Priority: 30,
+	})
+}
+
 type Data struct {
 	Data string
 }
@@ -28,12 +39,21 @@ type JuniperInterfaces []struct {
 		Description []Data
 		Unit        []struct {
 			Name   Data
+			VlanID []Data `json:"vlan-id"` // sub-interfaces
 			Family []struct {
 				Inet []struct {
 					Address []struct {
 						Name      Data
 						Preferred []interface{} // may be omitted
 					}
+					Filter []struct {
+						Input []struct {
+							FilterName Data `json:"filter-name"`
+						}
+						Output []struct {
+							FilterName Data `json:"filter-name"`
+						}
+					}
 				}
 				Inet6 []struct {
 					Address []struct {
@@ -41,21 +61,98 @@ type JuniperInterfaces []struct {
 						Preferred []interface{} // may be omitted
 					}
 				}
+				EthernetSwitching []struct {
+					InterfaceMode []Data `json:"interface-mode"`
+					PortMode      []Data `json:"port-mode"` // older releases
+					Vlan          []struct {
+						Members []Data
+					}
+				} `json:"ethernet-switching"`
 			}
 		}
 	}
 }
 
+type JuniperRoutingOptions []struct {
+	Static []struct {
+		Route []struct {
+			Name    Data
+			NextHop []Data `json:"next-hop"`
+		}
+	}
+	AutonomousSystem []struct {
+		ASNumber Data `json:"as-number"`
+	} `json:"autonomous-system"`
+	RouterID []Data `json:"router-id"`
+}
+
+type JuniperProtocols []struct {
+	OSPF []struct {
+		Area []struct {
+			Name      Data
+			Interface []struct {
+				Name    Data
+				Passive []interface{} // may be omitted
+			}
+		}
+	}
+	BGP []struct {
+		Group []struct {
+			Name     Data
+			PeerAS   []Data `json:"peer-as"`
+			Neighbor []struct {
+				Name   Data
+				PeerAS []Data `json:"peer-as"` // overrides the group's
+			}
+		}
+	}
+}
+
+type JuniperVlans []struct {
+	Vlan []struct {
+		Name        Data
+		VlanID      []Data `json:"vlan-id"`
+		L3Interface []Data `json:"l3-interface"`
+	}
+}
+
+type JuniperSystem []struct {
+	HostName []Data `json:"host-name"`
+}
+
 type JuniperConfig struct {
-	Interfaces JuniperInterfaces
-	Groups     []struct {
-		Name       Data
-		Interfaces JuniperInterfaces
+	Interfaces     JuniperInterfaces
+	System         JuniperSystem
+	RoutingOptions JuniperRoutingOptions `json:"routing-options"`
+	Protocols      JuniperProtocols
+	Vlans          JuniperVlans
+	Firewall       JuniperFirewall
+	Security       JuniperSecurity
+	Applications   JuniperApplications
+}
+
+// juniperVlans maps the names and l3-interfaces of the VLANs to their IDs
+func juniperVlans(vlans JuniperVlans) map[string]string {
+	res := map[string]string{}
+
+	for _, v := range vlans {
+		for _, vlan := range v.Vlan {
+			if len(vlan.VlanID) == 0 {
+				continue
+			}
+
+			id := vlan.VlanID[0].Data
+			res[vlan.Name.Data] = id
+			for _, l3 := range vlan.L3Interface {
+				res[l3.Data] = id
+			}
+		}
 	}
-	ApplyGroups []Data `json:"apply-groups"`
+
+	return res
 }
 
-func processJuniper(dc *discovery.Client, ID int, interfaces JuniperInterfaces) error {
+func processJuniper(d *Device, interfaces JuniperInterfaces, vlans, zones map[string]string) {
 	for _, ifaces := range interfaces {
 		for _, iface := range ifaces.Interface {
 			log.Info("found interface: %v", iface.Name.Data)
@@ -64,6 +161,20 @@ func processJuniper(dc *discovery.Client, ID int, interfaces JuniperInterfaces)
 				// assumption... only one ip/ipv6 per unit
 				var ip, ip6 string
 
+				name := iface.Name.Data + "." + unit.Name.Data
+
+				i := Interface{
+					Name: name,
+					Desc: iface.Name.Data + ":" + unit.Name.Data,
+					VLAN: vlans[name], // irb or vlan interface
+					Zone: zones[name],
+				}
+
+				for _, v := range unit.VlanID {
+					i.VLAN = v.Data
+					i.Mode = VLAN_TRUNK
+				}
+
 				for _, family := range unit.Family {
 					for _, inet := range family.Inet {
 						for _, addr := range inet.Address {
@@ -79,6 +190,15 @@ func processJuniper(dc *discovery.Client, ID int, interfaces JuniperInterfaces)
 								ip = addr.Name.Data
 							}
 						}
+
+						for _, filter := range inet.Filter {
+							for _, v := range filter.Input {
+								d.Security.AddAccessGroup(name, v.FilterName.Data, "in")
+							}
+							for _, v := range filter.Output {
+								d.Security.AddAccessGroup(name, v.FilterName.Data, "out")
+							}
+						}
 					}
 
 					for _, inet6 := range family.Inet6 {
@@ -96,98 +216,139 @@ func processJuniper(dc *discovery.Client, ID int, interfaces JuniperInterfaces)
 							}
 						}
 					}
-				}
 
-				desc := iface.Name.Data + ":" + unit.Name.Data
+					for _, es := range family.EthernetSwitching {
+						for _, v := range append(es.InterfaceMode, es.PortMode...) {
+							i.Mode = v.Data
+						}
+
+						var members []string
+						for _, vlan := range es.Vlan {
+							for _, m := range vlan.Members {
+								if id, ok := vlans[m.Data]; ok {
+									members = append(members, id)
+								} else {
+									members = append(members, expandVLANs(m.Data)...)
+								}
+							}
+						}
 
-				if err := AddInterface(dc, ID, desc, ip, ip6); err != nil {
-					return err
+						if i.Mode == VLAN_TRUNK {
+							i.Allowed = members
+						} else if len(members) > 0 {
+							i.Mode = VLAN_ACCESS
+							i.VLAN = members[0]
+						}
+						log.Info("found %v port in vlans: %v", i.Mode, members)
+					}
 				}
+
+				i.IP, i.IP6 = ip, ip6
+
+				d.AddInterface(i)
 			}
 		}
 	}
-
-	return nil
 }
 
-func parseJuniper(f *os.File, dc *discovery.Client) error {
-	scanner := bufio.NewScanner(f)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, `show configuration | display json`) {
-			break
+func processJuniperSystem(d *Device, system JuniperSystem) {
+	for _, v := range system {
+		for _, name := range v.HostName {
+			d.Name = name.Data
 		}
 	}
+}
 
-	// Should be JSON until line starting with comment
-	var data []byte
-	for scanner.Scan() {
-		if strings.HasPrefix(scanner.Text(), "#") {
-			break
+func processJuniperRouting(r *Routing, config JuniperConfig) {
+	for _, opts := range config.RoutingOptions {
+		for _, static := range opts.Static {
+			for _, route := range static.Route {
+				if len(route.NextHop) == 0 {
+					log.Debug("ignoring route without next hop: %v", route.Name.Data)
+					continue
+				}
+
+				r.AddRoute([]string{route.Name.Data, route.NextHop[0].Data})
+			}
 		}
 
-		data = append(data, scanner.Bytes()...)
-		data = append(data, 10) // new line
-	}
+		for _, as := range opts.AutonomousSystem {
+			r.ASN = as.ASNumber.Data
+			log.Debug("got bgp asn: %v", r.ASN)
+		}
 
-	var configs struct {
-		Configuration []JuniperConfig
-	}
-	if err := json.Unmarshal(data, &configs); err != nil {
-		syntax, ok := err.(*json.SyntaxError)
-		if !ok {
-			return err
+		for _, id := range opts.RouterID {
+			r.RouterID = id.Data
 		}
+	}
 
-		js := string(data)
+	for _, protos := range config.Protocols {
+		for _, ospf := range protos.OSPF {
+			for _, area := range ospf.Area {
+				for _, iface := range area.Interface {
+					name := strings.ToLower(iface.Name.Data)
 
-		start, end := strings.LastIndex(js[:syntax.Offset], "\n")+1, len(js)
-		if idx := strings.Index(js[start:], "\n"); idx >= 0 {
-			end = start + idx
+					log.Debug("got ospf area %v on %v", area.Name.Data, name)
+					r.OSPFInterfaces[name] = area.Name.Data
+					if len(iface.Passive) > 0 {
+						r.Passive[name] = true
+					}
+				}
+			}
 		}
 
-		line, pos := strings.Count(js[:start], "\n"), int(syntax.Offset)-start-1
-
-		fmt.Printf("Error in line %d: %s \n", line, err)
-		fmt.Printf("%s\n%s^", js[start:end], strings.Repeat(" ", pos))
+		for _, bgp := range protos.BGP {
+			for _, group := range bgp.Group {
+				for _, neighbor := range group.Neighbor {
+					var asn string
+					for _, v := range append(group.PeerAS, neighbor.PeerAS...) {
+						asn = v.Data
+					}
 
-		return err
+					r.AddNeighbor(neighbor.Name.Data, asn)
+				}
+			}
+		}
 	}
+}
 
-	e := &minigraph.Endpoint{
-		D: map[string]string{
-			"router": "true",
-			"type":   "juniper",
-			"name":   filepath.Base(f.Name()),
-			"icon":   "router",
-		},
+func parseJuniper(f io.Reader) (*Device, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
 	}
 
-	var ID int
-
-	if !*f_dryrun {
-		es, err := dc.InsertEndpoints(e)
-		e = es[0]
-		if err != nil {
-			return err
-		}
+	// convert everything to json with the groups applied
+	data, err := parseJuniperTree(b)
+	if err != nil {
+		return nil, err
+	}
 
-		ID = es[0].ID()
+	var configs struct {
+		Configuration []JuniperConfig
+	}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, err
 	}
 
+	d := NewDevice("juniper")
+
 	for _, config := range configs.Configuration {
-		processJuniper(dc, ID, config.Interfaces)
+		vlans := juniperVlans(config.Vlans)
 
-		// not really how groups work but good enough for now
-		for _, apply := range config.ApplyGroups {
-			for _, group := range config.Groups {
-				if apply.Data == group.Name.Data {
-					processJuniper(dc, ID, group.Interfaces)
-				}
-			}
+		// devices with security zones are SRX firewalls
+		zones := juniperZones(config.Security)
+		if len(zones) > 0 {
+			d.Firewall = true
 		}
+
+		processJuniper(d, config.Interfaces, vlans, zones)
+		processJuniperSystem(d, config.System)
+		processJuniperRouting(d.Routing, config)
+		processJuniperFilters(d.Security, config.Firewall)
+		processJuniperPolicies(d.Security, config)
+		processJuniperNAT(d.Security, config.Security)
 	}
 
-	return nil
+	return d, nil
 }
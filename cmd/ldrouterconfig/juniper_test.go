@@ -0,0 +1,189 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the same config in each of the formats, with a group applied to all the
+// ge interfaces, an inactive interface, and an inactive route
+var juniperConfigs = []struct {
+	name, config string
+}{
+	{"set", `
+user@j1> show configuration | display set
+set version 18.4R1.8
+set groups node0 system host-name j1
+set groups common interfaces <ge-*> unit 0 family inet address 192.0.2.1/24
+set apply-groups node0
+set apply-groups common
+set interfaces ge-0/0/0 description uplink
+set interfaces ge-0/0/0 unit 0 family inet address 10.0.0.1/24
+set interfaces ge-0/0/1 unit 0 family inet address 10.0.1.1/24
+deactivate interfaces ge-0/0/1
+set interfaces ge-0/0/2 vlan-tagging
+set interfaces ge-0/0/2 unit 100 vlan-id 100
+set interfaces ge-0/0/2 unit 100 family inet address 10.1.0.1/24
+set routing-options static route 0.0.0.0/0 next-hop 10.0.0.254
+set routing-options static route 10.2.0.0/16 next-hop 10.0.0.253
+deactivate routing-options static route 10.2.0.0/16
+set protocols ospf area 0.0.0.0 interface ge-0/0/0.0
+`},
+	{"curly", `
+## Last commit: 2019-01-01 00:00:00 UTC by admin
+version 18.4R1.8;
+groups {
+    node0 {
+        system {
+            host-name j1;
+        }
+    }
+    common {
+        interfaces {
+            "<ge-*>" {
+                unit 0 {
+                    family inet {
+                        address 192.0.2.1/24;
+                    }
+                }
+            }
+        }
+    }
+}
+apply-groups [ node0 common ];
+interfaces {
+    ge-0/0/0 {
+        description uplink;
+        unit 0 {
+            family inet {
+                address 10.0.0.1/24;
+            }
+        }
+    }
+    inactive: ge-0/0/1 {
+        unit 0 {
+            family inet {
+                address 10.0.1.1/24;
+            }
+        }
+    }
+    ge-0/0/2 {
+        vlan-tagging;
+        unit 100 {
+            vlan-id 100;
+            family inet {
+                address 10.1.0.1/24;
+            }
+        }
+    }
+}
+routing-options {
+    static {
+        route 0.0.0.0/0 next-hop 10.0.0.254;
+        inactive: route 10.2.0.0/16 next-hop 10.0.0.253;
+    }
+}
+protocols {
+    ospf {
+        area 0.0.0.0 {
+            interface ge-0/0/0.0;
+        }
+    }
+}
+`},
+	{"xml", `
+user@j1> show configuration | display xml
+<rpc-reply xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+    <configuration junos:commit-seconds="1546300800">
+        <version>18.4R1.8</version>
+        <groups>
+            <name>node0</name>
+            <system><host-name>j1</host-name></system>
+        </groups>
+        <groups>
+            <name>common</name>
+            <interfaces>
+                <interface>
+                    <name>&lt;ge-*&gt;</name>
+                    <unit><name>0</name><family><inet><address><name>192.0.2.1/24</name></address></inet></family></unit>
+                </interface>
+            </interfaces>
+        </groups>
+        <apply-groups>node0</apply-groups>
+        <apply-groups>common</apply-groups>
+        <interfaces>
+            <interface>
+                <name>ge-0/0/0</name>
+                <description>uplink</description>
+                <unit><name>0</name><family><inet><address><name>10.0.0.1/24</name></address></inet></family></unit>
+            </interface>
+            <interface inactive="inactive">
+                <name>ge-0/0/1</name>
+                <unit><name>0</name><family><inet><address><name>10.0.1.1/24</name></address></inet></family></unit>
+            </interface>
+            <interface>
+                <name>ge-0/0/2</name>
+                <vlan-tagging/>
+                <unit><name>100</name><vlan-id>100</vlan-id><family><inet><address><name>10.1.0.1/24</name></address></inet></family></unit>
+            </interface>
+        </interfaces>
+        <routing-options>
+            <static>
+                <route><name>0.0.0.0/0</name><next-hop>10.0.0.254</next-hop></route>
+                <route inactive="inactive"><name>10.2.0.0/16</name><next-hop>10.0.0.253</next-hop></route>
+            </static>
+        </routing-options>
+        <protocols>
+            <ospf>
+                <area><name>0.0.0.0</name><interface><name>ge-0/0/0.0</name></interface></area>
+            </ospf>
+        </protocols>
+    </configuration>
+</rpc-reply>
+`},
+}
+
+func TestParseJuniper(t *testing.T) {
+	want := []Interface{
+		{Name: "ge-0/0/0.0", Desc: "ge-0/0/0:0", IP: "10.0.0.1/24"},
+		{Name: "ge-0/0/2.100", Desc: "ge-0/0/2:100", IP: "10.1.0.1/24", VLAN: "100", Mode: VLAN_TRUNK},
+		{Name: "ge-0/0/2.0", Desc: "ge-0/0/2:0", IP: "192.0.2.1/24"},
+	}
+
+	for _, test := range juniperConfigs {
+		if got := detectType([]byte(test.config)); got != "juniper" {
+			t.Errorf("%v: detected %v", test.name, got)
+		}
+
+		d, err := parseJuniper(strings.NewReader(test.config))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if d.Name != "j1" {
+			t.Errorf("%v: got name %q, want j1", test.name, d.Name)
+		}
+
+		if !reflect.DeepEqual(d.Interfaces, want) {
+			t.Errorf("%v: got interfaces\n%+v\nwant\n%+v", test.name, d.Interfaces, want)
+		}
+
+		var routes []string
+		for _, r := range d.Routing.Routes {
+			routes = append(routes, r.Dst.String()+" via "+r.Via.String())
+		}
+		if want := []string{"0.0.0.0/0 via 10.0.0.254"}; !reflect.DeepEqual(routes, want) {
+			t.Errorf("%v: got routes %v, want %v", test.name, routes, want)
+		}
+
+		if area := d.Routing.OSPFInterfaces["ge-0/0/0.0"]; area != "0.0.0.0" {
+			t.Errorf("%v: got ospf area %q, want 0.0.0.0", test.name, area)
+		}
+	}
+}
@@ -0,0 +1,730 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+type JuniperFilter struct {
+	Name Data
+	Term []struct {
+		Name Data
+		From []struct {
+			SourceAddress      []JuniperFilterAddress `json:"source-address"`
+			DestinationAddress []JuniperFilterAddress `json:"destination-address"`
+			Protocol           []Data
+			SourcePort         []Data `json:"source-port"`
+			DestinationPort    []Data `json:"destination-port"`
+		}
+		Then []struct {
+			Accept  []interface{}
+			Discard []interface{}
+			Reject  []interface{}
+		}
+	}
+}
+
+type JuniperFilterAddress struct {
+	Name   Data
+	Except []interface{} // may be omitted
+}
+
+type JuniperFirewall []struct {
+	Family []struct {
+		Inet []struct {
+			Filter []JuniperFilter
+		}
+	}
+	Filter []JuniperFilter // older releases
+}
+
+type JuniperAddressBook struct {
+	Name    Data
+	Address []struct {
+		Name     Data
+		IPPrefix Data `json:"ip-prefix"`
+	}
+	AddressSet []struct {
+		Name       Data
+		Address    []struct{ Name Data }
+		AddressSet []struct{ Name Data } `json:"address-set"`
+	} `json:"address-set"`
+}
+
+type JuniperPolicy struct {
+	Name  Data
+	Match []struct {
+		SourceAddress      []Data `json:"source-address"`
+		DestinationAddress []Data `json:"destination-address"`
+		Application        []Data
+		FromZone           []Data `json:"from-zone"` // global policies
+		ToZone             []Data `json:"to-zone"`
+	}
+	Then []struct {
+		Permit []interface{}
+		Deny   []interface{}
+		Reject []interface{}
+	}
+}
+
+type JuniperNATMatch struct {
+	SourceAddress      []Data `json:"source-address"`
+	DestinationAddress []Data `json:"destination-address"`
+	DestinationPort    []Data `json:"destination-port"`
+	Protocol           []Data
+}
+
+type JuniperNATPoolName []struct {
+	PoolName Data `json:"pool-name"`
+}
+
+type JuniperNATPool struct {
+	Name    Data
+	Address []struct {
+		Name   Data   // source pools
+		IPAddr Data   `json:"ipaddr"`
+		Port   []Data // destination pools
+		To     []struct {
+			IPAddr Data `json:"ipaddr"`
+		}
+	}
+}
+
+type JuniperNATRuleSet struct {
+	Name     Data
+	From, To []struct {
+		Zone      []Data
+		Interface []Data
+	}
+	Rule []struct {
+		Name        Data
+		SrcMatch    []JuniperNATMatch `json:"src-nat-rule-match"`
+		DestMatch   []JuniperNATMatch `json:"dest-nat-rule-match"`
+		StaticMatch []JuniperNATMatch `json:"static-nat-rule-match"`
+		Then        []struct {
+			SourceNAT []struct {
+				Interface []interface{}
+				Off       []interface{}
+				Pool      JuniperNATPoolName
+			} `json:"source-nat"`
+			DestinationNAT []struct {
+				Off  []interface{}
+				Pool JuniperNATPoolName
+			} `json:"destination-nat"`
+			StaticNAT []struct {
+				Prefix []struct {
+					AddrPrefix Data `json:"addr-prefix"`
+				}
+			} `json:"static-nat"`
+		}
+	}
+}
+
+type JuniperNAT []struct {
+	Pool    []JuniperNATPool
+	RuleSet []JuniperNATRuleSet `json:"rule-set"`
+}
+
+type JuniperSecurity []struct {
+	AddressBook []JuniperAddressBook `json:"address-book"`
+	Zones       []struct {
+		SecurityZone []struct {
+			Name        Data
+			Interfaces  []struct{ Name Data }
+			AddressBook []JuniperAddressBook `json:"address-book"`
+		} `json:"security-zone"`
+	}
+	Policies []struct {
+		Policy []struct {
+			FromZoneName Data `json:"from-zone-name"`
+			ToZoneName   Data `json:"to-zone-name"`
+			Policy       []JuniperPolicy
+		}
+		Global []struct {
+			Policy []JuniperPolicy
+		}
+	}
+	NAT []struct {
+		Source      JuniperNAT
+		Destination JuniperNAT
+		Static      JuniperNAT
+	}
+}
+
+type JuniperApplications []struct {
+	Application []struct {
+		Name            Data
+		Protocol        []Data
+		DestinationPort []Data `json:"destination-port"`
+		Term            []struct {
+			Name            Data
+			Protocol        []Data
+			DestinationPort []Data `json:"destination-port"`
+		}
+	}
+	ApplicationSet []struct {
+		Name           Data
+		Application    []struct{ Name Data }
+		ApplicationSet []struct{ Name Data } `json:"application-set"`
+	} `json:"application-set"`
+}
+
+// juniperApplication is the protocol and destination port of an application
+type juniperApplication struct {
+	Protocol, Port string
+}
+
+// juniperApplications are the predefined applications that are commonly used
+// in policies
+var juniperApplications = map[string][]juniperApplication{
+	"any":               {{}},
+	"junos-bgp":         {{"tcp", "179"}},
+	"junos-dns-tcp":     {{"tcp", "53"}},
+	"junos-dns-udp":     {{"udp", "53"}},
+	"junos-ftp":         {{"tcp", "21"}},
+	"junos-http":        {{"tcp", "80"}},
+	"junos-https":       {{"tcp", "443"}},
+	"junos-icmp-all":    {{"icmp", ""}},
+	"junos-ntp":         {{"udp", "123"}},
+	"junos-ping":        {{"icmp", ""}},
+	"junos-smtp":        {{"tcp", "25"}},
+	"junos-snmp-agentx": {{"tcp", "705"}},
+	"junos-ssh":         {{"tcp", "22"}},
+	"junos-syslog":      {{"udp", "514"}},
+	"junos-telnet":      {{"tcp", "23"}},
+	"junos-tftp":        {{"udp", "69"}},
+}
+
+// juniperPort converts a port or range, which may use names, to the format
+// in Rules
+func juniperPort(s string) (string, bool) {
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		if v, ok := parsePort(s); ok {
+			return strconv.Itoa(v), true
+		}
+		return "", false
+	}
+
+	v, ok := parsePort(lo)
+	v2, ok2 := parsePort(hi)
+	if !ok || !ok2 {
+		return "", false
+	}
+
+	return strconv.Itoa(v) + ":" + strconv.Itoa(v2), true
+}
+
+// values returns the data of each element or a single empty string, which
+// matches anything, if there aren't any
+func values(ds []Data) []string {
+	if len(ds) == 0 {
+		return []string{""}
+	}
+
+	var res []string
+	for _, v := range ds {
+		res = append(res, v.Data)
+	}
+
+	return res
+}
+
+// juniperZones maps the interfaces in security zones to the zones
+func juniperZones(security JuniperSecurity) map[string]string {
+	res := map[string]string{}
+
+	for _, sec := range security {
+		for _, zones := range sec.Zones {
+			for _, zone := range zones.SecurityZone {
+				for _, iface := range zone.Interfaces {
+					name := iface.Name.Data
+					if !strings.Contains(name, ".") {
+						// unit 0 is implied
+						name += ".0"
+					}
+
+					res[name] = zone.Name.Data
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func processJuniperFilters(s *Security, firewall JuniperFirewall) {
+	var filters []JuniperFilter
+	for _, fw := range firewall {
+		for _, family := range fw.Family {
+			for _, inet := range family.Inet {
+				filters = append(filters, inet.Filter...)
+			}
+		}
+
+		filters = append(filters, fw.Filter...)
+	}
+
+	for _, filter := range filters {
+		name := filter.Name.Data
+
+		for _, term := range filter.Term {
+			action := ACTION_PERMIT
+			for _, then := range term.Then {
+				if len(then.Discard) > 0 || len(then.Reject) > 0 {
+					action = ACTION_DENY
+				}
+			}
+
+			if len(term.From) == 0 {
+				s.ACLs[name] = append(s.ACLs[name], Rule{Action: action})
+				continue
+			}
+
+			for _, from := range term.From {
+				addresses := func(addrs []JuniperFilterAddress) []string {
+					if len(addrs) == 0 {
+						return []string{""}
+					}
+
+					var res []string
+					for _, v := range addrs {
+						if len(v.Except) > 0 {
+							log.Warn("ignoring except %v in filter %v term %v", v.Name.Data, name, term.Name.Data)
+							continue
+						}
+						res = append(res, v.Name.Data)
+					}
+					return res
+				}
+
+				rules := juniperRules(
+					Rule{Action: action},
+					addresses(from.SourceAddress),
+					addresses(from.DestinationAddress),
+					values(from.Protocol),
+					values(from.SourcePort),
+					values(from.DestinationPort),
+				)
+
+				log.Debug("got %v rules for filter %v term %v", len(rules), name, term.Name.Data)
+				s.ACLs[name] = append(s.ACLs[name], rules...)
+			}
+		}
+	}
+}
+
+// juniperRules returns a rule for each combination of the values, which may
+// be empty to match anything. Ports are converted from names.
+func juniperRules(r Rule, srcs, dsts, protos, sports, dports []string) []Rule {
+	var res []Rule
+
+	for _, src := range srcs {
+		for _, dst := range dsts {
+			for _, proto := range protos {
+				for _, sport := range sports {
+					for _, dport := range dports {
+						r.Src, r.Dst, r.Protocol = src, dst, proto
+						r.SrcPort, r.DstPort = "", ""
+
+						var ok bool
+						if sport != "" {
+							if r.SrcPort, ok = juniperPort(sport); !ok {
+								log.Warn("invalid port: %v", sport)
+								continue
+							}
+						}
+						if dport != "" {
+							if r.DstPort, ok = juniperPort(dport); !ok {
+								log.Warn("invalid port: %v", dport)
+								continue
+							}
+						}
+
+						res = append(res, r)
+					}
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// juniperAddressBook resolves names of addresses and address sets to the
+// prefixes, any matches anything
+type juniperAddressBook struct {
+	addresses map[string]string
+	sets      map[string][]string
+}
+
+func (b *juniperAddressBook) add(book JuniperAddressBook) {
+	for _, v := range book.Address {
+		b.addresses[v.Name.Data] = v.IPPrefix.Data
+	}
+
+	for _, v := range book.AddressSet {
+		for _, a := range append(v.Address, v.AddressSet...) {
+			b.sets[v.Name.Data] = append(b.sets[v.Name.Data], a.Name.Data)
+		}
+	}
+}
+
+func (b *juniperAddressBook) resolve(names []string) []string {
+	var res []string
+
+	for _, name := range names {
+		switch name {
+		case "", "any", "any-ipv4", "any-ipv6":
+			return []string{""}
+		}
+
+		if v, ok := b.addresses[name]; ok {
+			res = append(res, v)
+		} else if v, ok := b.sets[name]; ok {
+			delete(b.sets, name) // avoid loops
+			res = append(res, b.resolve(v)...)
+			b.sets[name] = v
+		} else if _, _, err := net.ParseCIDR(name); err == nil {
+			res = append(res, name)
+		} else {
+			log.Warn("unknown address: %v", name)
+		}
+	}
+
+	return res
+}
+
+// juniperApps resolves names of applications and application sets
+func juniperApps(config JuniperConfig) map[string][]juniperApplication {
+	res := map[string][]juniperApplication{}
+	for k, v := range juniperApplications {
+		res[k] = v
+	}
+
+	sets := map[string][]string{}
+
+	for _, apps := range config.Applications {
+		for _, app := range apps.Application {
+			var vs []juniperApplication
+
+			terms := []struct{ Protocol, DestinationPort []Data }{
+				{app.Protocol, app.DestinationPort},
+			}
+			for _, term := range app.Term {
+				terms = append(terms, struct{ Protocol, DestinationPort []Data }{term.Protocol, term.DestinationPort})
+			}
+
+			for _, term := range terms {
+				if len(term.Protocol) == 0 {
+					continue
+				}
+
+				v := juniperApplication{Protocol: term.Protocol[0].Data}
+				if len(term.DestinationPort) > 0 {
+					port, ok := juniperPort(term.DestinationPort[0].Data)
+					if !ok {
+						log.Warn("invalid port in application %v", app.Name.Data)
+						continue
+					}
+					v.Port = port
+				}
+
+				vs = append(vs, v)
+			}
+
+			res[app.Name.Data] = vs
+		}
+
+		for _, set := range apps.ApplicationSet {
+			for _, v := range append(set.Application, set.ApplicationSet...) {
+				sets[set.Name.Data] = append(sets[set.Name.Data], v.Name.Data)
+			}
+		}
+	}
+
+	// resolve sets, which may contain other sets
+	var resolve func(name string, seen map[string]bool) []juniperApplication
+	resolve = func(name string, seen map[string]bool) []juniperApplication {
+		if v, ok := res[name]; ok {
+			return v
+		}
+
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+
+		if _, ok := sets[name]; !ok {
+			log.Warn("unknown application: %v", name)
+			return nil
+		}
+
+		var vs []juniperApplication
+		for _, v := range sets[name] {
+			vs = append(vs, resolve(v, seen)...)
+		}
+		return vs
+	}
+
+	for name := range sets {
+		res[name] = resolve(name, map[string]bool{})
+	}
+
+	return res
+}
+
+func processJuniperPolicies(s *Security, config JuniperConfig) {
+	book := &juniperAddressBook{
+		addresses: map[string]string{},
+		sets:      map[string][]string{},
+	}
+
+	for _, sec := range config.Security {
+		for _, v := range sec.AddressBook {
+			book.add(v)
+		}
+
+		for _, zones := range sec.Zones {
+			for _, zone := range zones.SecurityZone {
+				for _, v := range zone.AddressBook {
+					book.add(v)
+				}
+			}
+		}
+	}
+
+	apps := juniperApps(config)
+
+	addPolicy := func(from, to string, policy JuniperPolicy) {
+		action := ACTION_PERMIT
+		for _, then := range policy.Then {
+			if len(then.Deny) > 0 || len(then.Reject) > 0 {
+				action = ACTION_DENY
+			}
+		}
+
+		for _, match := range policy.Match {
+			froms, tos := []string{from}, []string{to}
+			if from == "" {
+				froms, tos = values(match.FromZone), values(match.ToZone)
+			}
+
+			var matched []juniperApplication
+			for _, app := range values(match.Application) {
+				if v, ok := apps[app]; ok {
+					matched = append(matched, v...)
+				} else if app == "" {
+					matched = append(matched, juniperApplication{})
+				} else {
+					log.Warn("unknown application %v in policy %v", app, policy.Name.Data)
+				}
+			}
+
+			for _, from := range froms {
+				for _, to := range tos {
+					for _, app := range matched {
+						r := Rule{Action: action, FromZone: from, ToZone: to}
+						if from == "any" {
+							r.FromZone = ""
+						}
+						if to == "any" {
+							r.ToZone = ""
+						}
+
+						rules := juniperRules(
+							r,
+							book.resolve(values(match.SourceAddress)),
+							book.resolve(values(match.DestinationAddress)),
+							[]string{app.Protocol},
+							[]string{""},
+							[]string{app.Port},
+						)
+
+						log.Debug("got %v rules for policy %v", len(rules), policy.Name.Data)
+						s.Policies = append(s.Policies, rules...)
+					}
+				}
+			}
+		}
+	}
+
+	for _, sec := range config.Security {
+		for _, policies := range sec.Policies {
+			for _, pair := range policies.Policy {
+				for _, policy := range pair.Policy {
+					addPolicy(pair.FromZoneName.Data, pair.ToZoneName.Data, policy)
+				}
+			}
+
+			// global policies apply after the zone policies
+			for _, global := range policies.Global {
+				for _, policy := range global.Policy {
+					addPolicy("", "", policy)
+				}
+			}
+		}
+	}
+}
+
+func processJuniperNAT(s *Security, security JuniperSecurity) {
+	for _, sec := range security {
+		for _, nat := range sec.NAT {
+			for _, v := range nat.Source {
+				processJuniperNATRules(s, NAT_SOURCE, v.Pool, v.RuleSet)
+			}
+			for _, v := range nat.Destination {
+				processJuniperNATRules(s, NAT_DESTINATION, v.Pool, v.RuleSet)
+			}
+			for _, v := range nat.Static {
+				processJuniperNATRules(s, NAT_STATIC, v.Pool, v.RuleSet)
+			}
+		}
+	}
+}
+
+// processJuniperNATRules adds the rules from the rule sets of one type, pools
+// are only used for source and destination NAT
+func processJuniperNATRules(s *Security, typ string, pools []JuniperNATPool, ruleSets []JuniperNATRuleSet) {
+	// addresses and ports of the pools
+	addrs, ports := map[string]string{}, map[string]string{}
+
+	for _, pool := range pools {
+		for _, v := range pool.Address {
+			switch {
+			case v.Name.Data != "" && len(v.To) > 0:
+				lo, _, _ := net.ParseCIDR(v.Name.Data)
+				hi, _, _ := net.ParseCIDR(v.To[0].IPAddr.Data)
+				if lo != nil && hi != nil {
+					addrs[pool.Name.Data] = lo.String() + "-" + hi.String()
+				}
+			case v.Name.Data != "":
+				addrs[pool.Name.Data] = addressRange(v.Name.Data)
+			case v.IPAddr.Data != "":
+				addrs[pool.Name.Data] = addressRange(v.IPAddr.Data)
+			}
+
+			for _, port := range v.Port {
+				ports[pool.Name.Data] = port.Data
+			}
+		}
+	}
+
+	pool := func(names JuniperNATPoolName) (string, string, bool) {
+		for _, v := range names {
+			addr, ok := addrs[v.PoolName.Data]
+			if !ok {
+				log.Warn("undefined nat pool: %v", v.PoolName.Data)
+			}
+			return addr, ports[v.PoolName.Data], ok
+		}
+
+		// no pool, translate to the interface
+		return "", "", true
+	}
+
+	for _, rs := range ruleSets {
+		// NAT with the zones or interfaces that the rule set applies to,
+		// outgoing for source NAT and incoming otherwise
+		var nats []NAT
+		for _, v := range rs.From {
+			if typ == NAT_SOURCE {
+				continue
+			}
+
+			for _, zone := range v.Zone {
+				nats = append(nats, NAT{Type: typ, FromZone: zone.Data})
+			}
+			for _, iface := range v.Interface {
+				nats = append(nats, NAT{Type: typ, Interface: iface.Data})
+			}
+		}
+		for _, v := range rs.To {
+			if typ != NAT_SOURCE {
+				continue
+			}
+
+			for _, zone := range v.Zone {
+				nats = append(nats, NAT{Type: typ, ToZone: zone.Data})
+			}
+			for _, iface := range v.Interface {
+				nats = append(nats, NAT{Type: typ, Interface: iface.Data})
+			}
+		}
+		if len(nats) == 0 {
+			nats = append(nats, NAT{Type: typ})
+		}
+
+		for _, rule := range rs.Rule {
+			var off, ok bool
+			var to, toPort string
+
+			for _, then := range rule.Then {
+				for _, v := range then.SourceNAT {
+					off = off || len(v.Off) > 0
+					to, toPort, ok = pool(v.Pool)
+				}
+				for _, v := range then.DestinationNAT {
+					off = off || len(v.Off) > 0
+					to, toPort, ok = pool(v.Pool)
+				}
+				for _, v := range then.StaticNAT {
+					for _, prefix := range v.Prefix {
+						to, ok = prefix.AddrPrefix.Data, true
+					}
+				}
+			}
+
+			if off {
+				log.Debug("ignoring nat rule %v that turns off nat", rule.Name.Data)
+				continue
+			} else if !ok {
+				log.Warn("ignoring nat rule %v without translation", rule.Name.Data)
+				continue
+			}
+
+			matches := append(append(rule.SrcMatch, rule.DestMatch...), rule.StaticMatch...)
+			for _, match := range matches {
+				for _, n := range nats {
+					for _, src := range values(match.SourceAddress) {
+						for _, dst := range values(match.DestinationAddress) {
+							n.Src, n.Dst, n.To, n.ToPort = src, dst, to, toPort
+
+							if typ == NAT_STATIC {
+								// the destination is the outside prefix and the
+								// translation is the inside prefix
+								n.Src, n.Dst, n.To = to, "", dst
+							}
+
+							protos := values(match.Protocol)
+							if len(match.DestinationPort) > 0 && len(match.Protocol) == 0 {
+								protos = []string{"tcp", "udp"}
+							}
+
+							for _, proto := range protos {
+								for _, port := range values(match.DestinationPort) {
+									n.Protocol, n.DstPort = proto, port
+									if port != "" {
+										if n.DstPort, ok = juniperPort(port); !ok {
+											log.Warn("invalid port in nat rule %v: %v", rule.Name.Data, port)
+											continue
+										}
+									}
+
+									s.AddNAT(n)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
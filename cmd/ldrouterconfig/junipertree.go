@@ -0,0 +1,1108 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// juniperNode is a statement in the configuration hierarchy. Configs in all
+// the formats (display json, display xml, display set, and the default) are
+// parsed into a tree of juniperNodes so that groups can be applied the same
+// way for all of them before the tree is converted to a JuniperConfig.
+type juniperNode struct {
+	// Data is the value of leaves
+	Data    string
+	HasData bool
+
+	// Inactive statements are ignored
+	Inactive bool
+
+	// keys of the children in the order they were added
+	keys     []string
+	children map[string][]*juniperNode
+}
+
+func newJuniperNode() *juniperNode {
+	return &juniperNode{children: map[string][]*juniperNode{}}
+}
+
+func newJuniperLeaf(v string) *juniperNode {
+	n := newJuniperNode()
+	n.Data, n.HasData = v, true
+	return n
+}
+
+func (n *juniperNode) add(k string, c ...*juniperNode) {
+	if _, ok := n.children[k]; !ok {
+		n.keys = append(n.keys, k)
+	}
+
+	n.children[k] = append(n.children[k], c...)
+}
+
+// replace replaces the children with the given key
+func (n *juniperNode) replace(k string, c ...*juniperNode) {
+	if _, ok := n.children[k]; !ok {
+		n.keys = append(n.keys, k)
+	}
+
+	n.children[k] = c
+}
+
+// name returns the value of the name child, which identifies the elements of
+// lists such as interfaces and units. Zone pairs in security policies are
+// identified by the from and to zones instead.
+func (n *juniperNode) name() string {
+	for _, c := range n.children["name"] {
+		return c.Data
+	}
+
+	from, to := n.children["from-zone-name"], n.children["to-zone-name"]
+	if len(from) > 0 && len(to) > 0 {
+		return from[0].Data + " " + to[0].Data
+	}
+
+	return ""
+}
+
+// container returns the child container with the given key, creating it if
+// necessary
+func (n *juniperNode) container(k string) *juniperNode {
+	if cs := n.children[k]; len(cs) > 0 {
+		return cs[0]
+	}
+
+	c := newJuniperNode()
+	n.add(k, c)
+	return c
+}
+
+// element returns the list element with the given key and name, creating it
+// if necessary
+func (n *juniperNode) element(k, name string) *juniperNode {
+	for _, c := range n.children[k] {
+		if c.name() == name {
+			return c
+		}
+	}
+
+	c := newJuniperNode()
+	c.add("name", newJuniperLeaf(name))
+	n.add(k, c)
+	return c
+}
+
+func (n *juniperNode) copy() *juniperNode {
+	res := &juniperNode{
+		Data:     n.Data,
+		HasData:  n.HasData,
+		Inactive: n.Inactive,
+		children: map[string][]*juniperNode{},
+	}
+
+	for _, k := range n.keys {
+		for _, c := range n.children[k] {
+			res.add(k, c.copy())
+		}
+	}
+
+	return res
+}
+
+// juniperIdentifiers are the leaves that display json doesn't wrap in an array,
+// such as the names of list elements
+var juniperIdentifiers = map[string]bool{
+	"name":           true,
+	"as-number":      true,
+	"filter-name":    true,
+	"ip-prefix":      true,
+	"from-zone-name": true,
+	"to-zone-name":   true,
+	"pool-name":      true,
+	"ipaddr":         true,
+	"addr-prefix":    true,
+}
+
+// value converts the node to the display json representation: containers
+// and list elements are objects, leaves are {"data": value} and flags are
+// null. Everything but the identifiers is wrapped in an array.
+func (n *juniperNode) value() interface{} {
+	if len(n.keys) == 0 {
+		if n.HasData {
+			return map[string]string{"data": n.Data}
+		}
+
+		return nil
+	}
+
+	res := map[string]interface{}{}
+
+	for _, k := range n.keys {
+		var vs []interface{}
+		for _, c := range n.children[k] {
+			if !c.Inactive {
+				vs = append(vs, c.value())
+			}
+		}
+
+		if len(vs) == 0 {
+			continue
+		}
+
+		if juniperIdentifiers[k] {
+			res[k] = vs[0]
+		} else {
+			res[k] = vs
+		}
+	}
+
+	return res
+}
+
+// juniperMatch tests whether the name of a list element in a group, which may
+// be a wildcard such as <ge-*>, matches the name of a list element in the
+// config. Unlike path.Match, * also matches slashes.
+func juniperMatch(pattern, name string) bool {
+	if !strings.HasPrefix(pattern, "<") || !strings.HasSuffix(pattern, ">") {
+		return pattern == name
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	var class bool
+	for _, r := range pattern[1 : len(pattern)-1] {
+		switch {
+		case class:
+			// character class, e.g. [0-3], copied as is
+			expr.WriteRune(r)
+			class = r != ']'
+		case r == '[':
+			expr.WriteRune(r)
+			class = true
+		case r == '*':
+			expr.WriteString(".*")
+		case r == '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		log.Warn("invalid wildcard %v: %v", pattern, err)
+		return false
+	}
+
+	return re.MatchString(name)
+}
+
+// inherit adds the statements from the group that are not already in the
+// node. Wildcards in the group only apply to existing list elements.
+func (n *juniperNode) inherit(g *juniperNode) {
+	for _, k := range g.keys {
+		if k == "name" || k == "apply-groups" {
+			continue
+		}
+
+		for _, src := range g.children[k] {
+			if src.Inactive {
+				continue
+			}
+
+			switch {
+			case src.name() != "":
+				name := src.name()
+
+				var matched bool
+				for _, dst := range n.children[k] {
+					if juniperMatch(name, dst.name()) {
+						dst.inherit(src)
+						matched = true
+					}
+				}
+
+				if !matched && !strings.HasPrefix(name, "<") {
+					n.add(k, src.copy())
+				}
+			case len(src.keys) > 0:
+				// container
+				if len(n.children[k]) > 0 {
+					n.children[k][0].inherit(src)
+				} else {
+					n.add(k, src.copy())
+				}
+			case len(n.children[k]) == 0:
+				// leaf or flag that isn't set in the config, copy all the
+				// values in case it's a list
+				for _, v := range g.children[k] {
+					n.add(k, v.copy())
+				}
+			}
+		}
+	}
+}
+
+// juniperStep is a step in the path from the root of the config to a node,
+// name is set for list elements
+type juniperStep struct {
+	Key, Name string
+}
+
+// find returns the nodes at the path, which may be more than one when list
+// elements in groups have wildcards
+func (n *juniperNode) find(steps []juniperStep) []*juniperNode {
+	if len(steps) == 0 {
+		return []*juniperNode{n}
+	}
+
+	var res []*juniperNode
+
+	for _, c := range n.children[steps[0].Key] {
+		if c.Inactive {
+			continue
+		}
+
+		if steps[0].Name == "" || juniperMatch(c.name(), steps[0].Name) {
+			res = append(res, c.find(steps[1:])...)
+		}
+	}
+
+	return res
+}
+
+// applyGroups applies the groups named by the apply-groups statements at each
+// level of the config. Groups are applied in order, so the first group that
+// sets a statement wins, and they never override the config itself.
+func (n *juniperNode) applyGroups(groups map[string]*juniperNode, steps []juniperStep) {
+	for _, apply := range n.children["apply-groups"] {
+		names := []string{apply.Data}
+		if apply.Data == "${node}" {
+			// routing engine or cluster node specific groups, assume
+			// we are the first one
+			names = []string{"re0", "node0"}
+		}
+
+		for _, name := range names {
+			g, ok := groups[name]
+			if !ok {
+				if apply.Data != "${node}" {
+					log.Warn("apply-groups: no such group %v", name)
+				}
+				continue
+			}
+
+			log.Debug("applying group %v", name)
+			for _, src := range g.find(steps) {
+				n.inherit(src)
+			}
+		}
+	}
+
+	for _, k := range n.keys {
+		if k == "groups" && len(steps) == 0 {
+			continue
+		}
+
+		for _, c := range n.children[k] {
+			if len(c.keys) > 0 {
+				c.applyGroups(groups, append(steps[:len(steps):len(steps)], juniperStep{k, c.name()}))
+			}
+		}
+	}
+}
+
+var (
+	juniperJSON = regexp.MustCompile(`"configuration"\s*:`)
+
+	// statements in show configuration, e.g. interfaces {
+	juniperCurly = regexp.MustCompile(`(?m)^\s*(inactive: )?[\w-]+( \S+)? \{\s*$`)
+	juniperSet   = regexp.MustCompile(`(?m)^(set|deactivate) \S`)
+)
+
+// parseJuniperTree parses the configs in any of the formats and applies the
+// groups. Returns the configs in the display json representation.
+func parseJuniperTree(b []byte) ([]byte, error) {
+	var roots []*juniperNode
+	var err error
+
+	switch {
+	case juniperJSON.Match(b):
+		log.Debug("parsing juniper json config")
+		roots, err = parseJuniperJSON(b)
+	case bytes.Contains(b, []byte("<configuration")):
+		log.Debug("parsing juniper xml config")
+		roots, err = parseJuniperXML(b)
+	case juniperCurly.Match(b) && !juniperSet.Match(b):
+		log.Debug("parsing juniper curly config")
+		roots, err = parseJuniperCurly(b)
+	default:
+		log.Debug("parsing juniper set config")
+		roots, err = parseJuniperSet(b)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []interface{}
+
+	for _, root := range roots {
+		groups := map[string]*juniperNode{}
+		for _, g := range root.children["groups"] {
+			if !g.Inactive {
+				groups[g.name()] = g
+			}
+		}
+
+		root.applyGroups(groups, nil)
+
+		configs = append(configs, root.value())
+	}
+
+	return json.Marshal(map[string]interface{}{"configuration": configs})
+}
+
+// parseJuniperJSON parses configs from show configuration | display json,
+// both the older format where values are wrapped in {"data": value} and the
+// newer format where they aren't
+func parseJuniperJSON(b []byte) ([]*juniperNode, error) {
+	// skip anything before the JSON such as the command and prompt
+	loc := juniperJSON.FindIndex(b)
+	if loc == nil {
+		return nil, errors.New("no json configuration found")
+	}
+
+	i := bytes.LastIndexByte(b[:loc[0]], '{')
+	if i < 0 {
+		return nil, errors.New("no json configuration found")
+	}
+	data := b[i:]
+
+	var v struct {
+		Configuration interface{}
+	}
+
+	// only decode the first value in case there's anything after it
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		syntax, ok := err.(*json.SyntaxError)
+		if !ok {
+			return nil, err
+		}
+
+		js := string(data)
+
+		start, end := strings.LastIndex(js[:syntax.Offset], "\n")+1, len(js)
+		if idx := strings.Index(js[start:], "\n"); idx >= 0 {
+			end = start + idx
+		}
+
+		line, pos := strings.Count(js[:start], "\n"), int(syntax.Offset)-start-1
+
+		return nil, fmt.Errorf("error in line %d: %v\n%s\n%s^", line, err, js[start:end], strings.Repeat(" ", pos))
+	}
+
+	if v.Configuration == nil {
+		return nil, errors.New("no json configuration found")
+	}
+
+	return juniperFromJSON(v.Configuration), nil
+}
+
+func juniperFromJSON(v interface{}) []*juniperNode {
+	switch v := v.(type) {
+	case []interface{}:
+		var res []*juniperNode
+		for _, v := range v {
+			res = append(res, juniperFromJSON(v)...)
+		}
+		return res
+	case map[string]interface{}:
+		n := newJuniperNode()
+
+		// attributes such as inactive are in "@"
+		if attrs, ok := v["@"].(map[string]interface{}); ok {
+			n.Inactive = attrs["inactive"] == true
+		}
+
+		if data, ok := v["data"]; ok {
+			n.Data, n.HasData = fmt.Sprint(data), true
+			return []*juniperNode{n}
+		}
+
+		// sort so the order is consistent
+		var keys []string
+		for k := range v {
+			if !strings.HasPrefix(k, "@") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			n.add(k, juniperFromJSON(v[k])...)
+		}
+
+		return []*juniperNode{n}
+	case nil:
+		// flag
+		return []*juniperNode{newJuniperNode()}
+	default:
+		return []*juniperNode{newJuniperLeaf(fmt.Sprint(v))}
+	}
+}
+
+// parseJuniperXML parses configs from show configuration | display xml
+func parseJuniperXML(b []byte) ([]*juniperNode, error) {
+	// skip anything before the XML such as the command
+	if i := bytes.IndexByte(b, '<'); i > 0 {
+		b = b[i:]
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.Strict = false
+
+	var roots []*juniperNode
+
+	// stack of nodes, empty until we find a configuration
+	var stack []*juniperNode
+	var text bytes.Buffer
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if len(roots) > 0 && len(stack) == 0 {
+				// probably junk after the XML
+				log.Debug("ignoring error after configuration: %v", err)
+				break
+			}
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 && tok.Name.Local != "configuration" {
+				continue
+			}
+
+			n := newJuniperNode()
+			for _, attr := range tok.Attr {
+				if attr.Name.Local == "inactive" {
+					n.Inactive = true
+				}
+			}
+
+			if len(stack) > 0 {
+				stack[len(stack)-1].add(tok.Name.Local, n)
+			} else {
+				roots = append(roots, n)
+			}
+
+			stack = append(stack, n)
+			text.Reset()
+		case xml.CharData:
+			if len(stack) > 0 {
+				text.Write(tok)
+			}
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if v := strings.TrimSpace(text.String()); len(n.keys) == 0 && v != "" {
+				n.Data, n.HasData = v, true
+			}
+			text.Reset()
+		}
+	}
+
+	if len(roots) == 0 {
+		return nil, errors.New("no xml configuration found")
+	}
+
+	return roots, nil
+}
+
+// Kinds of statements in juniperSchema
+const (
+	JUNIPER_CONTAINER = iota
+	JUNIPER_LIST
+	JUNIPER_LEAF
+	JUNIPER_LEAF_LIST
+	JUNIPER_FLAG
+)
+
+// juniperSchema describes the statements that we parse from set configs,
+// which unlike the json and xml formats don't say which words are keywords
+// and which are values.
+type juniperSchema struct {
+	Kind int
+
+	// Default is the child to use when the word isn't a keyword, for
+	// example interfaces ge-0/0/0 is short for interfaces interface ge-0/0/0
+	Default string
+
+	// Key is the name of the statement in the json and xml formats when it
+	// differs from the set format, such as match in NAT rules
+	Key string
+
+	Children map[string]*juniperSchema
+}
+
+var juniperConfigSchema = func() *juniperSchema {
+	leaf := &juniperSchema{Kind: JUNIPER_LEAF}
+	leafList := &juniperSchema{Kind: JUNIPER_LEAF_LIST}
+	flag := &juniperSchema{Kind: JUNIPER_FLAG}
+
+	container := func(children map[string]*juniperSchema) *juniperSchema {
+		return &juniperSchema{Kind: JUNIPER_CONTAINER, Children: children}
+	}
+	list := func(children map[string]*juniperSchema) *juniperSchema {
+		return &juniperSchema{Kind: JUNIPER_LIST, Children: children}
+	}
+
+	address := list(map[string]*juniperSchema{
+		"preferred": flag,
+		"primary":   flag,
+	})
+
+	// withDefault returns a copy of the schema with the given default
+	withDefault := func(s *juniperSchema, k string) *juniperSchema {
+		res := *s
+		res.Default = k
+		return &res
+	}
+
+	// firewall filter input F
+	filterName := withDefault(container(map[string]*juniperSchema{
+		"filter-name": leaf,
+	}), "filter-name")
+
+	filter := list(map[string]*juniperSchema{
+		"term": list(map[string]*juniperSchema{
+			"from": container(map[string]*juniperSchema{
+				"source-address":      list(map[string]*juniperSchema{"except": flag}),
+				"destination-address": list(map[string]*juniperSchema{"except": flag}),
+				"protocol":            leafList,
+				"source-port":         leafList,
+				"destination-port":    leafList,
+			}),
+			"then": container(map[string]*juniperSchema{
+				"accept":  flag,
+				"discard": flag,
+				"reject":  flag,
+			}),
+		}),
+	})
+
+	addressBook := container(map[string]*juniperSchema{
+		"address": withDefault(list(map[string]*juniperSchema{
+			"ip-prefix": leaf,
+		}), "ip-prefix"),
+		"address-set": list(map[string]*juniperSchema{
+			"address":     list(nil),
+			"address-set": list(nil),
+		}),
+	})
+
+	policy := list(map[string]*juniperSchema{
+		"match": container(map[string]*juniperSchema{
+			"source-address":      leafList,
+			"destination-address": leafList,
+			"application":         leafList,
+			"from-zone":           leafList,
+			"to-zone":             leafList,
+		}),
+		"then": container(map[string]*juniperSchema{
+			"permit": flag,
+			"deny":   flag,
+			"reject": flag,
+		}),
+	})
+
+	// NAT rule sets differ in the match and then statements
+	zones := container(map[string]*juniperSchema{
+		"zone":      leafList,
+		"interface": leafList,
+	})
+	ruleSet := func(match string, then map[string]*juniperSchema) *juniperSchema {
+		return list(map[string]*juniperSchema{
+			"from": zones,
+			"to":   zones,
+			"rule": list(map[string]*juniperSchema{
+				"match": {
+					Kind: JUNIPER_CONTAINER,
+					Key:  match,
+					Children: map[string]*juniperSchema{
+						"source-address":      leafList,
+						"destination-address": leafList,
+						"destination-port":    leafList,
+						"protocol":            leafList,
+					},
+				},
+				"then": container(then),
+			}),
+		})
+	}
+	poolName := withDefault(container(map[string]*juniperSchema{
+		"pool-name": leaf,
+	}), "pool-name")
+
+	application := map[string]*juniperSchema{
+		"protocol":         leaf,
+		"source-port":      leaf,
+		"destination-port": leaf,
+	}
+
+	root := container(map[string]*juniperSchema{
+		"system": container(map[string]*juniperSchema{
+			"host-name": leaf,
+		}),
+		"interfaces": {
+			Kind:    JUNIPER_CONTAINER,
+			Default: "interface",
+			Children: map[string]*juniperSchema{
+				"interface": list(map[string]*juniperSchema{
+					"description": leaf,
+					"unit": list(map[string]*juniperSchema{
+						"description": leaf,
+						"vlan-id":     leaf,
+						"family": container(map[string]*juniperSchema{
+							"inet": container(map[string]*juniperSchema{
+								"address": address,
+								"filter": container(map[string]*juniperSchema{
+									"input":  filterName,
+									"output": filterName,
+								}),
+							}),
+							"inet6": container(map[string]*juniperSchema{"address": address}),
+							"ethernet-switching": container(map[string]*juniperSchema{
+								"interface-mode": leaf,
+								"port-mode":      leaf,
+								"vlan": container(map[string]*juniperSchema{
+									"members": leafList,
+								}),
+							}),
+						}),
+					}),
+				}),
+			},
+		},
+		"routing-options": container(map[string]*juniperSchema{
+			"static": container(map[string]*juniperSchema{
+				"route": list(map[string]*juniperSchema{
+					"next-hop": leafList,
+				}),
+			}),
+			"autonomous-system": {
+				Kind:    JUNIPER_CONTAINER,
+				Default: "as-number",
+				Children: map[string]*juniperSchema{
+					"as-number": leaf,
+				},
+			},
+			"router-id": leaf,
+		}),
+		"protocols": container(map[string]*juniperSchema{
+			"ospf": container(map[string]*juniperSchema{
+				"area": list(map[string]*juniperSchema{
+					"interface": list(map[string]*juniperSchema{
+						"passive": flag,
+					}),
+				}),
+			}),
+			"bgp": container(map[string]*juniperSchema{
+				"group": list(map[string]*juniperSchema{
+					"peer-as": leaf,
+					"neighbor": list(map[string]*juniperSchema{
+						"peer-as": leaf,
+					}),
+				}),
+			}),
+		}),
+		"vlans": {
+			Kind:    JUNIPER_CONTAINER,
+			Default: "vlan",
+			Children: map[string]*juniperSchema{
+				"vlan": list(map[string]*juniperSchema{
+					"vlan-id":      leaf,
+					"l3-interface": leaf,
+				}),
+			},
+		},
+		"firewall": container(map[string]*juniperSchema{
+			"family": container(map[string]*juniperSchema{
+				"inet": container(map[string]*juniperSchema{"filter": filter}),
+			}),
+			"filter": filter,
+		}),
+		"security": container(map[string]*juniperSchema{
+			"address-book": list(addressBook.Children),
+			"zones": container(map[string]*juniperSchema{
+				"security-zone": list(map[string]*juniperSchema{
+					"interfaces":   list(nil),
+					"address-book": addressBook,
+				}),
+			}),
+			"policies": container(map[string]*juniperSchema{
+				// from-zone A to-zone B, see set
+				"policy": list(map[string]*juniperSchema{
+					"policy": policy,
+				}),
+				"global": container(map[string]*juniperSchema{
+					"policy": policy,
+				}),
+			}),
+			"nat": container(map[string]*juniperSchema{
+				"source": container(map[string]*juniperSchema{
+					"pool": list(map[string]*juniperSchema{
+						"address": list(map[string]*juniperSchema{
+							"to": withDefault(container(map[string]*juniperSchema{
+								"ipaddr": leaf,
+							}), "ipaddr"),
+						}),
+					}),
+					"rule-set": ruleSet("src-nat-rule-match", map[string]*juniperSchema{
+						"source-nat": container(map[string]*juniperSchema{
+							"interface": flag,
+							"off":       flag,
+							"pool":      poolName,
+						}),
+					}),
+				}),
+				"destination": container(map[string]*juniperSchema{
+					"pool": list(map[string]*juniperSchema{
+						"address": withDefault(container(map[string]*juniperSchema{
+							"ipaddr": leaf,
+							"port":   leaf,
+						}), "ipaddr"),
+					}),
+					"rule-set": ruleSet("dest-nat-rule-match", map[string]*juniperSchema{
+						"destination-nat": container(map[string]*juniperSchema{
+							"off":  flag,
+							"pool": poolName,
+						}),
+					}),
+				}),
+				"static": container(map[string]*juniperSchema{
+					"rule-set": ruleSet("static-nat-rule-match", map[string]*juniperSchema{
+						"static-nat": container(map[string]*juniperSchema{
+							"prefix": withDefault(container(map[string]*juniperSchema{
+								"addr-prefix": leaf,
+							}), "addr-prefix"),
+						}),
+					}),
+				}),
+			}),
+		}),
+		"applications": container(map[string]*juniperSchema{
+			"application": list(map[string]*juniperSchema{
+				"protocol":         application["protocol"],
+				"source-port":      application["source-port"],
+				"destination-port": application["destination-port"],
+				"term":             list(application),
+			}),
+			"application-set": list(map[string]*juniperSchema{
+				"application":     list(nil),
+				"application-set": list(nil),
+			}),
+		}),
+	})
+
+	// groups contain a copy of the hierarchy
+	root.Children["groups"] = list(root.Children)
+
+	return root
+}()
+
+// zonePair returns the security policies for the pair of zones, creating it
+// if necessary
+func (n *juniperNode) zonePair(from, to string) *juniperNode {
+	name := from + " " + to
+
+	for _, c := range n.children["policy"] {
+		if c.name() == name {
+			return c
+		}
+	}
+
+	c := newJuniperNode()
+	c.add("from-zone-name", newJuniperLeaf(from))
+	c.add("to-zone-name", newJuniperLeaf(to))
+	n.add("policy", c)
+	return c
+}
+
+// set applies a set or deactivate statement, fields are the words after the
+// command. Statements that aren't in the schema are ignored. Returns the
+// nodes for each of the fields that is a keyword, such as the element for
+// interfaces in interfaces ge-0/0/0, so that the caller can find the node for
+// a statement that doesn't start at the top of the hierarchy.
+func (n *juniperNode) set(fields []string, deactivate bool) [][]*juniperNode {
+	s := juniperConfigSchema
+
+	res := make([][]*juniperNode, len(fields))
+
+	for i := 0; i < len(fields); i++ {
+		k := fields[i]
+
+		// index of the value or name for the keyword
+		next := i + 1
+
+		c, ok := s.Children[k]
+		if k == "apply-groups" {
+			// allowed at every level
+			c, ok = &juniperSchema{Kind: JUNIPER_LEAF_LIST}, true
+		} else if k == "from-zone" && s == juniperConfigSchema.Children["security"].Children["policies"] {
+			// policies from-zone A to-zone B is a list element identified by
+			// both zones
+			if i+3 >= len(fields) || fields[i+2] != "to-zone" {
+				log.Debug("ignoring invalid statement: %v", strings.Join(fields[i:], " "))
+				return res
+			}
+
+			n = n.zonePair(fields[i+1], fields[i+3])
+			res[i] = []*juniperNode{n}
+			s = s.Children["policy"]
+			i += 3
+			continue
+		} else if !ok && s.Default != "" {
+			k, c, ok = s.Default, s.Children[s.Default], true
+			next = i
+		}
+
+		if !ok {
+			log.Debug("ignoring unknown statement: %v", strings.Join(fields[i:], " "))
+			return res
+		}
+
+		if c.Key != "" {
+			k = c.Key
+		}
+
+		if deactivate && next == len(fields) {
+			// deactivate the whole statement, e.g. description
+			for _, c := range n.children[k] {
+				c.Inactive = true
+			}
+			return res
+		}
+
+		if next >= len(fields) {
+			if c.Kind == JUNIPER_FLAG {
+				res[i] = []*juniperNode{newJuniperNode()}
+				n.add(k, res[i]...)
+			}
+			return res
+		}
+
+		switch c.Kind {
+		case JUNIPER_CONTAINER:
+			n = n.container(k)
+			res[i] = []*juniperNode{n}
+		case JUNIPER_LIST:
+			n = n.element(k, fields[next])
+			res[i] = []*juniperNode{n}
+			i = next
+		case JUNIPER_LEAF:
+			res[i] = []*juniperNode{newJuniperLeaf(fields[next])}
+			n.replace(k, res[i]...)
+			if deactivate {
+				return res
+			}
+
+			// may be followed by siblings, e.g. address 10.0.0.1/32 port 80, as
+			// may leaf lists
+			i = next
+			continue
+		case JUNIPER_LEAF_LIST:
+			if fields[next] != "[" {
+				res[i] = []*juniperNode{newJuniperLeaf(fields[next])}
+			} else {
+				// multiple values, e.g. protocol [ tcp udp ]
+				for next++; next < len(fields) && fields[next] != "]"; next++ {
+					res[i] = append(res[i], newJuniperLeaf(fields[next]))
+				}
+			}
+			n.add(k, res[i]...)
+
+			if deactivate {
+				return res
+			}
+
+			i = next
+			continue
+		case JUNIPER_FLAG:
+			// may be followed by options that we don't care about
+			res[i] = []*juniperNode{newJuniperNode()}
+			n.add(k, res[i]...)
+			return res
+		}
+
+		s = c
+	}
+
+	if deactivate {
+		n.Inactive = true
+	}
+
+	return res
+}
+
+// parseJuniperSet parses a config from show configuration | display set
+func parseJuniperSet(b []byte) ([]*juniperNode, error) {
+	root := newJuniperNode()
+
+	var found bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := splitQuoted(scanner.Text(), `"`)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "set":
+			root.set(fields[1:], false)
+			found = true
+		case "deactivate":
+			root.set(fields[1:], true)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, errors.New("no set configuration found")
+	}
+
+	return []*juniperNode{root}, nil
+}
+
+// juniperBlock is a statement that contains other statements in show
+// configuration output
+type juniperBlock struct {
+	fields   []string
+	inactive bool
+}
+
+// parseJuniperCurly parses a config from show configuration, the default
+// format with nested statements in braces. Each statement is applied as if
+// it was the equivalent set command.
+func parseJuniperCurly(b []byte) ([]*juniperNode, error) {
+	root := newJuniperNode()
+
+	var found, comment bool
+	var blocks []juniperBlock
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// annotations may span lines
+		if comment || strings.HasPrefix(line, "/*") {
+			comment = !strings.Contains(line, "*/")
+			continue
+		}
+
+		// trailing comments such as ## SECRET-DATA
+		if i := strings.Index(line, "; ##"); i >= 0 {
+			line = line[:i+1]
+		}
+
+		if line == "}" {
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			continue
+		}
+
+		// skip comments, the prompt, and anything else that isn't a statement
+		if strings.HasPrefix(line, "#") || !(strings.HasSuffix(line, "{") || strings.HasSuffix(line, ";")) {
+			continue
+		}
+
+		open := strings.HasSuffix(line, "{")
+
+		fields := splitQuoted(strings.TrimSpace(line[:len(line)-1]), `"`)
+
+		var inactive bool
+		for len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+			// inactive:, protect:, etc.
+			inactive = inactive || fields[0] == "inactive:"
+			fields = fields[1:]
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		var path []string
+		for _, v := range blocks {
+			path = append(path, v.fields...)
+		}
+
+		if open {
+			blocks = append(blocks, juniperBlock{fields: fields, inactive: inactive})
+			inactive = false
+		}
+
+		nodes := root.set(append(path, fields...), false)
+		found = true
+
+		// deactivate the statements that were inactive, blocks are marked
+		// as their children are set since blocks are only created then
+		i := 0
+		for _, v := range blocks {
+			if v.inactive {
+				for _, n := range nodes[i] {
+					n.Inactive = true
+				}
+			}
+			i += len(v.fields)
+		}
+
+		if inactive {
+			for _, n := range nodes[len(path)] {
+				n.Inactive = true
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, errors.New("no configuration found")
+	}
+
+	return []*juniperNode{root}, nil
+}
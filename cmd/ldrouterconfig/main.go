@@ -5,26 +5,75 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/importer"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
 )
 
 var (
-	f_type   = flag.String("type", "cisco", "specify config type: [cisco, arista, brocade, juniper]")
-	f_server = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
-	f_dryrun = flag.Bool("dry-run", false, "do a dry run and do not push data to the server")
+	f_type    = flag.String("type", "auto", "specify config type, auto to detect it from the config")
+	f_server  = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
+	f_dryrun  = flag.Bool("dry-run", false, "do a dry run and do not push data to the server")
+	f_workers = flag.Int("workers", runtime.NumCPU(), "number of configs to process concurrently")
 )
 
+// Result of processing one config
+type Result struct {
+	File   string
+	Device *Device
+	Stats  *Stats
+	Err    error
+}
+
 func usage() {
-	fmt.Printf("USAGE: %v [OPTIONS] CONFIG\n", os.Args[0])
+	fmt.Printf("USAGE: %v [OPTIONS] CONFIG...\n", os.Args[0])
+	fmt.Println("\nCONFIG may be a file, a directory of configs, or a glob.")
+	fmt.Printf("Config types: %v\n\n", strings.Join(parserNames(), ", "))
 	flag.PrintDefaults()
 	os.Exit(1)
 }
 
+// process parses the config and pushes the device to the server, unless this
+// is a dry run
+func process(dc *discovery.Client, filename string) *Result {
+	res := &Result{File: filename}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	typ := *f_type
+	if typ == "auto" {
+		typ = detectType(b)
+	}
+	log.Debug("parsing %v as %v", filename, typ)
+
+	res.Device, res.Err = parsers[typ].Parse(bytes.NewReader(b))
+	if res.Err != nil {
+		return res
+	}
+
+	if res.Device.Name == "" {
+		res.Device.Name = filepath.Base(filename)
+	}
+
+	if !*f_dryrun {
+		res.Stats, res.Err = res.Device.Push(dc)
+	}
+
+	return res
+}
+
 func main() {
 	flag.Parse()
 
@@ -32,35 +81,61 @@ func main() {
 
 	dc := discovery.New(*f_server)
 
-	if flag.NArg() != 1 {
+	if flag.NArg() == 0 {
 		usage()
 	}
 
-	var parser func(*os.File, *discovery.Client) error
-
-	switch *f_type {
-	case "cisco":
-		parser = parseCisco
-	case "brocade":
-		parser = parseBrocade
-	case "arista":
-		parser = parseArista
-	case "juniper":
-		parser = parseJuniper
-	default:
+	if _, ok := parsers[*f_type]; !ok && *f_type != "auto" {
 		log.Error("invalid config type")
 		usage()
 	}
 
-	filename := flag.Arg(0)
-	log.Debug("using filename: %v", filename)
+	if *f_workers < 1 {
+		log.Fatal("must have at least one worker")
+	}
 
-	f, err := os.Open(filename)
+	files, err := importer.Expand(flag.Args())
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	if err := parser(f, dc); err != nil {
-		log.Fatalln(err)
+	results := importer.Process(files, *f_workers, func(f string) *Result {
+		return process(dc, f)
+	})
+
+	if *f_dryrun {
+		for _, res := range results {
+			if res.Device != nil {
+				fmt.Printf("%v: %v\n", res.File, res.Device)
+			}
+		}
+	}
+
+	var edges, networks int
+
+	t := importer.NewTable(os.Stdout, "TYPE", "NAME", "INTERFACES", "EDGES", "NETWORKS")
+	t.DryRun = *f_dryrun
+
+	for _, res := range results {
+		typ, name, ifaces := "-", "-", "-"
+		if d := res.Device; d != nil {
+			typ, name, ifaces = d.Type, d.Name, fmt.Sprint(len(d.Interfaces))
+		}
+
+		added, created := "-", "-"
+		if s := res.Stats; s != nil {
+			added, created = fmt.Sprint(s.Edges), fmt.Sprint(s.Networks)
+			edges += s.Edges
+			networks += s.Networks
+		}
+
+		t.Row(res.File, res.Err, typ, name, ifaces, added, created)
+	}
+	t.Flush()
+
+	fmt.Printf("\n%v configs, %v failed, %v edges and %v networks added\n", t.Files, t.Failed, edges, networks)
+
+	if t.Failed > 0 {
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,178 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+func init() {
+	Register(&Parser{
+		Name:  "mikrotik",
+		Parse: parseMikroTik,
+		Signatures: signatures(
+			`by RouterOS`,
+			`(?m)^/(ip address|interface|system identity)\b`,
+		),
+		Priority: 40,
+	})
+}
+
+// mikroTikCommand is a command from an export such as
+// add address=10.0.0.1/24 interface=ether1
+type mikroTikCommand struct {
+	// Section is the menu, e.g. /ip address
+	Section string
+
+	// Command is add, set, etc.
+	Command string
+
+	// Args are the key=value arguments, Find are the arguments in [ find ]
+	Args, Find map[string]string
+}
+
+// mikroTikCommands parses the commands from an export (/export), joining
+// continued lines
+func mikroTikCommands(f io.Reader) ([]mikroTikCommand, error) {
+	var res []mikroTikCommand
+
+	var section, line string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		v := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(v, `\`) {
+			line += strings.TrimSuffix(v, `\`)
+			continue
+		}
+
+		line += v
+		fields := splitQuoted(line, `"`)
+		line = ""
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "/") {
+			// menu, may be followed by a command in terse exports
+			i := 1
+			for i < len(fields) && !strings.Contains(fields[i], "=") && fields[i] != "add" && fields[i] != "set" {
+				i++
+			}
+
+			section = strings.Join(fields[:i], " ")
+			fields = fields[i:]
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		cmd := mikroTikCommand{
+			Section: section,
+			Command: fields[0],
+			Args:    map[string]string{},
+			Find:    map[string]string{},
+		}
+
+		var find bool
+		for _, f := range fields[1:] {
+			switch f {
+			case "[":
+				find = true
+			case "]":
+				find = false
+			}
+
+			k, v, ok := strings.Cut(f, "=")
+			if !ok {
+				continue
+			}
+
+			if find {
+				cmd.Find[k] = v
+			} else {
+				cmd.Args[k] = v
+			}
+		}
+
+		res = append(res, cmd)
+	}
+
+	return res, scanner.Err()
+}
+
+func parseMikroTik(f io.Reader) (*Device, error) {
+	d := NewDevice("mikrotik")
+
+	cmds, err := mikroTikCommands(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces interfaceTable
+
+	// interface lists, the closest thing to zones
+	zones := map[string]string{}
+
+	for _, cmd := range cmds {
+		log.Debug("processing command: %v %v %v", cmd.Section, cmd.Command, cmd.Args)
+
+		if cmd.Args["disabled"] == "yes" {
+			continue
+		}
+
+		switch cmd.Section {
+		case "/system identity":
+			if cmd.Args["name"] != "" {
+				d.Name = cmd.Args["name"]
+			}
+		case "/interface ethernet":
+			// set [ find default-name=ether1 ] name=wan comment=uplink
+			name := cmd.Args["name"]
+			if name == "" {
+				name = cmd.Find["default-name"]
+			}
+
+			if name != "" && cmd.Args["comment"] != "" {
+				interfaces.Get(name).Desc = cmd.Args["comment"]
+			}
+		case "/interface vlan":
+			if cmd.Command != "add" || cmd.Args["name"] == "" {
+				continue
+			}
+
+			iface := interfaces.Get(cmd.Args["name"])
+			iface.VLAN, iface.Mode = cmd.Args["vlan-id"], VLAN_TRUNK
+			if cmd.Args["comment"] != "" {
+				iface.Desc = cmd.Args["comment"]
+			}
+		case "/ip address", "/ipv6 address":
+			if cmd.Command != "add" || cmd.Args["interface"] == "" {
+				continue
+			}
+
+			interfaces.Get(cmd.Args["interface"]).AddAddress(cmd.Args["address"])
+		case "/interface list member":
+			k := cmd.Args["interface"]
+			if _, ok := zones[k]; !ok && cmd.Command == "add" {
+				zones[k] = cmd.Args["list"]
+			}
+		}
+	}
+
+	for name, zone := range zones {
+		interfaces.SetZone(name, zone)
+	}
+
+	interfaces.AddTo(d)
+
+	return d, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+func init() {
+	Register(&Parser{
+		Name:  "paloalto",
+		Parse: parsePaloAlto,
+		Signatures: signatures(
+			`<deviceconfig>`,
+			`<config [^>]*urldb=`,
+		),
+		Priority: 40,
+	})
+}
+
+type PaloAltoEntry struct {
+	Name string `xml:"name,attr"`
+}
+
+// PaloAltoUnit is a sub-interface or a logical interface such as a loopback
+type PaloAltoUnit struct {
+	Name    string          `xml:"name,attr"`
+	Comment string          `xml:"comment"`
+	Tag     string          `xml:"tag"`
+	IP      []PaloAltoEntry `xml:"ip>entry"`
+	IPv6    []PaloAltoEntry `xml:"ipv6>address>entry"`
+}
+
+type PaloAltoInterface struct {
+	Name    string `xml:"name,attr"`
+	Comment string `xml:"comment"`
+	Layer3  struct {
+		IP    []PaloAltoEntry `xml:"ip>entry"`
+		IPv6  []PaloAltoEntry `xml:"ipv6>address>entry"`
+		Units []PaloAltoUnit  `xml:"units>entry"`
+	} `xml:"layer3"`
+	Layer2 struct {
+		Units []PaloAltoUnit `xml:"units>entry"`
+	} `xml:"layer2"`
+}
+
+// PaloAltoAddress is an address object, which interfaces may use instead of
+// a literal address
+type PaloAltoAddress struct {
+	Name      string `xml:"name,attr"`
+	IPNetmask string `xml:"ip-netmask"`
+}
+
+type PaloAltoConfig struct {
+	Devices []struct {
+		Hostname          string              `xml:"deviceconfig>system>hostname"`
+		Ethernet          []PaloAltoInterface `xml:"network>interface>ethernet>entry"`
+		AggregateEthernet []PaloAltoInterface `xml:"network>interface>aggregate-ethernet>entry"`
+		Loopback          []PaloAltoUnit      `xml:"network>interface>loopback>units>entry"`
+		Vlan              []PaloAltoUnit      `xml:"network>interface>vlan>units>entry"`
+		Tunnel            []PaloAltoUnit      `xml:"network>interface>tunnel>units>entry"`
+		Vsys              []struct {
+			Zones []struct {
+				Name    string   `xml:"name,attr"`
+				Layer3  []string `xml:"network>layer3>member"`
+				Layer2  []string `xml:"network>layer2>member"`
+				Wire    []string `xml:"network>virtual-wire>member"`
+				Tap     []string `xml:"network>tap>member"`
+				Tunnels []string `xml:"network>tunnel>member"`
+			} `xml:"zone>entry"`
+			Address []PaloAltoAddress `xml:"address>entry"`
+		} `xml:"vsys>entry"`
+	} `xml:"devices>entry"`
+	Shared struct {
+		Address []PaloAltoAddress `xml:"address>entry"`
+	} `xml:"shared"`
+}
+
+func parsePaloAlto(f io.Reader) (*Device, error) {
+	d := NewDevice("paloalto")
+	d.Firewall = true
+
+	var config PaloAltoConfig
+	if err := xml.NewDecoder(f).Decode(&config); err != nil {
+		return nil, err
+	}
+
+	if len(config.Devices) == 0 {
+		return nil, errors.New("no devices in config")
+	}
+
+	addresses := map[string]string{}
+	for _, v := range config.Shared.Address {
+		addresses[v.Name] = v.IPNetmask
+	}
+
+	var interfaces interfaceTable
+
+	// addAddresses adds the addresses, resolving address objects
+	addAddresses := func(iface *Interface, entries []PaloAltoEntry) {
+		for _, v := range entries {
+			addr := v.Name
+			if _, _, err := net.ParseCIDR(addr); err != nil && addresses[addr] != "" {
+				log.Debug("resolved address object %v to %v", addr, addresses[addr])
+				addr = addresses[addr]
+			}
+
+			iface.AddAddress(addr)
+		}
+	}
+
+	addUnits := func(units []PaloAltoUnit) {
+		for _, u := range units {
+			iface := interfaces.Get(u.Name)
+			if u.Comment != "" {
+				iface.Desc = u.Comment
+			}
+			if u.Tag != "" {
+				iface.VLAN, iface.Mode = u.Tag, VLAN_TRUNK
+			}
+
+			addAddresses(iface, u.IP)
+			addAddresses(iface, u.IPv6)
+		}
+	}
+
+	for _, dev := range config.Devices {
+		if dev.Hostname != "" {
+			d.Name = dev.Hostname
+		}
+
+		for _, vsys := range dev.Vsys {
+			for _, v := range vsys.Address {
+				addresses[v.Name] = v.IPNetmask
+			}
+		}
+
+		for _, v := range append(dev.Ethernet, dev.AggregateEthernet...) {
+			iface := interfaces.Get(v.Name)
+			if v.Comment != "" {
+				iface.Desc = v.Comment
+			}
+
+			addAddresses(iface, v.Layer3.IP)
+			addAddresses(iface, v.Layer3.IPv6)
+
+			addUnits(v.Layer3.Units)
+			addUnits(v.Layer2.Units)
+		}
+
+		addUnits(dev.Loopback)
+		addUnits(dev.Vlan)
+		addUnits(dev.Tunnel)
+
+		for _, vsys := range dev.Vsys {
+			for _, zone := range vsys.Zones {
+				members := [][]string{zone.Layer3, zone.Layer2, zone.Wire, zone.Tap, zone.Tunnels}
+				for _, v := range members {
+					for _, name := range v {
+						interfaces.SetZone(strings.TrimSpace(name), zone.Name)
+					}
+				}
+			}
+		}
+	}
+
+	interfaces.AddTo(d)
+
+	return d, nil
+}
@@ -0,0 +1,292 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name, parser, config string
+
+		// hostname and whether it's a firewall
+		host     string
+		firewall bool
+
+		want []Interface
+	}{
+		{"vyos config.boot", "vyos", `
+firewall {
+    zone LAN {
+        member {
+            interface eth1
+        }
+    }
+    zone WAN {
+        member {
+            interface eth0
+        }
+    }
+}
+interfaces {
+    ethernet eth0 {
+        address 203.0.113.2/30
+        address dhcpv6
+        description "ISP uplink"
+        hw-id 00:0c:29:00:00:01
+    }
+    ethernet eth1 {
+        address 10.20.0.1/24
+        address 2001:db8:20::1/64
+        vif 30 {
+            address 10.30.0.1/24
+            description "guest"
+        }
+    }
+    loopback lo {
+    }
+}
+system {
+    host-name edge1
+}
+// Warning: Do not remove the following line.
+// vyos-config-version: "bgp@4:broadcast-relay@1"
+`, "edge1", false, []Interface{
+			{Name: "eth0", Desc: "ISP uplink", IP: "203.0.113.2/30", Zone: "WAN"},
+			{Name: "eth1", Desc: "eth1", IP: "10.20.0.1/24", IP6: "2001:db8:20::1/64", Zone: "LAN"},
+			{Name: "eth1.30", Desc: "guest", IP: "10.30.0.1/24", VLAN: "30", Mode: VLAN_TRUNK},
+		}},
+		{"vyos set", "vyos", `
+set interfaces ethernet eth0 address '203.0.113.6/30'
+set interfaces ethernet eth0 description 'WAN'
+set interfaces ethernet eth1 address '10.21.0.1/24'
+set interfaces ethernet eth1 vif 40 address '10.40.0.1/24'
+set system host-name 'edge2'
+set zone-policy zone LAN interface 'eth1'
+set zone-policy zone WAN interface 'eth0'
+`, "edge2", false, []Interface{
+			{Name: "eth0", Desc: "WAN", IP: "203.0.113.6/30", Zone: "WAN"},
+			{Name: "eth1", Desc: "eth1", IP: "10.21.0.1/24", Zone: "LAN"},
+			{Name: "eth1.40", Desc: "eth1.40", IP: "10.40.0.1/24", VLAN: "40", Mode: VLAN_TRUNK},
+		}},
+		{"mikrotik", "mikrotik", `
+# jan/02/2023 10:00:00 by RouterOS 6.49.7
+# software id = ABCD-1234
+#
+# model = RB4011
+/interface bridge
+add admin-mac=00:11:22:33:44:55 auto-mac=no comment=defconf name=bridge
+/interface ethernet
+set [ find default-name=ether1 ] comment="ISP uplink"
+set [ find default-name=ether2 ] name=ether2-lan
+/interface vlan
+add interface=bridge name=vlan50 vlan-id=50
+/interface list
+add comment=defconf name=WAN
+add comment=defconf name=LAN
+/interface list member
+add comment=defconf interface=bridge list=LAN
+add comment=defconf interface=ether1 list=WAN
+/ip address
+add address=192.168.88.1/24 comment=defconf interface=bridge network=\
+    192.168.88.0
+add address=198.51.100.2/30 interface=ether1 network=198.51.100.0
+add address=10.50.0.1/24 interface=vlan50 network=10.50.0.0
+add address=10.99.0.1/24 disabled=yes interface=ether2-lan network=10.99.0.0
+/ipv6 address
+add address=2001:db8:88::1/64 advertise=no interface=bridge
+/system identity
+set name=mt-gw
+`, "mt-gw", false, []Interface{
+			{Name: "ether1", Desc: "ISP uplink", IP: "198.51.100.2/30", Zone: "WAN"},
+			{Name: "vlan50", Desc: "vlan50", IP: "10.50.0.1/24", VLAN: "50", Mode: VLAN_TRUNK},
+			{Name: "bridge", Desc: "bridge", IP: "192.168.88.1/24", IP6: "2001:db8:88::1/64", Zone: "LAN"},
+		}},
+		{"paloalto", "paloalto", `
+<?xml version="1.0"?>
+<config version="10.1.0" urldb="paloaltonetworks">
+  <devices>
+    <entry name="localhost.localdomain">
+      <network>
+        <interface>
+          <ethernet>
+            <entry name="ethernet1/1">
+              <layer3>
+                <ip><entry name="192.0.2.10/24"/></ip>
+              </layer3>
+              <comment>untrust uplink</comment>
+            </entry>
+            <entry name="ethernet1/2">
+              <layer3>
+                <ip><entry name="fw-inside"/></ip>
+                <ipv6><enabled>yes</enabled><address><entry name="2001:db8:80::1/64"/></address></ipv6>
+                <units>
+                  <entry name="ethernet1/2.90">
+                    <tag>90</tag>
+                    <ip><entry name="10.90.0.1/24"/></ip>
+                    <comment>dmz</comment>
+                  </entry>
+                </units>
+              </layer3>
+            </entry>
+          </ethernet>
+          <loopback>
+            <units>
+              <entry name="loopback.1"><ip><entry name="10.255.0.1/32"/></ip></entry>
+            </units>
+          </loopback>
+        </interface>
+      </network>
+      <deviceconfig>
+        <system>
+          <hostname>pa-edge</hostname>
+        </system>
+      </deviceconfig>
+      <vsys>
+        <entry name="vsys1">
+          <zone>
+            <entry name="untrust"><network><layer3><member>ethernet1/1</member></layer3></network></entry>
+            <entry name="trust"><network><layer3><member>ethernet1/2</member></layer3></network></entry>
+            <entry name="dmz"><network><layer3><member>ethernet1/2.90</member></layer3></network></entry>
+          </zone>
+          <address>
+            <entry name="fw-inside"><ip-netmask>10.80.0.1/24</ip-netmask></entry>
+          </address>
+        </entry>
+      </vsys>
+    </entry>
+  </devices>
+</config>
+`, "pa-edge", true, []Interface{
+			{Name: "ethernet1/1", Desc: "untrust uplink", IP: "192.0.2.10/24", Zone: "untrust"},
+			{Name: "ethernet1/2", Desc: "ethernet1/2", IP: "10.80.0.1/24", IP6: "2001:db8:80::1/64", Zone: "trust"},
+			{Name: "ethernet1/2.90", Desc: "dmz", IP: "10.90.0.1/24", VLAN: "90", Mode: VLAN_TRUNK, Zone: "dmz"},
+			{Name: "loopback.1", Desc: "loopback.1", IP: "10.255.0.1/32"},
+		}},
+		{"fortigate", "fortigate", `
+#config-version=FGVM64-6.4.5-FW-build1828-210217:opmode=0:vdom=0:user=admin
+#conf_file_ver=1234
+#buildno=1828
+config system global
+    set alias "FortiGate-VM64"
+    set hostname "fgt-hq"
+    set timezone 04
+end
+config system interface
+    edit "port1"
+        set vdom "root"
+        set ip 198.51.100.10 255.255.255.0
+        set allowaccess ping https ssh
+        set type physical
+        set alias "WAN"
+        set role wan
+        config ipv6
+            set ip6-address 2001:db8:1::10/64
+        end
+    next
+    edit "port2"
+        set vdom "root"
+        set ip 10.60.0.1 255.255.255.0
+        set type physical
+        set description "inside network"
+    next
+    edit "port3"
+        set vdom "root"
+        set type physical
+    next
+    edit "vlan70"
+        set vdom "root"
+        set ip 10.70.0.1 255.255.255.0
+        set interface "port2"
+        set vlanid 70
+    next
+end
+config system zone
+    edit "inside"
+        set interface "port2" "vlan70"
+    next
+end
+`, "fgt-hq", true, []Interface{
+			{Name: "port1", Desc: "WAN", IP: "198.51.100.10/24", IP6: "2001:db8:1::10/64"},
+			{Name: "port2", Desc: "inside network", IP: "10.60.0.1/24", Zone: "inside"},
+			{Name: "vlan70", Desc: "vlan70", IP: "10.70.0.1/24", VLAN: "70", Mode: VLAN_TRUNK, Zone: "inside"},
+		}},
+		{"pfsense", "pfsense", `
+<?xml version="1.0"?>
+<pfsense>
+	<version>21.7</version>
+	<system>
+		<hostname>pf-branch</hostname>
+		<domain>example.com</domain>
+	</system>
+	<interfaces>
+		<wan>
+			<enable></enable>
+			<if>em0</if>
+			<ipaddr>192.0.2.50</ipaddr>
+			<subnet>28</subnet>
+			<ipaddrv6>dhcp6</ipaddrv6>
+		</wan>
+		<lan>
+			<enable></enable>
+			<if>em1</if>
+			<ipaddr>10.100.0.1</ipaddr>
+			<subnet>24</subnet>
+			<ipaddrv6>2001:db8:100::1</ipaddrv6>
+			<subnetv6>64</subnetv6>
+		</lan>
+		<opt1>
+			<descr><![CDATA[CAMERAS]]></descr>
+			<enable></enable>
+			<if>em1.110</if>
+			<ipaddr>10.110.0.1</ipaddr>
+			<subnet>24</subnet>
+		</opt1>
+		<opt2>
+			<descr>OLD</descr>
+			<if>em2</if>
+			<ipaddr>10.120.0.1</ipaddr>
+			<subnet>24</subnet>
+		</opt2>
+	</interfaces>
+	<vlans>
+		<vlan>
+			<if>em1</if>
+			<tag>110</tag>
+			<descr/>
+			<vlanif>em1.110</vlanif>
+		</vlan>
+	</vlans>
+</pfsense>
+`, "pf-branch", true, []Interface{
+			{Name: "em0", Desc: "WAN", IP: "192.0.2.50/28", Zone: "wan"},
+			{Name: "em1", Desc: "LAN", IP: "10.100.0.1/24", IP6: "2001:db8:100::1/64", Zone: "lan"},
+			{Name: "em1.110", Desc: "CAMERAS", IP: "10.110.0.1/24", VLAN: "110", Mode: VLAN_TRUNK, Zone: "opt1"},
+		}},
+	}
+
+	for _, test := range tests {
+		if got := detectType([]byte(test.config)); got != test.parser {
+			t.Errorf("%v: detected %v, want %v", test.name, got, test.parser)
+		}
+
+		d, err := parsers[test.parser].Parse(strings.NewReader(test.config))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if d.Name != test.host || d.Firewall != test.firewall {
+			t.Errorf("%v: got %v (firewall %v), want %v (firewall %v)", test.name, d.Name, d.Firewall, test.host, test.firewall)
+		}
+
+		if !reflect.DeepEqual(d.Interfaces, test.want) {
+			t.Errorf("%v: got interfaces\n%+v\nwant\n%+v", test.name, d.Interfaces, test.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+func init() {
+	// also handles OPNsense, which has the same config.xml layout
+	Register(&Parser{
+		Name:  "pfsense",
+		Parse: parsePfSense,
+		Signatures: signatures(
+			`<pfsense>`,
+			`<opnsense>`,
+		),
+		Priority: 40,
+	})
+}
+
+// PfSenseInterface is an assigned interface, the element name is the
+// interface's identifier such as wan, lan, or opt1
+type PfSenseInterface struct {
+	XMLName  xml.Name
+	Enable   *struct{} `xml:"enable"`
+	If       string    `xml:"if"`
+	Descr    string    `xml:"descr"`
+	IPAddr   string    `xml:"ipaddr"`
+	Subnet   string    `xml:"subnet"`
+	IPAddrV6 string    `xml:"ipaddrv6"`
+	SubnetV6 string    `xml:"subnetv6"`
+}
+
+type PfSenseConfig struct {
+	System struct {
+		Hostname string `xml:"hostname"`
+	} `xml:"system"`
+	Interfaces struct {
+		Interfaces []PfSenseInterface `xml:",any"`
+	} `xml:"interfaces"`
+	VLANs []struct {
+		If     string `xml:"if"`
+		Tag    string `xml:"tag"`
+		VLANIf string `xml:"vlanif"`
+	} `xml:"vlans>vlan"`
+}
+
+func parsePfSense(f io.Reader) (*Device, error) {
+	d := NewDevice("pfsense")
+	d.Firewall = true
+
+	var config PfSenseConfig
+	if err := xml.NewDecoder(f).Decode(&config); err != nil {
+		return nil, err
+	}
+
+	d.Name = config.System.Hostname
+
+	vlans := map[string]string{}
+	for _, v := range config.VLANs {
+		vlanif := v.VLANIf
+		if vlanif == "" {
+			vlanif = v.If + "." + v.Tag
+		}
+
+		vlans[vlanif] = v.Tag
+	}
+
+	for _, v := range config.Interfaces.Interfaces {
+		zone := v.XMLName.Local
+
+		if v.Enable == nil {
+			log.Debug("skipping disabled interface: %v", zone)
+			continue
+		}
+
+		iface := Interface{
+			Name: v.If,
+			Desc: v.Descr,
+			// rules are by interface identifier so use it as the zone
+			Zone: zone,
+		}
+
+		if iface.Desc == "" {
+			iface.Desc = strings.ToUpper(zone)
+		}
+
+		if vlan, ok := vlans[v.If]; ok {
+			iface.VLAN, iface.Mode = vlan, VLAN_TRUNK
+		}
+
+		// ipaddr may also be dhcp, pppoe, track6, etc.
+		if net.ParseIP(v.IPAddr) != nil {
+			iface.AddAddress(v.IPAddr + "/" + v.Subnet)
+		}
+		if net.ParseIP(v.IPAddrV6) != nil {
+			iface.AddAddress(v.IPAddrV6 + "/" + v.SubnetV6)
+		}
+
+		d.AddInterface(iface)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Routing is the routing configuration of a router, collected while parsing
+// its config and applied once all the interfaces have been added.
+type Routing struct {
+	Routes []StaticRoute
+
+	// OSPF areas by network statement and by interface name
+	OSPFNetworks   []OSPFNetwork
+	OSPFInterfaces map[string]string
+
+	// interfaces that are explicitly passive (true) or not passive (false),
+	// PassiveDefault applies to the others
+	Passive        map[string]bool
+	PassiveDefault bool
+
+	// BGP local AS, router-id, and remote AS by neighbor
+	ASN       string
+	RouterID  string
+	Neighbors map[string]string
+}
+
+type StaticRoute struct {
+	Dst *net.IPNet
+	Via net.IP
+}
+
+type OSPFNetwork struct {
+	Net  *net.IPNet
+	Area string
+}
+
+// route is the next hop to a destination, the same format as collect routes
+// so that the static routes are emitted by the same template
+type route struct {
+	Via       string `json:"via"`
+	Interface int    `json:"interface"`
+}
+
+func NewRouting() *Routing {
+	return &Routing{
+		OSPFInterfaces: map[string]string{},
+		Passive:        map[string]bool{},
+		Neighbors:      map[string]string{},
+	}
+}
+
+// AddRoute parses the arguments of a static route: the destination as a
+// prefix or an address and a mask followed by the next hop, optionally after
+// an interface name. Routes without a next hop IP, such as to Null0, are
+// ignored, as are routes in VRFs.
+func (r *Routing) AddRoute(fields []string) {
+	if len(fields) > 0 && fields[0] == "vrf" {
+		log.Debug("ignoring vrf route: %v", strings.Join(fields, " "))
+		return
+	}
+
+	dst, rest := parsePrefix(fields, false)
+	if dst == nil {
+		log.Warn("invalid route: %v", strings.Join(fields, " "))
+		return
+	}
+
+	for _, f := range rest {
+		if ip := net.ParseIP(f); ip != nil {
+			log.Debug("got static route: %v via %v", dst, ip)
+			r.Routes = append(r.Routes, StaticRoute{Dst: dst, Via: ip})
+			return
+		}
+	}
+
+	log.Debug("ignoring route without next hop: %v", strings.Join(fields, " "))
+}
+
+// AddOSPFNetwork parses the arguments of an OSPF network statement: the
+// network as a prefix or an address and a wildcard mask followed by the area.
+func (r *Routing) AddOSPFNetwork(fields []string) {
+	n, rest := parsePrefix(fields, true)
+	if n == nil || len(rest) != 2 || rest[0] != "area" {
+		log.Warn("invalid ospf network: %v", strings.Join(fields, " "))
+		return
+	}
+
+	log.Debug("got ospf network: %v area %v", n, rest[1])
+	r.OSPFNetworks = append(r.OSPFNetworks, OSPFNetwork{Net: n, Area: rest[1]})
+}
+
+// AddNeighbor records a BGP neighbor and its remote AS
+func (r *Routing) AddNeighbor(ip, asn string) {
+	if net.ParseIP(ip) == nil {
+		log.Warn("invalid bgp neighbor: %v", ip)
+		return
+	}
+
+	log.Debug("got bgp neighbor: %v remote-as %v", ip, asn)
+	r.Neighbors[ip] = asn
+}
+
+// ospfArea returns the OSPF area for the interface with the given name and
+// IP, preferring the area set on the interface to the most specific network
+// statement
+func (r *Routing) ospfArea(iface string, ip net.IP) (string, bool) {
+	if area, ok := r.OSPFInterfaces[strings.ToLower(iface)]; ok {
+		return area, true
+	}
+
+	area, best := "", -1
+	for _, n := range r.OSPFNetworks {
+		if ones, _ := n.Net.Mask.Size(); ip != nil && n.Net.Contains(ip) && ones > best {
+			area, best = n.Area, ones
+		}
+	}
+
+	return area, best >= 0
+}
+
+func (r *Routing) passive(iface string) bool {
+	if v, ok := r.Passive[strings.ToLower(iface)]; ok {
+		return v
+	}
+
+	return r.PassiveDefault
+}
+
+// AddRouting annotates the router's edges with their OSPF areas and the router
+// with its static routes and BGP configuration.
+func AddRouting(dc *discovery.Client, ID int, r *Routing) error {
+	res, err := dc.LookupEndpoints("nid", strconv.Itoa(ID))
+	if err != nil {
+		return err
+	}
+	if len(res) != 1 {
+		return fmt.Errorf("no such endpoint: %v", ID)
+	}
+	e := res[0]
+
+	// so that we only send what changed
+	orig := e.Copy()
+
+	// subnet of each edge, nil if it doesn't have an IPv4 or IPv6 address
+	ipnets := make([][]*net.IPNet, len(e.Edges))
+
+	for i, edge := range e.Edges {
+		for _, k := range []string{"ip", "ip6"} {
+			if _, ipnet, err := net.ParseCIDR(edge.D[k]); err == nil {
+				ipnets[i] = append(ipnets[i], ipnet)
+			}
+		}
+
+		var ip net.IP
+		if v, _, err := net.ParseCIDR(edge.D["ip"]); err == nil {
+			ip = v
+		}
+
+		iface := edge.D["interface"]
+
+		area, ok := r.ospfArea(iface, ip)
+		if !ok {
+			continue
+		}
+
+		log.Info("ospf area %v on %v", area, edge.D["name"])
+		edge.D["OSPF"] = "true"
+		edge.D["ospf_area"] = area
+
+		if iface != "" && r.passive(iface) {
+			edge.D["ospf_passive"] = "true"
+		}
+	}
+
+	routes := map[string]route{}
+
+	for _, v := range r.Routes {
+		iface := -1
+		for i := range e.Edges {
+			for _, ipnet := range ipnets[i] {
+				if ipnet.Contains(v.Via) && iface == -1 {
+					iface = i
+				}
+			}
+		}
+
+		if iface == -1 {
+			log.Warn("skipping route to %v, next hop %v is not directly connected", v.Dst, v.Via)
+			continue
+		}
+
+		routes[v.Dst.String()] = route{Via: v.Via.String(), Interface: iface}
+	}
+
+	if len(routes) > 0 {
+		b, err := json.Marshal(routes)
+		if err != nil {
+			return err
+		}
+		e.D["routes"] = string(b)
+	}
+
+	// use the router-id as the local address if there is one, otherwise use
+	// the first IPv4 address
+	local := r.RouterID
+	for i := 0; r.ASN != "" && local == "" && i < len(e.Edges); i++ {
+		if v, _, err := net.ParseCIDR(e.Edges[i].D["ip"]); err == nil {
+			local = v.String()
+		}
+	}
+
+	if r.ASN != "" && local == "" {
+		log.Warn("skipping bgp as %v, no local address", r.ASN)
+	} else if r.ASN != "" {
+		e.D["bgp_asn"] = r.ASN
+		e.D["bgp_local"] = local
+
+		if len(r.Neighbors) > 0 {
+			b, err := json.Marshal(r.Neighbors)
+			if err != nil {
+				return err
+			}
+			e.D["bgp_neighbors"] = string(b)
+		}
+	}
+
+	p := minigraph.DiffEndpoint(orig, e)
+	if p.Empty() {
+		return nil
+	}
+
+	// edges are patched by index, which may change
+	var rev int
+	if len(p.Edges) > 0 {
+		rev = orig.Rev
+	}
+
+	_, err = dc.PatchEndpoint(e.NID, p, rev)
+	return err
+}
+
+// parsePrefix parses either a prefix or an address followed by a mask from
+// the start of fields and returns the remaining fields. The mask may be a
+// netmask or, if wildcard is set, an inverse mask as in OSPF network
+// statements.
+func parsePrefix(fields []string, wildcard bool) (*net.IPNet, []string) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	if _, ipnet, err := net.ParseCIDR(fields[0]); err == nil {
+		return ipnet, fields[1:]
+	}
+
+	if len(fields) < 2 {
+		return nil, nil
+	}
+
+	ip, mask := net.ParseIP(fields[0]).To4(), net.ParseIP(fields[1]).To4()
+	if ip == nil || mask == nil {
+		return nil, nil
+	}
+
+	m := net.IPMask(mask)
+	if wildcard {
+		for i := range m {
+			m[i] = ^m[i]
+		}
+	}
+
+	if _, bits := m.Size(); bits == 0 {
+		// not a valid mask
+		return nil, nil
+	}
+
+	return &net.IPNet{IP: ip.Mask(m), Mask: m}, fields[2:]
+}
@@ -0,0 +1,543 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Actions for Rules
+const (
+	ACTION_PERMIT = "permit"
+	ACTION_DENY   = "deny"
+)
+
+// Types of NAT
+const (
+	// NAT_SOURCE translates the source address to To or, if To is empty,
+	// to the address of the outgoing interface
+	NAT_SOURCE = "source"
+
+	// NAT_DESTINATION translates the destination address and port to To and
+	// ToPort
+	NAT_DESTINATION = "destination"
+
+	// NAT_STATIC maps Src, the inside prefix, to To, the outside prefix, in
+	// both directions
+	NAT_STATIC = "static"
+)
+
+// Security is the ACLs, security policies, and NAT rules of a router,
+// collected while parsing its config and applied once all the interfaces
+// have been added.
+type Security struct {
+	// ACLs by name, see ParseACE
+	ACLs map[string][]Rule
+
+	// Policies between zones in order
+	Policies []Rule
+
+	NAT []NAT
+
+	// ACLs applied to interfaces by interface name
+	ACLIn, ACLOut map[string]string
+
+	// NAT role of interfaces by interface name, inside or outside
+	NATRole map[string]string
+}
+
+// Rule is an ACL entry or a security policy. Empty fields match anything.
+type Rule struct {
+	Action   string `json:"action"`
+	Protocol string `json:"protocol,omitempty"`
+	Src      string `json:"src,omitempty"`
+	Dst      string `json:"dst,omitempty"`
+
+	// ports or port ranges such as 1024:65535
+	SrcPort string `json:"sport,omitempty"`
+	DstPort string `json:"dport,omitempty"`
+
+	// Established only matches packets of existing TCP connections
+	Established bool `json:"established,omitempty"`
+
+	// zones for policies
+	FromZone string `json:"from_zone,omitempty"`
+	ToZone   string `json:"to_zone,omitempty"`
+}
+
+// NAT is an address translation, fields other than Type and To match the
+// original packet.
+type NAT struct {
+	Type     string `json:"type"`
+	Protocol string `json:"protocol,omitempty"`
+	Src      string `json:"src,omitempty"`
+	Dst      string `json:"dst,omitempty"`
+	DstPort  string `json:"dport,omitempty"`
+
+	// translated address, or range of addresses, and port
+	To     string `json:"to,omitempty"`
+	ToPort string `json:"to_port,omitempty"`
+
+	// Interfaces are the indices of the edges that the translation applies
+	// to, outgoing for source NAT and incoming otherwise, set by AddSecurity
+	// from Interface, ToZone, or FromZone. Translations without any of
+	// those apply to the edges with the outside NAT role.
+	Interfaces []int `json:"interfaces,omitempty"`
+
+	Interface string `json:"-"`
+	FromZone  string `json:"from_zone,omitempty"`
+	ToZone    string `json:"to_zone,omitempty"`
+
+	// ACL whose permit entries are the sources, expanded by AddSecurity
+	ACL string `json:"-"`
+}
+
+func (r Rule) String() string {
+	res := []string{r.Action}
+
+	add := func(k, v string) {
+		if v != "" {
+			res = append(res, k, v)
+		}
+	}
+
+	add("from-zone", r.FromZone)
+	add("to-zone", r.ToZone)
+	add("protocol", r.Protocol)
+	add("src", r.Src)
+	add("sport", r.SrcPort)
+	add("dst", r.Dst)
+	add("dport", r.DstPort)
+
+	if r.Established {
+		res = append(res, "established")
+	}
+
+	return strings.Join(res, " ")
+}
+
+func (n NAT) String() string {
+	res := []string{n.Type}
+
+	add := func(k, v string) {
+		if v != "" {
+			res = append(res, k, v)
+		}
+	}
+
+	add("interface", n.Interface)
+	add("from-zone", n.FromZone)
+	add("to-zone", n.ToZone)
+	add("protocol", n.Protocol)
+	add("list", n.ACL)
+	add("src", n.Src)
+	add("dst", n.Dst)
+	add("dport", n.DstPort)
+
+	to := n.To
+	if to == "" {
+		to = "interface"
+	}
+	add("to", to)
+	add("to-port", n.ToPort)
+
+	return strings.Join(res, " ")
+}
+
+func NewSecurity() *Security {
+	return &Security{
+		ACLs:    map[string][]Rule{},
+		ACLIn:   map[string]string{},
+		ACLOut:  map[string]string{},
+		NATRole: map[string]string{},
+	}
+}
+
+// ports are the well-known port names used in Cisco and Arista ACLs
+var ports = map[string]int{
+	"bgp":      179,
+	"bootpc":   68,
+	"bootps":   67,
+	"domain":   53,
+	"ftp":      21,
+	"ftp-data": 20,
+	"http":     80,
+	"https":    443,
+	"imap":     143,
+	"isakmp":   500,
+	"ldap":     389,
+	"ntp":      123,
+	"pop3":     110,
+	"smtp":     25,
+	"snmp":     161,
+	"snmptrap": 162,
+	"ssh":      22,
+	"syslog":   514,
+	"telnet":   23,
+	"tftp":     69,
+	"www":      80,
+}
+
+func parsePort(s string) (int, bool) {
+	if v, ok := ports[s]; ok {
+		return v, true
+	}
+
+	v, err := strconv.Atoi(s)
+	return v, err == nil && v >= 0 && v <= 65535
+}
+
+// parseACEAddress parses an address from the start of fields: any, host IP,
+// IP and wildcard mask, or prefix. Returns the prefix, empty for any, and
+// the remaining fields.
+func parseACEAddress(fields []string) (string, []string, bool) {
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+
+	switch fields[0] {
+	case "any":
+		return "", fields[1:], true
+	case "host":
+		if len(fields) < 2 || net.ParseIP(fields[1]) == nil {
+			return "", nil, false
+		}
+		return fields[1] + "/32", fields[2:], true
+	}
+
+	if ipnet, rest := parsePrefix(fields, true); ipnet != nil {
+		return ipnet.String(), rest, true
+	}
+
+	if ip := net.ParseIP(fields[0]).To4(); ip != nil {
+		// standard ACLs may omit the wildcard for hosts
+		return ip.String() + "/32", fields[1:], true
+	}
+
+	return "", nil, false
+}
+
+// parseACEPort parses a port match from the start of fields: eq, lt, gt, or
+// range. Returns the port or range, empty if there is no port match, and the
+// remaining fields.
+func parseACEPort(fields []string) (string, []string, bool) {
+	if len(fields) < 2 {
+		return "", fields, true
+	}
+
+	switch fields[0] {
+	case "eq":
+		if v, ok := parsePort(fields[1]); ok {
+			if len(fields) > 2 {
+				if _, ok := parsePort(fields[2]); ok {
+					// multiple ports aren't supported
+					return "", nil, false
+				}
+			}
+
+			return strconv.Itoa(v), fields[2:], true
+		}
+	case "lt":
+		if v, ok := parsePort(fields[1]); ok && v > 0 {
+			return fmt.Sprintf("0:%v", v-1), fields[2:], true
+		}
+	case "gt":
+		if v, ok := parsePort(fields[1]); ok && v < 65535 {
+			return fmt.Sprintf("%v:65535", v+1), fields[2:], true
+		}
+	case "range":
+		if len(fields) < 3 {
+			return "", nil, false
+		}
+
+		lo, ok := parsePort(fields[1])
+		hi, ok2 := parsePort(fields[2])
+		if ok && ok2 {
+			return fmt.Sprintf("%v:%v", lo, hi), fields[3:], true
+		}
+	case "neq":
+		return "", nil, false
+	default:
+		return "", fields, true
+	}
+
+	return "", nil, false
+}
+
+// ParseACE parses a Cisco or Arista access list entry, fields start with the
+// action and omit the sequence number. Standard ACEs only have a source.
+// Returns false for remarks and entries that can't be represented, such as
+// ones that match multiple ports. See parseACEQualifiers for what follows the
+// addresses and ports.
+func ParseACE(fields []string, standard bool) (Rule, bool) {
+	var r Rule
+
+	if len(fields) < 2 || (fields[0] != ACTION_PERMIT && fields[0] != ACTION_DENY) {
+		return r, false
+	}
+
+	r.Action = fields[0]
+	fields = fields[1:]
+
+	var ok bool
+
+	if standard {
+		if r.Src, fields, ok = parseACEAddress(fields); !ok {
+			return r, false
+		}
+
+		return r, parseACEQualifiers(&r, fields)
+	}
+
+	switch proto := fields[0]; proto {
+	case "ip":
+	case "tcp", "udp", "icmp", "gre", "esp", "ahp", "ospf", "pim", "sctp":
+		r.Protocol = proto
+		if proto == "ahp" {
+			r.Protocol = "ah"
+		}
+	default:
+		if _, err := strconv.Atoi(proto); err != nil {
+			return r, false
+		}
+		r.Protocol = proto
+	}
+	fields = fields[1:]
+
+	ports := r.Protocol == "tcp" || r.Protocol == "udp"
+
+	if r.Src, fields, ok = parseACEAddress(fields); !ok {
+		return r, false
+	}
+
+	if ports {
+		if r.SrcPort, fields, ok = parseACEPort(fields); !ok {
+			return r, false
+		}
+	}
+
+	if r.Dst, fields, ok = parseACEAddress(fields); !ok {
+		return r, false
+	}
+
+	if ports {
+		if r.DstPort, fields, ok = parseACEPort(fields); !ok {
+			return r, false
+		}
+	}
+
+	return r, parseACEQualifiers(&r, fields)
+}
+
+// parseACEQualifiers handles the fields after the addresses and ports.
+// Logging doesn't change what matches and established is kept in the rule.
+// Anything else, such as ICMP types or TCP flags, narrows the entry so
+// dropping it would widen a permit: those entries are rejected. Denies are
+// kept without the qualifier since denying more is the safer mistake.
+func parseACEQualifiers(r *Rule, fields []string) bool {
+	var unknown []string
+
+	for _, f := range fields {
+		switch f {
+		case "log", "log-input":
+		case "established":
+			if r.Protocol != "tcp" {
+				unknown = append(unknown, f)
+				continue
+			}
+			r.Established = true
+		default:
+			unknown = append(unknown, f)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return true
+	}
+
+	if r.Action == ACTION_PERMIT {
+		return false
+	}
+
+	log.Warn("ignoring %v in access list entry, denying more than configured", strings.Join(unknown, " "))
+	return true
+}
+
+// AddACE parses an access list entry and appends it to the named ACL
+func (s *Security) AddACE(name string, fields []string, standard bool) {
+	r, ok := ParseACE(fields, standard)
+	if !ok {
+		if len(fields) > 0 && fields[0] != "remark" {
+			log.Warn("ignoring access list entry in %v: %v", name, strings.Join(fields, " "))
+		}
+		return
+	}
+
+	log.Debug("got access list entry in %v: %+v", name, r)
+	s.ACLs[name] = append(s.ACLs[name], r)
+}
+
+// AddAccessGroup applies the named ACL to an interface, dir is in or out
+func (s *Security) AddAccessGroup(iface, name, dir string) {
+	log.Debug("got access group %v %v on %v", name, dir, iface)
+
+	switch dir {
+	case "in":
+		s.ACLIn[strings.ToLower(iface)] = name
+	case "out":
+		s.ACLOut[strings.ToLower(iface)] = name
+	}
+}
+
+// AddNAT appends a translation
+func (s *Security) AddNAT(n NAT) {
+	log.Debug("got nat: %+v", n)
+	s.NAT = append(s.NAT, n)
+}
+
+// addressRange converts a prefix to an address or range of addresses, as
+// used for NAT pools
+func addressRange(s string) string {
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return s
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	if ones == bits || ip.To4() == nil {
+		return ip.String()
+	}
+
+	first := binary.BigEndian.Uint32(ipnet.IP.To4())
+	last := first | ^binary.BigEndian.Uint32(net.IP(ipnet.Mask).To4())
+
+	var lo, hi [4]byte
+	binary.BigEndian.PutUint32(lo[:], first)
+	binary.BigEndian.PutUint32(hi[:], last)
+
+	return fmt.Sprintf("%v-%v", net.IP(lo[:]), net.IP(hi[:]))
+}
+
+// AddSecurity annotates the router's edges with their ACLs and NAT roles and
+// the router with its ACLs, security policies, and NAT rules.
+func AddSecurity(dc *discovery.Client, ID int, s *Security) error {
+	if len(s.ACLs) == 0 && len(s.Policies) == 0 && len(s.NAT) == 0 {
+		return nil
+	}
+
+	res, err := dc.LookupEndpoints("nid", strconv.Itoa(ID))
+	if err != nil {
+		return err
+	}
+	if len(res) != 1 {
+		return fmt.Errorf("no such endpoint: %v", ID)
+	}
+	e := res[0]
+
+	for _, edge := range e.Edges {
+		iface := strings.ToLower(edge.D["interface"])
+
+		if v, ok := s.ACLIn[iface]; ok {
+			edge.D["acl_in"] = v
+		}
+		if v, ok := s.ACLOut[iface]; ok {
+			edge.D["acl_out"] = v
+		}
+		if v, ok := s.NATRole[iface]; ok {
+			edge.D["nat"] = v
+		}
+	}
+
+	// only store the ACLs that are used, skipping ones that are used for
+	// other things like NAT and route maps
+	acls := map[string][]Rule{}
+	for _, m := range []map[string]string{s.ACLIn, s.ACLOut} {
+		for iface, name := range m {
+			if _, ok := s.ACLs[name]; !ok {
+				log.Warn("undefined access list %v on %v", name, iface)
+				continue
+			}
+
+			acls[name] = s.ACLs[name]
+		}
+	}
+
+	var nats []NAT
+
+	for _, n := range s.NAT {
+		for i, edge := range e.Edges {
+			var ok bool
+
+			switch {
+			case n.Interface != "":
+				ok = strings.EqualFold(edge.D["interface"], n.Interface)
+			case n.Type == NAT_SOURCE && n.ToZone != "":
+				ok = edge.D["zone"] == n.ToZone
+			case n.Type != NAT_SOURCE && n.FromZone != "":
+				ok = edge.D["zone"] == n.FromZone
+			default:
+				ok = edge.D["nat"] == "outside"
+			}
+
+			if ok {
+				n.Interfaces = append(n.Interfaces, i)
+			}
+		}
+
+		if n.ACL == "" {
+			nats = append(nats, n)
+			continue
+		}
+
+		if _, ok := s.ACLs[n.ACL]; !ok {
+			log.Warn("undefined access list %v for nat", n.ACL)
+			continue
+		}
+
+		// one translation for each source that the ACL permits
+		for _, r := range s.ACLs[n.ACL] {
+			if r.Action == ACTION_PERMIT {
+				n2 := n
+				n2.Src = r.Src
+				nats = append(nats, n2)
+			}
+		}
+	}
+
+	if len(acls) > 0 {
+		b, err := json.Marshal(acls)
+		if err != nil {
+			return err
+		}
+		e.D["acls"] = string(b)
+	}
+
+	if len(s.Policies) > 0 {
+		b, err := json.Marshal(s.Policies)
+		if err != nil {
+			return err
+		}
+		e.D["policies"] = string(b)
+	}
+
+	if len(nats) > 0 {
+		b, err := json.Marshal(nats)
+		if err != nil {
+			return err
+		}
+		e.D["nat"] = string(b)
+	}
+
+	_, err = dc.UpdateEndpoints(e)
+	return err
+}
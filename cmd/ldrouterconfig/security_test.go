@@ -0,0 +1,284 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseACE(t *testing.T) {
+	tests := []struct {
+		ace      string
+		standard bool
+		want     Rule
+		ok       bool
+	}{
+		{"permit 10.0.0.0 0.255.255.255", true, Rule{Action: "permit", Src: "10.0.0.0/8"}, true},
+		{"deny host 10.1.1.1 log", true, Rule{Action: "deny", Src: "10.1.1.1/32"}, true},
+		{"permit any", true, Rule{Action: "permit"}, true},
+		{"permit ip any any", false, Rule{Action: "permit"}, true},
+		{"permit tcp any host 10.0.0.5 eq www", false, Rule{Action: "permit", Protocol: "tcp", Dst: "10.0.0.5/32", DstPort: "80"}, true},
+		{"permit udp 10.0.0.0/24 range 1024 2048 any eq 53 log", false, Rule{Action: "permit", Protocol: "udp", Src: "10.0.0.0/24", SrcPort: "1024:2048", DstPort: "53"}, true},
+		{"deny tcp any any gt 1023", false, Rule{Action: "deny", Protocol: "tcp", DstPort: "1024:65535"}, true},
+		{"permit ahp any any", false, Rule{Action: "permit", Protocol: "ah"}, true},
+		{"permit 47 any any", false, Rule{Action: "permit", Protocol: "47"}, true},
+		// established is kept rather than widening the permit
+		{"permit tcp any any established", false, Rule{Action: "permit", Protocol: "tcp", Established: true}, true},
+		{"permit tcp any 10.0.0.0 0.0.0.255 established log", false, Rule{Action: "permit", Protocol: "tcp", Dst: "10.0.0.0/24", Established: true}, true},
+		// permits that would be widened are rejected
+		{"permit icmp any any echo-reply", false, Rule{}, false},
+		{"permit tcp any any eq 22 syn", false, Rule{}, false},
+		{"permit udp any any established", false, Rule{}, false},
+		{"permit ip any any fragments", false, Rule{}, false},
+		// denies that would be widened deny more
+		{"deny icmp any any echo", false, Rule{Action: "deny", Protocol: "icmp"}, true},
+		{"deny tcp any any eq 80 ack", false, Rule{Action: "deny", Protocol: "tcp", DstPort: "80"}, true},
+		// not representable
+		{"permit tcp any any eq 80 443", false, Rule{}, false},
+		{"permit tcp any any neq 80", false, Rule{}, false},
+		{"permit foo any any", false, Rule{}, false},
+		{"remark web servers", false, Rule{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseACE(strings.Fields(test.ace), test.standard)
+		if ok != test.ok {
+			t.Errorf("%q: got ok = %v, want %v", test.ace, ok, test.ok)
+			continue
+		}
+
+		if ok && got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.ace, got, test.want)
+		}
+	}
+}
+
+// strs converts the rules or NAT translations to strings for comparison
+func strs[T fmt.Stringer](vs []T) []string {
+	var res []string
+	for _, v := range vs {
+		res = append(res, v.String())
+	}
+
+	return res
+}
+
+func TestParseSecurity(t *testing.T) {
+	tests := []struct {
+		name, parser, config string
+
+		acls     map[string][]string
+		policies []string
+		nat      []string
+
+		// by lowercase interface name
+		aclIn, aclOut, natRole map[string]string
+	}{
+		{"cisco", "cisco", `
+hostname edge1
+!
+interface GigabitEthernet0/0
+ description uplink
+ ip address 203.0.113.2 255.255.255.0
+ ip access-group OUTSIDE-IN in
+ ip nat outside
+!
+interface GigabitEthernet0/1
+ description lan
+ ip address 10.1.0.1 255.255.255.0
+ ip access-group 101 out
+ ip nat inside
+!
+ip nat pool PUB 203.0.113.10 203.0.113.20 netmask 255.255.255.0
+ip nat inside source list NAT-LAN interface GigabitEthernet0/0 overload
+ip nat inside source list 10 pool PUB
+ip nat inside source static 10.1.0.5 203.0.113.5
+ip nat inside source static tcp 10.1.0.6 www interface GigabitEthernet0/0 8080
+!
+ip access-list standard NAT-LAN
+ 10 permit 10.1.0.0 0.0.0.255
+ 20 deny any
+ip access-list extended OUTSIDE-IN
+ remark allow web
+ 10 permit tcp any host 203.0.113.5 eq www
+ 20 permit tcp any host 203.0.113.5 range 8000 8080
+ 30 permit udp any eq domain any gt 1023
+ 40 permit icmp any any
+ 50 permit tcp any any established
+ 60 deny ip any any log
+access-list 10 permit 10.1.0.128 0.0.0.127
+access-list 101 permit tcp 10.0.0.0 0.255.255.255 any eq 22
+access-list 101 deny ip any any
+ip route 0.0.0.0 0.0.0.0 203.0.113.1
+`,
+			map[string][]string{
+				"OUTSIDE-IN": {
+					"permit protocol tcp dst 203.0.113.5/32 dport 80",
+					"permit protocol tcp dst 203.0.113.5/32 dport 8000:8080",
+					"permit protocol udp sport 53 dport 1024:65535",
+					"permit protocol icmp",
+					"permit protocol tcp established",
+					"deny",
+				},
+				"NAT-LAN": {"permit src 10.1.0.0/24", "deny"},
+				"10":      {"permit src 10.1.0.128/25"},
+				"101":     {"permit protocol tcp src 10.0.0.0/8 dport 22", "deny"},
+			},
+			nil,
+			[]string{
+				"source interface GigabitEthernet0/0 list NAT-LAN to interface",
+				"source list 10 to 203.0.113.10-203.0.113.20",
+				"static src 10.1.0.5 to 203.0.113.5",
+				"destination interface GigabitEthernet0/0 protocol tcp dport 8080 to 10.1.0.6 to-port 80",
+			},
+			map[string]string{"gigabitethernet0/0": "OUTSIDE-IN"},
+			map[string]string{"gigabitethernet0/1": "101"},
+			map[string]string{"gigabitethernet0/0": "outside", "gigabitethernet0/1": "inside"},
+		},
+		{"arista", "arista", `
+! device: edge2 (DCS-7050, EOS-4.20)
+hostname edge2
+!
+interface Ethernet1
+   description wan
+   ip address 198.51.100.2/24
+   ip access-group EDGE in
+   ip nat source dynamic access-list INSIDE overload
+   ip nat destination static 198.51.100.5 443 10.2.0.5 8443 protocol tcp
+!
+interface Ethernet2
+   description lan
+   ip address 10.2.0.1/24
+!
+ip access-list EDGE
+   10 permit tcp any host 198.51.100.5 eq https
+   20 deny ip any any
+ip access-list standard INSIDE
+   10 permit 10.2.0.0/24
+`,
+			map[string][]string{
+				"EDGE":   {"permit protocol tcp dst 198.51.100.5/32 dport 443", "deny"},
+				"INSIDE": {"permit src 10.2.0.0/24"},
+			},
+			nil,
+			[]string{
+				"source interface Ethernet1 list INSIDE to interface",
+				"destination interface Ethernet1 protocol tcp dst 198.51.100.5 dport 443 to 10.2.0.5 to-port 8443",
+			},
+			map[string]string{"ethernet1": "EDGE"},
+			map[string]string{},
+			map[string]string{"ethernet1": "outside"},
+		},
+		{"juniper srx", "juniper", `
+set version 18.4
+set system host-name srx1
+set interfaces ge-0/0/0 unit 0 family inet address 192.0.2.2/24
+set interfaces ge-0/0/1 unit 0 family inet address 10.3.0.1/24
+set interfaces ge-0/0/1 unit 0 family inet filter input LAN-IN
+set interfaces ge-0/0/2 unit 0 family inet address 10.4.0.1/24
+set firewall family inet filter LAN-IN term ssh from source-address 10.3.0.0/24
+set firewall family inet filter LAN-IN term ssh from protocol tcp
+set firewall family inet filter LAN-IN term ssh from destination-port ssh
+set firewall family inet filter LAN-IN term ssh then accept
+set firewall family inet filter LAN-IN term web from protocol [ tcp udp ]
+set firewall family inet filter LAN-IN term web from destination-port 1024-2048
+set firewall family inet filter LAN-IN term web then accept
+set firewall family inet filter LAN-IN term rest then discard
+set security address-book global address web1 10.4.0.5/32
+set security address-book global address web2 10.4.0.6/32
+set security address-book global address-set webs address web1
+set security address-book global address-set webs address web2
+set security zones security-zone untrust interfaces ge-0/0/0.0 host-inbound-traffic system-services ping
+set security zones security-zone trust interfaces ge-0/0/1.0
+set security zones security-zone dmz interfaces ge-0/0/2
+set applications application tcp-8080 protocol tcp destination-port 8080
+set applications application-set web-apps application junos-http
+set applications application-set web-apps application tcp-8080
+set security policies from-zone trust to-zone untrust policy out match source-address any destination-address any application any
+set security policies from-zone trust to-zone untrust policy out then permit
+set security policies from-zone untrust to-zone dmz policy web match source-address any destination-address webs application web-apps
+set security policies from-zone untrust to-zone dmz policy web then permit
+set security policies global policy deny-all match source-address any destination-address any application any from-zone any to-zone any
+set security policies global policy deny-all then deny
+set security nat source pool pub address 192.0.2.10/32 to 192.0.2.20/32
+set security nat source rule-set out from zone trust
+set security nat source rule-set out to zone untrust
+set security nat source rule-set out rule r1 match source-address 10.3.0.0/24
+set security nat source rule-set out rule r1 then source-nat interface
+set security nat source rule-set out rule r2 match source-address 10.4.0.0/24
+set security nat source rule-set out rule r2 then source-nat pool pub
+set security nat destination pool web address 10.4.0.5/32 port 80
+set security nat destination rule-set in from zone untrust
+set security nat destination rule-set in rule r1 match destination-address 192.0.2.5/32
+set security nat destination rule-set in rule r1 match destination-port 80
+set security nat destination rule-set in rule r1 then destination-nat pool web
+set security nat static rule-set st from zone untrust
+set security nat static rule-set st rule s1 match destination-address 192.0.2.6/32
+set security nat static rule-set st rule s1 then static-nat prefix 10.4.0.6/32
+`,
+			map[string][]string{
+				"LAN-IN": {
+					"permit protocol tcp src 10.3.0.0/24 dport 22",
+					"permit protocol tcp dport 1024:2048",
+					"permit protocol udp dport 1024:2048",
+					"deny",
+				},
+			},
+			[]string{
+				"permit from-zone trust to-zone untrust",
+				"permit from-zone untrust to-zone dmz protocol tcp dst 10.4.0.5/32 dport 80",
+				"permit from-zone untrust to-zone dmz protocol tcp dst 10.4.0.6/32 dport 80",
+				"permit from-zone untrust to-zone dmz protocol tcp dst 10.4.0.5/32 dport 8080",
+				"permit from-zone untrust to-zone dmz protocol tcp dst 10.4.0.6/32 dport 8080",
+				"deny",
+			},
+			[]string{
+				"source to-zone untrust src 10.3.0.0/24 to interface",
+				"source to-zone untrust src 10.4.0.0/24 to 192.0.2.10-192.0.2.20",
+				"destination from-zone untrust protocol tcp dst 192.0.2.5/32 dport 80 to 10.4.0.5 to-port 80",
+				"destination from-zone untrust protocol udp dst 192.0.2.5/32 dport 80 to 10.4.0.5 to-port 80",
+				"static from-zone untrust src 10.4.0.6/32 to 192.0.2.6/32",
+			},
+			map[string]string{"ge-0/0/1.0": "LAN-IN"},
+			map[string]string{},
+			map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		d, err := parsers[test.parser].Parse(strings.NewReader(test.config))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		s := d.Security
+
+		acls := map[string][]string{}
+		for k, v := range s.ACLs {
+			acls[k] = strs(v)
+		}
+
+		if !reflect.DeepEqual(acls, test.acls) {
+			t.Errorf("%v: got acls %q, want %q", test.name, acls, test.acls)
+		}
+		if got := strs(s.Policies); !reflect.DeepEqual(got, test.policies) {
+			t.Errorf("%v: got policies %q, want %q", test.name, got, test.policies)
+		}
+		if got := strs(s.NAT); !reflect.DeepEqual(got, test.nat) {
+			t.Errorf("%v: got nat %q, want %q", test.name, got, test.nat)
+		}
+
+		if !reflect.DeepEqual(s.ACLIn, test.aclIn) || !reflect.DeepEqual(s.ACLOut, test.aclOut) {
+			t.Errorf("%v: got acls in %v out %v, want in %v out %v", test.name, s.ACLIn, s.ACLOut, test.aclIn, test.aclOut)
+		}
+		if !reflect.DeepEqual(s.NATRole, test.natRole) {
+			t.Errorf("%v: got nat roles %v, want %v", test.name, s.NATRole, test.natRole)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// VLAN modes for switch ports and sub-interfaces, stored in the edge's
+// vlan_mode field. Sub-interfaces are tagged so they're trunks.
+const (
+	VLAN_ACCESS = "access"
+	VLAN_TRUNK  = "trunk"
+)
+
+// MAX_TRUNK_VLANS limits how many VLANs a trunk can be expanded to since each
+// one becomes an edge. Trunks that allow more, such as 1-4094, are treated as
+// allowing all VLANs.
+const MAX_TRUNK_VLANS = 256
+
+// expandVLANs expands a comma-separated list of VLANs and VLAN ranges, such as
+// 10,20-22. Returns nil if the list allows too many VLANs or isn't a list,
+// such as all or none.
+func expandVLANs(s string) []string {
+	var res []string
+
+	for _, v := range strings.Split(s, ",") {
+		lo, hi := v, v
+		if i := strings.Index(v, "-"); i > 0 {
+			lo, hi = v[:i], v[i+1:]
+		}
+
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			log.Debug("not a vlan list: %v", s)
+			return nil
+		}
+
+		end, err := strconv.Atoi(hi)
+		if err != nil || end < start {
+			log.Debug("not a vlan list: %v", s)
+			return nil
+		}
+
+		for i := start; i <= end; i++ {
+			res = append(res, strconv.Itoa(i))
+
+			if len(res) > MAX_TRUNK_VLANS {
+				log.Warn("too many vlans, ignoring: %v", s)
+				return nil
+			}
+		}
+	}
+
+	return res
+}
+
+// vlanInterface returns the VLAN of a VLAN interface such as Vlan10, empty if
+// the interface isn't one
+func vlanInterface(name string) string {
+	if !strings.HasPrefix(strings.ToLower(name), "vlan") {
+		return ""
+	}
+
+	v := strings.TrimSpace(name[4:])
+	if _, err := strconv.Atoi(v); err != nil {
+		return ""
+	}
+
+	return v
+}
+
+// Switchport updates the interface from a Cisco or Arista switchport command,
+// fields are the fields after switchport.
+func (iface *Interface) Switchport(fields []string) {
+	switch {
+	case len(fields) == 2 && fields[0] == "mode":
+		if fields[1] == VLAN_ACCESS || fields[1] == VLAN_TRUNK {
+			iface.Mode = fields[1]
+		}
+	case len(fields) == 3 && fields[0] == "access" && fields[1] == "vlan":
+		iface.VLAN = fields[2]
+		if iface.Mode == "" {
+			iface.Mode = VLAN_ACCESS
+		}
+		log.Debug("got access vlan %v on %v", iface.VLAN, iface.Name)
+	case len(fields) >= 4 && fields[0] == "trunk" && fields[1] == "allowed" && fields[2] == "vlan":
+		switch fields[3] {
+		case "add":
+			if len(fields) == 5 {
+				iface.Allowed = append(iface.Allowed, expandVLANs(fields[4])...)
+			}
+		case "remove":
+			if len(fields) != 5 {
+				break
+			}
+
+			remove := map[string]bool{}
+			for _, v := range expandVLANs(fields[4]) {
+				remove[v] = true
+			}
+
+			var allowed []string
+			for _, v := range iface.Allowed {
+				if !remove[v] {
+					allowed = append(allowed, v)
+				}
+			}
+			iface.Allowed = allowed
+		default:
+			// all, none, except, or a list
+			iface.Allowed = expandVLANs(fields[3])
+		}
+		log.Debug("got trunk vlans %v on %v", iface.Allowed, iface.Name)
+	}
+}
+
+// Encapsulation updates a sub-interface from a Cisco (dot1Q 20) or Arista
+// (dot1q vlan 20) encapsulation command, fields are the fields after
+// encapsulation.
+func (iface *Interface) Encapsulation(fields []string) {
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "dot1q") {
+		return
+	}
+
+	vlan := fields[1]
+	if vlan == "vlan" && len(fields) > 2 {
+		vlan = fields[2]
+	}
+
+	if _, err := strconv.Atoi(vlan); err != nil {
+		return
+	}
+
+	iface.VLAN = vlan
+	iface.Mode = VLAN_TRUNK
+	if fields[len(fields)-1] == "native" {
+		// native VLAN is untagged
+		iface.Mode = VLAN_ACCESS
+	}
+	log.Debug("got sub-interface vlan %v on %v", iface.VLAN, iface.Name)
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+func init() {
+	// also handles EdgeOS, which shares the configuration syntax
+	Register(&Parser{
+		Name:  "vyos",
+		Parse: parseVyOS,
+		Signatures: signatures(
+			`(vyos|vyatta)-config-version`,
+			`(?m)^set interfaces (ethernet|loopback|bonding|bridge|pppoe|switch|wireguard) `,
+			`(?m)^interfaces \{\s*\n\s+(ethernet|loopback|bonding|bridge) (eth|lo|bond|br)\S* \{`,
+			`(?m)^\s+vif \d+ \{`,
+		),
+		Priority: 40,
+	})
+}
+
+// vyosPaths converts a config in either the config.boot format or the set
+// commands format (show configuration commands) to a list of paths such as
+// [interfaces ethernet eth0 address 10.0.0.1/24]
+func vyosPaths(f io.Reader) ([][]string, error) {
+	var res [][]string
+
+	// words for each level of braces
+	var stack [][]string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "/*") || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitQuoted(line, `"'`)
+
+		switch {
+		case fields[0] == "set":
+			res = append(res, fields[1:])
+		case fields[len(fields)-1] == "{":
+			stack = append(stack, fields[:len(fields)-1])
+		case fields[0] == "}":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case len(stack) > 0:
+			var path []string
+			for _, v := range stack {
+				path = append(path, v...)
+			}
+
+			res = append(res, append(path, fields...))
+		}
+	}
+
+	return res, scanner.Err()
+}
+
+func parseVyOS(f io.Reader) (*Device, error) {
+	d := NewDevice("vyos")
+
+	paths, err := vyosPaths(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces interfaceTable
+
+	zones := map[string]string{}
+
+	for _, p := range paths {
+		log.Debug("processing path: %v", p)
+
+		switch {
+		case len(p) == 3 && p[0] == "system" && p[1] == "host-name":
+			d.Name = p[2]
+		case len(p) >= 5 && p[0] == "interfaces":
+			// interfaces TYPE NAME [vif VLAN] ATTR VALUE, QinQ has vif-s and
+			// vif-c instead of vif
+			name, rest := p[2], p[3:]
+
+			var vlan string
+			for len(rest) > 2 && (rest[0] == "vif" || rest[0] == "vif-s" || rest[0] == "vif-c") {
+				name += "." + rest[1]
+				vlan = rest[1]
+				rest = rest[2:]
+			}
+
+			if len(rest) != 2 || (rest[0] != "address" && rest[0] != "description") {
+				continue
+			}
+
+			iface := interfaces.Get(name)
+			if vlan != "" {
+				iface.VLAN, iface.Mode = vlan, VLAN_TRUNK
+			}
+
+			if rest[0] == "address" {
+				// may also be dhcp or dhcpv6
+				iface.AddAddress(rest[1])
+			} else {
+				iface.Desc = rest[1]
+			}
+		case len(p) == 5 && p[0] == "zone-policy" && p[1] == "zone" && p[3] == "interface":
+			// EdgeOS and VyOS 1.3
+			zones[p[4]] = p[2]
+		case len(p) == 6 && p[0] == "firewall" && p[1] == "zone" && p[3] == "member" && p[4] == "interface":
+			// VyOS 1.4+
+			zones[p[5]] = p[2]
+		}
+	}
+
+	for name, zone := range zones {
+		interfaces.SetZone(name, zone)
+	}
+
+	interfaces.AddTo(d)
+
+	return d, nil
+}
@@ -19,9 +19,12 @@ import (
 
 var (
 	f_templatePath = flag.String("path", "templates", "template path")
-	f_server       = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server       = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	f_output       = flag.String("w", "minemiter.mm", "output file")
 	dc             *discovery.Client
+
+	// networks by ID, for network and netspec template functions
+	networks map[int]*minigraph.Network
 )
 
 const (
@@ -43,18 +46,23 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	networks, err := dc.GetNetworks("", "")
+	ns, err := dc.GetNetworks("", "")
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	networks = make(map[int]*minigraph.Network)
+	for _, n := range ns {
+		networks[n.ID()] = n
+	}
+
 	endpoints, err := dc.GetEndpoints("", "")
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	var nodes []minigraph.Node
-	for _, n := range networks {
+	for _, n := range ns {
 		nodes = append(nodes, n)
 	}
 	for _, e := range endpoints {
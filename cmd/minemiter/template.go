@@ -64,6 +64,33 @@ func isNetwork(n minigraph.Node) bool {
 	return n.Type() == minigraph.TYPE_NETWORK
 }
 
+// network returns the network with the given ID or an empty network if there
+// is no such network so that templates can safely check fields.
+func network(id int) *minigraph.Network {
+	if n, ok := networks[id]; ok && n.D != nil {
+		return n
+	}
+
+	return &minigraph.Network{NID: id, D: map[string]string{}}
+}
+
+// netspec returns the name that minimega should use for the network with the
+// given ID: the network's name or network-<ID> if it doesn't have one. The
+// VLAN is only added to the synthetic name since networks from different
+// collectors may reuse a VLAN without being the same segment and bare numbers
+// would clash with the VLANs that minimega assigns to aliases.
+func netspec(id int) string {
+	n := network(id)
+	if n.D["name"] != "" {
+		return n.D["name"]
+	}
+	if n.D["vlan"] != "" {
+		return fmt.Sprintf("network-%v-vlan-%v", id, n.D["vlan"])
+	}
+
+	return fmt.Sprintf("network-%v", id)
+}
+
 func setData(n minigraph.Node, key, value string) string {
 	if !isEndpoint(n) {
 		return ""
@@ -150,6 +177,8 @@ func init() {
 		"once":          once,
 		"isEndpoint":    isEndpoint,
 		"isNetwork":     isNetwork,
+		"network":       network,
+		"netspec":       netspec,
 		"set":           set,
 		"get":           get,
 		"setData":       setData,
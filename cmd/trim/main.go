@@ -10,7 +10,6 @@ import (
 	"os"
 	"sort"
 	"strconv"
-	"strings"
 
 	"github.com/sandia-minimega/discovery/v2/pkg/discovery"
 	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
@@ -18,14 +17,14 @@ import (
 )
 
 var (
-	f_server      = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service")
+	f_server      = flag.String("server", fmt.Sprintf("localhost:%v", discovery.Port), "web service, host:port[/model] to use a named model")
 	f_unconnected = flag.Bool("unconnected", false, "trim nodes that are not connected to other nodes")
 	f_delete      = flag.Bool("delete", false, "trim nodes by deleting them (default is to mark them trimmed=true)")
 	f_size        = flag.Int("size", -1, "trim until -size nodes are left")
 	f_root        = flag.Int("root", -1, "root node ID for walks")
 	f_trimmed     = flag.Bool("trimmed", false, "trim trimmed=true endpoints (use with -delete)")
 	f_clear       = flag.Bool("clear", false, "clear trimmed flags on endpoints")
-	f_search      = flag.String("q", "", "trim nodes matching term or key=value")
+	f_search      = flag.String("q", "", "trim endpoints matching a query, such as: os=linux AND NOT has(uuid)")
 )
 
 type Client struct {
@@ -86,78 +85,17 @@ func (c Client) getEndpoints(args ...string) map[int]*minigraph.Endpoint {
 	return res
 }
 
-// getNetworks wraps discovery.Client.GetNetworks and turns the result into a
-// map based on network ID instead of a slice
-func (c Client) getNetworks() map[int]*minigraph.Network {
-	networks, err := c.GetNetworks("", "")
+// connected returns a map of the nodes that are connected to the specified
+// node, including the node itself.
+func (c Client) connected(id int) map[int]bool {
+	nids, err := c.Component(id)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	res := map[int]*minigraph.Network{}
-	for _, n := range networks {
-		res[n.ID()] = n
-	}
-
-	return res
-}
-
-// connected walks the graph from the specified node and returns a map of nodes
-// that are connected to it.
-func (c Client) connected(id int) map[int]bool {
-	networks := c.getNetworks()
-
-	// list of networks to process and networks that have been visited
-	working := []int{}
-	visited := map[int]bool{}
-
-	// mapping of endpoint -> list of networks
-	reverse := map[int][]int{}
-
-	// find the initial starting point
-	for _, n := range networks {
-		for _, v := range n.Endpoints {
-			if v == id {
-				log.Debug("inital network: %v", n.ID())
-				working = append(working, n.ID())
-				visited[n.ID()] = true
-			}
-
-			reverse[v] = append(reverse[v], n.ID())
-		}
-	}
-
-	// expand visited until we run out of new networks to visit
-	for len(working) > 0 {
-		next := []int{}
-
-		for _, n := range working {
-			log.Debug("visiting %v", n)
-
-			for _, e := range networks[n].Endpoints {
-				for _, n2 := range reverse[e] {
-					if !visited[n2] {
-						visited[n2] = true
-						next = append(next, n2)
-					}
-				}
-			}
-		}
-
-		working = next
-	}
-
-	// build result -- visited map for endpoints
 	res := map[int]bool{}
-
-	// go through and mark endpoints as visited
-	for e, networks := range reverse {
-		for _, n := range networks {
-			if visited[n] {
-				res[e] = true
-				break
-			}
-		}
+	for _, v := range nids {
+		res[v] = true
 	}
 
 	return res
@@ -256,12 +194,15 @@ func (c Client) trimSize(root, size int) {
 
 // trimQuery trims endpoints matching the query
 func (c Client) trimQuery(q string) {
-	endpoints := c.getEndpoints(strings.SplitN(q, "=", 2)...)
+	endpoints, err := c.QueryEndpoints(q)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	// trim all matches
-	for id := range endpoints {
-		if err := c.trimNode(id); err != nil {
-			log.Error("trim %v: %v", id, err)
+	for _, e := range endpoints {
+		if err := c.trimNode(e.ID()); err != nil {
+			log.Error("trim %v: %v", e, err)
 		}
 	}
 }
@@ -527,7 +527,7 @@ btnFit.on("click", function() {
 
 function searchNodes(value) {
 	if (value) {
-		$.ajax({url: "/endpoints/"+value, success: function(result){
+		$.ajax({url: "endpoints/"+value, success: function(result){
 			d3.selectAll(".highlight-search").attr("style", "display: none;");
 			if (result) {
 				result.forEach(function(n){
@@ -562,6 +562,26 @@ force = d3.layout.force()
 
 checkUpdate();
 
+// refetch the nodes when the model changes, at most once per UPDATE_PERIOD
+var pendingUpdate = null;
+
+function scheduleUpdate() {
+	if (pendingUpdate == null) {
+		pendingUpdate = setTimeout(function() {
+			pendingUpdate = null;
+			checkUpdate();
+		}, UPDATE_PERIOD);
+	}
+}
+
+if (window.EventSource) {
+	var events = new EventSource("events/");
+	["node-created", "node-updated", "node-deleted", "edge-connected",
+		"edge-disconnected", "reset"].forEach(function(t) {
+		events.addEventListener(t, scheduleUpdate);
+	});
+}
+
 function info(n) {
 	// get rid of old data
 	d3.select("#info")
@@ -575,7 +595,7 @@ function info(n) {
 
 	if (isNode(n)) {
 		if (n.D.hasOwnProperty("image") && n.D["image"]) {
-			f.append("img").attr("src", "/image/" + n.D["image"]);
+			f.append("img").attr("src", "image/" + n.D["image"]);
 		}
 		if (n.D.hasOwnProperty("picture") && n.D["picture"]) {
 			f.append("img").attr("src", "data:image/jpeg;base64," + n.D["picture"]).attr("height", "200px");
@@ -0,0 +1,118 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Environment variables that New uses for the credentials, so that every tool
+// that uses the client can connect to a server that requires TLS or
+// authentication.
+const (
+	ENV_TOKEN = "DISCOVERY_TOKEN"
+	ENV_CA    = "DISCOVERY_CA"
+	ENV_CERT  = "DISCOVERY_CERT"
+	ENV_KEY   = "DISCOVERY_KEY"
+)
+
+// Credentials for connecting to a server that requires TLS or authentication.
+// TLS is used if the server is specified as https://... or if CA or Cert is
+// set.
+type Credentials struct {
+	// Token is sent as a bearer token, if set
+	Token string
+
+	// CA is a PEM bundle used to verify the server instead of the system
+	// roots, if set
+	CA string
+
+	// Cert and Key are PEM files with the client certificate for mutual
+	// TLS, if set
+	Cert, Key string
+}
+
+// EnvCredentials reads the credentials from the environment.
+func EnvCredentials() *Credentials {
+	return &Credentials{
+		Token: os.Getenv(ENV_TOKEN),
+		CA:    os.Getenv(ENV_CA),
+		Cert:  os.Getenv(ENV_CERT),
+		Key:   os.Getenv(ENV_KEY),
+	}
+}
+
+func (c *Credentials) tls() bool {
+	return c.CA != "" || c.Cert != ""
+}
+
+// tlsConfig creates the TLS config to connect to the server.
+func (c *Credentials) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if c.CA != "" {
+		b, err := os.ReadFile(c.CA)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %v", c.CA)
+		}
+	}
+
+	if c.Cert != "" {
+		key := c.Key
+		if key == "" {
+			// assume the key is in the same file
+			key = c.Cert
+		}
+
+		cert, err := tls.LoadX509KeyPair(c.Cert, key)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// bearer adds the token to each request
+type bearer struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (b *bearer) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+b.token)
+
+	return b.next.RoundTrip(r)
+}
+
+// httpClient creates the HTTP client for the credentials.
+func (c *Credentials) httpClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	config, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = config
+
+	var rt http.RoundTripper = transport
+	if c.Token != "" {
+		rt = &bearer{token: c.Token, next: transport}
+	}
+
+	return &http.Client{Transport: rt}, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+const (
+	OP_INSERT        = "insert"
+	OP_UPDATE        = "update"
+	OP_PATCH         = "patch"
+	OP_DELETE        = "delete"
+	OP_CONNECT       = "connect"
+	OP_DISCONNECT    = "disconnect"
+	OP_SET_CONFIG    = "set-config"
+	OP_DELETE_CONFIG = "delete-config"
+)
+
+// Op is a single mutation of the graph or config. A batch of ops is applied
+// atomically by the server: if any op fails, none of them are applied.
+//
+// Nodes inserted with a negative NID are assigned a real NID by the server.
+// Later ops in the same batch may use the negative NID to refer to the new
+// node in NID, NNID, ENID, or the NID of an updated node. Edge.N is never
+// rewritten, use a connect op instead.
+//
+// Use the *Op constructors rather than building Ops by hand -- the zero value
+// of Edge refers to the first edge, not a new edge.
+type Op struct {
+	Action string
+
+	// node to insert or update, only one should be set. In the response, set
+	// to the state of the node after the op was applied.
+	Endpoint *minigraph.Endpoint `json:",omitempty"`
+	Network  *minigraph.Network  `json:",omitempty"`
+
+	// node to delete or patch
+	NID int `json:",omitempty"`
+
+	// changes to apply to the node
+	Patch *minigraph.Patch `json:",omitempty"`
+
+	// expected revision of the node to update, patch, or delete. If set and
+	// the node has been modified since, the op fails with ErrConflict.
+	Rev int `json:",omitempty"`
+
+	// network, endpoint, and edge index to connect or disconnect. Edge may
+	// be EDGE_NONE to create a new edge.
+	NNID, ENID, Edge int
+
+	// config key and value
+	Key   string `json:",omitempty"`
+	Value string `json:",omitempty"`
+}
+
+func InsertEndpointOp(e *minigraph.Endpoint) *Op {
+	return &Op{Action: OP_INSERT, Endpoint: e}
+}
+
+func InsertNetworkOp(n *minigraph.Network) *Op {
+	return &Op{Action: OP_INSERT, Network: n}
+}
+
+func UpdateEndpointOp(e *minigraph.Endpoint) *Op {
+	return &Op{Action: OP_UPDATE, Endpoint: e}
+}
+
+func UpdateNetworkOp(n *minigraph.Network) *Op {
+	return &Op{Action: OP_UPDATE, Network: n}
+}
+
+func PatchOp(nid int, p *minigraph.Patch) *Op {
+	return &Op{Action: OP_PATCH, NID: nid, Patch: p}
+}
+
+func DeleteOp(nid int) *Op {
+	return &Op{Action: OP_DELETE, NID: nid}
+}
+
+func ConnectOp(nnid, enid, eidx int) *Op {
+	return &Op{Action: OP_CONNECT, NNID: nnid, ENID: enid, Edge: eidx}
+}
+
+func DisconnectOp(nnid, enid int) *Op {
+	return &Op{Action: OP_DISCONNECT, NNID: nnid, ENID: enid}
+}
+
+func SetConfigOp(k, v string) *Op {
+	return &Op{Action: OP_SET_CONFIG, Key: k, Value: v}
+}
+
+func DeleteConfigOp(k string) *Op {
+	return &Op{Action: OP_DELETE_CONFIG, Key: k}
+}
+
+func (o *Op) String() string {
+	switch o.Action {
+	case OP_INSERT, OP_UPDATE:
+		if o.Endpoint != nil {
+			return fmt.Sprintf("%v endpoint %v", o.Action, o.Endpoint)
+		}
+		return fmt.Sprintf("%v network %v", o.Action, o.Network)
+	case OP_DELETE, OP_PATCH:
+		return fmt.Sprintf("%v %v", o.Action, o.NID)
+	case OP_CONNECT, OP_DISCONNECT:
+		return fmt.Sprintf("%v %v %v", o.Action, o.NNID, o.ENID)
+	}
+
+	return fmt.Sprintf("%v %v", o.Action, o.Key)
+}
+
+// Batch applies the ops atomically. Fails with ErrConflict if any op's Rev
+// does not match the node's current revision. Returns the ops as applied by the server,
+// with placeholder NIDs replaced and Endpoint/Network set to the resulting
+// state of the node, where applicable.
+func (c *Client) Batch(ops ...*Op) ([]*Op, error) {
+	b, err := json.MarshalIndent(ops, "", "    ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	body := bytes.NewReader(b)
+
+	path := fmt.Sprintf("%v/batch/", c.server)
+
+	httpRequest, err := http.NewRequest(http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var ret []*Op
+	d := json.NewDecoder(resp.Body)
+	err = d.Decode(&ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -12,7 +12,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"sort"
+	"strings"
 
 	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
 	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
@@ -44,29 +46,99 @@ func WriteError(w io.Writer, r *http.Request, err error) {
 }
 
 func ReadError(i io.Reader) error {
-	var e jsonError
-	d := json.NewDecoder(i)
-	err := d.Decode(&e)
+	b, err := io.ReadAll(i)
 	if err != nil {
-		log.Fatalln(err)
+		return err
+	}
+
+	var e jsonError
+	if err := json.Unmarshal(b, &e); err != nil {
+		// not from the daemon, e.g. a plain HTTP request to an HTTPS server
+		return fmt.Errorf("unexpected response: %v", strings.TrimSpace(string(b)))
 	}
 	return fmt.Errorf("%v : %v", e.Request, e.Error)
 }
 
 type Client struct {
+	client *http.Client
+
+	// base URL of the daemon
+	base string
+
+	// base URL of the selected model
 	server string
+	model  string
 }
 
+// New creates a client for the server, which may be host or host:port,
+// optionally prefixed by http:// or https://. Either may be followed by
+// /<model> to select one of the named models hosted by the daemon, otherwise
+// the client uses the default model. The credentials are read from the
+// environment, see EnvCredentials. Exits if the credentials are invalid, use
+// NewWithCredentials to handle the error instead.
 func New(s string) *Client {
+	c, err := NewWithCredentials(s, EnvCredentials())
+	if err != nil {
+		log.Fatalln("invalid credentials:", err)
+	}
+
+	return c
+}
+
+// NewWithCredentials is New with the specified credentials.
+func NewWithCredentials(s string, creds *Credentials) (*Client, error) {
+	scheme := "http"
+	if creds.tls() {
+		scheme = "https"
+	}
+
+	for _, v := range []string{"http", "https"} {
+		if strings.HasPrefix(s, v+"://") {
+			scheme, s = v, strings.TrimPrefix(s, v+"://")
+		}
+	}
+
+	var model string
+	if i := strings.Index(s, "/"); i != -1 {
+		s, model = s[:i], s[i+1:]
+	}
+
 	server := s
 	_, _, err := net.SplitHostPort(s)
 	if err != nil {
 		server = net.JoinHostPort(s, fmt.Sprintf("%v", Port))
 	}
-	log.Debug("using server %v", server)
-	return &Client{
-		server: fmt.Sprintf("http://%v", server),
+	log.Debug("using server %v://%v", scheme, server)
+
+	client, err := creds.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		client: client,
+		base:   fmt.Sprintf("%v://%v", scheme, server),
 	}
+	c.SetModel(model)
+
+	return c, nil
+}
+
+// SetModel selects the model used by all the other methods, the default model
+// if name is empty.
+func (c *Client) SetModel(name string) {
+	name = strings.Trim(name, "/")
+
+	c.model = name
+	c.server = c.base
+	if name != "" {
+		c.server = fmt.Sprintf("%v/models/%v", c.base, url.PathEscape(name))
+	}
+}
+
+// Model returns the name of the selected model, empty for the default model.
+func (c *Client) Model() string {
+	return c.model
 }
 
 // GetEndpoint wraps GetEndpoints and returns the first node that matches or an
@@ -101,7 +173,7 @@ func (c *Client) GetEndpoints(k, v string) ([]*minigraph.Endpoint, error) {
 		path = fmt.Sprintf("%v/endpoints/%v/%v", c.server, k, v)
 	}
 
-	resp, err := http.Get(path)
+	resp, err := c.client.Get(path)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +202,7 @@ func (c *Client) GetEndpoints(k, v string) ([]*minigraph.Endpoint, error) {
 func (c *Client) GetConfig() (map[string]string, error) {
 	path := fmt.Sprintf("%v/config/", c.server)
 
-	resp, err := http.Get(path)
+	resp, err := c.client.Get(path)
 	if err != nil {
 		return nil, err
 	}
@@ -151,8 +223,6 @@ func (c *Client) GetConfig() (map[string]string, error) {
 }
 
 func (c *Client) SetConfig(k, v string) error {
-	httpClient := &http.Client{}
-
 	body := bytes.NewBufferString(v)
 
 	path := fmt.Sprintf("%v/config/%v", c.server, k)
@@ -162,7 +232,7 @@ func (c *Client) SetConfig(k, v string) error {
 		return err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return err
 	}
@@ -177,8 +247,6 @@ func (c *Client) SetConfig(k, v string) error {
 }
 
 func (c *Client) DeleteConfig(k string) error {
-	httpClient := &http.Client{}
-
 	var path string
 	path = fmt.Sprintf("%v/config/%v", c.server, k)
 
@@ -187,7 +255,7 @@ func (c *Client) DeleteConfig(k string) error {
 		return err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return err
 	}
@@ -214,7 +282,7 @@ func (c *Client) GetNetworks(k, v string) ([]*minigraph.Network, error) {
 		path = fmt.Sprintf("%v/networks/%v/%v", c.server, k, v)
 	}
 
-	resp, err := http.Get(path)
+	resp, err := c.client.Get(path)
 	if err != nil {
 		return nil, err
 	}
@@ -241,8 +309,6 @@ func (c *Client) GetNetworks(k, v string) ([]*minigraph.Network, error) {
 }
 
 func (c *Client) InsertEndpoints(e ...*minigraph.Endpoint) ([]*minigraph.Endpoint, error) {
-	httpClient := &http.Client{}
-
 	b, err := json.MarshalIndent(e, "", "    ")
 	if err != nil {
 		log.Fatalln(err)
@@ -257,7 +323,7 @@ func (c *Client) InsertEndpoints(e ...*minigraph.Endpoint) ([]*minigraph.Endpoin
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -278,8 +344,6 @@ func (c *Client) InsertEndpoints(e ...*minigraph.Endpoint) ([]*minigraph.Endpoin
 }
 
 func (c *Client) UpdateEndpoints(e ...*minigraph.Endpoint) ([]*minigraph.Endpoint, error) {
-	httpClient := &http.Client{}
-
 	b, err := json.MarshalIndent(e, "", "    ")
 	if err != nil {
 		log.Fatalln(err)
@@ -294,7 +358,7 @@ func (c *Client) UpdateEndpoints(e ...*minigraph.Endpoint) ([]*minigraph.Endpoin
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -316,8 +380,6 @@ func (c *Client) UpdateEndpoints(e ...*minigraph.Endpoint) ([]*minigraph.Endpoin
 }
 
 func (c *Client) InsertNetworks(n ...*minigraph.Network) ([]*minigraph.Network, error) {
-	httpClient := &http.Client{}
-
 	b, err := json.MarshalIndent(n, "", "    ")
 	if err != nil {
 		log.Fatalln(err)
@@ -332,7 +394,7 @@ func (c *Client) InsertNetworks(n ...*minigraph.Network) ([]*minigraph.Network,
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -353,8 +415,6 @@ func (c *Client) InsertNetworks(n ...*minigraph.Network) ([]*minigraph.Network,
 }
 
 func (c *Client) UpdateNetworks(n ...*minigraph.Network) ([]*minigraph.Network, error) {
-	httpClient := &http.Client{}
-
 	b, err := json.MarshalIndent(n, "", "    ")
 	if err != nil {
 		log.Fatalln(err)
@@ -372,7 +432,7 @@ func (c *Client) UpdateNetworks(n ...*minigraph.Network) ([]*minigraph.Network,
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -393,8 +453,6 @@ func (c *Client) UpdateNetworks(n ...*minigraph.Network) ([]*minigraph.Network,
 }
 
 func (c *Client) DeleteEndpoints(k, v string) ([]*minigraph.Endpoint, error) {
-	httpClient := &http.Client{}
-
 	var path string
 	if k == "" {
 		path = fmt.Sprintf("%v/endpoints/%v", c.server, v)
@@ -407,7 +465,7 @@ func (c *Client) DeleteEndpoints(k, v string) ([]*minigraph.Endpoint, error) {
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -429,8 +487,6 @@ func (c *Client) DeleteEndpoints(k, v string) ([]*minigraph.Endpoint, error) {
 }
 
 func (c *Client) DeleteNetworks(k, v string) ([]*minigraph.Network, error) {
-	httpClient := &http.Client{}
-
 	var path string
 	if k == "" {
 		path = fmt.Sprintf("%v/networks/%v", c.server, v)
@@ -443,7 +499,7 @@ func (c *Client) DeleteNetworks(k, v string) ([]*minigraph.Network, error) {
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(httpRequest)
+	resp, err := c.client.Do(httpRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -464,41 +520,17 @@ func (c *Client) DeleteNetworks(k, v string) ([]*minigraph.Network, error) {
 	return ret, nil
 }
 
+// Neighbors returns the direct neighbors of the node found by the search
+// terms, which must match exactly one node. See NeighborsDepth.
 func (c *Client) Neighbors(k, v string) ([]minigraph.Node, error) {
-	var path string
-	if k == "" && v == "" {
-		path = fmt.Sprintf("%v/neighbors/", c.server)
-	} else if k == "" {
-		path = fmt.Sprintf("%v/neighbors/%v", c.server, v)
-	} else {
-		path = fmt.Sprintf("%v/neighbors/%v/%v", c.server, k, v)
-	}
-
-	resp, err := http.Get(path)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		err := ReadError(resp.Body)
-		return nil, err
-	}
-
-	var ret []minigraph.Node
-	d := json.NewDecoder(resp.Body)
-	err = d.Decode(&ret)
-	if err != nil {
-		return nil, err
-	}
-	return ret, nil
+	return c.NeighborsDepth(k, v, 1)
 }
 
 func (c *Client) Save(path string) error {
 	url := fmt.Sprintf("%v/daemon/save/%v", c.server, path)
 	log.Debug("using url: %v", url)
 
-	resp, err := http.Get(url)
+	resp, err := c.client.Get(url)
 	if err != nil {
 		return err
 	}
@@ -516,7 +548,7 @@ func (c *Client) Load(path string) error {
 	url := fmt.Sprintf("%v/daemon/load/%v", c.server, path)
 	log.Debug("using url: %v", url)
 
-	resp, err := http.Get(url)
+	resp, err := c.client.Get(url)
 	if err != nil {
 		return err
 	}
@@ -538,7 +570,7 @@ func (c *Client) Connect(nnid, enid int, eidx int) (*minigraph.Endpoint, error)
 		url = fmt.Sprintf("%v/connect/%v/%v/%v", c.server, nnid, enid, eidx)
 	}
 
-	resp, err := http.Post(url, "", nil)
+	resp, err := c.client.Post(url, "", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -562,7 +594,7 @@ func (c *Client) Connect(nnid, enid int, eidx int) (*minigraph.Endpoint, error)
 func (c *Client) Disconnect(nnid, enid int) (*minigraph.Endpoint, error) {
 	url := fmt.Sprintf("%v/disconnect/%v/%v", c.server, nnid, enid)
 
-	resp, err := http.Post(url, "", nil)
+	resp, err := c.client.Post(url, "", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,212 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+const (
+	EVENT_NODE_CREATED      = "node-created"
+	EVENT_NODE_UPDATED      = "node-updated"
+	EVENT_NODE_DELETED      = "node-deleted"
+	EVENT_EDGE_CONNECTED    = "edge-connected"
+	EVENT_EDGE_DISCONNECTED = "edge-disconnected"
+	EVENT_CONFIG_CHANGED    = "config-changed"
+
+	// the server could not replay all the events since the requested
+	// revision (or the whole model was replaced), clients should refetch
+	// everything they care about
+	EVENT_RESET = "reset"
+)
+
+// REVISION_HEADER is set on the event stream to the revision that the stream
+// starts after so that clients that only want new events know where to resume
+// if the stream drops before any events arrive.
+const REVISION_HEADER = "Discovery-Revision"
+
+// Event describes a change to the model. All the events from a single commit
+// share the same revision. Revisions increase monotonically.
+type Event struct {
+	Rev  uint64
+	Type string
+
+	// node that was created, updated, or deleted. For created and updated
+	// nodes, Endpoint or Network contains the new state of the node.
+	NID      int                 `json:",omitempty"`
+	Endpoint *minigraph.Endpoint `json:",omitempty"`
+	Network  *minigraph.Network  `json:",omitempty"`
+
+	// network, endpoint, and edge index that were connected or disconnected
+	NNID, ENID, Edge int
+
+	// changes to the node's D or to the config
+	Diff *minigraph.DataDiff `json:",omitempty"`
+}
+
+func (e *Event) String() string {
+	switch e.Type {
+	case EVENT_EDGE_CONNECTED, EVENT_EDGE_DISCONNECTED:
+		return fmt.Sprintf("%v: %v %v <-> %v", e.Rev, e.Type, e.NNID, e.ENID)
+	case EVENT_CONFIG_CHANGED, EVENT_RESET:
+		return fmt.Sprintf("%v: %v", e.Rev, e.Type)
+	}
+
+	return fmt.Sprintf("%v: %v %v", e.Rev, e.Type, e.NID)
+}
+
+// Watch streams events from the server until the context is cancelled, at
+// which point the returned channel is closed. If the connection drops, Watch
+// reconnects and resumes after the last revision it received. Returns an error
+// if the initial connection fails.
+func (c *Client) Watch(ctx context.Context) (<-chan *Event, error) {
+	return c.WatchSince(ctx, 0)
+}
+
+// WatchSince is like Watch but starts with the events after the specified
+// revision. If rev is 0, only new events are streamed.
+func (c *Client) WatchSince(ctx context.Context, rev uint64) (<-chan *Event, error) {
+	resp, err := c.events(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	if rev == 0 {
+		rev = streamRev(resp)
+	}
+
+	out := make(chan *Event)
+
+	go func() {
+		defer close(out)
+
+		for {
+			last, err := readEvents(ctx, resp, out)
+			resp.Body.Close()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			if last > rev {
+				rev = last
+			}
+
+			log.Info("event stream interrupted at rev %v: %v", rev, err)
+
+			// reconnect until it works or we're cancelled
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+
+				resp, err = c.events(ctx, rev)
+				if err == nil {
+					if rev == 0 {
+						rev = streamRev(resp)
+					}
+					break
+				}
+
+				log.Debug("unable to reconnect event stream: %v", err)
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (c *Client) events(ctx context.Context, rev uint64) (*http.Response, error) {
+	path := fmt.Sprintf("%v/events/", c.server)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	if rev > 0 {
+		req.Header.Set("Last-Event-ID", fmt.Sprintf("%v", rev))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, ReadError(resp.Body)
+	}
+
+	return resp, nil
+}
+
+// streamRev returns the revision that the stream starts after, 0 if the
+// server didn't say.
+func streamRev(resp *http.Response) uint64 {
+	rev, err := strconv.ParseUint(resp.Header.Get(REVISION_HEADER), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return rev
+}
+
+// readEvents parses the server-sent events stream and sends the events to
+// out. Returns the last complete revision seen, as indicated by the id field.
+func readEvents(ctx context.Context, resp *http.Response, out chan<- *Event) (uint64, error) {
+	var last uint64
+	var data strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case strings.HasPrefix(line, "id:"):
+			fmt.Sscan(strings.TrimPrefix(line, "id:"), &last)
+		case line == "":
+			// dispatch
+			if data.Len() == 0 {
+				continue
+			}
+
+			e := &Event{}
+			if err := json.Unmarshal([]byte(data.String()), e); err != nil {
+				return last, err
+			}
+			data.Reset()
+
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return last, ctx.Err()
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return last, err
+	}
+
+	return last, fmt.Errorf("stream closed")
+}
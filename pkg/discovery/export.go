@@ -0,0 +1,56 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Export writes the model to w in the specified format: graphml, json (JSON
+// Graph Format), or dot. The config is included as graph-level attributes.
+func (c *Client) Export(format string, w io.Writer) error {
+	path := fmt.Sprintf("%v/export/%v", c.server, format)
+
+	resp, err := c.client.Get(path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := ReadError(resp.Body)
+		return err
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import replaces the model with one read from r in the specified format:
+// graphml, json (JSON Graph Format), or dot. Graph-level attributes become the
+// config.
+func (c *Client) Import(format string, r io.Reader) error {
+	path := fmt.Sprintf("%v/import/%v", c.server, format)
+
+	httpRequest, err := http.NewRequest(http.MethodPost, path, r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := ReadError(resp.Body)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// DefaultPageSize is the number of nodes that iterators fetch per request.
+const DefaultPageSize = 1000
+
+// pager fetches pages of a listing using the limit and after parameters. The
+// server sorts listings by NID so the NID of the last node is the cursor for
+// the next page.
+type pager struct {
+	c    *Client
+	path string
+	v    url.Values
+
+	// PageSize is the number of nodes to fetch per request, DefaultPageSize
+	// if zero. Must be set before the first call to Next.
+	PageSize int
+
+	after int
+	done  bool
+	err   error
+}
+
+func newPager(c *Client, kind, k, v, q string) pager {
+	path := fmt.Sprintf("%v/%v/", c.server, kind)
+	if k == "" && v != "" {
+		path += url.PathEscape(v)
+	} else if k != "" {
+		path += url.PathEscape(k) + "/" + url.PathEscape(v)
+	}
+
+	p := pager{
+		c:    c,
+		path: path,
+		v:    url.Values{},
+	}
+
+	if q != "" {
+		p.v.Set("q", q)
+	}
+
+	return p
+}
+
+// fetch fetches the next page into ret, which must be a pointer to a slice.
+func (p *pager) fetch(ret interface{}) error {
+	if p.PageSize <= 0 {
+		p.PageSize = DefaultPageSize
+	}
+
+	p.v.Set("limit", strconv.Itoa(p.PageSize))
+	p.v.Set("after", strconv.Itoa(p.after))
+
+	return p.c.get(p.path+"?"+p.v.Encode(), ret)
+}
+
+// Err returns the first error encountered while iterating, if any.
+func (p *pager) Err() error {
+	return p.err
+}
+
+// EndpointIterator iterates over endpoints in order of NID, fetching them a
+// page at a time so that large models are never read into memory at once:
+//
+//	it := dc.IterEndpoints("", "")
+//	for it.Next() {
+//		e := it.Endpoint()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Endpoints that are inserted or deleted while iterating may or may not be
+// returned.
+type EndpointIterator struct {
+	pager
+
+	page []*minigraph.Endpoint
+	i    int
+}
+
+// IterEndpoints iterates over the endpoints matching the search terms, like
+// GetEndpoints.
+func (c *Client) IterEndpoints(k, v string) *EndpointIterator {
+	return &EndpointIterator{pager: newPager(c, "endpoints", k, v, "")}
+}
+
+// IterEndpointsQuery iterates over the endpoints matching the query, like
+// QueryEndpoints.
+func (c *Client) IterEndpointsQuery(q string) *EndpointIterator {
+	return &EndpointIterator{pager: newPager(c, "endpoints", "", "", q)}
+}
+
+// Next advances to the next endpoint, fetching the next page if needed.
+// Returns false when there are no more endpoints or there was an error.
+func (it *EndpointIterator) Next() bool {
+	it.i++
+	if it.i < len(it.page) {
+		return true
+	}
+
+	if it.done || it.err != nil {
+		return false
+	}
+
+	var page []*minigraph.Endpoint
+	if it.err = it.fetch(&page); it.err != nil {
+		return false
+	}
+
+	it.page, it.i = page, 0
+	it.done = len(page) < it.PageSize
+
+	if len(page) == 0 {
+		return false
+	}
+
+	it.after = page[len(page)-1].NID
+
+	return true
+}
+
+// Endpoint returns the current endpoint.
+func (it *EndpointIterator) Endpoint() *minigraph.Endpoint {
+	return it.page[it.i]
+}
+
+// NetworkIterator iterates over networks in order of NID, see
+// EndpointIterator.
+type NetworkIterator struct {
+	pager
+
+	page []*minigraph.Network
+	i    int
+}
+
+// IterNetworks iterates over the networks matching the search terms, like
+// GetNetworks.
+func (c *Client) IterNetworks(k, v string) *NetworkIterator {
+	return &NetworkIterator{pager: newPager(c, "networks", k, v, "")}
+}
+
+// IterNetworksQuery iterates over the networks matching the query, like
+// QueryNetworks.
+func (c *Client) IterNetworksQuery(q string) *NetworkIterator {
+	return &NetworkIterator{pager: newPager(c, "networks", "", "", q)}
+}
+
+// Next advances to the next network, fetching the next page if needed.
+// Returns false when there are no more networks or there was an error.
+func (it *NetworkIterator) Next() bool {
+	it.i++
+	if it.i < len(it.page) {
+		return true
+	}
+
+	if it.done || it.err != nil {
+		return false
+	}
+
+	var page []*minigraph.Network
+	if it.err = it.fetch(&page); it.err != nil {
+		return false
+	}
+
+	it.page, it.i = page, 0
+	it.done = len(page) < it.PageSize
+
+	if len(page) == 0 {
+		return false
+	}
+
+	it.after = page[len(page)-1].NID
+
+	return true
+}
+
+// Network returns the current network.
+func (it *NetworkIterator) Network() *minigraph.Network {
+	return it.page[it.i]
+}
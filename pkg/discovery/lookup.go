@@ -0,0 +1,77 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// Indexes returns the fields that the server has indexed.
+func (c *Client) Indexes() ([]string, error) {
+	var ret []string
+	err := c.get(fmt.Sprintf("%v/lookup/", c.server), &ret)
+	return ret, err
+}
+
+// LookupEndpoints returns the endpoints where the field exactly matches the
+// value. Fields are the same as in queries, e.g. "hostname" or "edge.ip". Fast
+// if the server has indexed the field. Endpoints will be sorted by ID.
+func (c *Client) LookupEndpoints(field, value string) ([]*minigraph.Endpoint, error) {
+	var ret []*minigraph.Endpoint
+	err := c.get(lookupPath(c.server, "endpoints", field, value), &ret)
+	return ret, err
+}
+
+// LookupEndpointsCIDR returns the endpoints where the field is an IP (or CIDR)
+// within the subnet. Endpoints will be sorted by ID.
+func (c *Client) LookupEndpointsCIDR(field, cidr string) ([]*minigraph.Endpoint, error) {
+	var ret []*minigraph.Endpoint
+	err := c.get(lookupCIDRPath(c.server, "endpoints", field, cidr), &ret)
+	return ret, err
+}
+
+// LookupNetworks is LookupEndpoints for networks.
+func (c *Client) LookupNetworks(field, value string) ([]*minigraph.Network, error) {
+	var ret []*minigraph.Network
+	err := c.get(lookupPath(c.server, "networks", field, value), &ret)
+	return ret, err
+}
+
+// LookupNetworksCIDR is LookupEndpointsCIDR for networks.
+func (c *Client) LookupNetworksCIDR(field, cidr string) ([]*minigraph.Network, error) {
+	var ret []*minigraph.Network
+	err := c.get(lookupCIDRPath(c.server, "networks", field, cidr), &ret)
+	return ret, err
+}
+
+func lookupPath(server, kind, field, value string) string {
+	return fmt.Sprintf("%v/lookup/%v/%v/%v", server, kind, url.PathEscape(field), url.PathEscape(value))
+}
+
+func lookupCIDRPath(server, kind, field, cidr string) string {
+	return fmt.Sprintf("%v/lookup/%v/%v?cidr=%v", server, kind, url.PathEscape(field), url.QueryEscape(cidr))
+}
+
+// get fetches the path and decodes the JSON response into ret
+func (c *Client) get(path string, ret interface{}) error {
+	resp, err := c.client.Get(path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := ReadError(resp.Body)
+		return err
+	}
+
+	d := json.NewDecoder(resp.Body)
+	return d.Decode(ret)
+}
@@ -0,0 +1,37 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// Merge merges the current state of another model hosted by the daemon into
+// the model, see minigraph.Merge. opts may be nil to use the defaults.
+func (c *Client) Merge(src string, opts *minigraph.MergeOptions) (*minigraph.MergeResult, error) {
+	v := url.Values{}
+	if opts != nil {
+		if len(opts.Keys) > 0 {
+			v.Set("keys", strings.Join(opts.Keys, ","))
+		}
+		if opts.Policy != "" {
+			v.Set("policy", opts.Policy)
+		}
+	}
+
+	path := fmt.Sprintf("%v/merge/%v", c.server, url.PathEscape(src))
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+
+	ret := &minigraph.MergeResult{}
+	err := c.do(http.MethodPost, path, ret)
+	return ret, err
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DEFAULT_MODEL is the model served at the top level of the API
+const DEFAULT_MODEL = "default"
+
+// Model summarizes one of the models hosted by the daemon
+type Model struct {
+	Name      string
+	Rev       uint64
+	Endpoints int
+	Networks  int
+}
+
+func (m *Model) String() string {
+	return fmt.Sprintf("%v\trev %v\t%v endpoints\t%v networks", m.Name, m.Rev, m.Endpoints, m.Networks)
+}
+
+// Models lists the models hosted by the daemon, regardless of the selected
+// model.
+func (c *Client) Models() ([]*Model, error) {
+	var ret []*Model
+	err := c.get(fmt.Sprintf("%v/models/", c.base), &ret)
+	return ret, err
+}
+
+// CreateModel creates a new, empty model. If src is not empty, the new model
+// is a copy of the current state of src instead.
+func (c *Client) CreateModel(name, src string) (*Model, error) {
+	path := modelPath(c.base, name)
+	if src != "" {
+		path += "?clone=" + url.QueryEscape(src)
+	}
+
+	ret := &Model{}
+	err := c.do(http.MethodPost, path, ret)
+	return ret, err
+}
+
+// ExtractModel creates a model containing a copy of the subgraph of the
+// selected model that matches the query, see minigraph.Extract.
+func (c *Client) ExtractModel(name, q string) (*Model, error) {
+	src := c.model
+	if src == "" {
+		src = DEFAULT_MODEL
+	}
+
+	v := url.Values{}
+	v.Set("clone", src)
+	v.Set("q", q)
+
+	ret := &Model{}
+	err := c.do(http.MethodPost, modelPath(c.base, name)+"?"+v.Encode(), ret)
+	return ret, err
+}
+
+// DeleteModel deletes the model along with its history and snapshots.
+func (c *Client) DeleteModel(name string) error {
+	return c.do(http.MethodDelete, modelPath(c.base, name), nil)
+}
+
+func modelPath(base, name string) string {
+	return fmt.Sprintf("%v/models/%v", base, url.PathEscape(name))
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// ErrConflict is returned when a conditional update, patch, or delete fails
+// because the node has been modified since the expected revision. Callers
+// should read the node again and retry.
+var ErrConflict = errors.New("conflict")
+
+// ETag formats the node revision as an HTTP entity tag. The server sets it on
+// responses that contain a single node and checks it against If-Match.
+func ETag(rev int) string {
+	return strconv.Quote(strconv.Itoa(rev))
+}
+
+// ParseETag parses an entity tag from ETag. Returns 0 for "*", which matches
+// any revision.
+func ParseETag(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return 0, nil
+	}
+
+	v, err := strconv.Unquote(strings.TrimPrefix(s, "W/"))
+	if err == nil {
+		var rev int
+		rev, err = strconv.Atoi(v)
+		if err == nil && rev > 0 {
+			return rev, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid entity tag: %v", s)
+}
+
+// responseError reads the error from the response, wrapping ErrConflict if
+// the server responded with 409.
+func responseError(resp *http.Response) error {
+	err := ReadError(resp.Body)
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+
+	return err
+}
+
+// PatchEndpoint applies the changes to the endpoint's data and edges without
+// affecting the other keys. If rev is not zero, the patch fails with
+// ErrConflict if the endpoint has been modified since that revision. Returns
+// the endpoint after the patch was applied.
+func (c *Client) PatchEndpoint(nid int, p *minigraph.Patch, rev int) (*minigraph.Endpoint, error) {
+	var ret *minigraph.Endpoint
+	if err := c.send(http.MethodPatch, fmt.Sprintf("%v/endpoints/%v", c.server, nid), p, rev, &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// PatchNetwork applies the changes to the network's data, see PatchEndpoint.
+func (c *Client) PatchNetwork(nid int, p *minigraph.Patch, rev int) (*minigraph.Network, error) {
+	var ret *minigraph.Network
+	if err := c.send(http.MethodPatch, fmt.Sprintf("%v/networks/%v", c.server, nid), p, rev, &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateEndpoint replaces the endpoint if it has not been modified since
+// e.Rev, otherwise it fails with ErrConflict. If e.Rev is zero, the endpoint
+// is replaced unconditionally, like UpdateEndpoints.
+func (c *Client) UpdateEndpoint(e *minigraph.Endpoint) (*minigraph.Endpoint, error) {
+	var ret []*minigraph.Endpoint
+	if err := c.send(http.MethodPut, fmt.Sprintf("%v/endpoints/", c.server), []*minigraph.Endpoint{e}, e.Rev, &ret); err != nil {
+		return nil, err
+	}
+
+	if len(ret) != 1 {
+		return nil, fmt.Errorf("expected 1 endpoint, got %v", len(ret))
+	}
+
+	return ret[0], nil
+}
+
+// UpdateNetwork replaces the network if it has not been modified since n.Rev,
+// see UpdateEndpoint.
+func (c *Client) UpdateNetwork(n *minigraph.Network) (*minigraph.Network, error) {
+	var ret []*minigraph.Network
+	if err := c.send(http.MethodPut, fmt.Sprintf("%v/networks/", c.server), []*minigraph.Network{n}, n.Rev, &ret); err != nil {
+		return nil, err
+	}
+
+	if len(ret) != 1 {
+		return nil, fmt.Errorf("expected 1 network, got %v", len(ret))
+	}
+
+	return ret[0], nil
+}
+
+// send encodes v as the body of the request, setting If-Match if rev is not
+// zero, and decodes the response into ret.
+func (c *Client) send(method, path string, v interface{}, rev int, ret interface{}) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	httpRequest, err := http.NewRequest(method, path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	if rev != 0 {
+		httpRequest.Header.Set("If-Match", ETag(rev))
+	}
+
+	resp, err := c.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return responseError(resp)
+	}
+
+	d := json.NewDecoder(resp.Body)
+	return d.Decode(ret)
+}
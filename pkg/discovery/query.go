@@ -0,0 +1,49 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// QueryEndpoints returns the endpoints matching the query. See minigraph.Query
+// for the query syntax. Endpoints will be sorted by ID.
+func (c *Client) QueryEndpoints(q string) ([]*minigraph.Endpoint, error) {
+	var ret []*minigraph.Endpoint
+	if err := c.query("endpoints", q, &ret); err != nil {
+		return nil, err
+	}
+
+	// sort so that they can be processed in a consistent order
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].NID < ret[j].NID
+	})
+
+	return ret, nil
+}
+
+// QueryNetworks returns the networks matching the query. See minigraph.Query
+// for the query syntax. Networks will be sorted by ID.
+func (c *Client) QueryNetworks(q string) ([]*minigraph.Network, error) {
+	var ret []*minigraph.Network
+	if err := c.query("networks", q, &ret); err != nil {
+		return nil, err
+	}
+
+	// sort so that they can be processed in a consistent order
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].NID < ret[j].NID
+	})
+
+	return ret, nil
+}
+
+func (c *Client) query(kind, q string, ret interface{}) error {
+	return c.get(fmt.Sprintf("%v/%v/?q=%v", c.server, kind, url.QueryEscape(q)), ret)
+}
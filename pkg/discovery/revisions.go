@@ -0,0 +1,138 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// Revision describes a change to the model. Every request that modifies the
+// model creates a new revision.
+type Revision struct {
+	Rev  uint64
+	Time time.Time
+
+	// number of nodes that were changed
+	Nodes int
+}
+
+// History describes the revisions that the server can diff and roll back to.
+// Older revisions are only available through snapshots.
+type History struct {
+	// current revision
+	Rev uint64
+
+	// recent revisions, oldest first
+	Revisions []*Revision
+}
+
+// Snapshot is a named copy of the model at a revision.
+type Snapshot struct {
+	Name string
+	Rev  uint64
+	Time time.Time
+}
+
+// Diff describes the changes between two revisions of the model.
+type Diff struct {
+	From, To uint64
+
+	Config minigraph.DataDiff
+	Nodes  []*minigraph.NodeDiff
+}
+
+func (r *Revision) String() string {
+	return fmt.Sprintf("%v\t%v\t%v nodes", r.Rev, r.Time.Format(time.RFC3339), r.Nodes)
+}
+
+func (s *Snapshot) String() string {
+	return fmt.Sprintf("%v\t%v\t%v", s.Name, s.Rev, s.Time.Format(time.RFC3339))
+}
+
+// Revisions returns the current revision and the recent revisions.
+func (c *Client) Revisions() (*History, error) {
+	ret := &History{}
+	err := c.get(fmt.Sprintf("%v/revisions/", c.server), ret)
+	return ret, err
+}
+
+// Snapshots returns all the snapshots, sorted by name.
+func (c *Client) Snapshots() ([]*Snapshot, error) {
+	var ret []*Snapshot
+	err := c.get(fmt.Sprintf("%v/snapshots/", c.server), &ret)
+	return ret, err
+}
+
+// CreateSnapshot tags the current revision with the name. Fails if the
+// snapshot already exists.
+func (c *Client) CreateSnapshot(name string) (*Snapshot, error) {
+	ret := &Snapshot{}
+	err := c.do(http.MethodPost, snapshotPath(c.server, name), ret)
+	return ret, err
+}
+
+// DeleteSnapshot deletes the snapshot.
+func (c *Client) DeleteSnapshot(name string) error {
+	return c.do(http.MethodDelete, snapshotPath(c.server, name), nil)
+}
+
+// GetDiff returns the changes from one revision to another. Revisions may be
+// revision numbers or snapshot names. If to is empty, the current revision is
+// used.
+func (c *Client) GetDiff(from, to string) (*Diff, error) {
+	path := fmt.Sprintf("%v/diff/%v", c.server, url.PathEscape(from))
+	if to != "" {
+		path += "/" + url.PathEscape(to)
+	}
+
+	ret := &Diff{}
+	err := c.get(path, ret)
+	return ret, err
+}
+
+// Rollback restores the model to a revision number or snapshot name. The
+// rollback itself creates a new revision so it can be undone.
+func (c *Client) Rollback(rev string) (*Revision, error) {
+	ret := &Revision{}
+	err := c.do(http.MethodPost, fmt.Sprintf("%v/rollback/%v", c.server, url.PathEscape(rev)), ret)
+	return ret, err
+}
+
+func snapshotPath(server, name string) string {
+	return fmt.Sprintf("%v/snapshots/%v", server, url.PathEscape(name))
+}
+
+// do sends a request with no body to the path and decodes the JSON response
+// into ret, if it is not nil
+func (c *Client) do(method, path string, ret interface{}) error {
+	httpRequest, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := ReadError(resp.Body)
+		return err
+	}
+
+	if ret == nil {
+		return nil
+	}
+
+	d := json.NewDecoder(resp.Body)
+	return d.Decode(ret)
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// NeighborsDepth returns the nodes within depth hops of the node found by the
+// search terms, or all the nodes connected to it if depth is -1. Endpoints
+// are only linked to networks so endpoints on the same network are two hops
+// apart. The nodes are sorted by ID.
+func (c *Client) NeighborsDepth(k, v string, depth int) ([]minigraph.Node, error) {
+	var path string
+	if k == "" {
+		path = fmt.Sprintf("%v/neighbors/%v", c.server, v)
+	} else {
+		path = fmt.Sprintf("%v/neighbors/%v/%v", c.server, k, v)
+	}
+
+	path += fmt.Sprintf("?depth=%v", depth)
+
+	var raw []json.RawMessage
+	if err := c.get(path, &raw); err != nil {
+		return nil, err
+	}
+
+	return decodeNodes(raw)
+}
+
+// decodeNodes decodes a list containing both endpoints and networks.
+func decodeNodes(raw []json.RawMessage) ([]minigraph.Node, error) {
+	var res []minigraph.Node
+
+	for _, b := range raw {
+		// only endpoints have edges
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return nil, err
+		}
+
+		var n minigraph.Node = &minigraph.Network{}
+		if _, ok := fields["Edges"]; ok {
+			n = &minigraph.Endpoint{}
+		}
+
+		if err := json.Unmarshal(b, n); err != nil {
+			return nil, err
+		}
+
+		res = append(res, n)
+	}
+
+	return res, nil
+}
+
+// ShortestPath finds the shortest path between two nodes. If weight is empty,
+// the path has the fewest hops. Otherwise, weight is the key of a numeric
+// edge attribute, such as delay, and the path has the lowest total weight.
+func (c *Client) ShortestPath(from, to int, weight string) (*minigraph.Path, error) {
+	path := fmt.Sprintf("%v/paths/%v/%v", c.server, from, to)
+	if weight != "" {
+		path += "?weight=" + url.QueryEscape(weight)
+	}
+
+	ret := &minigraph.Path{}
+	if err := c.get(path, ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Components returns the connected components of the graph as lists of NIDs,
+// largest first.
+func (c *Client) Components() ([][]int, error) {
+	var ret [][]int
+	if err := c.get(fmt.Sprintf("%v/components/", c.server), &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Component returns the NIDs of the nodes in the same connected component as
+// the node, including the node itself.
+func (c *Client) Component(nid int) ([]int, error) {
+	var ret []int
+	if err := c.get(fmt.Sprintf("%v/components/%v", c.server, nid), &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// ArticulationPoints returns the NIDs of the nodes whose removal would
+// disconnect the graph.
+func (c *Client) ArticulationPoints() ([]int, error) {
+	var ret []int
+	if err := c.get(fmt.Sprintf("%v/components/articulation", c.server), &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Bridges returns the links whose removal would disconnect the graph.
+func (c *Client) Bridges() ([]minigraph.Bridge, error) {
+	var ret []minigraph.Bridge
+	if err := c.get(fmt.Sprintf("%v/components/bridges", c.server), &ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sandia-minimega/discovery/v2/pkg/minigraph"
+)
+
+// Validate checks whether the model can be emulated, see minigraph.Validate.
+func (c *Client) Validate() (*minigraph.Report, error) {
+	ret := &minigraph.Report{}
+	err := c.do(http.MethodGet, fmt.Sprintf("%v/validate/", c.server), ret)
+	return ret, err
+}
+
+// Repair fixes the referential inconsistencies in the model. Returns the
+// report from before the repairs with the repaired problems marked as fixed.
+func (c *Client) Repair() (*minigraph.Report, error) {
+	ret := &minigraph.Report{}
+	err := c.do(http.MethodPost, fmt.Sprintf("%v/validate/", c.server), ret)
+	return ret, err
+}
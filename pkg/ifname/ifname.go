@@ -0,0 +1,60 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+// Package ifname compares interface names from device output, such as
+// neighbor and MAC address tables, with the interface names on edges, which
+// usually come from configs and may be spelled differently.
+package ifname
+
+import (
+	"strings"
+)
+
+// abbreviations of interface names, longest first
+var abbreviations = []struct {
+	Short, Long string
+}{
+	{"hu", "hundredgige"},
+	{"fo", "fortygigabitethernet"},
+	{"twe", "twentyfivegige"},
+	{"te", "tengigabitethernet"},
+	{"gi", "gigabitethernet"},
+	{"fa", "fastethernet"},
+	{"eth", "ethernet"},
+	{"et", "ethernet"},
+	{"po", "port-channel"},
+	{"vl", "vlan"},
+	{"mgmt", "management"},
+	{"ma", "management"},
+}
+
+// Canonical returns the interface name in lowercase with common
+// abbreviations expanded so that Gi1/0/1 matches GigabitEthernet1/0/1
+func Canonical(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+
+	// find where the number starts
+	i := strings.IndexAny(s, "0123456789")
+	if i <= 0 {
+		return s
+	}
+
+	prefix := s[:i]
+	for _, v := range abbreviations {
+		if prefix == v.Short || (strings.HasPrefix(v.Long, prefix) && len(prefix) > len(v.Short)) {
+			return v.Long + s[i:]
+		}
+	}
+
+	return s
+}
+
+// Same tests whether the interfaces are the same. Either may be a logical
+// unit of the other such as ge-0/0/0.0 and ge-0/0/0 since devices usually
+// report the physical interface while configs address the units.
+func Same(a, b string) bool {
+	a, b = Canonical(a), Canonical(b)
+
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package ifname
+
+import (
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"Gi1/0/1", "gigabitethernet1/0/1"},
+		{"GigabitEthernet1/0/1", "gigabitethernet1/0/1"},
+		{"Gig 1/0/1", "gigabitethernet1/0/1"},
+		{"Te1/1", "tengigabitethernet1/1"},
+		{"Twe1/0/1", "twentyfivegige1/0/1"},
+		{"Eth1/1", "ethernet1/1"},
+		{"Et1", "ethernet1"},
+		{"Ethernet1", "ethernet1"},
+		{"Po10", "port-channel10"},
+		{"Vl10", "vlan10"},
+		{"Ma1", "management1"},
+		{"mgmt0", "management0"},
+		{"ge-0/0/0.0", "ge-0/0/0.0"},
+		{"eth0", "ethernet0"},
+		{"bridge", "bridge"},
+	}
+
+	for _, test := range tests {
+		if got := Canonical(test.s); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Gi0/1", "GigabitEthernet0/1", true},
+		{"ge-0/0/0", "ge-0/0/0.0", true},
+		{"ge-0/0/0.0", "ge-0/0/0", true},
+		{"Gi0/1", "Gi0/1.10", true},
+		{"Gi0/1", "Gi0/10", false},
+		{"Gi0/1.10", "Gi0/1.20", false},
+		{"ge-0/0/1", "ge-0/0/10.0", false},
+		{"Te1/1", "Gi1/1", false},
+	}
+
+	for _, test := range tests {
+		if got := Same(test.a, test.b); got != test.want {
+			t.Errorf("%q, %q: got %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+// Package importer contains the parts shared by the commands that import
+// files from devices, such as configs and show output, into the graph: finding
+// the files, processing them, and summarizing the results.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	log "github.com/sandia-minimega/discovery/v2/pkg/minilog"
+)
+
+// Expand returns the files for each argument, which may be a file, a
+// directory, or a glob. Directories are walked recursively, skipping hidden
+// files.
+func Expand(args []string) ([]string, error) {
+	var res []string
+
+	for _, arg := range args {
+		files, err := expand(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, files...)
+	}
+
+	return res, nil
+}
+
+func expand(arg string) ([]string, error) {
+	matches, err := filepath.Glob(arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		// not a glob, let it fail when we try to open it
+		matches = []string{arg}
+	}
+
+	var res []string
+
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fi.IsDir() {
+			res = append(res, m)
+			continue
+		}
+
+		err = filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if path != m && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if d.Type().IsRegular() {
+				res = append(res, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Process calls fn for each file using the number of workers and returns the
+// results in the same order as the files. With one worker, the files are
+// processed in order.
+func Process[T any](files []string, workers int, fn func(string) T) []T {
+	results := make([]T, len(files))
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				log.Debug("using filename: %v", files[j])
+				results[j] = fn(files[j])
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return results
+}
+
+// Table summarizes the results with a row for each file, followed by the
+// columns for the command and the status.
+type Table struct {
+	w *tabwriter.Writer
+
+	// the status of files without errors is "dry run" rather than "ok"
+	DryRun bool
+
+	// number of rows and how many of them had errors
+	Files, Failed int
+}
+
+// NewTable writes the header for a table with the columns.
+func NewTable(w io.Writer, columns ...string) *Table {
+	t := &Table{
+		w: tabwriter.NewWriter(w, 2, 0, 2, ' ', 0),
+	}
+
+	fmt.Fprintf(t.w, "FILE\t%v\tSTATUS\n", strings.Join(columns, "\t"))
+
+	return t
+}
+
+// Row adds the row for the file, logging the error if there is one.
+func (t *Table) Row(file string, err error, values ...interface{}) {
+	t.Files++
+
+	status := "ok"
+	if err != nil {
+		t.Failed++
+		// only the first line, syntax errors include context
+		status = "error: " + strings.SplitN(err.Error(), "\n", 2)[0]
+		log.Error("%v: %v", file, err)
+	} else if t.DryRun {
+		status = "dry run"
+	}
+
+	fmt.Fprint(t.w, file)
+	for _, v := range values {
+		fmt.Fprintf(t.w, "\t%v", v)
+	}
+	fmt.Fprintf(t.w, "\t%v\n", status)
+}
+
+// Flush writes the table.
+func (t *Table) Flush() error {
+	return t.w.Flush()
+}
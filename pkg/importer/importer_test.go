@@ -0,0 +1,98 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, f := range []string{"r1.cfg", "r2.cfg", "notes.txt", ".hidden", "site/r3.cfg", ".git/config"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"r1.cfg"}, []string{"r1.cfg"}},
+		{[]string{"*.cfg"}, []string{"r1.cfg", "r2.cfg"}},
+		{[]string{"."}, []string{"notes.txt", "r1.cfg", "r2.cfg", "site/r3.cfg"}},
+		{[]string{"site", "r2.cfg"}, []string{"site/r3.cfg", "r2.cfg"}},
+	}
+
+	for _, test := range tests {
+		var args []string
+		for _, v := range test.args {
+			args = append(args, filepath.Join(dir, v))
+		}
+
+		got, err := Expand(args)
+		if err != nil {
+			t.Errorf("%v: %v", test.args, err)
+			continue
+		}
+
+		for i := range got {
+			got[i], _ = filepath.Rel(dir, got[i])
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.args, got, test.want)
+		}
+	}
+
+	if _, err := Expand([]string{filepath.Join(dir, "missing.cfg")}); err == nil {
+		t.Error("expanded a file that doesn't exist")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e"}
+
+	for _, workers := range []int{1, 3, 10} {
+		got := Process(files, workers, strings.ToUpper)
+
+		if want := []string{"A", "B", "C", "D", "E"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v workers: got %v, want %v", workers, got, want)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	var buf bytes.Buffer
+
+	table := NewTable(&buf, "NAME", "EDGES")
+	table.Row("r1.cfg", nil, "r1", 2)
+	table.Row("r2.cfg", errors.New("bad config\nline 3"), "-", "-")
+	table.Flush()
+
+	want := `FILE    NAME  EDGES  STATUS
+r1.cfg  r1    2      ok
+r2.cfg  -     -      error: bad config
+`
+
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+
+	if table.Files != 2 || table.Failed != 1 {
+		t.Errorf("got %v files and %v failed, want 2 and 1", table.Files, table.Failed)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"sort"
+)
+
+// DataDiff describes the changes between two data maps (Endpoint.D,
+// Network.D, or Edge.D).
+type DataDiff struct {
+	// keys that were added or changed and their new values
+	Set map[string]string `json:",omitempty"`
+
+	// keys that were removed
+	Deleted []string `json:",omitempty"`
+}
+
+// DiffData returns the changes needed to turn a into b.
+func DiffData(a, b map[string]string) DataDiff {
+	var d DataDiff
+
+	for k, v := range b {
+		if v2, ok := a[k]; !ok || v != v2 {
+			if d.Set == nil {
+				d.Set = make(map[string]string)
+			}
+			d.Set[k] = v
+		}
+	}
+
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			d.Deleted = append(d.Deleted, k)
+		}
+	}
+
+	sort.Strings(d.Deleted)
+
+	return d
+}
+
+// Empty returns true if there are no changes.
+func (d DataDiff) Empty() bool {
+	return len(d.Set) == 0 && len(d.Deleted) == 0
+}
+
+// Apply the changes to the data map.
+func (d DataDiff) Apply(m map[string]string) {
+	for k, v := range d.Set {
+		m[k] = v
+	}
+	for _, k := range d.Deleted {
+		delete(m, k)
+	}
+}
+
+// NodeDiff describes the changes to a node between two graphs.
+type NodeDiff struct {
+	NID  int
+	Type int
+
+	// set if the node only exists in one of the graphs
+	Added   bool `json:",omitempty"`
+	Removed bool `json:",omitempty"`
+
+	Data DataDiff
+
+	// edges that changed, endpoints only
+	Edges []*EdgeDiff `json:",omitempty"`
+
+	// endpoints that were connected or disconnected, networks only
+	Connected    []int `json:",omitempty"`
+	Disconnected []int `json:",omitempty"`
+}
+
+// EdgeDiff describes the changes to an endpoint's edge. Edges are compared by
+// their index in Endpoint.Edges.
+type EdgeDiff struct {
+	Edge int
+
+	// set if the edge only exists in one of the endpoints
+	Added   bool `json:",omitempty"`
+	Removed bool `json:",omitempty"`
+
+	// network the edge was connected to before and after, UNCONNECTED if
+	// the edge was not connected or did not exist
+	From, To int
+
+	Data DataDiff
+}
+
+// DiffNodes returns the changes needed to turn the nodes in a into the nodes
+// in b, sorted by NID. Nodes are matched by NID.
+func DiffNodes(a, b map[int]Node) []*NodeDiff {
+	var res []*NodeDiff
+
+	for id, n := range a {
+		n2 := b[id]
+		if n2 != nil && n2.Type() != n.Type() {
+			// NID was reused for a different type of node
+			res = append(res, DiffNode(n, nil), DiffNode(nil, n2))
+		} else if d := DiffNode(n, n2); d != nil {
+			res = append(res, d)
+		}
+	}
+
+	for id, n := range b {
+		if _, ok := a[id]; !ok {
+			res = append(res, DiffNode(nil, n))
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].NID < res[j].NID
+	})
+
+	return res
+}
+
+// DiffNode returns the changes needed to turn node a into node b, or nil if
+// they are the same. Either node may be nil if it does not exist. The nodes
+// must have the same type.
+func DiffNode(a, b Node) *NodeDiff {
+	if a == nil && b == nil {
+		return nil
+	}
+
+	d := &NodeDiff{
+		Added:   a == nil,
+		Removed: b == nil,
+	}
+
+	var da, db map[string]string
+
+	if a != nil {
+		d.NID, d.Type = a.ID(), a.Type()
+		da = a.Data()
+	}
+	if b != nil {
+		d.NID, d.Type = b.ID(), b.Type()
+		db = b.Data()
+	}
+
+	d.Data = DiffData(da, db)
+
+	switch d.Type {
+	case TYPE_ENDPOINT:
+		var ea, eb []*Edge
+		if a != nil {
+			ea = a.(*Endpoint).Edges
+		}
+		if b != nil {
+			eb = b.(*Endpoint).Edges
+		}
+
+		d.Edges = diffEdges(ea, eb)
+	case TYPE_NETWORK:
+		var na, nb []int
+		if a != nil {
+			na = a.(*Network).Endpoints
+		}
+		if b != nil {
+			nb = b.(*Network).Endpoints
+		}
+
+		d.Connected = subtract(nb, na)
+		d.Disconnected = subtract(na, nb)
+	}
+
+	if !d.Added && !d.Removed && d.Data.Empty() && len(d.Edges) == 0 && len(d.Connected) == 0 && len(d.Disconnected) == 0 {
+		return nil
+	}
+
+	return d
+}
+
+func diffEdges(a, b []*Edge) []*EdgeDiff {
+	var res []*EdgeDiff
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		d := &EdgeDiff{
+			Edge:    i,
+			Added:   i >= len(a),
+			Removed: i >= len(b),
+			From:    UNCONNECTED,
+			To:      UNCONNECTED,
+		}
+
+		var da, db map[string]string
+
+		if i < len(a) {
+			d.From = a[i].N
+			da = a[i].D
+		}
+		if i < len(b) {
+			d.To = b[i].N
+			db = b[i].D
+		}
+
+		d.Data = DiffData(da, db)
+
+		if d.Added || d.Removed || d.From != d.To || !d.Data.Empty() {
+			res = append(res, d)
+		}
+	}
+
+	return res
+}
+
+// subtract returns the sorted values in a that are not in b
+func subtract(a, b []int) []int {
+	var res []int
+
+Outer:
+	for _, v := range a {
+		for _, v2 := range b {
+			if v == v2 {
+				continue Outer
+			}
+		}
+
+		res = append(res, v)
+	}
+
+	sort.Ints(res)
+
+	return res
+}
@@ -0,0 +1,480 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Graphviz DOT, see https://graphviz.org/doc/info/lang.html. We write an
+// undirected graph and read the common subset of the language: graph, node,
+// and edge statements, default attributes, and graph attributes. Subgraphs are
+// not supported and ports are ignored. In quoted strings, we escape
+// backslashes as well as quotes so that values round trip exactly.
+var dotPlainID = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*|-?[0-9]+)$`)
+
+var dotKeywords = []string{"node", "edge", "graph", "digraph", "subgraph", "strict"}
+
+func dotID(s string) string {
+	if dotPlainID.MatchString(s) {
+		keyword := false
+		for _, k := range dotKeywords {
+			keyword = keyword || strings.EqualFold(s, k)
+		}
+
+		if !keyword {
+			return s
+		}
+	}
+
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+
+	return `"` + s + `"`
+}
+
+func dotAttrs(attrs map[string]string) string {
+	var res []string
+	for _, k := range sortedKeys(attrs) {
+		res = append(res, dotID(k)+"="+dotID(attrs[k]))
+	}
+
+	return "[" + strings.Join(res, ", ") + "]"
+}
+
+func writeDOT(w io.Writer, fg *flatGraph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "graph minigraph {")
+
+	if len(fg.Attrs) > 0 {
+		fmt.Fprintf(bw, "\tgraph %v;\n", dotAttrs(fg.Attrs))
+	}
+
+	for _, n := range fg.Nodes {
+		fmt.Fprintf(bw, "\t%v %v;\n", dotID(n.ID), dotAttrs(n.Attrs))
+	}
+
+	for _, e := range fg.Edges {
+		fmt.Fprintf(bw, "\t%v -- %v %v;\n", dotID(e.Source), dotID(e.Target), dotAttrs(e.Attrs))
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+const (
+	dotEOF = iota
+	dotIdent
+	dotPunct
+	dotEdgeOp
+)
+
+type dotToken struct {
+	typ int
+	val string
+
+	// quoted or HTML string, never a keyword
+	quoted bool
+}
+
+func (t dotToken) String() string {
+	if t.typ == dotEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+func (t dotToken) keyword(k string) bool {
+	return t.typ == dotIdent && !t.quoted && strings.EqualFold(t.val, k)
+}
+
+func (t dotToken) punct(p string) bool {
+	return t.typ == dotPunct && t.val == p
+}
+
+func lexDOT(s string) ([]dotToken, error) {
+	var res []dotToken
+
+	r := []rune(s)
+	lineStart := true
+
+	for i := 0; i < len(r); {
+		c := r[i]
+
+		switch {
+		case c == '\n':
+			lineStart = true
+			i++
+			continue
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '#' && lineStart:
+			// preprocessor output, skip the line
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(r) && r[i+1] == '/':
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(r) && r[i+1] == '*':
+			end := strings.Index(string(r[i+2:]), "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("dot: unterminated comment")
+			}
+			i += 2 + len([]rune(string(r[i+2:])[:end])) + 2
+			continue
+		}
+
+		lineStart = false
+
+		switch {
+		case strings.ContainsRune("{}[];,=:", c):
+			res = append(res, dotToken{typ: dotPunct, val: string(c)})
+			i++
+		case c == '-' && i+1 < len(r) && (r[i+1] == '-' || r[i+1] == '>'):
+			res = append(res, dotToken{typ: dotEdgeOp, val: string(r[i : i+2])})
+			i += 2
+		case c == '"':
+			var b strings.Builder
+
+			i++
+			for ; i < len(r) && r[i] != '"'; i++ {
+				if r[i] == '\\' && i+1 < len(r) {
+					switch r[i+1] {
+					case '"', '\\':
+						i++
+					case '\n':
+						// line continuation
+						i++
+						continue
+					}
+				}
+				b.WriteRune(r[i])
+			}
+			if i == len(r) {
+				return nil, fmt.Errorf("dot: unterminated string")
+			}
+			i++
+
+			// "a" + "b" concatenates
+			if n := len(res); n >= 2 && res[n-1].punct("+") && res[n-2].quoted {
+				res[n-2].val += b.String()
+				res = res[:n-1]
+			} else {
+				res = append(res, dotToken{typ: dotIdent, val: b.String(), quoted: true})
+			}
+		case c == '+':
+			res = append(res, dotToken{typ: dotPunct, val: "+"})
+			i++
+		case c == '<':
+			depth := 0
+			start := i
+			for ; i < len(r); i++ {
+				if r[i] == '<' {
+					depth++
+				} else if r[i] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if i == len(r) {
+				return nil, fmt.Errorf("dot: unterminated HTML string")
+			}
+			res = append(res, dotToken{typ: dotIdent, val: string(r[start+1 : i]), quoted: true})
+			i++
+		case c == '_' || c == '-' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c) || c > unicode.MaxASCII:
+			start := i
+			for i < len(r) && (r[i] == '_' || r[i] == '-' || r[i] == '.' || unicode.IsLetter(r[i]) || unicode.IsDigit(r[i]) || r[i] > unicode.MaxASCII) {
+				// stop before an edge op
+				if r[i] == '-' && i+1 < len(r) && (r[i+1] == '-' || r[i+1] == '>') {
+					break
+				}
+				i++
+			}
+			res = append(res, dotToken{typ: dotIdent, val: string(r[start:i])})
+		default:
+			return nil, fmt.Errorf("dot: unexpected character %q", c)
+		}
+	}
+
+	return res, nil
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+
+	fg    *flatGraph
+	nodes map[string]*flatNode
+
+	// default attributes for new nodes and edges
+	nodeAttrs, edgeAttrs map[string]string
+}
+
+func (p *dotParser) peek() dotToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return dotToken{typ: dotEOF}
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *dotParser) expect(punct string) error {
+	if t := p.next(); !t.punct(punct) {
+		return fmt.Errorf("dot: expected %q, got %v", punct, t)
+	}
+	return nil
+}
+
+func (p *dotParser) ident() (string, error) {
+	t := p.next()
+	if t.typ != dotIdent {
+		return "", fmt.Errorf("dot: expected ID, got %v", t)
+	}
+	return t.val, nil
+}
+
+func readDOT(r io.Reader) (*flatGraph, error) {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, err
+	}
+
+	tokens, err := lexDOT(b.String())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dotParser{
+		tokens:    tokens,
+		fg:        &flatGraph{Attrs: map[string]string{}},
+		nodes:     map[string]*flatNode{},
+		nodeAttrs: map[string]string{},
+		edgeAttrs: map[string]string{},
+	}
+
+	if p.peek().keyword("strict") {
+		p.next()
+	}
+
+	if t := p.next(); !t.keyword("graph") && !t.keyword("digraph") {
+		return nil, fmt.Errorf("dot: expected graph or digraph, got %v", t)
+	}
+
+	// optional graph ID
+	if p.peek().typ == dotIdent {
+		p.next()
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for !p.peek().punct("}") {
+		if p.peek().typ == dotEOF {
+			return nil, fmt.Errorf("dot: expected \"}\", got end of input")
+		}
+
+		if err := p.stmt(); err != nil {
+			return nil, err
+		}
+
+		if p.peek().punct(";") {
+			p.next()
+		}
+	}
+	p.next()
+
+	if t := p.peek(); t.typ != dotEOF {
+		return nil, fmt.Errorf("dot: unexpected %v after graph", t)
+	}
+
+	return p.fg, nil
+}
+
+func (p *dotParser) stmt() error {
+	t := p.peek()
+
+	switch {
+	case t.keyword("graph"), t.keyword("node"), t.keyword("edge"):
+		p.next()
+
+		attrs, err := p.attrList()
+		if err != nil {
+			return err
+		}
+
+		dst := p.fg.Attrs
+		if t.keyword("node") {
+			dst = p.nodeAttrs
+		} else if t.keyword("edge") {
+			dst = p.edgeAttrs
+		}
+
+		for k, v := range attrs {
+			dst[k] = v
+		}
+
+		return nil
+	case t.keyword("subgraph"), t.punct("{"):
+		return fmt.Errorf("dot: subgraphs are not supported")
+	case t.typ != dotIdent:
+		return fmt.Errorf("dot: unexpected %v", t)
+	}
+
+	id, err := p.nodeID()
+	if err != nil {
+		return err
+	}
+
+	// graph attribute
+	if p.peek().punct("=") {
+		p.next()
+
+		v, err := p.ident()
+		if err != nil {
+			return err
+		}
+
+		p.fg.Attrs[id] = v
+		return nil
+	}
+
+	ids := []string{id}
+	for p.peek().typ == dotEdgeOp {
+		p.next()
+
+		if p.peek().keyword("subgraph") || p.peek().punct("{") {
+			return fmt.Errorf("dot: subgraphs are not supported")
+		}
+
+		id, err := p.nodeID()
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	attrs, err := p.attrList()
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 1 {
+		n := p.node(id)
+		for k, v := range attrs {
+			n.Attrs[k] = v
+		}
+
+		return nil
+	}
+
+	for i := 0; i+1 < len(ids); i++ {
+		p.node(ids[i])
+		p.node(ids[i+1])
+
+		e := &flatEdge{
+			Source: ids[i],
+			Target: ids[i+1],
+			Attrs:  copyData(p.edgeAttrs),
+		}
+		for k, v := range attrs {
+			e.Attrs[k] = v
+		}
+
+		p.fg.Edges = append(p.fg.Edges, e)
+	}
+
+	return nil
+}
+
+// nodeID reads a node ID, skipping the port if there is one
+func (p *dotParser) nodeID() (string, error) {
+	id, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < 2 && p.peek().punct(":"); i++ {
+		p.next()
+		if _, err := p.ident(); err != nil {
+			return "", err
+		}
+	}
+
+	return id, nil
+}
+
+// node returns the node with the ID, creating it if needed
+func (p *dotParser) node(id string) *flatNode {
+	if n, ok := p.nodes[id]; ok {
+		return n
+	}
+
+	n := &flatNode{
+		ID:    id,
+		Attrs: copyData(p.nodeAttrs),
+	}
+
+	p.nodes[id] = n
+	p.fg.Nodes = append(p.fg.Nodes, n)
+
+	return n
+}
+
+// attrList reads zero or more bracketed lists of attributes
+func (p *dotParser) attrList() (map[string]string, error) {
+	res := map[string]string{}
+
+	for p.peek().punct("[") {
+		p.next()
+
+		for !p.peek().punct("]") {
+			k, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+
+			v, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+
+			res[k] = v
+
+			if t := p.peek(); t.punct(",") || t.punct(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+
+	return res, nil
+}
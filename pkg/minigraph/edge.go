@@ -22,6 +22,27 @@ func newEdge() *Edge {
 	}
 }
 
+// Copy returns a deep copy of the edge.
+func (e *Edge) Copy() *Edge {
+	return &Edge{
+		N: e.N,
+		D: copyData(e.D),
+	}
+}
+
+func copyData(d map[string]string) map[string]string {
+	if d == nil {
+		return nil
+	}
+
+	res := make(map[string]string, len(d))
+	for k, v := range d {
+		res[k] = v
+	}
+
+	return res
+}
+
 func (e *Edge) Match(k, v string) bool {
 	if k != "" {
 		if strings.ToUpper(k) == "N" {
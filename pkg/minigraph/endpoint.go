@@ -15,6 +15,9 @@ type Endpoint struct {
 	NID   int
 	Edges []*Edge
 	D     map[string]string
+
+	// Rev is incremented each time the endpoint is modified in a transaction
+	Rev int
 }
 
 func (e *Endpoint) Data() map[string]string {
@@ -25,6 +28,29 @@ func (e *Endpoint) setID(id int) {
 	e.NID = id
 }
 
+func (e *Endpoint) Revision() int {
+	return e.Rev
+}
+
+func (e *Endpoint) setRevision(rev int) {
+	e.Rev = rev
+}
+
+// Copy returns a deep copy of the endpoint.
+func (e *Endpoint) Copy() *Endpoint {
+	e2 := &Endpoint{
+		NID: e.NID,
+		D:   copyData(e.D),
+		Rev: e.Rev,
+	}
+
+	for _, edge := range e.Edges {
+		e2.Edges = append(e2.Edges, edge.Copy())
+	}
+
+	return e2
+}
+
 func (e *Endpoint) Match(k, v string) bool {
 	if k != "" {
 		if k == "nid" {
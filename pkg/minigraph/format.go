@@ -0,0 +1,337 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats supported by Export and Import. Endpoints and networks become nodes
+// with their D as attributes and each Edge becomes an edge between an
+// endpoint and a network with its D as attributes. Unconnected edges become
+// loops on the endpoint. Two attributes are reserved: ATTR_TYPE on nodes
+// ("endpoint" or "network") and ATTR_EDGE on edges (the index of the edge in
+// Endpoint.Edges). Graph-level attributes can be used to carry other data,
+// such as the daemon's config.
+const (
+	FORMAT_GRAPHML = "graphml"
+	FORMAT_JSON    = "json" // JSON Graph Format
+	FORMAT_DOT     = "dot"
+
+	ATTR_TYPE = "_type"
+	ATTR_EDGE = "_edge"
+)
+
+var Formats = []string{FORMAT_GRAPHML, FORMAT_JSON, FORMAT_DOT}
+
+// flatGraph is the common form of a graph for all the formats.
+type flatGraph struct {
+	Attrs map[string]string
+	Nodes []*flatNode
+	Edges []*flatEdge
+}
+
+type flatNode struct {
+	ID    string
+	Attrs map[string]string
+}
+
+type flatEdge struct {
+	Source, Target string
+	Attrs          map[string]string
+}
+
+// Export writes the graph and graph-level attributes, which may be nil, to w
+// in the specified format. Nodes, edges, and attributes are sorted so that
+// exporting the same graph always produces the same output.
+func (g *Graph) Export(w io.Writer, format string, attrs map[string]string) error {
+	fg := g.flatten(attrs)
+
+	switch strings.ToLower(format) {
+	case FORMAT_GRAPHML:
+		return writeGraphML(w, fg)
+	case FORMAT_JSON:
+		return writeJGF(w, fg)
+	case FORMAT_DOT:
+		return writeDOT(w, fg)
+	}
+
+	return fmt.Errorf("invalid format: %v", format)
+}
+
+// Import reads a graph and graph-level attributes in the specified format.
+// Node IDs that are positive integers are used as NIDs, other nodes are
+// assigned new NIDs. Nodes without ATTR_TYPE are networks if they are the
+// target of an edge and endpoints otherwise. Edges without ATTR_EDGE are
+// added after the other edges, in order.
+func Import(r io.Reader, format string) (*Graph, map[string]string, error) {
+	var fg *flatGraph
+	var err error
+
+	switch strings.ToLower(format) {
+	case FORMAT_GRAPHML:
+		fg, err = readGraphML(r)
+	case FORMAT_JSON:
+		fg, err = readJGF(r)
+	case FORMAT_DOT:
+		fg, err = readDOT(r)
+	default:
+		return nil, nil, fmt.Errorf("invalid format: %v", format)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g, err := fg.unflatten()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return g, fg.Attrs, nil
+}
+
+func (g *Graph) flatten(attrs map[string]string) *flatGraph {
+	fg := &flatGraph{
+		Attrs: copyData(attrs),
+	}
+	if fg.Attrs == nil {
+		fg.Attrs = make(map[string]string)
+	}
+
+	for _, id := range g.ids() {
+		n := &flatNode{
+			ID:    strconv.Itoa(id),
+			Attrs: copyData(g.Nodes[id].Data()),
+		}
+		if n.Attrs == nil {
+			n.Attrs = make(map[string]string)
+		}
+
+		switch v := g.Nodes[id].(type) {
+		case *Endpoint:
+			n.Attrs[ATTR_TYPE] = "endpoint"
+
+			for i, edge := range v.Edges {
+				e := &flatEdge{
+					Source: n.ID,
+					Target: n.ID,
+					Attrs:  copyData(edge.D),
+				}
+				if e.Attrs == nil {
+					e.Attrs = make(map[string]string)
+				}
+				e.Attrs[ATTR_EDGE] = strconv.Itoa(i)
+
+				if edge.N != UNCONNECTED {
+					e.Target = strconv.Itoa(edge.N)
+				}
+
+				fg.Edges = append(fg.Edges, e)
+			}
+		case *Network:
+			n.Attrs[ATTR_TYPE] = "network"
+		}
+
+		fg.Nodes = append(fg.Nodes, n)
+	}
+
+	return fg
+}
+
+func (fg *flatGraph) unflatten() (*Graph, error) {
+	// node ID -> NID
+	nids := map[string]int{}
+
+	var maxID int
+	for _, n := range fg.Nodes {
+		if _, ok := nids[n.ID]; ok {
+			return nil, fmt.Errorf("duplicate node: %v", n.ID)
+		}
+		nids[n.ID] = 0
+
+		if id, err := strconv.Atoi(n.ID); err == nil && id > 0 {
+			nids[n.ID] = id
+			if id > maxID {
+				maxID = id
+			}
+		}
+	}
+
+	for _, n := range fg.Nodes {
+		if nids[n.ID] == 0 {
+			maxID++
+			nids[n.ID] = maxID
+		}
+	}
+
+	// nodes that are the target of an edge, for nodes without a type
+	targets := map[string]bool{}
+	for _, e := range fg.Edges {
+		if e.Source != e.Target {
+			targets[e.Target] = true
+		}
+	}
+
+	g := New()
+
+	for _, n := range fg.Nodes {
+		d := copyData(n.Attrs)
+		if d == nil {
+			d = make(map[string]string)
+		}
+
+		typ := d[ATTR_TYPE]
+		delete(d, ATTR_TYPE)
+
+		if typ == "" {
+			typ = "endpoint"
+			if targets[n.ID] {
+				typ = "network"
+			}
+		}
+
+		var node Node
+
+		switch typ {
+		case "endpoint":
+			node = &Endpoint{NID: nids[n.ID], D: d}
+		case "network":
+			node = &Network{NID: nids[n.ID], D: d}
+		default:
+			return nil, fmt.Errorf("invalid type for node %v: %v", n.ID, typ)
+		}
+
+		if _, err := g.Insert(node); err != nil {
+			return nil, err
+		}
+	}
+
+	type indexed struct {
+		index int
+		edge  *Edge
+	}
+
+	// endpoint NID -> edges
+	edges := map[int][]indexed{}
+
+	for _, e := range fg.Edges {
+		src, ok := g.Nodes[nids[e.Source]]
+		if !ok {
+			return nil, fmt.Errorf("edge references unknown node: %v", e.Source)
+		}
+		dst, ok := g.Nodes[nids[e.Target]]
+		if !ok {
+			return nil, fmt.Errorf("edge references unknown node: %v", e.Target)
+		}
+
+		d := copyData(e.Attrs)
+		if d == nil {
+			d = make(map[string]string)
+		}
+
+		index := -1
+		if v, ok := d[ATTR_EDGE]; ok {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid edge index: %v", v)
+			}
+			index = i
+		}
+		delete(d, ATTR_EDGE)
+
+		edge := &Edge{N: UNCONNECTED, D: d}
+
+		// edges may be in either direction
+		if src.Type() == TYPE_NETWORK {
+			src, dst = dst, src
+		}
+
+		if src.ID() == dst.ID() {
+			if src.Type() != TYPE_ENDPOINT {
+				return nil, fmt.Errorf("invalid loop on network: %v", e.Source)
+			}
+		} else if src.Type() != TYPE_ENDPOINT || dst.Type() != TYPE_NETWORK {
+			return nil, fmt.Errorf("edge must connect an endpoint and a network: %v, %v", e.Source, e.Target)
+		} else {
+			edge.N = dst.ID()
+		}
+
+		edges[src.ID()] = append(edges[src.ID()], indexed{index, edge})
+	}
+
+	for id, v := range edges {
+		// edges without an index go at the end, in order
+		sort.SliceStable(v, func(i, j int) bool {
+			if v[i].index == -1 || v[j].index == -1 {
+				return v[j].index == -1 && v[i].index != -1
+			}
+			return v[i].index < v[j].index
+		})
+
+		endpoint := g.Nodes[id].(*Endpoint)
+		for _, e := range v {
+			if e.edge.N != UNCONNECTED && endpoint.Connected(e.edge.N) {
+				return nil, fmt.Errorf("endpoint %v connected to network %v multiple times", id, e.edge.N)
+			}
+
+			endpoint.Edges = append(endpoint.Edges, e.edge)
+		}
+	}
+
+	// populate networks in the same order as Connect would
+	for _, id := range g.ids() {
+		endpoint, ok := g.Nodes[id].(*Endpoint)
+		if !ok {
+			continue
+		}
+
+		for _, edge := range endpoint.Edges {
+			if edge.N != UNCONNECTED {
+				network := g.Nodes[edge.N].(*Network)
+				network.Endpoints = append(network.Endpoints, endpoint.ID())
+			}
+		}
+	}
+
+	// edges were added after the nodes were inserted
+	g.reindex(g.ids()...)
+
+	return g, nil
+}
+
+func (g *Graph) ids() []int {
+	var res []int
+	for id := range g.Nodes {
+		res = append(res, id)
+	}
+
+	sort.Ints(res)
+
+	return res
+}
+
+// sortedKeys returns the sorted keys of the maps
+func sortedKeys(maps ...map[string]string) []string {
+	seen := map[string]bool{}
+
+	var res []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				res = append(res, k)
+			}
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
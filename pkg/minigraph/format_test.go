@@ -0,0 +1,134 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	g := queryGraph()
+
+	n1 := g.Nodes[4].(*Network)
+	n2 := g.NewNetwork()
+	n2.D["name"] = `quoted "net" \ with spaces`
+
+	e2 := g.Nodes[2].(*Endpoint)
+	if err := g.Connect(e2, n1, e2.Edges[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Connect(e2, n2, e2.Edges[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := map[string]string{"default_kernel": "/tmp/vmlinuz"}
+
+	for _, format := range Formats {
+		var b bytes.Buffer
+		if err := g.Export(&b, format, attrs); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+
+		g2, attrs2, err := Import(bytes.NewReader(b.Bytes()), format)
+		if err != nil {
+			t.Fatalf("%v: %v\n%v", format, err, b.String())
+		}
+
+		if d := DiffNodes(g.Nodes, g2.Nodes); len(d) != 0 {
+			t.Fatalf("%v: graph changed: %+v\n%v", format, d[0], b.String())
+		}
+		if d := DiffData(attrs, attrs2); !d.Empty() {
+			t.Fatalf("%v: attrs changed: %v", format, d)
+		}
+
+		// exports should be stable
+		var b2 bytes.Buffer
+		if err := g2.Export(&b2, format, attrs2); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		if b.String() != b2.String() {
+			t.Fatalf("%v: export changed:\n%v\n%v", format, b.String(), b2.String())
+		}
+
+		// imported graph should be indexed and have the right max ID
+		if n := g2.NewNetwork(); n.ID() != 6 {
+			t.Fatalf("%v: expected new ID 6, got %v", format, n.ID())
+		}
+	}
+}
+
+func TestFormatImport(t *testing.T) {
+	// exported by other tools, without types or edge indexes
+	inputs := map[string]string{
+		FORMAT_GRAPHML: `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:y="http://www.yworks.com/xml/graphml">
+  <key id="d0" for="node" attr.name="hostname" attr.type="string"><default>unknown</default></key>
+  <key id="d1" for="edge" attr.name="ip" attr.type="string"/>
+  <key id="d2" for="node" yfiles.type="nodegraphics"/>
+  <graph id="G" edgedefault="directed">
+    <node id="n0"><data key="d0">foo</data><data key="d2"><y:ShapeNode/></data></node>
+    <node id="n1"/>
+    <node id="n2"/>
+    <edge source="n0" target="n1"><data key="d1">10.0.0.1/24</data></edge>
+    <edge source="n2" target="n1"/>
+  </graph>
+</graphml>`,
+		FORMAT_JSON: `{"graph": {"directed": true, "nodes": {
+			"n0": {"label": "foo", "metadata": {"hostname": "foo"}},
+			"n1": {"metadata": {"hostname": "unknown"}},
+			"n2": {"metadata": {"hostname": "unknown"}}},
+		"edges": [
+			{"source": "n0", "target": "n1", "metadata": {"ip": "10.0.0.1/24"}},
+			{"source": "n2", "target": "n1"}]}}`,
+		FORMAT_DOT: `/* comment */
+digraph G {
+	node [hostname=unknown]
+	n0 [hostname="foo", shape=box] // comment
+	n0 -> n1 [ip="10.0.0.1/24"]
+	n2 -> n1;
+}`,
+	}
+
+	for format, s := range inputs {
+		g, _, err := Import(strings.NewReader(s), format)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+
+		if len(g.GetEndpoints()) != 2 || len(g.GetNetworks()) != 1 {
+			t.Fatalf("%v: expected 2 endpoints and 1 network, got %v", format, g.GetNodes())
+		}
+
+		es := g.FindEndpoints("hostname", "foo")
+		if len(es) != 1 || len(es[0].Edges) != 1 || es[0].Edges[0].D["ip"] != "10.0.0.1/24" {
+			t.Fatalf("%v: invalid endpoint: %v", format, es)
+		}
+
+		n := g.GetNetworks()[0]
+		if len(n.Endpoints) != 2 || !es[0].Connected(n.ID()) {
+			t.Fatalf("%v: invalid network: %v", format, n)
+		}
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	inputs := map[string]string{
+		FORMAT_DOT:     `graph { a -- b; b -- c; a [_type=endpoint]; b [_type=endpoint] }`,
+		FORMAT_JSON:    `{"graph": {"nodes": [{"id": "1"}, {"id": "1"}]}}`,
+		FORMAT_GRAPHML: `<graphml><graph><edge source="1" target="2"/></graph></graphml>`,
+	}
+
+	for format, s := range inputs {
+		if _, _, err := Import(strings.NewReader(s), format); err == nil {
+			t.Fatalf("%v: expected error", format)
+		}
+	}
+
+	if _, _, err := Import(strings.NewReader(""), "gob"); err == nil {
+		t.Fatalf("expected error for invalid format")
+	}
+}
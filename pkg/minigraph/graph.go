@@ -29,6 +29,12 @@ type Graph struct {
 	lock  sync.Mutex
 	Nodes map[int]Node
 	maxID int
+
+	// undo log for the transaction in progress, if any
+	undo *undo
+
+	// secondary indexes by field
+	indexes map[string]*index
 }
 
 type Node interface {
@@ -39,6 +45,8 @@ type Node interface {
 	Match(string, string) bool
 	setID(int)
 	Data() map[string]string
+	Revision() int
+	setRevision(int)
 }
 
 type networks []*Network
@@ -76,6 +84,14 @@ func Read(f io.Reader) (*Graph, error) {
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
+
+	// graphs written before nodes had revisions
+	for _, n := range g.Nodes {
+		if n.Revision() == 0 {
+			n.setRevision(1)
+		}
+	}
+
 	return g, nil
 }
 
@@ -94,7 +110,9 @@ func (g *Graph) NewEndpoint() *Endpoint {
 	n := &Endpoint{
 		NID: g.newID(),
 		D:   make(map[string]string),
+		Rev: 1,
 	}
+	g.Touch(n.ID())
 	g.Nodes[n.ID()] = n
 	return n
 }
@@ -103,7 +121,9 @@ func (g *Graph) NewNetwork() *Network {
 	n := &Network{
 		NID: g.newID(),
 		D:   make(map[string]string),
+		Rev: 1,
 	}
+	g.Touch(n.ID())
 	g.Nodes[n.ID()] = n
 	return n
 }
@@ -129,7 +149,18 @@ func (g *Graph) Insert(n Node) (Node, error) {
 		n.setID(g.newID())
 	}
 	if _, ok := g.Nodes[n.ID()]; !ok {
+		g.Touch(n.ID())
+		n.setRevision(1)
 		g.Nodes[n.ID()] = n
+		g.reindex(n.ID())
+
+		// make sure we don't hand out this ID later
+		g.lock.Lock()
+		if g.maxID != 0 && n.ID() > g.maxID {
+			g.maxID = n.ID()
+		}
+		g.lock.Unlock()
+
 		return n, nil
 	}
 	return n, fmt.Errorf("node %v already exists", n)
@@ -138,6 +169,7 @@ func (g *Graph) Insert(n Node) (Node, error) {
 // Delete a node from the graph and remove all references to it in other nodes.
 func (g *Graph) Delete(n Node) error {
 	if _, ok := g.Nodes[n.ID()]; ok {
+		g.Touch(n.ID())
 		for _, v := range n.Neighbors() {
 			err := g.Disconnect(n, g.Nodes[v])
 			if err != nil {
@@ -145,6 +177,7 @@ func (g *Graph) Delete(n Node) error {
 			}
 		}
 		delete(g.Nodes, n.ID())
+		g.reindex(n.ID())
 		return nil
 	}
 	return fmt.Errorf("no such node %v", n)
@@ -227,12 +260,17 @@ func (g *Graph) HasNode(n Node) bool {
 	return ok
 }
 
+// Update replaces the node with the same ID. The revision is carried over from
+// the node that is replaced, not taken from n.
 func (g *Graph) Update(n Node) (Node, error) {
 	if !g.HasNode(n) {
 		return nil, fmt.Errorf("no such node %v", n)
 	}
 
+	g.Touch(n.ID())
+	n.setRevision(g.Nodes[n.ID()].Revision())
 	g.Nodes[n.ID()] = n
+	g.reindex(n.ID())
 	return n, nil
 }
 
@@ -265,9 +303,14 @@ func (g *Graph) Connect(e, n Node, edge *Edge) error {
 		return fmt.Errorf("endpoint %v already connected to net %v", endpoint, network)
 	}
 
+	g.Touch(endpoint.ID(), network.ID())
+
 	edge.N = network.ID()
 	network.Endpoints = append(network.Endpoints, endpoint.ID())
 
+	// the edge may be new
+	g.reindex(endpoint.ID())
+
 	return nil
 }
 
@@ -299,6 +342,8 @@ func (g *Graph) Disconnect(n1, n2 Node) error {
 		return fmt.Errorf("node type mismatch: %v, %v", n1.Type(), n2.Type())
 	}
 
+	g.Touch(endpoint.ID(), network.ID())
+
 	for _, v := range endpoint.Edges {
 		if v.N == network.ID() {
 			v.N = UNCONNECTED
@@ -6,8 +6,10 @@ package minigraph
 
 import (
 	"flag"
+	"fmt"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -199,6 +201,114 @@ func TestDisconnect(t *testing.T) {
 
 }
 
+func TestRollback(t *testing.T) {
+	// e1 -- n1 -- e2
+
+	g := New()
+
+	e1 := g.NewEndpoint()
+	e2 := g.NewEndpoint()
+	n1 := g.NewNetwork()
+
+	if err := g.Connect(e1, n1, e1.NewEdge()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Connect(e2, n1, e2.NewEdge()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Begin(); err != nil {
+		t.Fatal(err)
+	}
+
+	// make a mess: delete e2, update e1, add a new network and connect e1
+	if err := g.Delete(e2); err != nil {
+		t.Fatal(err)
+	}
+
+	e1u := e1.Copy()
+	e1u.D["foo"] = "bar"
+	if _, err := g.Update(e1u); err != nil {
+		t.Fatal(err)
+	}
+
+	n2 := g.NewNetwork()
+	g.Touch(e1u.ID())
+	if err := g.Connect(e1u, n2, e1u.NewEdge()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Rollback()
+
+	if len(g.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %v", len(g.Nodes))
+	}
+	if g.HasNode(n2) {
+		t.Fatalf("network still in graph")
+	}
+
+	e1 = g.Nodes[e1.ID()].(*Endpoint)
+	if _, ok := e1.D["foo"]; ok {
+		t.Fatalf("update not undone: %v", e1.D)
+	}
+	if len(e1.Edges) != 1 || e1.Edges[0].N != n1.ID() {
+		t.Fatalf("invalid edges on e1: %v", e1.Edges)
+	}
+
+	n1n := g.Nodes[n1.ID()].Neighbors()
+	if len(n1n) != 2 || n1n[0] != e1.ID() || n1n[1] != e2.ID() {
+		t.Fatalf("invalid edges on n1: %v", n1n)
+	}
+
+	// IDs should be reused after rollback
+	if n := g.NewNetwork(); n.ID() != n2.ID() {
+		t.Fatalf("expected ID %v, got %v", n2.ID(), n.ID())
+	}
+}
+
+func TestNetworkMatch(t *testing.T) {
+	g := New()
+
+	n := g.NewNetwork()
+	n.D["cidr"] = "10.0.0.0/24"
+	n.D["vlan"] = "100"
+
+	if !n.Match("vlan", "100") || !n.Match("cidr", "10.0.0.") {
+		t.Fatalf("expected match on D: %v", n.D)
+	}
+	if !n.Match("", "10.0.0.0/24") {
+		t.Fatalf("expected freeform match on D: %v", n.D)
+	}
+	if !n.Match("nid", fmt.Sprintf("%v", n.ID())) {
+		t.Fatalf("expected match on nid")
+	}
+	if n.Match("vlan", "200") || n.Match("mtu", "") {
+		t.Fatalf("unexpected match: %v", n.D)
+	}
+
+	if res := g.FindNetworks("vlan", "100"); len(res) != 1 {
+		t.Fatalf("expected 1 network, got %v", res)
+	}
+}
+
+func TestDiffData(t *testing.T) {
+	a := map[string]string{"name": "foo", "ip": "10.0.0.1", "mac": "aa"}
+	b := map[string]string{"name": "bar", "ip": "10.0.0.1", "os": "linux"}
+
+	d := DiffData(a, b)
+	if len(d.Set) != 2 || d.Set["name"] != "bar" || d.Set["os"] != "linux" {
+		t.Fatalf("invalid set: %v", d.Set)
+	}
+	if len(d.Deleted) != 1 || d.Deleted[0] != "mac" {
+		t.Fatalf("invalid deleted: %v", d.Deleted)
+	}
+
+	d.Apply(a)
+	if d := DiffData(a, b); !d.Empty() {
+		t.Fatalf("expected no changes after apply, got %v", d)
+	}
+}
+
 func BenchmarkBigGraph(b *testing.B) {
 	g := New()
 	rand.Seed(time.Now().UnixNano())
@@ -214,3 +324,220 @@ func BenchmarkBigGraph(b *testing.B) {
 		}
 	}
 }
+
+func TestDiffNodes(t *testing.T) {
+	g := New()
+
+	n := g.NewNetwork()
+	e := g.NewEndpoint()
+	e.D["name"] = "foo"
+
+	edge := e.NewEdge()
+	edge.N = UNCONNECTED
+	edge.D["ip"] = "10.0.0.1/24"
+
+	if err := g.Begin(); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Touch(e.ID())
+	e.D["name"] = "bar"
+	if err := g.Connect(e, n, edge); err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := g.NewEndpoint()
+
+	before := map[int]Node{}
+	for id, n := range g.Nodes {
+		before[id] = n
+	}
+	for id, n := range g.Touched() {
+		if n == nil {
+			delete(before, id)
+		} else {
+			before[id] = n
+		}
+	}
+
+	g.Commit()
+
+	diffs := DiffNodes(before, g.Nodes)
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %v", len(diffs))
+	}
+
+	nd, ed, ed2 := diffs[0], diffs[1], diffs[2]
+
+	if nd.NID != n.ID() || len(nd.Connected) != 1 || nd.Connected[0] != e.ID() {
+		t.Fatalf("invalid network diff: %+v", nd)
+	}
+
+	if ed.NID != e.ID() || ed.Data.Set["name"] != "bar" || len(ed.Edges) != 1 {
+		t.Fatalf("invalid endpoint diff: %+v", ed)
+	}
+	if d := ed.Edges[0]; d.From != UNCONNECTED || d.To != n.ID() || !d.Data.Empty() {
+		t.Fatalf("invalid edge diff: %+v", d)
+	}
+
+	if ed2.NID != e2.ID() || !ed2.Added {
+		t.Fatalf("invalid added diff: %+v", ed2)
+	}
+
+	// reverse diff
+	diffs = DiffNodes(g.Nodes, before)
+	if len(diffs) != 3 || !diffs[2].Removed || len(diffs[0].Disconnected) != 1 {
+		t.Fatalf("invalid reverse diff: %+v", diffs)
+	}
+
+	if diffs := DiffNodes(g.Nodes, g.Nodes); len(diffs) != 0 {
+		t.Fatalf("expected no diffs, got %v", diffs)
+	}
+}
+
+func TestRevision(t *testing.T) {
+	g := New()
+
+	e := g.NewEndpoint()
+	n := g.NewNetwork()
+	if e.Rev != 1 || n.Rev != 1 {
+		t.Fatalf("expected new nodes at rev 1, got %v and %v", e.Rev, n.Rev)
+	}
+
+	// changes outside of a transaction don't change the revision
+	edge := e.NewEdge()
+	if err := g.Connect(e, n, edge); err != nil {
+		t.Fatal(err)
+	}
+	if e.Rev != 1 || n.Rev != 1 {
+		t.Fatalf("expected rev 1, got %v and %v", e.Rev, n.Rev)
+	}
+
+	if err := g.Begin(); err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := &Endpoint{NID: e.ID(), D: map[string]string{"name": "foo"}, Rev: 100}
+	if _, err := g.Update(e2); err != nil {
+		t.Fatal(err)
+	}
+	e3 := g.NewEndpoint()
+
+	g.Commit()
+
+	if e2.Rev != 2 {
+		t.Fatalf("expected updated endpoint at rev 2, got %v", e2.Rev)
+	}
+	if n.Rev != 1 || e3.Rev != 1 {
+		t.Fatalf("expected rev 1, got %v and %v", n.Rev, e3.Rev)
+	}
+
+	// rolled back changes don't change the revision either
+	if err := g.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Patch(e.ID(), &Patch{Data: DataDiff{Set: map[string]string{"name": "bar"}}}); err != nil {
+		t.Fatal(err)
+	}
+	g.Rollback()
+
+	if g.Nodes[e.ID()].Revision() != 2 {
+		t.Fatalf("expected rev 2 after rollback, got %v", g.Nodes[e.ID()].Revision())
+	}
+}
+
+func TestPatch(t *testing.T) {
+	g := New()
+	g.AddIndex("name")
+
+	e := g.NewEndpoint()
+	e.D["name"] = "foo"
+	e.D["os"] = "linux"
+	edge := e.NewEdge()
+	edge.D["ip"] = "10.0.0.1/24"
+	g.reindex(e.ID())
+
+	n := g.NewNetwork()
+
+	p := &Patch{
+		Data: DataDiff{
+			Set:     map[string]string{"name": "bar", "icon": "router"},
+			Deleted: []string{"os"},
+		},
+		Edges: map[int]DataDiff{
+			0: {Set: map[string]string{"mac": "00:11:22:33:44:55"}},
+		},
+	}
+
+	if _, err := g.Patch(e.ID(), p); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.D["name"] != "bar" || e.D["icon"] != "router" || len(e.D) != 2 {
+		t.Fatalf("invalid data after patch: %v", e.D)
+	}
+	if edge.D["ip"] != "10.0.0.1/24" || edge.D["mac"] != "00:11:22:33:44:55" {
+		t.Fatalf("invalid edge data after patch: %v", edge.D)
+	}
+
+	if res := g.Lookup("name", "bar"); len(res) != 1 {
+		t.Fatalf("expected patched endpoint in index, got %v", res)
+	}
+	if res := g.Lookup("name", "foo"); len(res) != 0 {
+		t.Fatalf("expected old value removed from index, got %v", res)
+	}
+
+	// invalid edges leave the node unchanged
+	p = &Patch{
+		Data:  DataDiff{Set: map[string]string{"name": "baz"}},
+		Edges: map[int]DataDiff{1: {Deleted: []string{"ip"}}},
+	}
+	if _, err := g.Patch(e.ID(), p); err == nil {
+		t.Fatal("expected error for invalid edge")
+	}
+	if e.D["name"] != "bar" {
+		t.Fatalf("expected unchanged endpoint, got %v", e.D)
+	}
+
+	// networks have no edges
+	if _, err := g.Patch(n.ID(), p); err == nil {
+		t.Fatal("expected error for network edges")
+	}
+
+	if _, err := g.Patch(100, &Patch{}); err == nil {
+		t.Fatal("expected error for missing node")
+	}
+}
+
+func TestDiffEndpoint(t *testing.T) {
+	a := &Endpoint{
+		D: map[string]string{"name": "r1", "os": "ios"},
+		Edges: []*Edge{
+			{D: map[string]string{"ip": "10.0.0.1/24"}},
+			{D: map[string]string{"ip": "10.0.1.1/24"}},
+		},
+	}
+
+	b := a.Copy()
+	b.D["router"] = "true"
+	delete(b.D, "os")
+	b.Edges[1].D["OSPF"] = "true"
+	b.Edges = append(b.Edges, &Edge{D: map[string]string{"ip": "10.0.2.1/24"}})
+
+	p := DiffEndpoint(a, b)
+
+	want := &Patch{
+		Data: DataDiff{Set: map[string]string{"router": "true"}, Deleted: []string{"os"}},
+		Edges: map[int]DataDiff{
+			1: {Set: map[string]string{"OSPF": "true"}},
+		},
+	}
+
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+
+	if p := DiffEndpoint(a, a.Copy()); !p.Empty() {
+		t.Fatalf("expected no changes, got %+v", p)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const graphmlNS = "http://graphml.graphdrawing.org/xmlns"
+
+type graphmlDoc struct {
+	XMLName xml.Name      `xml:"graphml"`
+	XMLNS   string        `xml:"xmlns,attr,omitempty"`
+	Keys    []*graphmlKey `xml:"key"`
+	Graph   *graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID      string  `xml:"id,attr"`
+	For     string  `xml:"for,attr,omitempty"`
+	Name    string  `xml:"attr.name,attr,omitempty"`
+	Type    string  `xml:"attr.type,attr,omitempty"`
+	Default *string `xml:"default"`
+
+	// yEd stores its graphics in keys like this, which we ignore
+	YFiles string `xml:"yfiles.type,attr,omitempty"`
+}
+
+type graphmlGraph struct {
+	ID          string         `xml:"id,attr,omitempty"`
+	EdgeDefault string         `xml:"edgedefault,attr"`
+	Data        []*graphmlData `xml:"data"`
+	Nodes       []*graphmlNode `xml:"node"`
+	Edges       []*graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string         `xml:"id,attr"`
+	Data []*graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source string         `xml:"source,attr"`
+	Target string         `xml:"target,attr"`
+	Data   []*graphmlData `xml:"data"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// name returns the attribute name for the key, which is optional
+func (k *graphmlKey) name() string {
+	if k.Name == "" {
+		return k.ID
+	}
+	return k.Name
+}
+
+func writeGraphML(w io.Writer, fg *flatGraph) error {
+	doc := &graphmlDoc{
+		XMLNS: graphmlNS,
+		Graph: &graphmlGraph{
+			ID:          "G",
+			EdgeDefault: "undirected",
+		},
+	}
+
+	// key IDs for each attribute name, by domain
+	keys := map[string]map[string]string{}
+
+	addKeys := func(domain, prefix string, names []string) {
+		keys[domain] = map[string]string{}
+
+		for i, name := range names {
+			k := &graphmlKey{
+				ID:   fmt.Sprintf("%v%v", prefix, i),
+				For:  domain,
+				Name: name,
+				Type: "string",
+			}
+
+			keys[domain][name] = k.ID
+			doc.Keys = append(doc.Keys, k)
+		}
+	}
+
+	data := func(domain string, attrs map[string]string) []*graphmlData {
+		var res []*graphmlData
+		for _, k := range sortedKeys(attrs) {
+			res = append(res, &graphmlData{
+				Key:   keys[domain][k],
+				Value: attrs[k],
+			})
+		}
+		return res
+	}
+
+	var nodeAttrs, edgeAttrs []map[string]string
+	for _, n := range fg.Nodes {
+		nodeAttrs = append(nodeAttrs, n.Attrs)
+	}
+	for _, e := range fg.Edges {
+		edgeAttrs = append(edgeAttrs, e.Attrs)
+	}
+
+	addKeys("graph", "g", sortedKeys(fg.Attrs))
+	addKeys("node", "n", sortedKeys(nodeAttrs...))
+	addKeys("edge", "e", sortedKeys(edgeAttrs...))
+
+	doc.Graph.Data = data("graph", fg.Attrs)
+
+	for _, n := range fg.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, &graphmlNode{
+			ID:   n.ID,
+			Data: data("node", n.Attrs),
+		})
+	}
+
+	for _, e := range fg.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, &graphmlEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data:   data("edge", e.Attrs),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func readGraphML(r io.Reader) (*flatGraph, error) {
+	doc := &graphmlDoc{}
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Graph == nil {
+		return nil, fmt.Errorf("graphml: missing graph")
+	}
+
+	keys := map[string]*graphmlKey{}
+	for _, k := range doc.Keys {
+		keys[k.ID] = k
+	}
+
+	// attrs converts data to attributes for the domain, including defaults
+	attrs := func(domain string, data []*graphmlData) map[string]string {
+		res := map[string]string{}
+
+		for _, k := range doc.Keys {
+			if k.Default != nil && k.YFiles == "" && (k.For == domain || k.For == "all") {
+				res[k.name()] = *k.Default
+			}
+		}
+
+		for _, d := range data {
+			k, ok := keys[d.Key]
+			if !ok {
+				res[d.Key] = d.Value
+			} else if k.YFiles == "" {
+				res[k.name()] = d.Value
+			}
+		}
+
+		return res
+	}
+
+	fg := &flatGraph{
+		Attrs: attrs("graph", doc.Graph.Data),
+	}
+
+	for _, n := range doc.Graph.Nodes {
+		fg.Nodes = append(fg.Nodes, &flatNode{
+			ID:    n.ID,
+			Attrs: attrs("node", n.Data),
+		})
+	}
+
+	for _, e := range doc.Graph.Edges {
+		fg.Edges = append(fg.Edges, &flatEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Attrs:  attrs("edge", e.Data),
+		})
+	}
+
+	return fg, nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// index is a secondary index on a field, see AddIndex.
+type index struct {
+	field string
+
+	// value -> NIDs of nodes with that value
+	values map[string]map[int]bool
+
+	// NID -> values that the node is indexed under so that we can remove
+	// the node even if it was modified in place
+	nodes map[int][]string
+
+	// values that are IPv4 and IPv6 addresses or CIDRs, for LookupCIDR
+	ip4, ip6 *ipTrie
+}
+
+// AddIndex adds a secondary index on the field, using the same field names as
+// Query (e.g. "hostname" or "edge.ip"). Indexes are updated by Insert, Update,
+// Delete, Connect, Disconnect, and Rollback -- nodes that are modified
+// directly are not reindexed until they are passed to Update. Indexes are not
+// saved by Write. The "nid" field is always indexed.
+func (g *Graph) AddIndex(field string) {
+	if strings.EqualFold(field, "nid") {
+		return
+	}
+
+	if _, ok := g.indexes[field]; ok {
+		return
+	}
+
+	idx := &index{
+		field:  field,
+		values: make(map[string]map[int]bool),
+		nodes:  make(map[int][]string),
+		ip4:    &ipTrie{},
+		ip6:    &ipTrie{},
+	}
+
+	for _, n := range g.Nodes {
+		idx.add(n)
+	}
+
+	if g.indexes == nil {
+		g.indexes = make(map[string]*index)
+	}
+	g.indexes[field] = idx
+}
+
+// Indexes returns the sorted list of indexed fields, not including "nid".
+func (g *Graph) Indexes() []string {
+	var res []string
+	for k := range g.indexes {
+		res = append(res, k)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// Lookup returns the nodes with a field that exactly matches the value, sorted
+// by ID. For edge fields, any of an endpoint's edges may match. Falls back to
+// scanning all the nodes if the field is not indexed.
+func (g *Graph) Lookup(field, value string) []Node {
+	if strings.EqualFold(field, "nid") {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil
+		}
+
+		if n, ok := g.Nodes[id]; ok {
+			return []Node{n}
+		}
+		return nil
+	}
+
+	if idx, ok := g.indexes[field]; ok {
+		return g.sortedNodes(idx.values[value])
+	}
+
+	var res []Node
+	for _, n := range g.Nodes {
+		match := matchField(n, field, func(v string, ok bool) bool {
+			return ok && v == value
+		})
+
+		if match {
+			res = append(res, n)
+		}
+	}
+
+	sortNodes(res)
+
+	return res
+}
+
+// LookupCIDR returns the nodes with a field containing an IP (or CIDR) within
+// the subnet, sorted by ID. Falls back to scanning all the nodes if the field
+// is not indexed.
+func (g *Graph) LookupCIDR(field string, subnet *net.IPNet) []Node {
+	if idx, ok := g.indexes[field]; ok {
+		t, ip := idx.trie(subnet.IP)
+		bits, size := subnet.Mask.Size()
+
+		// IPv4-mapped IPv6 subnets have a 128-bit mask
+		if len(ip) == net.IPv4len && size == 8*net.IPv6len {
+			bits -= 8 * (net.IPv6len - net.IPv4len)
+			if bits < 0 {
+				bits = 0
+			}
+		}
+
+		nids := map[int]bool{}
+		t.find(ip, bits, nids)
+
+		return g.sortedNodes(nids)
+	}
+
+	var res []Node
+	for _, n := range g.Nodes {
+		match := matchField(n, field, func(v string, ok bool) bool {
+			ip := parseIP(v)
+			return ok && ip != nil && subnet.Contains(ip)
+		})
+
+		if match {
+			res = append(res, n)
+		}
+	}
+
+	sortNodes(res)
+
+	return res
+}
+
+// reindex updates the indexes for the nodes with the given IDs, removing them
+// if they are no longer in the graph.
+func (g *Graph) reindex(ids ...int) {
+	for _, idx := range g.indexes {
+		for _, id := range ids {
+			idx.remove(id)
+
+			if n, ok := g.Nodes[id]; ok {
+				idx.add(n)
+			}
+		}
+	}
+}
+
+func (g *Graph) sortedNodes(nids map[int]bool) []Node {
+	var res []Node
+	for id := range nids {
+		if n, ok := g.Nodes[id]; ok {
+			res = append(res, n)
+		}
+	}
+
+	sortNodes(res)
+
+	return res
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID() < nodes[j].ID()
+	})
+}
+
+func (idx *index) add(n Node) {
+	var vals []string
+
+	matchField(n, idx.field, func(v string, ok bool) bool {
+		if !ok {
+			return false
+		}
+
+		// skip duplicates from multiple edges
+		for _, v2 := range vals {
+			if v == v2 {
+				return false
+			}
+		}
+
+		vals = append(vals, v)
+		return false
+	})
+
+	if len(vals) == 0 {
+		return
+	}
+
+	for _, v := range vals {
+		if idx.values[v] == nil {
+			idx.values[v] = make(map[int]bool)
+		}
+		idx.values[v][n.ID()] = true
+
+		if ip := parseIP(v); ip != nil {
+			t, ip := idx.trie(ip)
+			t.insert(ip, n.ID())
+		}
+	}
+
+	idx.nodes[n.ID()] = vals
+}
+
+func (idx *index) remove(id int) {
+	for _, v := range idx.nodes[id] {
+		delete(idx.values[v], id)
+		if len(idx.values[v]) == 0 {
+			delete(idx.values, v)
+		}
+
+		if ip := parseIP(v); ip != nil {
+			t, ip := idx.trie(ip)
+			t.remove(ip, id)
+		}
+	}
+
+	delete(idx.nodes, id)
+}
+
+// trie returns the trie for the IP's address family and the IP in the form
+// used by that trie.
+func (idx *index) trie(ip net.IP) (*ipTrie, net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return idx.ip4, ip4
+	}
+
+	return idx.ip6, ip.To16()
+}
+
+// ipTrie is a binary trie of IPs to find the IPs within a subnet.
+type ipTrie struct {
+	child [2]*ipTrie
+
+	// NIDs of nodes with this IP and their reference count, only set on
+	// leaves
+	nids map[int]int
+}
+
+func ipBit(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+func (t *ipTrie) insert(ip net.IP, nid int) {
+	for i := 0; i < 8*len(ip); i++ {
+		b := ipBit(ip, i)
+		if t.child[b] == nil {
+			t.child[b] = &ipTrie{}
+		}
+		t = t.child[b]
+	}
+
+	if t.nids == nil {
+		t.nids = make(map[int]int)
+	}
+	t.nids[nid] += 1
+}
+
+func (t *ipTrie) remove(ip net.IP, nid int) {
+	for i := 0; i < 8*len(ip) && t != nil; i++ {
+		t = t.child[ipBit(ip, i)]
+	}
+
+	if t == nil {
+		return
+	}
+
+	if t.nids[nid] -= 1; t.nids[nid] <= 0 {
+		delete(t.nids, nid)
+	}
+}
+
+// find adds the NIDs for all the IPs with the prefix to res
+func (t *ipTrie) find(prefix net.IP, bits int, res map[int]bool) {
+	for i := 0; i < bits && t != nil; i++ {
+		t = t.child[ipBit(prefix, i)]
+	}
+
+	t.collect(res)
+}
+
+func (t *ipTrie) collect(res map[int]bool) {
+	if t == nil {
+		return
+	}
+
+	for nid := range t.nids {
+		res[nid] = true
+	}
+
+	t.child[0].collect(res)
+	t.child[1].collect(res)
+}
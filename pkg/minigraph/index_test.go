@@ -0,0 +1,102 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"net"
+	"testing"
+)
+
+func nodeIDs(nodes []Node) []int {
+	var res []int
+	for _, n := range nodes {
+		res = append(res, n.ID())
+	}
+	return res
+}
+
+func checkIDs(t *testing.T, desc string, got []Node, want ...int) {
+	t.Helper()
+
+	ids := nodeIDs(got)
+	if len(ids) != len(want) {
+		t.Fatalf("%v: got %v, want %v", desc, ids, want)
+	}
+	for i := range ids {
+		if ids[i] != want[i] {
+			t.Fatalf("%v: got %v, want %v", desc, ids, want)
+		}
+	}
+}
+
+func mustCIDR(t *testing.T, s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestIndex(t *testing.T) {
+	for _, indexed := range []bool{true, false} {
+		g := queryGraph()
+		if indexed {
+			g.AddIndex("os")
+			g.AddIndex("edge.ip")
+		}
+
+		checkIDs(t, "os=linux", g.Lookup("os", "linux"), 1, 2)
+		checkIDs(t, "os=lin", g.Lookup("os", "lin"))
+		checkIDs(t, "nid=3", g.Lookup("nid", "3"), 3)
+		checkIDs(t, "edge.ip", g.Lookup("edge.ip", "10.1.2.1"), 2)
+		checkIDs(t, "10/8", g.LookupCIDR("edge.ip", mustCIDR(t, "10.0.0.0/8")), 1, 2)
+		checkIDs(t, "10.1.2.0/31", g.LookupCIDR("edge.ip", mustCIDR(t, "10.1.2.0/31")), 2)
+		checkIDs(t, "0/0", g.LookupCIDR("edge.ip", mustCIDR(t, "0.0.0.0/0")), 1, 2, 3)
+		checkIDs(t, "::/0", g.LookupCIDR("edge.ip", mustCIDR(t, "::/0")))
+
+		// update e1 to windows with a new IP
+		e1 := g.Nodes[1].(*Endpoint).Copy()
+		e1.D["os"] = "windows"
+		e1.Edges[0].D["ip"] = "172.16.1.1"
+		if _, err := g.Update(e1); err != nil {
+			t.Fatal(err)
+		}
+
+		checkIDs(t, "os=linux after update", g.Lookup("os", "linux"), 2)
+		checkIDs(t, "os=windows after update", g.Lookup("os", "windows"), 1)
+		checkIDs(t, "172.16/12 after update", g.LookupCIDR("edge.ip", mustCIDR(t, "172.16.0.0/12")), 1, 3)
+
+		// new edges are indexed on connect
+		n := g.Nodes[4].(*Network)
+		edge := e1.NewEdge()
+		edge.D["ip"] = "192.168.1.2"
+		if err := g.Connect(e1, n, edge); err != nil {
+			t.Fatal(err)
+		}
+
+		checkIDs(t, "192.168/16 after connect", g.LookupCIDR("edge.ip", mustCIDR(t, "192.168.0.0/16")), 1, 2)
+
+		// changes are undone by rollback
+		if err := g.Begin(); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Delete(g.Nodes[2]); err != nil {
+			t.Fatal(err)
+		}
+
+		e3 := &Endpoint{D: map[string]string{"os": "linux"}}
+		if _, err := g.Insert(e3); err != nil {
+			t.Fatal(err)
+		}
+
+		checkIDs(t, "os=linux in tx", g.Lookup("os", "linux"), e3.ID())
+		checkIDs(t, "192.168/16 in tx", g.LookupCIDR("edge.ip", mustCIDR(t, "192.168.0.0/16")), 1)
+
+		g.Rollback()
+
+		checkIDs(t, "os=linux after rollback", g.Lookup("os", "linux"), 2)
+		checkIDs(t, "192.168/16 after rollback", g.LookupCIDR("edge.ip", mustCIDR(t, "192.168.0.0/16")), 1, 2)
+	}
+}
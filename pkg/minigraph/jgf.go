@@ -0,0 +1,181 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// JSON Graph Format, see http://jsongraphformat.info. We write version 1
+// (nodes as a list) and read both version 1 and 2 (nodes as an object keyed by
+// ID). Attributes are stored in the metadata.
+type jgfDoc struct {
+	Graph  *jgfGraph   `json:"graph,omitempty"`
+	Graphs []*jgfGraph `json:"graphs,omitempty"`
+}
+
+type jgfGraph struct {
+	Directed bool                   `json:"directed"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Nodes    json.RawMessage        `json:"nodes,omitempty"`
+	Edges    []*jgfEdge             `json:"edges,omitempty"`
+}
+
+type jgfNode struct {
+	ID       string                 `json:"id,omitempty"`
+	Label    string                 `json:"label,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type jgfEdge struct {
+	Source   string                 `json:"source"`
+	Target   string                 `json:"target"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func writeJGF(w io.Writer, fg *flatGraph) error {
+	metadata := func(attrs map[string]string) map[string]interface{} {
+		res := map[string]interface{}{}
+		for k, v := range attrs {
+			res[k] = v
+		}
+		return res
+	}
+
+	nodes := []*jgfNode{}
+	for _, n := range fg.Nodes {
+		nodes = append(nodes, &jgfNode{
+			ID:       n.ID,
+			Label:    n.Attrs["name"],
+			Metadata: metadata(n.Attrs),
+		})
+	}
+
+	b, err := json.Marshal(nodes)
+	if err != nil {
+		return err
+	}
+
+	g := &jgfGraph{
+		Metadata: metadata(fg.Attrs),
+		Nodes:    b,
+	}
+
+	for _, e := range fg.Edges {
+		g.Edges = append(g.Edges, &jgfEdge{
+			Source:   e.Source,
+			Target:   e.Target,
+			Metadata: metadata(e.Attrs),
+		})
+	}
+
+	b, err = json.MarshalIndent(&jgfDoc{Graph: g}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func readJGF(r io.Reader) (*flatGraph, error) {
+	doc := &jgfDoc{}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(doc); err != nil {
+		return nil, err
+	}
+
+	g := doc.Graph
+	if g == nil && len(doc.Graphs) > 0 {
+		g = doc.Graphs[0]
+	}
+	if g == nil {
+		return nil, fmt.Errorf("json: missing graph")
+	}
+
+	fg := &flatGraph{
+		Attrs: jgfAttrs(g.Metadata),
+	}
+
+	var nodes []*jgfNode
+
+	if len(g.Nodes) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(g.Nodes))
+		dec.UseNumber()
+		if err := dec.Decode(&nodes); err != nil {
+			// try version 2, nodes keyed by ID
+			byID := map[string]*jgfNode{}
+
+			dec := json.NewDecoder(bytes.NewReader(g.Nodes))
+			dec.UseNumber()
+			if err := dec.Decode(&byID); err != nil {
+				return nil, err
+			}
+
+			var ids []string
+			for k := range byID {
+				ids = append(ids, k)
+			}
+			sort.Strings(ids)
+
+			for _, k := range ids {
+				n := byID[k]
+				n.ID = k
+				nodes = append(nodes, n)
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		attrs := jgfAttrs(n.Metadata)
+		if _, ok := attrs["name"]; !ok && n.Label != "" {
+			attrs["name"] = n.Label
+		}
+
+		fg.Nodes = append(fg.Nodes, &flatNode{
+			ID:    n.ID,
+			Attrs: attrs,
+		})
+	}
+
+	for _, e := range g.Edges {
+		fg.Edges = append(fg.Edges, &flatEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Attrs:  jgfAttrs(e.Metadata),
+		})
+	}
+
+	return fg, nil
+}
+
+// jgfAttrs converts metadata to attributes, encoding values that are not
+// strings as JSON.
+func jgfAttrs(m map[string]interface{}) map[string]string {
+	res := map[string]string{}
+
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			res[k] = v
+		case json.Number:
+			res[k] = v.String()
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			res[k] = string(b)
+		}
+	}
+
+	return res
+}
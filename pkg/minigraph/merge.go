@@ -0,0 +1,408 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Policies for resolving conflicting values when merging data maps.
+const (
+	MERGE_PREFER_LEFT  = "prefer-left"
+	MERGE_PREFER_RIGHT = "prefer-right"
+	MERGE_JOIN         = "join"
+)
+
+// DefaultMergeKeys are the identity keys used to match endpoints when none are
+// specified.
+var DefaultMergeKeys = []string{"edge.mac", "edge.ip", "uuid", "hostname"}
+
+// MergeOptions control how one graph is merged into another.
+type MergeOptions struct {
+	// Keys identify endpoints: an endpoint in the other graph is merged with
+	// the first endpoint in the graph that has the same value for one of the
+	// keys, in order. Keys are fields of the endpoint's data or, with the
+	// edge. prefix, fields of its edges' data. MACs and IPs are compared
+	// after parsing them so formatting and prefix lengths don't matter.
+	// DefaultMergeKeys if empty.
+	Keys []string
+
+	// Policy resolves keys that have different values in the two graphs,
+	// MERGE_PREFER_LEFT if empty.
+	Policy string
+}
+
+// MergeResult summarizes a merge.
+type MergeResult struct {
+	// NIDs maps the NIDs of the nodes in the other graph to the NIDs of the
+	// nodes they were merged into or inserted as
+	NIDs map[int]int
+
+	// number of nodes that were merged with an existing node
+	Matched int
+
+	// number of nodes that were inserted
+	Inserted int
+
+	// number of keys with conflicting values
+	Conflicts int
+}
+
+// link is an edge from the other graph that should be connected to the
+// network that the other graph's network was mapped to
+type link struct {
+	e    *Endpoint
+	edge *Edge
+	n    int
+}
+
+// Merge imports the nodes from other into the graph, leaving other unchanged.
+// Endpoints are matched using the identity keys and networks are matched when
+// matched edges connect them or, failing that, when they have the same cidr or
+// cidr6. Matched nodes have their data merged using the policy and other nodes
+// are inserted with new NIDs. The edges of matched endpoints are matched by
+// mac or ip and other edges are appended. An edge that is already connected
+// keeps its connection.
+func (g *Graph) Merge(other *Graph, opts *MergeOptions) (*MergeResult, error) {
+	keys := DefaultMergeKeys
+	policy := MERGE_PREFER_LEFT
+
+	if opts != nil {
+		if len(opts.Keys) > 0 {
+			keys = opts.Keys
+		}
+		if opts.Policy != "" {
+			policy = opts.Policy
+		}
+	}
+
+	switch policy {
+	case MERGE_PREFER_LEFT, MERGE_PREFER_RIGHT, MERGE_JOIN:
+	default:
+		return nil, fmt.Errorf("invalid merge policy: %v", policy)
+	}
+
+	res := &MergeResult{NIDs: map[int]int{}}
+
+	merge := func(dst, src map[string]string) {
+		res.Conflicts += mergeData(dst, src, policy)
+	}
+
+	// key -> identity -> NID of the first endpoint with the identity
+	endpoints := map[string]map[string]int{}
+	for _, k := range keys {
+		endpoints[k] = map[string]int{}
+	}
+
+	// cidr -> NID of the first network with the subnet
+	networks := map[string]int{}
+
+	for _, id := range g.ids() {
+		switch n := g.Nodes[id].(type) {
+		case *Endpoint:
+			for _, k := range keys {
+				for _, v := range identities(n, k) {
+					if _, ok := endpoints[k][v]; !ok {
+						endpoints[k][v] = id
+					}
+				}
+			}
+		case *Network:
+			for _, v := range subnets(n) {
+				if _, ok := networks[v]; !ok {
+					networks[v] = id
+				}
+			}
+		}
+	}
+
+	var links []link
+
+	for _, id := range other.ids() {
+		e, ok := other.Nodes[id].(*Endpoint)
+		if !ok {
+			continue
+		}
+
+		var match *Endpoint
+		for _, k := range keys {
+			for _, v := range identities(e, k) {
+				if nid, ok := endpoints[k][v]; ok && match == nil {
+					match = g.Nodes[nid].(*Endpoint)
+				}
+			}
+		}
+
+		if match == nil {
+			e2 := e.Copy()
+			e2.NID = 0
+			for i, edge := range e2.Edges {
+				edge.N = UNCONNECTED
+				links = append(links, link{e2, edge, e.Edges[i].N})
+			}
+
+			g.Insert(e2)
+
+			res.NIDs[id] = e2.NID
+			res.Inserted++
+			continue
+		}
+
+		g.Touch(match.NID)
+		merge(match.D, e.D)
+
+		for _, edge := range e.Edges {
+			edge2 := matchEdge(match, edge)
+			if edge2 == nil {
+				edge2 = edge.Copy()
+				edge2.N = UNCONNECTED
+				match.Edges = append(match.Edges, edge2)
+			} else {
+				merge(edge2.D, edge.D)
+
+				// matched edges on both sides map the networks
+				if _, ok := res.NIDs[edge.N]; !ok && edge.N != UNCONNECTED && edge2.N != UNCONNECTED {
+					res.NIDs[edge.N] = edge2.N
+				}
+			}
+
+			links = append(links, link{match, edge2, edge.N})
+		}
+
+		g.reindex(match.NID)
+
+		res.NIDs[id] = match.NID
+		res.Matched++
+	}
+
+	for _, id := range other.ids() {
+		n, ok := other.Nodes[id].(*Network)
+		if !ok {
+			continue
+		}
+
+		nid, ok := res.NIDs[id]
+		if !ok {
+			for _, v := range subnets(n) {
+				if nid2, ok2 := networks[v]; ok2 && !ok {
+					nid, ok = nid2, true
+				}
+			}
+		}
+
+		if ok {
+			g.Touch(nid)
+			merge(g.Nodes[nid].Data(), n.D)
+			g.reindex(nid)
+
+			res.NIDs[id] = nid
+			res.Matched++
+			continue
+		}
+
+		n2 := n.Copy()
+		n2.NID = 0
+		n2.Endpoints = nil
+
+		g.Insert(n2)
+
+		res.NIDs[id] = n2.NID
+		res.Inserted++
+	}
+
+	for _, l := range links {
+		if l.n == UNCONNECTED || l.edge.N != UNCONNECTED {
+			continue
+		}
+
+		nid, ok := res.NIDs[l.n]
+		if !ok || l.e.Connected(nid) {
+			// dangling in the other graph or already connected
+			continue
+		}
+
+		if err := g.Connect(l.e, g.Nodes[nid], l.edge); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// mergeData merges src into dst using the policy and returns the number of
+// conflicting keys. MACs and IPs that only differ in formatting don't
+// conflict.
+func mergeData(dst, src map[string]string, policy string) int {
+	var conflicts int
+
+	for k, v := range src {
+		v2, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		} else if identity(k, v) == identity(k, v2) {
+			continue
+		}
+
+		conflicts++
+
+		switch policy {
+		case MERGE_PREFER_RIGHT:
+			dst[k] = v
+		case MERGE_JOIN:
+			vals := strings.Split(v2, ",")
+			for _, s := range strings.Split(v, ",") {
+				var found bool
+				for _, s2 := range vals {
+					found = found || s == s2
+				}
+
+				if !found {
+					vals = append(vals, s)
+				}
+			}
+
+			dst[k] = strings.Join(vals, ",")
+		}
+	}
+
+	return conflicts
+}
+
+// identities returns the normalized values of the identity key for the
+// endpoint
+func identities(e *Endpoint, k string) []string {
+	var res []string
+
+	if field := strings.TrimPrefix(k, "edge."); field != k {
+		for _, edge := range e.Edges {
+			if v := identity(field, edge.D[field]); v != "" {
+				res = append(res, v)
+			}
+		}
+	} else if v := identity(k, e.D[k]); v != "" {
+		res = append(res, v)
+	}
+
+	return res
+}
+
+// identity normalizes MACs and IPs so that they can be compared
+func identity(k, v string) string {
+	switch {
+	case v == "":
+		return ""
+	case strings.HasSuffix(k, "mac"):
+		if mac, err := net.ParseMAC(v); err == nil {
+			return mac.String()
+		}
+	case k == "ip" || k == "ip6":
+		if ip := parseIP(v); ip != nil {
+			return ip.String()
+		}
+	}
+
+	return v
+}
+
+// subnets returns the normalized cidr and cidr6 of the network
+func subnets(n *Network) []string {
+	var res []string
+
+	for _, k := range []string{"cidr", "cidr6"} {
+		if _, ipnet, err := net.ParseCIDR(n.D[k]); err == nil {
+			res = append(res, ipnet.String())
+		}
+	}
+
+	return res
+}
+
+// matchEdge finds the endpoint's edge with the same mac or, if the edges don't
+// have macs, the same ip or ip6
+func matchEdge(e *Endpoint, edge *Edge) *Edge {
+	for _, keys := range [][]string{{"mac"}, {"ip", "ip6"}} {
+		for _, k := range keys {
+			v := identity(k, edge.D[k])
+			if v == "" {
+				continue
+			}
+
+			for _, edge2 := range e.Edges {
+				if identity(k, edge2.D[k]) == v {
+					return edge2
+				}
+			}
+		}
+
+		for _, k := range keys {
+			if edge.D[k] != "" {
+				// has a mac that didn't match, don't fall back to ips
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// Extract returns a new graph containing copies of the nodes that match the
+// query with the same NIDs. The networks that the matching endpoints are
+// connected to are included so that the endpoints keep their links. Links to
+// nodes that aren't included are removed. If q is nil, the whole graph is
+// copied.
+func (g *Graph) Extract(q *Query) *Graph {
+	include := map[int]bool{}
+
+	for id, n := range g.Nodes {
+		if q != nil && !q.Match(n) {
+			continue
+		}
+
+		include[id] = true
+
+		if e, ok := n.(*Endpoint); ok {
+			for _, v := range e.Neighbors() {
+				if _, ok := g.Nodes[v]; ok {
+					include[v] = true
+				}
+			}
+		}
+	}
+
+	res := New()
+
+	// don't reuse NIDs that have been used in the graph
+	g.lock.Lock()
+	res.maxID = g.maxID
+	g.lock.Unlock()
+
+	for _, n := range g.sortedNodes(include) {
+		switch n := CopyNode(n).(type) {
+		case *Endpoint:
+			for _, edge := range n.Edges {
+				if !include[edge.N] {
+					edge.N = UNCONNECTED
+				}
+			}
+
+			res.Insert(n)
+		case *Network:
+			var eps []int
+			for _, v := range n.Endpoints {
+				if include[v] {
+					eps = append(eps, v)
+				}
+			}
+			n.Endpoints = eps
+
+			res.Insert(n)
+		}
+	}
+
+	return res
+}
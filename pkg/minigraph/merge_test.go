@@ -0,0 +1,176 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// mergeGraph creates endpoints connected to a single network. Each endpoint
+// is a map of data with the edge data under the edge.* keys.
+func mergeGraph(t *testing.T, cidr string, eps ...map[string]string) *Graph {
+	g := New()
+
+	n := g.NewNetwork()
+	n.D["cidr"] = cidr
+
+	for _, d := range eps {
+		e := g.NewEndpoint()
+		edge := e.NewEdge()
+
+		for k, v := range d {
+			if k == "edge.mac" || k == "edge.ip" {
+				edge.D[k[5:]] = v
+			} else {
+				e.D[k] = v
+			}
+		}
+
+		if err := g.Connect(e, n, edge); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return g
+}
+
+func TestMerge(t *testing.T) {
+	g := mergeGraph(t, "10.0.0.0/24",
+		map[string]string{"hostname": "a", "edge.mac": "00:00:00:00:00:01", "os": "linux"},
+		map[string]string{"hostname": "b", "edge.ip": "10.0.0.2/24"},
+	)
+
+	other := mergeGraph(t, "10.0.0.0/24",
+		// matches a by mac, different format
+		map[string]string{"edge.mac": "00-00-00-00-00-01", "edge.ip": "10.0.0.1/24", "os": "windows"},
+		// matches b by ip without a prefix length
+		map[string]string{"edge.ip": "10.0.0.2", "vendor": "cisco"},
+		// new
+		map[string]string{"hostname": "c", "edge.ip": "10.0.0.3/24"},
+	)
+	other.NewNetwork().D["name"] = "empty"
+
+	res, err := g.Merge(other, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Matched != 3 || res.Inserted != 2 || res.Conflicts != 1 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	a := g.Nodes[res.NIDs[2]].(*Endpoint)
+	if a.D["hostname"] != "a" || a.D["os"] != "linux" || a.Edges[0].D["ip"] != "10.0.0.1/24" || len(a.Edges) != 1 {
+		t.Errorf("unexpected merged endpoint: %v %v", a, a.Edges[0])
+	}
+
+	b := g.Nodes[res.NIDs[3]].(*Endpoint)
+	if b.D["hostname"] != "b" || b.D["vendor"] != "cisco" {
+		t.Errorf("unexpected merged endpoint: %v", b)
+	}
+
+	c := g.Nodes[res.NIDs[4]].(*Endpoint)
+	if c.D["hostname"] != "c" || c.Edges[0].N != 1 {
+		t.Errorf("unexpected inserted endpoint: %v %v", c, c.Edges[0])
+	}
+
+	n := g.Nodes[1].(*Network)
+	eps := append([]int{}, n.Endpoints...)
+	sort.Ints(eps)
+	if want := []int{2, 3, c.NID}; !reflect.DeepEqual(eps, want) {
+		t.Errorf("expected endpoints %v, got %v", want, eps)
+	}
+
+	if r := g.Validate(); r.Errors != 0 {
+		t.Errorf("merged graph is invalid: %v", r.Problems)
+	}
+
+	// the other graph is unchanged
+	if other.Nodes[2].Data()["os"] != "windows" || len(other.Nodes) != 5 {
+		t.Errorf("other graph modified")
+	}
+}
+
+func TestMergePolicy(t *testing.T) {
+	for _, v := range []struct {
+		policy, want string
+	}{
+		{MERGE_PREFER_LEFT, "linux,bsd"},
+		{MERGE_PREFER_RIGHT, "bsd,windows"},
+		{MERGE_JOIN, "linux,bsd,windows"},
+	} {
+		g := mergeGraph(t, "10.0.0.0/24", map[string]string{"uuid": "x", "os": "linux,bsd"})
+		other := mergeGraph(t, "10.0.1.0/24", map[string]string{"uuid": "x", "os": "bsd,windows"})
+
+		res, err := g.Merge(other, &MergeOptions{Keys: []string{"uuid"}, Policy: v.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e := g.Nodes[res.NIDs[2]].(*Endpoint)
+		if e.NID != 2 || e.D["os"] != v.want {
+			t.Errorf("%v: expected %v, got %v", v.policy, v.want, e.D["os"])
+		}
+
+		// networks have different cidrs, edges have no mac or ip
+		if len(e.Edges) != 2 || res.NIDs[1] == 1 || e.Edges[1].N != res.NIDs[1] {
+			t.Errorf("%v: expected second edge to new network, got %v", v.policy, e.Edges)
+		}
+	}
+
+	g := New()
+	if _, err := g.Merge(New(), &MergeOptions{Policy: "invalid"}); err == nil {
+		t.Errorf("expected error for invalid policy")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	g, ids := traverseGraph(t)
+
+	g.Nodes[ids["e2"]].Data()["os"] = "linux"
+	g.Nodes[ids["e3"]].Data()["os"] = "linux"
+
+	q, err := ParseQuery("os=linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := g.Extract(q)
+
+	var got []int
+	for id := range sub.Nodes {
+		got = append(got, id)
+	}
+	sort.Ints(got)
+
+	want := []int{ids["e2"], ids["e3"], ids["n1"], ids["n2"], ids["n3"], ids["n4"]}
+	sort.Ints(want)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// e1 and e4 were not extracted
+	n1 := sub.Nodes[ids["n1"]].(*Network)
+	if !reflect.DeepEqual(n1.Endpoints, []int{ids["e2"]}) {
+		t.Errorf("expected n1 to only have e2, got %v", n1.Endpoints)
+	}
+
+	if r := sub.Validate(); r.Errors != 0 {
+		t.Errorf("extracted graph is invalid: %v", r.Problems)
+	}
+
+	// copies
+	sub.Nodes[ids["e2"]].Data()["os"] = "windows"
+	if g.Nodes[ids["e2"]].Data()["os"] != "linux" {
+		t.Errorf("graph modified by changing extracted graph")
+	}
+
+	if len(g.Extract(nil).Nodes) != len(g.Nodes) {
+		t.Errorf("expected full copy")
+	}
+}
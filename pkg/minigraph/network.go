@@ -6,12 +6,18 @@ package minigraph
 
 import (
 	"fmt"
+	"strings"
 )
 
+// A network connects endpoints. Networks contain a map for specifying
+// arbitrary information, commonly: cidr, cidr6, vlan, name, bridge, and mtu.
 type Network struct {
 	NID       int
 	Endpoints []int
 	D         map[string]string
+
+	// Rev is incremented each time the network is modified in a transaction
+	Rev int
 }
 
 func (n *Network) Data() map[string]string {
@@ -22,18 +28,51 @@ func (n *Network) setID(id int) {
 	n.NID = id
 }
 
+func (n *Network) Revision() int {
+	return n.Rev
+}
+
+func (n *Network) setRevision(rev int) {
+	n.Rev = rev
+}
+
+// Copy returns a deep copy of the network.
+func (n *Network) Copy() *Network {
+	n2 := &Network{
+		NID: n.NID,
+		D:   copyData(n.D),
+		Rev: n.Rev,
+	}
+
+	if n.Endpoints != nil {
+		n2.Endpoints = append([]int{}, n.Endpoints...)
+	}
+
+	return n2
+}
+
 func (n *Network) Match(k, v string) bool {
 	if k != "" {
-		switch k {
-		case "nid":
+		if k == "nid" {
 			if fmt.Sprintf("%v", n.NID) == v {
 				return true
 			}
 		}
+
+		if val, ok := n.D[k]; ok {
+			if strings.Contains(val, v) {
+				return true
+			}
+		}
 	} else {
 		if fmt.Sprintf("%v", n.NID) == v {
 			return true
 		}
+		for _, val := range n.D {
+			if strings.Contains(val, v) {
+				return true
+			}
+		}
 	}
 
 	return false
@@ -0,0 +1,105 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"fmt"
+)
+
+// Patch describes changes to individual keys of a node rather than replacing
+// the whole node, so that concurrent writers that modify different keys don't
+// overwrite each other's changes.
+type Patch struct {
+	// changes to the node's data
+	Data DataDiff
+
+	// changes to the data of the endpoint's edges, by index in
+	// Endpoint.Edges, endpoints only
+	Edges map[int]DataDiff `json:",omitempty"`
+}
+
+// Empty returns true if the patch makes no changes.
+func (p *Patch) Empty() bool {
+	for _, d := range p.Edges {
+		if !d.Empty() {
+			return false
+		}
+	}
+
+	return p.Data.Empty()
+}
+
+// DiffEndpoint returns the patch that turns the data of a and its edges into
+// the data of b. Patches can't add or remove edges so edges that are only in
+// one of them are ignored.
+func DiffEndpoint(a, b *Endpoint) *Patch {
+	p := &Patch{
+		Data: DiffData(a.D, b.D),
+	}
+
+	for i, edge := range b.Edges {
+		if i >= len(a.Edges) {
+			break
+		}
+
+		if d := DiffData(a.Edges[i].D, edge.D); !d.Empty() {
+			if p.Edges == nil {
+				p.Edges = map[int]DataDiff{}
+			}
+			p.Edges[i] = d
+		}
+	}
+
+	return p
+}
+
+// Patch applies the changes to the node with the given ID. Either all of the
+// changes are applied or, if there is an error, none of them are.
+func (g *Graph) Patch(id int, p *Patch) (Node, error) {
+	n, ok := g.Nodes[id]
+	if !ok {
+		return nil, fmt.Errorf("no such node %v", id)
+	}
+
+	if len(p.Edges) > 0 {
+		endpoint, ok := n.(*Endpoint)
+		if !ok {
+			return nil, fmt.Errorf("node %v not an endpoint", n)
+		}
+
+		for i := range p.Edges {
+			if i < 0 || i >= len(endpoint.Edges) {
+				return nil, fmt.Errorf("invalid edge id: %v", i)
+			}
+		}
+	}
+
+	g.Touch(id)
+
+	switch n := n.(type) {
+	case *Endpoint:
+		if n.D == nil {
+			n.D = make(map[string]string)
+		}
+		p.Data.Apply(n.D)
+
+		for i, d := range p.Edges {
+			edge := n.Edges[i]
+			if edge.D == nil {
+				edge.D = make(map[string]string)
+			}
+			d.Apply(edge.D)
+		}
+	case *Network:
+		if n.D == nil {
+			n.D = make(map[string]string)
+		}
+		p.Data.Apply(n.D)
+	}
+
+	g.reindex(id)
+
+	return n, nil
+}
@@ -0,0 +1,549 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query is a boolean expression over node fields. The grammar is:
+//
+//	expr	= term { ["AND"] term } { "OR" term { ["AND"] term } }
+//	term	= "NOT" term | "(" expr ")" | "has(" field ")" | cmp | value
+//	cmp	= field op value | field "in" value
+//	op	= "=" | "!=" | "~" | "!~" | "<" | "<=" | ">" | ">="
+//
+// Keywords are case-insensitive and adjacent terms are ANDed together. Fields
+// are keys in D, "nid", or "edge.<key>" to match against any of an endpoint's
+// edges. Values may be quoted with double quotes and must be quoted if they
+// contain whitespace, parentheses, or operator characters.
+//
+// "=" and "!=" are exact matches, "~" and "!~" are regular expressions, and
+// "<", "<=", ">", ">=" compare values as numbers. "in" takes a comma-separated
+// list of values or CIDRs (matching IPs in the subnet). has(field) checks that
+// the field exists. A bare value is a freeform search, as in Match("", value).
+//
+// For example:
+//
+//	os=linux AND edge.ip in 10.0.0.0/8 AND NOT router=true
+//
+// "!=" and "!~" are the negation of "=" and "~" so they also match nodes
+// that don't have the field.
+type Query struct {
+	src  string
+	root queryNode
+}
+
+// queryNode is a node in the query's syntax tree.
+type queryNode interface {
+	match(n Node) bool
+}
+
+type queryAnd []queryNode
+type queryOr []queryNode
+
+type queryNot struct {
+	q queryNode
+}
+
+type queryHas struct {
+	field string
+}
+
+type queryCmp struct {
+	field, op, value string
+
+	re   *regexp.Regexp
+	num  float64
+	nets []*net.IPNet
+	set  map[string]bool
+}
+
+// queryFree is a freeform search
+type queryFree struct {
+	value string
+}
+
+// ParseQuery parses the query string, returning an error if it is malformed.
+func ParseQuery(s string) (*Query, error) {
+	toks, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(toks) == 0 {
+		return nil, errors.New("empty query")
+	}
+
+	p := &queryParser{toks: toks}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.i < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %v", p.toks[p.i])
+	}
+
+	return &Query{src: s, root: root}, nil
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// Match returns true if the node satisfies the query.
+func (q *Query) Match(n Node) bool {
+	return q.root.match(n)
+}
+
+// QueryNodes returns the nodes that match the query.
+func (g *Graph) QueryNodes(q *Query) []Node {
+	var ret []Node
+	for _, n := range g.Nodes {
+		if q.Match(n) {
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
+
+// QueryEndpoints returns the endpoints that match the query, sorted by ID.
+func (g *Graph) QueryEndpoints(q *Query) []*Endpoint {
+	var ret []*Endpoint
+	for _, n := range g.GetEndpoints() {
+		if q.Match(n) {
+			ret = append(ret, n)
+		}
+	}
+
+	sort.Sort(endpoints(ret))
+
+	return ret
+}
+
+// QueryNetworks returns the networks that match the query, sorted by ID.
+func (g *Graph) QueryNetworks(q *Query) []*Network {
+	var ret []*Network
+	for _, n := range g.GetNetworks() {
+		if q.Match(n) {
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
+
+func (q queryAnd) match(n Node) bool {
+	for _, v := range q {
+		if !v.match(n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (q queryOr) match(n Node) bool {
+	for _, v := range q {
+		if v.match(n) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q queryNot) match(n Node) bool {
+	return !q.q.match(n)
+}
+
+func (q queryFree) match(n Node) bool {
+	return n.Match("", q.value)
+}
+
+func (q queryHas) match(n Node) bool {
+	return matchField(n, q.field, func(_ string, ok bool) bool {
+		return ok
+	})
+}
+
+func (q *queryCmp) match(n Node) bool {
+	switch q.op {
+	case "!=":
+		return !matchField(n, q.field, q.test("="))
+	case "!~":
+		return !matchField(n, q.field, q.test("~"))
+	}
+
+	return matchField(n, q.field, q.test(q.op))
+}
+
+// test returns a function that tests a single value using op
+func (q *queryCmp) test(op string) func(string, bool) bool {
+	return func(v string, ok bool) bool {
+		if !ok {
+			return false
+		}
+
+		switch op {
+		case "=":
+			return v == q.value
+		case "~":
+			return q.re.MatchString(v)
+		case "in":
+			if q.set[v] {
+				return true
+			}
+
+			ip := parseIP(v)
+			if ip == nil {
+				return false
+			}
+
+			for _, n := range q.nets {
+				if n.Contains(ip) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+
+		switch op {
+		case "<":
+			return f < q.num
+		case "<=":
+			return f <= q.num
+		case ">":
+			return f > q.num
+		case ">=":
+			return f >= q.num
+		}
+
+		return false
+	}
+}
+
+// matchField calls fn with the value of the field and whether it exists. For
+// edge fields, fn is called for each edge and returns true if any call does.
+func matchField(n Node, field string, fn func(string, bool) bool) bool {
+	if strings.EqualFold(field, "nid") {
+		return fn(strconv.Itoa(n.ID()), true)
+	}
+
+	if strings.HasPrefix(field, "edge.") {
+		e, ok := n.(*Endpoint)
+		if !ok {
+			return false
+		}
+
+		k := strings.TrimPrefix(field, "edge.")
+		for _, edge := range e.Edges {
+			v, ok := edge.D[k]
+			if fn(v, ok) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	v, ok := n.Data()[field]
+	return fn(v, ok)
+}
+
+// parseIP parses an IP address, ignoring any prefix length
+func parseIP(s string) net.IP {
+	if ip, _, err := net.ParseCIDR(s); err == nil {
+		return ip
+	}
+
+	return net.ParseIP(s)
+}
+
+// token types
+const (
+	tokWord = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type queryToken struct {
+	typ int
+	s   string
+}
+
+func (t queryToken) String() string {
+	if t.typ == tokString {
+		return strconv.Quote(t.s)
+	}
+
+	return fmt.Sprintf("%q", t.s)
+}
+
+// keyword returns true if the token is an unquoted word matching the keyword
+func (t queryToken) keyword(k string) bool {
+	return t.typ == tokWord && strings.EqualFold(t.s, k)
+}
+
+const queryOpChars = "=!~<>"
+
+func lexQuery(s string) ([]queryToken, error) {
+	var toks []queryToken
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, queryToken{tokLParen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, queryToken{tokRParen, ")"})
+			i++
+		case c == '"':
+			// find the closing quote, only \" and \\ are escapes so that
+			// regular expressions don't need to be escaped twice
+			var v strings.Builder
+
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) && (s[j+1] == '"' || s[j+1] == '\\') {
+					j++
+				}
+				v.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %v", i)
+			}
+
+			toks = append(toks, queryToken{tokString, v.String()})
+			i = j + 1
+		case strings.IndexByte(queryOpChars, c) >= 0:
+			j := i + 1
+			if j < len(s) && (s[j] == '=' || (c == '!' && s[j] == '~')) {
+				j++
+			}
+
+			op := s[i:j]
+			switch op {
+			case "=", "!=", "~", "!~", "<", "<=", ">", ">=":
+			default:
+				return nil, fmt.Errorf("invalid operator %q at %v", op, i)
+			}
+
+			toks = append(toks, queryToken{tokOp, op})
+			i = j
+		default:
+			j := i
+			for ; j < len(s); j++ {
+				if strings.IndexByte(" \t\n\r()\""+queryOpChars, s[j]) >= 0 {
+					break
+				}
+			}
+
+			toks = append(toks, queryToken{tokWord, s[i:j]})
+			i = j
+		}
+	}
+
+	return toks, nil
+}
+
+type queryParser struct {
+	toks []queryToken
+	i    int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.i < len(p.toks) {
+		return p.toks[p.i], true
+	}
+
+	return queryToken{}, false
+}
+
+func (p *queryParser) next() (queryToken, error) {
+	if p.i < len(p.toks) {
+		p.i += 1
+		return p.toks[p.i-1], nil
+	}
+
+	return queryToken{}, errors.New("unexpected end of query")
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	var res queryOr
+
+	for {
+		q, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, q)
+
+		if t, ok := p.peek(); !ok || !t.keyword("or") {
+			break
+		}
+		p.i += 1
+	}
+
+	if len(res) == 1 {
+		return res[0], nil
+	}
+	return res, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var res queryAnd
+
+	for {
+		q, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, q)
+
+		t, ok := p.peek()
+		if !ok || t.typ == tokRParen || t.keyword("or") {
+			break
+		}
+		if t.keyword("and") {
+			p.i += 1
+		}
+	}
+
+	if len(res) == 1 {
+		return res[0], nil
+	}
+	return res, nil
+}
+
+func (p *queryParser) parseTerm() (queryNode, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t.keyword("not"):
+		q, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{q}, nil
+	case t.typ == tokLParen:
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return q, nil
+	case t.keyword("has"):
+		if t2, ok := p.peek(); !ok || t2.typ != tokLParen {
+			// just a freeform search for "has"
+			return queryFree{t.s}, nil
+		}
+		p.i += 1
+
+		field, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if field.typ != tokWord && field.typ != tokString {
+			return nil, fmt.Errorf("expected field, got %v", field)
+		}
+		if err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return queryHas{field.s}, nil
+	case t.typ != tokWord && t.typ != tokString:
+		return nil, fmt.Errorf("unexpected %v", t)
+	}
+
+	// field followed by an operator or a freeform search
+	op, ok := p.peek()
+	if !ok || !(op.typ == tokOp || op.keyword("in")) {
+		return queryFree{t.s}, nil
+	}
+	p.i += 1
+
+	v, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if v.typ != tokWord && v.typ != tokString {
+		return nil, fmt.Errorf("expected value after %v, got %v", op, v)
+	}
+
+	return newQueryCmp(t.s, strings.ToLower(op.s), v.s)
+}
+
+func (p *queryParser) expect(typ int) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.typ != typ {
+		return fmt.Errorf("unexpected %v", t)
+	}
+
+	return nil
+}
+
+func newQueryCmp(field, op, value string) (*queryCmp, error) {
+	q := &queryCmp{
+		field: field,
+		op:    op,
+		value: value,
+	}
+
+	var err error
+
+	switch op {
+	case "~", "!~":
+		q.re, err = regexp.Compile(value)
+	case "<", "<=", ">", ">=":
+		q.num, err = strconv.ParseFloat(value, 64)
+	case "in":
+		q.set = map[string]bool{}
+
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+
+			if _, n, err := net.ParseCIDR(v); err == nil {
+				q.nets = append(q.nets, n)
+			} else {
+				q.set[v] = true
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %v %v: %v", field, op, err)
+	}
+
+	return q, nil
+}
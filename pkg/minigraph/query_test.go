@@ -0,0 +1,153 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"sort"
+	"testing"
+)
+
+func queryGraph() *Graph {
+	g := New()
+
+	// e1: linux host on 10.0.0.0/8
+	e1 := g.NewEndpoint()
+	e1.D["os"] = "linux"
+	e1.D["cpus"] = "4"
+	e1.D["uuid"] = "1234"
+	edge := e1.NewEdge()
+	edge.D["ip"] = "10.1.2.3/24"
+
+	// e2: linux router on 192.168.0.0/16 and 10.0.0.0/8
+	e2 := g.NewEndpoint()
+	e2.D["os"] = "linux"
+	e2.D["router"] = "true"
+	e2.D["cpus"] = "16"
+	edge = e2.NewEdge()
+	edge.D["ip"] = "192.168.1.1"
+	edge = e2.NewEdge()
+	edge.D["ip"] = "10.1.2.1"
+
+	// e3: windows host
+	e3 := g.NewEndpoint()
+	e3.D["os"] = "windows 10"
+	edge = e3.NewEdge()
+	edge.D["ip"] = "172.16.0.5"
+
+	n1 := g.NewNetwork()
+	n1.D["name"] = "lan"
+
+	// NewEdge doesn't mark the edges as unconnected
+	for _, e := range g.GetEndpoints() {
+		for _, edge := range e.Edges {
+			edge.N = UNCONNECTED
+		}
+	}
+
+	return g
+}
+
+func TestQuery(t *testing.T) {
+	g := queryGraph()
+
+	tests := []struct {
+		q    string
+		want []int
+	}{
+		{`os=linux`, []int{1, 2}},
+		{`os=lin`, nil},
+		{`os~^lin`, []int{1, 2}},
+		{`os!=linux`, []int{3, 4}},
+		{`os="windows 10"`, []int{3}},
+		{`edge.ip in 10.0.0.0/8`, []int{1, 2}},
+		{`edge.ip in 172.16.0.0/12,192.168.0.0/16`, []int{2, 3}},
+		{`os in linux,bsd`, []int{1, 2}},
+		{`os=linux AND edge.ip in 10.0.0.0/8 AND NOT router=true`, []int{1}},
+		{`os=linux edge.ip in 10.0.0.0/8 not router=true`, []int{1}},
+		{`router=true OR os~windows`, []int{2, 3}},
+		{`(router=true OR os~windows) AND edge.ip in 172.16.0.0/12`, []int{3}},
+		{`has(uuid)`, []int{1}},
+		{`NOT has(edge.ip)`, []int{4}},
+		{`cpus>4`, []int{2}},
+		{`cpus>=4`, []int{1, 2}},
+		{`cpus<10`, []int{1}},
+		{`nid<=2`, []int{1, 2}},
+		{`name=lan`, []int{4}},
+		{`windows`, []int{3}},
+		{`os~"^(windows|bsd)"`, []int{3}},
+		{`os~"\d+$"`, []int{3}},
+	}
+
+	for _, test := range tests {
+		q, err := ParseQuery(test.q)
+		if err != nil {
+			t.Errorf("%v: %v", test.q, err)
+			continue
+		}
+
+		var got []int
+		for _, n := range g.GetNodes() {
+			if q.Match(n) {
+				got = append(got, n.ID())
+			}
+		}
+		sort.Ints(got)
+
+		if len(got) != len(test.want) {
+			t.Errorf("%v: got %v, want %v", test.q, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: got %v, want %v", test.q, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`os=`,
+		`(os=linux`,
+		`os=linux)`,
+		`os~"(unterminated"`,
+		`cpus>four`,
+		`os==linux`,
+		`has(uuid`,
+		`"unterminated`,
+		`NOT`,
+		`os=linux AND`,
+	} {
+		if _, err := ParseQuery(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestQueryEndpointsSorted(t *testing.T) {
+	g := New()
+	for i := 0; i < 100; i++ {
+		e := g.NewEndpoint()
+		e.D["os"] = "linux"
+	}
+
+	q, err := ParseQuery(`os=linux`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := g.QueryEndpoints(q)
+	if len(got) != 100 {
+		t.Fatalf("got %v endpoints, want 100", len(got))
+	}
+
+	for i := 1; i < len(got); i++ {
+		if got[i-1].ID() >= got[i].ID() {
+			t.Fatalf("endpoints not sorted: %v before %v", got[i-1].ID(), got[i].ID())
+		}
+	}
+}
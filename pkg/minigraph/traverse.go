@@ -0,0 +1,342 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// The graph is bipartite: endpoints are only connected to networks and vice
+// versa. A hop is a single link between an endpoint and a network so two
+// endpoints on the same network are two hops apart.
+
+// Path is a path between two nodes.
+type Path struct {
+	// NIDs of the nodes on the path, including both ends
+	Nodes []int
+
+	// number of links on the path
+	Hops int
+
+	// sum of the weights of the links on the path, the same as Hops if the
+	// path is not weighted
+	Cost float64
+}
+
+// Bridge is a link whose removal would disconnect the graph.
+type Bridge struct {
+	Endpoint, Network int
+}
+
+// neighbors returns the IDs of the nodes linked to the node
+func (g *Graph) neighbors(id int) []int {
+	n, ok := g.Nodes[id]
+	if !ok {
+		return nil
+	}
+
+	var res []int
+	for _, v := range n.Neighbors() {
+		if _, ok := g.Nodes[v]; ok {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// weight returns the weight of the link between the two nodes, using the
+// value of key on the endpoint's edge. Links without the key have a weight
+// of 1.
+func (g *Graph) weight(a, b int, key string) (float64, error) {
+	e, ok := g.Nodes[a].(*Endpoint)
+	if !ok {
+		a, b = b, a
+		e = g.Nodes[a].(*Endpoint)
+	}
+
+	for _, edge := range e.Edges {
+		if edge.N != b {
+			continue
+		}
+
+		s, ok := edge.D[key]
+		if !ok {
+			return 1, nil
+		}
+
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid %v on link %v -- %v: %v", key, a, b, s)
+		}
+
+		return v, nil
+	}
+
+	return 1, nil
+}
+
+// ShortestPath finds the shortest path between two nodes. If weight is empty,
+// the path with the fewest hops is returned. Otherwise, weight is the key of
+// an edge attribute, such as delay, and the path with the lowest total weight
+// is returned. Returns nil if there is no path.
+func (g *Graph) ShortestPath(from, to int, weight string) (*Path, error) {
+	for _, id := range []int{from, to} {
+		if _, ok := g.Nodes[id]; !ok {
+			return nil, fmt.Errorf("no such node %v", id)
+		}
+	}
+
+	// Dijkstra's algorithm, which is the same as a breadth-first search
+	// when every link has a weight of 1
+	dist := map[int]float64{from: 0}
+	prev := map[int]int{}
+	done := map[int]bool{}
+
+	pq := &pathQueue{{id: from}}
+
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(pathItem)
+		if done[cur.id] {
+			continue
+		}
+		done[cur.id] = true
+
+		if cur.id == to {
+			break
+		}
+
+		for _, v := range g.neighbors(cur.id) {
+			if done[v] {
+				continue
+			}
+
+			w := 1.0
+			if weight != "" {
+				var err error
+				if w, err = g.weight(cur.id, v, weight); err != nil {
+					return nil, err
+				}
+			}
+
+			if d, ok := dist[v]; !ok || cur.dist+w < d {
+				dist[v] = cur.dist + w
+				prev[v] = cur.id
+				heap.Push(pq, pathItem{id: v, dist: dist[v]})
+			}
+		}
+	}
+
+	if !done[to] {
+		return nil, nil
+	}
+
+	p := &Path{Cost: dist[to]}
+	for id := to; id != from; id = prev[id] {
+		p.Nodes = append(p.Nodes, id)
+	}
+	p.Nodes = append(p.Nodes, from)
+
+	// reverse so that the path starts at from
+	for i, j := 0, len(p.Nodes)-1; i < j; i, j = i+1, j-1 {
+		p.Nodes[i], p.Nodes[j] = p.Nodes[j], p.Nodes[i]
+	}
+
+	p.Hops = len(p.Nodes) - 1
+
+	return p, nil
+}
+
+type pathItem struct {
+	id   int
+	dist float64
+}
+
+// pathQueue is a min-heap of nodes by distance, ties are broken by ID so that
+// paths are deterministic
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].dist == q[j].dist {
+		return q[i].id < q[j].id
+	}
+	return q[i].dist < q[j].dist
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Neighborhood returns the nodes within depth hops of the node, not including
+// the node itself, sorted by ID. If depth is negative, all the nodes that are
+// connected to the node are returned.
+func (g *Graph) Neighborhood(id, depth int) ([]Node, error) {
+	if _, ok := g.Nodes[id]; !ok {
+		return nil, fmt.Errorf("no such node %v", id)
+	}
+
+	visited := map[int]bool{id: true}
+	working := []int{id}
+
+	for i := 0; len(working) > 0 && (depth < 0 || i < depth); i++ {
+		var next []int
+
+		for _, n := range working {
+			for _, v := range g.neighbors(n) {
+				if !visited[v] {
+					visited[v] = true
+					next = append(next, v)
+				}
+			}
+		}
+
+		working = next
+	}
+
+	delete(visited, id)
+
+	return g.sortedNodes(visited), nil
+}
+
+// Component returns the IDs of the nodes in the same connected component as
+// the node, including the node itself, sorted by ID.
+func (g *Graph) Component(id int) ([]int, error) {
+	nodes, err := g.Neighborhood(id, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []int{id}
+	for _, n := range nodes {
+		res = append(res, n.ID())
+	}
+
+	sort.Ints(res)
+
+	return res, nil
+}
+
+// Components returns the connected components of the graph as lists of node
+// IDs, sorted by ID. The largest components are first.
+func (g *Graph) Components() [][]int {
+	var res [][]int
+
+	seen := map[int]bool{}
+
+	for _, id := range g.ids() {
+		if seen[id] {
+			continue
+		}
+
+		c, _ := g.Component(id)
+		for _, v := range c {
+			seen[v] = true
+		}
+
+		res = append(res, c)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return len(res[i]) > len(res[j])
+	})
+
+	return res
+}
+
+// ArticulationPoints returns the IDs of the nodes whose removal would
+// disconnect the graph, sorted by ID.
+func (g *Graph) ArticulationPoints() []int {
+	points, _ := g.cuts()
+	return points
+}
+
+// Bridges returns the links whose removal would disconnect the graph, sorted
+// by endpoint and then network.
+func (g *Graph) Bridges() []Bridge {
+	_, bridges := g.cuts()
+	return bridges
+}
+
+// cuts finds the articulation points and bridges using Tarjan's algorithm.
+// There is at most one link between an endpoint and a network so we don't
+// have to worry about parallel links.
+func (g *Graph) cuts() ([]int, []Bridge) {
+	disc := map[int]int{}
+	low := map[int]int{}
+	points := map[int]bool{}
+
+	var bridges []Bridge
+	var time int
+
+	var visit func(id, parent int)
+	visit = func(id, parent int) {
+		time++
+		disc[id], low[id] = time, time
+
+		var children int
+
+		for _, v := range g.neighbors(id) {
+			if v == parent {
+				continue
+			}
+
+			if _, ok := disc[v]; ok {
+				low[id] = min(low[id], disc[v])
+				continue
+			}
+
+			children++
+			visit(v, id)
+			low[id] = min(low[id], low[v])
+
+			if parent != 0 && low[v] >= disc[id] {
+				points[id] = true
+			}
+
+			if low[v] > disc[id] {
+				b := Bridge{Endpoint: id, Network: v}
+				if g.Nodes[id].Type() == TYPE_NETWORK {
+					b = Bridge{Endpoint: v, Network: id}
+				}
+				bridges = append(bridges, b)
+			}
+		}
+
+		// the root is only an articulation point if it has multiple
+		// children in the DFS tree
+		if parent == 0 && children > 1 {
+			points[id] = true
+		}
+	}
+
+	for _, id := range g.ids() {
+		if _, ok := disc[id]; !ok {
+			visit(id, 0)
+		}
+	}
+
+	var res []int
+	for id := range points {
+		res = append(res, id)
+	}
+	sort.Ints(res)
+
+	sort.Slice(bridges, func(i, j int) bool {
+		if bridges[i].Endpoint == bridges[j].Endpoint {
+			return bridges[i].Network < bridges[j].Network
+		}
+		return bridges[i].Endpoint < bridges[j].Endpoint
+	})
+
+	return res, bridges
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// traverseGraph creates the following graph, plus an unconnected endpoint
+// (e5) and an endpoint connected to a network with nothing else (e6 -- n5):
+//
+//	e1 -- n1 -- e2 -- n2 -- e3
+//	            |           |
+//	            n3 -- e4 -- n4
+func traverseGraph(t *testing.T) (*Graph, map[string]int) {
+	g := New()
+
+	ids := map[string]int{}
+
+	for _, v := range []string{"e1", "e2", "e3", "e4", "e5", "e6"} {
+		ids[v] = g.NewEndpoint().ID()
+	}
+	for _, v := range []string{"n1", "n2", "n3", "n4", "n5"} {
+		ids[v] = g.NewNetwork().ID()
+	}
+
+	for _, v := range [][2]string{
+		{"e1", "n1"}, {"e2", "n1"}, {"e2", "n2"}, {"e3", "n2"},
+		{"e2", "n3"}, {"e4", "n3"}, {"e4", "n4"}, {"e3", "n4"},
+		{"e6", "n5"},
+	} {
+		e := g.Nodes[ids[v[0]]].(*Endpoint)
+		if err := g.Connect(e, g.Nodes[ids[v[1]]], e.NewEdge()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return g, ids
+}
+
+func TestShortestPath(t *testing.T) {
+	g, ids := traverseGraph(t)
+
+	p, err := g.ShortestPath(ids["e1"], ids["e3"], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{ids["e1"], ids["n1"], ids["e2"], ids["n2"], ids["e3"]}
+	if !reflect.DeepEqual(p.Nodes, want) || p.Hops != 4 || p.Cost != 4 {
+		t.Fatalf("expected %v, got %+v", want, p)
+	}
+
+	// make the direct route slow
+	e2 := g.Nodes[ids["e2"]].(*Endpoint)
+	for _, edge := range e2.Edges {
+		if edge.N == ids["n2"] {
+			edge.D["delay"] = "10"
+		}
+	}
+
+	p, err = g.ShortestPath(ids["e1"], ids["e3"], "delay")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = []int{ids["e1"], ids["n1"], ids["e2"], ids["n3"], ids["e4"], ids["n4"], ids["e3"]}
+	if !reflect.DeepEqual(p.Nodes, want) || p.Hops != 6 || p.Cost != 6 {
+		t.Fatalf("expected %v, got %+v", want, p)
+	}
+
+	if p, err := g.ShortestPath(ids["e1"], ids["e1"], ""); err != nil || p.Hops != 0 {
+		t.Fatalf("expected empty path, got %+v, %v", p, err)
+	}
+
+	if p, err := g.ShortestPath(ids["e1"], ids["e6"], ""); err != nil || p != nil {
+		t.Fatalf("expected no path, got %+v, %v", p, err)
+	}
+
+	if _, err := g.ShortestPath(ids["e1"], 100, ""); err == nil {
+		t.Fatal("expected error for missing node")
+	}
+
+	// e2 -- n2
+	e2.Edges[1].D["delay"] = "slow"
+	if _, err := g.ShortestPath(ids["e1"], ids["e3"], "delay"); err == nil {
+		t.Fatal("expected error for invalid weight")
+	}
+}
+
+func TestNeighborhood(t *testing.T) {
+	g, ids := traverseGraph(t)
+
+	check := func(depth int, want ...string) {
+		t.Helper()
+
+		nodes, err := g.Neighborhood(ids["e1"], depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := map[int]bool{}
+		for _, n := range nodes {
+			got[n.ID()] = true
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("depth %v: expected %v, got %v", depth, want, nodes)
+		}
+		for _, v := range want {
+			if !got[ids[v]] {
+				t.Fatalf("depth %v: expected %v, got %v", depth, want, nodes)
+			}
+		}
+	}
+
+	check(0)
+	check(1, "n1")
+	check(2, "n1", "e2")
+	check(3, "n1", "e2", "n2", "n3")
+	check(-1, "n1", "e2", "n2", "n3", "e3", "e4", "n4")
+}
+
+func TestComponents(t *testing.T) {
+	g, ids := traverseGraph(t)
+
+	cs := g.Components()
+	if len(cs) != 3 {
+		t.Fatalf("expected 3 components, got %v", cs)
+	}
+
+	if len(cs[0]) != 8 || !reflect.DeepEqual(cs[1], []int{ids["e6"], ids["n5"]}) || !reflect.DeepEqual(cs[2], []int{ids["e5"]}) {
+		t.Fatalf("invalid components: %v", cs)
+	}
+
+	c, err := g.Component(ids["n4"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, cs[0]) {
+		t.Fatalf("expected %v, got %v", cs[0], c)
+	}
+}
+
+func TestCuts(t *testing.T) {
+	g, ids := traverseGraph(t)
+
+	points := g.ArticulationPoints()
+	if want := []int{ids["e2"], ids["n1"]}; !reflect.DeepEqual(points, want) {
+		t.Fatalf("expected articulation points %v, got %v", want, points)
+	}
+
+	bridges := g.Bridges()
+	want := []Bridge{
+		{Endpoint: ids["e1"], Network: ids["n1"]},
+		{Endpoint: ids["e2"], Network: ids["n1"]},
+		{Endpoint: ids["e6"], Network: ids["n5"]},
+	}
+	if !reflect.DeepEqual(bridges, want) {
+		t.Fatalf("expected bridges %v, got %v", want, bridges)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"errors"
+)
+
+// undo records the original state of every node touched during a
+// transaction. A nil node means that the node did not exist.
+type undo struct {
+	nodes map[int]Node
+	maxID int
+}
+
+// Begin starts a transaction. Changes made through Graph methods are recorded
+// until Commit or Rollback is called. Callers that modify nodes directly (e.g.
+// Endpoint.NewEdge) must call Touch first. Only one transaction may be in
+// progress at a time.
+func (g *Graph) Begin() error {
+	if g.undo != nil {
+		return errors.New("transaction already in progress")
+	}
+
+	g.undo = &undo{
+		nodes: make(map[int]Node),
+		maxID: g.maxID,
+	}
+
+	return nil
+}
+
+// Commit ends the current transaction, keeping all changes. The revision of
+// each node that was modified, other than the nodes that were created, is
+// incremented.
+func (g *Graph) Commit() {
+	if g.undo == nil {
+		return
+	}
+
+	for id, orig := range g.undo.nodes {
+		n, ok := g.Nodes[id]
+		if orig == nil || !ok {
+			continue
+		}
+
+		n.setRevision(orig.Revision() + 1)
+	}
+
+	g.undo = nil
+}
+
+// Rollback ends the current transaction, undoing all changes.
+func (g *Graph) Rollback() {
+	if g.undo == nil {
+		return
+	}
+
+	for id, n := range g.undo.nodes {
+		if n == nil {
+			delete(g.Nodes, id)
+		} else {
+			g.Nodes[id] = n
+		}
+
+		g.reindex(id)
+	}
+
+	g.maxID = g.undo.maxID
+	g.undo = nil
+}
+
+// Touch records the current state of the nodes with the given IDs so that
+// they can be restored by Rollback. No-op if there is no transaction in
+// progress or if the node was already touched.
+func (g *Graph) Touch(ids ...int) {
+	if g.undo == nil {
+		return
+	}
+
+	for _, id := range ids {
+		if _, ok := g.undo.nodes[id]; ok {
+			continue
+		}
+
+		g.undo.nodes[id] = CopyNode(g.Nodes[id])
+	}
+}
+
+// Touched returns the original state of the nodes touched by the current
+// transaction, keyed by ID. A nil node means that the node did not exist.
+// Returns nil if there is no transaction in progress. The map must not be
+// modified.
+func (g *Graph) Touched() map[int]Node {
+	if g.undo == nil {
+		return nil
+	}
+
+	return g.undo.nodes
+}
+
+// CopyNode returns a deep copy of the node, or nil if the node is nil.
+func CopyNode(n Node) Node {
+	switch n := n.(type) {
+	case *Endpoint:
+		return n.Copy()
+	case *Network:
+		return n.Copy()
+	}
+
+	return nil
+}
@@ -0,0 +1,423 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+)
+
+// Problem is an issue with a node found by Validate.
+type Problem struct {
+	NID int
+
+	// index of the endpoint's edge, -1 if the problem is with the node
+	Edge int
+
+	Severity string
+
+	// name of the check that found the problem, such as duplicate-mac
+	Check string
+
+	Message string
+
+	// set if the problem is a referential inconsistency that Repairs fixes
+	Fixable bool `json:",omitempty"`
+
+	// set if the problem was fixed
+	Fixed bool `json:",omitempty"`
+}
+
+func (p *Problem) String() string {
+	s := fmt.Sprintf("%v: node %v", p.Severity, p.NID)
+	if p.Edge >= 0 {
+		s += fmt.Sprintf(" edge %v", p.Edge)
+	}
+	s += fmt.Sprintf(": %v: %v", p.Check, p.Message)
+
+	if p.Fixed {
+		s += " (fixed)"
+	}
+
+	return s
+}
+
+// Report contains the problems found by Validate, sorted by NID and edge.
+type Report struct {
+	Errors, Warnings int
+
+	Problems []*Problem
+}
+
+// ByNode groups the problems by NID.
+func (r *Report) ByNode() map[int][]*Problem {
+	res := map[int][]*Problem{}
+	for _, p := range r.Problems {
+		res[p.NID] = append(res[p.NID], p)
+	}
+
+	return res
+}
+
+func (r *Report) add(nid, edge int, severity, check, format string, args ...interface{}) *Problem {
+	p := &Problem{
+		NID:      nid,
+		Edge:     edge,
+		Severity: severity,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	}
+
+	if severity == SEVERITY_ERROR {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+
+	r.Problems = append(r.Problems, p)
+
+	return p
+}
+
+// edgeIP is an IP address on an endpoint's edge
+type edgeIP struct {
+	nid, edge int
+	key, s    string
+
+	ip    net.IP
+	ipnet *net.IPNet
+}
+
+// Validate checks that the graph can be emulated. The following are errors:
+//
+//   - dangling references in Edge.N or Network.Endpoints
+//   - Network.Endpoints out of sync with Edge.N
+//   - multiple edges connecting an endpoint to the same network
+//   - invalid or duplicate MACs and IPs (edge.mac, edge.ip, and edge.ip6)
+//   - edge IPs that aren't in the network's subnet: the network's cidr or
+//     cidr6, if set, otherwise the subnet used by most of the edges
+//   - routers (router=true) without any IPs
+//   - default_route that isn't in any of the endpoint's subnets
+//
+// IPs without a prefix length are warnings since their subnet is unknown.
+func (g *Graph) Validate() *Report {
+	r, _ := g.check()
+	return r
+}
+
+// Repairs returns copies of the nodes with the referential inconsistencies
+// reported by Validate fixed. Edge.N is trusted over Network.Endpoints:
+// dangling edges are disconnected and the networks' endpoints are updated to
+// match the edges. Pass the nodes to Update to apply the repairs.
+func (g *Graph) Repairs() []Node {
+	_, repairs := g.check()
+	return repairs
+}
+
+func (g *Graph) check() (*Report, []Node) {
+	r := &Report{}
+
+	ids := g.ids()
+
+	// endpoints that should be listed in each network, based on edges
+	members := map[int][]int{}
+
+	repaired := map[int]Node{}
+	repair := func(n Node) Node {
+		if v, ok := repaired[n.ID()]; ok {
+			return v
+		}
+
+		v := CopyNode(n)
+		repaired[n.ID()] = v
+		return v
+	}
+
+	var ips []*edgeIP
+	macs := map[string][]*edgeIP{}
+
+	for _, id := range ids {
+		e, ok := g.Nodes[id].(*Endpoint)
+		if !ok {
+			continue
+		}
+
+		linked := map[int]bool{}
+
+		for i, edge := range e.Edges {
+			if edge.N != UNCONNECTED {
+				if _, ok := g.Nodes[edge.N].(*Network); !ok {
+					p := r.add(id, i, SEVERITY_ERROR, "dangling-edge", "no such network: %v", edge.N)
+					p.Fixable = true
+					repair(e).(*Endpoint).Edges[i].N = UNCONNECTED
+				} else if linked[edge.N] {
+					p := r.add(id, i, SEVERITY_ERROR, "duplicate-link", "already connected to network %v", edge.N)
+					p.Fixable = true
+					repair(e).(*Endpoint).Edges[i].N = UNCONNECTED
+				} else {
+					linked[edge.N] = true
+					members[edge.N] = append(members[edge.N], id)
+				}
+			}
+
+			if s, ok := edge.D["mac"]; ok {
+				mac, err := net.ParseMAC(s)
+				if err != nil {
+					r.add(id, i, SEVERITY_ERROR, "invalid-mac", "invalid mac: %v", s)
+				} else {
+					k := mac.String()
+					macs[k] = append(macs[k], &edgeIP{nid: id, edge: i})
+				}
+			}
+
+			for _, k := range []string{"ip", "ip6"} {
+				s, ok := edge.D[k]
+				if !ok {
+					continue
+				}
+
+				v := &edgeIP{nid: id, edge: i, key: k, s: s}
+
+				var err error
+				if v.ip, v.ipnet, err = net.ParseCIDR(s); err != nil {
+					if v.ip = net.ParseIP(s); v.ip == nil {
+						r.add(id, i, SEVERITY_ERROR, "invalid-ip", "invalid %v: %v", k, s)
+						continue
+					}
+
+					r.add(id, i, SEVERITY_WARNING, "no-prefix", "%v has no prefix length: %v", k, s)
+				}
+
+				ips = append(ips, v)
+			}
+		}
+	}
+
+	// compare the networks' endpoints to the edges
+	for _, id := range ids {
+		n, ok := g.Nodes[id].(*Network)
+		if !ok {
+			continue
+		}
+
+		want := map[int]bool{}
+		for _, v := range members[id] {
+			want[v] = true
+		}
+
+		seen := map[int]bool{}
+		var fixed []int
+		var changed bool
+
+		for _, v := range n.Endpoints {
+			switch {
+			case seen[v]:
+				changed = true
+
+				p := r.add(id, -1, SEVERITY_ERROR, "duplicate-endpoint", "endpoint %v listed more than once", v)
+				p.Fixable = true
+			case !want[v]:
+				changed = true
+				if _, ok := g.Nodes[v].(*Endpoint); !ok {
+					p := r.add(id, -1, SEVERITY_ERROR, "dangling-endpoint", "no such endpoint: %v", v)
+					p.Fixable = true
+				} else {
+					p := r.add(id, -1, SEVERITY_ERROR, "unsynced-network", "endpoint %v has no edge to the network", v)
+					p.Fixable = true
+				}
+			default:
+				fixed = append(fixed, v)
+			}
+
+			seen[v] = true
+		}
+
+		for _, v := range members[id] {
+			if !seen[v] {
+				p := r.add(id, -1, SEVERITY_ERROR, "unsynced-network", "endpoint %v has an edge to the network but is not listed", v)
+				p.Fixable = true
+				fixed = append(fixed, v)
+				changed = true
+			}
+		}
+
+		if changed {
+			repair(n).(*Network).Endpoints = fixed
+		}
+	}
+
+	checkDuplicates(r, macs, "duplicate-mac", "mac")
+
+	byIP := map[string][]*edgeIP{}
+	for _, v := range ips {
+		byIP[v.ip.String()] = append(byIP[v.ip.String()], v)
+	}
+	checkDuplicates(r, byIP, "duplicate-ip", "ip")
+
+	g.checkSubnets(r, ips)
+	g.checkRoutes(r, ids, ips)
+
+	sort.SliceStable(r.Problems, func(i, j int) bool {
+		p, p2 := r.Problems[i], r.Problems[j]
+		if p.NID != p2.NID {
+			return p.NID < p2.NID
+		}
+		if p.Edge != p2.Edge {
+			return p.Edge < p2.Edge
+		}
+		return p.Message < p2.Message
+	})
+
+	var repairs []Node
+	for _, id := range ids {
+		if n, ok := repaired[id]; ok {
+			repairs = append(repairs, n)
+		}
+	}
+
+	return r, repairs
+}
+
+// checkDuplicates reports every edge that shares a value with another edge
+func checkDuplicates(r *Report, vals map[string][]*edgeIP, check, name string) {
+	for k, vs := range vals {
+		if len(vs) < 2 {
+			continue
+		}
+
+		for _, v := range vs {
+			var others []string
+			for _, v2 := range vs {
+				if v2 != v {
+					others = append(others, fmt.Sprintf("%v/%v", v2.nid, v2.edge))
+				}
+			}
+
+			r.add(v.nid, v.edge, SEVERITY_ERROR, check, "%v %v also used by %v", name, k, others)
+		}
+	}
+}
+
+// checkSubnets checks that the edge IPs on each network are in the same
+// subnet
+func (g *Graph) checkSubnets(r *Report, ips []*edgeIP) {
+	// network -> ip key -> edge IPs
+	byNetwork := map[int]map[string][]*edgeIP{}
+
+	for _, v := range ips {
+		if v.ipnet == nil {
+			continue
+		}
+
+		n := g.Nodes[v.nid].(*Endpoint).Edges[v.edge].N
+		if _, ok := g.Nodes[n].(*Network); !ok {
+			continue
+		}
+
+		if byNetwork[n] == nil {
+			byNetwork[n] = map[string][]*edgeIP{}
+		}
+		byNetwork[n][v.key] = append(byNetwork[n][v.key], v)
+	}
+
+	for n, byKey := range byNetwork {
+		for k, vs := range byKey {
+			cidr := "cidr"
+			if k == "ip6" {
+				cidr = "cidr6"
+			}
+
+			var subnet *net.IPNet
+			source := fmt.Sprintf("%v of network %v", cidr, n)
+
+			if s, ok := g.Nodes[n].Data()[cidr]; ok {
+				if _, ipnet, err := net.ParseCIDR(s); err == nil {
+					subnet = ipnet
+				}
+			}
+
+			if subnet == nil {
+				subnet = commonSubnet(vs)
+				source = fmt.Sprintf("the subnet used by most of network %v", n)
+			}
+
+			for _, v := range vs {
+				if !subnet.Contains(v.ip) {
+					r.add(v.nid, v.edge, SEVERITY_ERROR, "subnet-mismatch", "%v %v is not in %v, %v", k, v.s, subnet, source)
+				}
+			}
+		}
+	}
+}
+
+// commonSubnet returns the subnet used by the most edges, ties are broken by
+// the order of the edges
+func commonSubnet(vs []*edgeIP) *net.IPNet {
+	counts := map[string]int{}
+	for _, v := range vs {
+		counts[v.ipnet.String()]++
+	}
+
+	var res *net.IPNet
+	for _, v := range vs {
+		if res == nil || counts[v.ipnet.String()] > counts[res.String()] {
+			res = v.ipnet
+		}
+	}
+
+	return res
+}
+
+// checkRoutes checks that routers have IPs and that default routes are
+// reachable
+func (g *Graph) checkRoutes(r *Report, ids []int, ips []*edgeIP) {
+	byNode := map[int][]*edgeIP{}
+	for _, v := range ips {
+		byNode[v.nid] = append(byNode[v.nid], v)
+	}
+
+	for _, id := range ids {
+		e, ok := g.Nodes[id].(*Endpoint)
+		if !ok {
+			continue
+		}
+
+		if e.D["router"] == "true" && len(byNode[id]) == 0 {
+			r.add(id, -1, SEVERITY_ERROR, "router-no-ip", "router has no IPs")
+		}
+
+		s, ok := e.D["default_route"]
+		if !ok {
+			continue
+		}
+
+		ip := net.ParseIP(s)
+		if ip == nil {
+			r.add(id, -1, SEVERITY_ERROR, "default-route", "invalid default_route: %v", s)
+			continue
+		}
+
+		// if any of the IPs don't have a prefix length, the default route
+		// may be in its subnet
+		var found, unknown bool
+		for _, v := range byNode[id] {
+			if v.ipnet == nil {
+				unknown = true
+			} else if v.ipnet.Contains(ip) {
+				found = true
+			}
+		}
+
+		if !found && !unknown {
+			r.add(id, -1, SEVERITY_ERROR, "default-route", "default_route %v is not in any attached subnet", s)
+		}
+	}
+}
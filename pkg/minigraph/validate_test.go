@@ -0,0 +1,205 @@
+// Copyright 2018 National Technology & Engineering Solutions of Sandia, LLC
+// (NTESS). Under the terms of Contract DE-NA0003525 with NTESS, the U.S.
+// Government retains certain rights in this software.
+
+package minigraph
+
+import (
+	"testing"
+)
+
+// validGraph creates a router connecting two networks with a host on each
+func validGraph(t *testing.T) (*Graph, map[string]int) {
+	g := New()
+
+	ids := map[string]int{}
+
+	for _, v := range []string{"r", "h1", "h2"} {
+		ids[v] = g.NewEndpoint().ID()
+	}
+	for _, v := range []string{"n1", "n2"} {
+		ids[v] = g.NewNetwork().ID()
+	}
+
+	g.Nodes[ids["r"]].Data()["router"] = "true"
+	g.Nodes[ids["n1"]].Data()["cidr"] = "10.0.1.0/24"
+
+	for i, v := range []struct {
+		e, n, ip string
+	}{
+		{"r", "n1", "10.0.1.1/24"},
+		{"r", "n2", "10.0.2.1/24"},
+		{"h1", "n1", "10.0.1.2/24"},
+		{"h2", "n2", "10.0.2.2/24"},
+	} {
+		e := g.Nodes[ids[v.e]].(*Endpoint)
+		edge := e.NewEdge()
+		edge.D["ip"] = v.ip
+		edge.D["mac"] = "00:00:00:00:00:0" + string(rune('0'+i))
+
+		if err := g.Connect(e, g.Nodes[ids[v.n]], edge); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g.Nodes[ids["h1"]].Data()["default_route"] = "10.0.1.1"
+	g.Nodes[ids["h2"]].Data()["default_route"] = "10.0.2.1"
+
+	return g, ids
+}
+
+// checks returns the checks that found problems for each node
+func checks(r *Report) map[int][]string {
+	res := map[int][]string{}
+	for _, p := range r.Problems {
+		res[p.NID] = append(res[p.NID], p.Check)
+	}
+
+	return res
+}
+
+func TestValidate(t *testing.T) {
+	g, ids := validGraph(t)
+
+	if r := g.Validate(); len(r.Problems) != 0 {
+		t.Fatalf("expected no problems, got %v", r.Problems)
+	}
+
+	r := g.Nodes[ids["r"]].(*Endpoint)
+	h1 := g.Nodes[ids["h1"]].(*Endpoint)
+	h2 := g.Nodes[ids["h2"]].(*Endpoint)
+
+	// same MAC as h1 with different formatting
+	h2.Edges[0].D["mac"] = "00-00-00-00-00-02"
+	// same IP as the router, and not in n2's subnet
+	h2.Edges[0].D["ip"] = "10.0.1.1/24"
+	h2.D["default_route"] = "10.0.2.1"
+
+	r.Edges[1].D["ip"] = "10.0.2.300/24"
+	h1.Edges[0].D["ip"] = "10.0.1.2"
+
+	report := g.Validate()
+
+	want := map[int][]string{
+		ids["r"]:  {"duplicate-ip", "invalid-ip"},
+		ids["h1"]: {"duplicate-mac", "no-prefix"},
+		ids["h2"]: {"default-route", "duplicate-mac", "duplicate-ip"},
+	}
+
+	got := checks(report)
+	for id, v := range want {
+		if len(got[id]) != len(v) {
+			t.Errorf("node %v: expected %v, got %v", id, v, got[id])
+			continue
+		}
+
+		for _, check := range v {
+			var found bool
+			for _, check2 := range got[id] {
+				found = found || check == check2
+			}
+
+			if !found {
+				t.Errorf("node %v: expected %v, got %v", id, v, got[id])
+			}
+		}
+	}
+
+	if report.Warnings != 1 || report.Errors != 6 {
+		t.Errorf("expected 6 errors and 1 warning, got %+v", report)
+	}
+
+	if len(g.Repairs()) != 0 {
+		t.Errorf("expected no repairs")
+	}
+}
+
+func TestValidateSubnet(t *testing.T) {
+	g, ids := validGraph(t)
+
+	// n1 has a cidr, n2 uses the common subnet
+	g.Nodes[ids["h1"]].(*Endpoint).Edges[0].D["ip"] = "10.0.3.2/24"
+	g.Nodes[ids["h2"]].(*Endpoint).Edges[0].D["ip"] = "10.0.3.2/24"
+
+	got := checks(g.Validate())
+
+	for _, v := range []string{"h1", "h2"} {
+		if len(got[ids[v]]) != 3 {
+			t.Errorf("%v: expected subnet-mismatch, duplicate-ip, and default-route, got %v", v, got[ids[v]])
+		}
+	}
+
+	if len(got[ids["r"]]) != 0 {
+		t.Errorf("expected router to be valid, got %v", got[ids["r"]])
+	}
+
+	// routers need IPs
+	r := g.Nodes[ids["r"]].(*Endpoint)
+	for _, edge := range r.Edges {
+		delete(edge.D, "ip")
+	}
+
+	got = checks(g.Validate())
+	if len(got[ids["r"]]) != 1 || got[ids["r"]][0] != "router-no-ip" {
+		t.Errorf("expected router-no-ip, got %v", got[ids["r"]])
+	}
+}
+
+func TestRepairs(t *testing.T) {
+	g, ids := validGraph(t)
+
+	h1 := g.Nodes[ids["h1"]].(*Endpoint)
+	n1 := g.Nodes[ids["n1"]].(*Network)
+	n2 := g.Nodes[ids["n2"]].(*Network)
+
+	// dangling edge
+	edge := h1.NewEdge()
+	edge.N = 1000
+	// duplicate link
+	edge = h1.NewEdge()
+	edge.N = ids["n1"]
+
+	// dangling endpoint and duplicate endpoint
+	n1.Endpoints = append(n1.Endpoints, 1001, ids["r"])
+	// missing endpoint
+	n2.Endpoints = n2.Endpoints[:1]
+
+	report := g.Validate()
+
+	var fixable int
+	for _, p := range report.Problems {
+		if !p.Fixable {
+			t.Errorf("expected fixable problem, got %v", p)
+		}
+		fixable++
+	}
+
+	if fixable != 5 {
+		t.Errorf("expected 5 problems, got %v", report.Problems)
+	}
+
+	repairs := g.Repairs()
+	if len(repairs) != 3 {
+		t.Fatalf("expected 3 repairs, got %v", repairs)
+	}
+
+	// repairs are copies
+	if h1.Edges[1].N != 1000 {
+		t.Errorf("graph modified by Repairs")
+	}
+
+	for _, n := range repairs {
+		if _, err := g.Update(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if r := g.Validate(); len(r.Problems) != 0 {
+		t.Errorf("expected no problems after repair, got %v", r.Problems)
+	}
+
+	n2 = g.Nodes[ids["n2"]].(*Network)
+	if len(n2.Endpoints) != 2 {
+		t.Errorf("expected n2 to have both endpoints, got %v", n2.Endpoints)
+	}
+}